@@ -0,0 +1,181 @@
+package collection
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidCollection = errors.New("invalid collection")
+var ErrDuplicateName = errors.New("collection name already exists")
+var ErrUnknownSource = errors.New("unknown source")
+
+// maxNameLength bounds a collection name, which agents pass to qurio_search.
+const maxNameLength = 100
+
+// Collection is a named group of sources that searches can be scoped to.
+type Collection struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	SourceIDs   []string  `json:"source_ids"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type Repository interface {
+	// Save creates a collection with its initial sources.
+	Save(ctx context.Context, c *Collection) error
+	List(ctx context.Context) ([]Collection, error)
+	Get(ctx context.Context, id string) (*Collection, error)
+	GetByName(ctx context.Context, name string) (*Collection, error)
+	// Update saves a collection's name and description.
+	Update(ctx context.Context, c *Collection) error
+	Delete(ctx context.Context, id string) error
+	AddSources(ctx context.Context, id string, sourceIDs []string) error
+	RemoveSource(ctx context.Context, id, sourceID string) error
+	// CountSources counts the live sources among ids.
+	CountSources(ctx context.Context, ids []string) (int, error)
+}
+
+type Service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+func (s *Service) Create(ctx context.Context, c *Collection) error {
+	name, err := s.checkName(ctx, c.Name, "")
+	if err != nil {
+		return err
+	}
+	c.Name = name
+	c.Description = strings.TrimSpace(c.Description)
+	if c.SourceIDs, err = s.checkSources(ctx, c.SourceIDs); err != nil {
+		return err
+	}
+	if err := s.repo.Save(ctx, c); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "collection created", "collection_id", c.ID, "name", c.Name, "sources", len(c.SourceIDs))
+	return nil
+}
+
+func (s *Service) List(ctx context.Context) ([]Collection, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *Service) Get(ctx context.Context, id string) (*Collection, error) {
+	return s.repo.Get(ctx, id)
+}
+
+// Update renames a collection or changes its description; nil fields are
+// kept.
+func (s *Service) Update(ctx context.Context, id string, name, description *string) (*Collection, error) {
+	c, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if name != nil {
+		if c.Name, err = s.checkName(ctx, *name, id); err != nil {
+			return nil, err
+		}
+	}
+	if description != nil {
+		c.Description = strings.TrimSpace(*description)
+	}
+	if err := s.repo.Update(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *Service) Delete(ctx context.Context, id string) error {
+	return s.repo.Delete(ctx, id)
+}
+
+// AddSources adds sources to a collection; sources already in it are kept.
+func (s *Service) AddSources(ctx context.Context, id string, sourceIDs []string) (*Collection, error) {
+	if _, err := s.repo.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	ids, err := s.checkSources(ctx, sourceIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("%w: source_ids is required", ErrInvalidCollection)
+	}
+	if err := s.repo.AddSources(ctx, id, ids); err != nil {
+		return nil, err
+	}
+	return s.repo.Get(ctx, id)
+}
+
+func (s *Service) RemoveSource(ctx context.Context, id, sourceID string) error {
+	return s.repo.RemoveSource(ctx, id, sourceID)
+}
+
+// SourceIDs returns the live sources of the collection called name.
+func (s *Service) SourceIDs(ctx context.Context, name string) ([]string, error) {
+	c, err := s.repo.GetByName(ctx, strings.TrimSpace(name))
+	if err != nil {
+		return nil, err
+	}
+	return c.SourceIDs, nil
+}
+
+// checkName validates a collection name, which must not be taken by a
+// collection other than self.
+func (s *Service) checkName(ctx context.Context, name, self string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("%w: name is required", ErrInvalidCollection)
+	}
+	if len(name) > maxNameLength {
+		return "", fmt.Errorf("%w: name is longer than %d characters", ErrInvalidCollection, maxNameLength)
+	}
+	existing, err := s.repo.GetByName(ctx, name)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return name, nil
+	case err != nil:
+		return "", err
+	case existing.ID != self:
+		return "", ErrDuplicateName
+	}
+	return name, nil
+}
+
+// checkSources dedupes ids and makes sure each names a live source.
+func (s *Service) checkSources(ctx context.Context, ids []string) ([]string, error) {
+	unique := []string{}
+	for _, id := range ids {
+		if _, err := uuid.Parse(id); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownSource, id)
+		}
+		if !slices.Contains(unique, id) {
+			unique = append(unique, id)
+		}
+	}
+	if len(unique) == 0 {
+		return unique, nil
+	}
+	n, err := s.repo.CountSources(ctx, unique)
+	if err != nil {
+		return nil, err
+	}
+	if n != len(unique) {
+		return nil, fmt.Errorf("%w: %d of %d sources not found", ErrUnknownSource, len(unique)-n, len(unique))
+	}
+	return unique, nil
+}
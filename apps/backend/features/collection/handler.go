@@ -0,0 +1,185 @@
+package collection
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"qurio/apps/backend/internal/middleware"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(s *Service) *Handler {
+	return &Handler{service: s}
+}
+
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name        string   `json:"name"`
+		Description string   `json:"description"`
+		SourceIDs   []string `json:"source_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(r.Context(), w, "VALIDATION_ERROR", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c := &Collection{Name: req.Name, Description: req.Description, SourceIDs: req.SourceIDs}
+	if err := h.service.Create(r.Context(), c); err != nil {
+		h.writeServiceError(r.Context(), w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": c})
+}
+
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	collections, err := h.service.List(r.Context())
+	if err != nil {
+		h.writeError(r.Context(), w, "INTERNAL_ERROR", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": collections,
+		"meta": map[string]int{"count": len(collections)},
+	})
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.pathID(w, r, "id", "Collection not found")
+	if !ok {
+		return
+	}
+	c, err := h.service.Get(r.Context(), id)
+	if err != nil {
+		h.writeServiceError(r.Context(), w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": c})
+}
+
+// Update renames a collection or changes its description.
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.pathID(w, r, "id", "Collection not found")
+	if !ok {
+		return
+	}
+	var req struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(r.Context(), w, "VALIDATION_ERROR", err.Error(), http.StatusBadRequest)
+		return
+	}
+	c, err := h.service.Update(r.Context(), id, req.Name, req.Description)
+	if err != nil {
+		h.writeServiceError(r.Context(), w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": c})
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.pathID(w, r, "id", "Collection not found")
+	if !ok {
+		return
+	}
+	if err := h.service.Delete(r.Context(), id); err != nil {
+		h.writeServiceError(r.Context(), w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// AddSources adds the sources in source_ids to a collection.
+func (h *Handler) AddSources(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.pathID(w, r, "id", "Collection not found")
+	if !ok {
+		return
+	}
+	var req struct {
+		SourceIDs []string `json:"source_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(r.Context(), w, "VALIDATION_ERROR", err.Error(), http.StatusBadRequest)
+		return
+	}
+	c, err := h.service.AddSources(r.Context(), id, req.SourceIDs)
+	if err != nil {
+		h.writeServiceError(r.Context(), w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": c})
+}
+
+func (h *Handler) RemoveSource(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.pathID(w, r, "id", "Collection not found")
+	if !ok {
+		return
+	}
+	sourceID, ok := h.pathID(w, r, "sourceId", "Source not in collection")
+	if !ok {
+		return
+	}
+	if err := h.service.RemoveSource(r.Context(), id, sourceID); err != nil {
+		h.writeServiceError(r.Context(), w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// pathID reads a UUID path value; anything else can't name a row, so it's
+// reported as not found.
+func (h *Handler) pathID(w http.ResponseWriter, r *http.Request, name, notFound string) (string, bool) {
+	id := r.PathValue(name)
+	if _, err := uuid.Parse(id); err != nil {
+		h.writeError(r.Context(), w, "NOT_FOUND", notFound, http.StatusNotFound)
+		return "", false
+	}
+	return id, true
+}
+
+func (h *Handler) writeServiceError(ctx context.Context, w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		h.writeError(ctx, w, "NOT_FOUND", "Collection not found", http.StatusNotFound)
+	case errors.Is(err, ErrInvalidCollection), errors.Is(err, ErrUnknownSource):
+		h.writeError(ctx, w, "VALIDATION_ERROR", err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrDuplicateName):
+		h.writeError(ctx, w, "CONFLICT", err.Error(), http.StatusConflict)
+	default:
+		slog.ErrorContext(ctx, "collection operation failed", "error", err)
+		h.writeError(ctx, w, "INTERNAL_ERROR", "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) writeError(ctx context.Context, w http.ResponseWriter, code, message string, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	resp := map[string]interface{}{
+		"error": map[string]string{
+			"code":    code,
+			"message": message,
+		},
+		"correlationId": middleware.GetCorrelationID(ctx),
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,239 @@
+package collection_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"qurio/apps/backend/features/collection"
+)
+
+// MockRepo implements collection.Repository
+type MockRepo struct {
+	mock.Mock
+}
+
+func (m *MockRepo) Save(ctx context.Context, c *collection.Collection) error {
+	args := m.Called(ctx, c)
+	return args.Error(0)
+}
+func (m *MockRepo) List(ctx context.Context) ([]collection.Collection, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]collection.Collection), args.Error(1)
+}
+func (m *MockRepo) Get(ctx context.Context, id string) (*collection.Collection, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*collection.Collection), args.Error(1)
+}
+func (m *MockRepo) GetByName(ctx context.Context, name string) (*collection.Collection, error) {
+	args := m.Called(ctx, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*collection.Collection), args.Error(1)
+}
+func (m *MockRepo) Update(ctx context.Context, c *collection.Collection) error {
+	args := m.Called(ctx, c)
+	return args.Error(0)
+}
+func (m *MockRepo) Delete(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+func (m *MockRepo) AddSources(ctx context.Context, id string, sourceIDs []string) error {
+	args := m.Called(ctx, id, sourceIDs)
+	return args.Error(0)
+}
+func (m *MockRepo) RemoveSource(ctx context.Context, id, sourceID string) error {
+	args := m.Called(ctx, id, sourceID)
+	return args.Error(0)
+}
+func (m *MockRepo) CountSources(ctx context.Context, ids []string) (int, error) {
+	args := m.Called(ctx, ids)
+	return args.Int(0), args.Error(1)
+}
+
+const (
+	collectionID = "7d3c9a52-5f0e-4a8b-9d0e-1b2c3d4e5f60"
+	sourceA      = "0b1f6c1e-2a3b-4c5d-8e9f-0a1b2c3d4e5f"
+	sourceB      = "1c2d3e4f-5a6b-4c7d-8e9f-a0b1c2d3e4f5"
+)
+
+func TestHandler_Create(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		handler := collection.NewHandler(collection.NewService(mockRepo))
+
+		mockRepo.On("GetByName", mock.Anything, "payments").Return(nil, sql.ErrNoRows)
+		mockRepo.On("CountSources", mock.Anything, []string{sourceA, sourceB}).Return(2, nil)
+		mockRepo.On("Save", mock.Anything, mock.MatchedBy(func(c *collection.Collection) bool {
+			return c.Name == "payments" && c.Description == "Payments team docs" && len(c.SourceIDs) == 2
+		})).Run(func(args mock.Arguments) {
+			args.Get(1).(*collection.Collection).ID = collectionID
+		}).Return(nil)
+
+		body := `{"name":" payments ","description":"Payments team docs","source_ids":["` + sourceA + `","` + sourceB + `","` + sourceA + `"]}`
+		req := httptest.NewRequest("POST", "/collections", strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		handler.Create(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var resp struct {
+			Data collection.Collection `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, collectionID, resp.Data.ID)
+		assert.Equal(t, []string{sourceA, sourceB}, resp.Data.SourceIDs)
+	})
+
+	t.Run("DuplicateName", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		handler := collection.NewHandler(collection.NewService(mockRepo))
+		mockRepo.On("GetByName", mock.Anything, "payments").Return(&collection.Collection{ID: collectionID, Name: "payments"}, nil)
+
+		req := httptest.NewRequest("POST", "/collections", strings.NewReader(`{"name":"payments"}`))
+		w := httptest.NewRecorder()
+
+		handler.Create(w, req)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+	})
+
+	t.Run("UnknownSource", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		handler := collection.NewHandler(collection.NewService(mockRepo))
+		mockRepo.On("GetByName", mock.Anything, "payments").Return(nil, sql.ErrNoRows)
+		mockRepo.On("CountSources", mock.Anything, []string{sourceA}).Return(0, nil)
+
+		req := httptest.NewRequest("POST", "/collections", strings.NewReader(`{"name":"payments","source_ids":["`+sourceA+`"]}`))
+		w := httptest.NewRecorder()
+
+		handler.Create(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("MissingName", func(t *testing.T) {
+		handler := collection.NewHandler(collection.NewService(new(MockRepo)))
+
+		req := httptest.NewRequest("POST", "/collections", strings.NewReader(`{"name":"  "}`))
+		w := httptest.NewRecorder()
+
+		handler.Create(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestHandler_Update(t *testing.T) {
+	mockRepo := new(MockRepo)
+	handler := collection.NewHandler(collection.NewService(mockRepo))
+
+	mockRepo.On("Get", mock.Anything, collectionID).Return(&collection.Collection{ID: collectionID, Name: "payments"}, nil)
+	// Keeping its own name isn't a conflict
+	mockRepo.On("GetByName", mock.Anything, "payments").Return(&collection.Collection{ID: collectionID, Name: "payments"}, nil)
+	mockRepo.On("Update", mock.Anything, mock.MatchedBy(func(c *collection.Collection) bool {
+		return c.Name == "payments" && c.Description == "Billing and payouts"
+	})).Return(nil)
+
+	req := httptest.NewRequest("PATCH", "/collections/"+collectionID, strings.NewReader(`{"name":"payments","description":"Billing and payouts"}`))
+	req.SetPathValue("id", collectionID)
+	w := httptest.NewRecorder()
+
+	handler.Update(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestHandler_AddSources(t *testing.T) {
+	mockRepo := new(MockRepo)
+	handler := collection.NewHandler(collection.NewService(mockRepo))
+
+	mockRepo.On("Get", mock.Anything, collectionID).Return(&collection.Collection{ID: collectionID, Name: "payments", SourceIDs: []string{sourceA}}, nil)
+	mockRepo.On("CountSources", mock.Anything, []string{sourceA}).Return(1, nil)
+	mockRepo.On("AddSources", mock.Anything, collectionID, []string{sourceA}).Return(nil)
+
+	req := httptest.NewRequest("POST", "/collections/"+collectionID+"/sources", strings.NewReader(`{"source_ids":["`+sourceA+`"]}`))
+	req.SetPathValue("id", collectionID)
+	w := httptest.NewRecorder()
+
+	handler.AddSources(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestHandler_RemoveSource(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		handler := collection.NewHandler(collection.NewService(mockRepo))
+		mockRepo.On("RemoveSource", mock.Anything, collectionID, sourceB).Return(nil)
+
+		req := httptest.NewRequest("DELETE", "/collections/"+collectionID+"/sources/"+sourceB, nil)
+		req.SetPathValue("id", collectionID)
+		req.SetPathValue("sourceId", sourceB)
+		w := httptest.NewRecorder()
+
+		handler.RemoveSource(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("NotAMember", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		handler := collection.NewHandler(collection.NewService(mockRepo))
+		mockRepo.On("RemoveSource", mock.Anything, collectionID, sourceB).Return(sql.ErrNoRows)
+
+		req := httptest.NewRequest("DELETE", "/collections/"+collectionID+"/sources/"+sourceB, nil)
+		req.SetPathValue("id", collectionID)
+		req.SetPathValue("sourceId", sourceB)
+		w := httptest.NewRecorder()
+
+		handler.RemoveSource(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestHandler_Get_InvalidID(t *testing.T) {
+	mockRepo := new(MockRepo)
+	handler := collection.NewHandler(collection.NewService(mockRepo))
+
+	req := httptest.NewRequest("GET", "/collections/payments", nil)
+	req.SetPathValue("id", "payments")
+	w := httptest.NewRecorder()
+
+	handler.Get(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockRepo.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+}
+
+func TestService_SourceIDs(t *testing.T) {
+	mockRepo := new(MockRepo)
+	svc := collection.NewService(mockRepo)
+	mockRepo.On("GetByName", mock.Anything, "payments").Return(&collection.Collection{Name: "payments", SourceIDs: []string{sourceA}}, nil)
+	mockRepo.On("GetByName", mock.Anything, "nope").Return(nil, sql.ErrNoRows)
+
+	ids, err := svc.SourceIDs(context.Background(), " payments")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{sourceA}, ids)
+
+	_, err = svc.SourceIDs(context.Background(), "nope")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -0,0 +1,135 @@
+package collection
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+type PostgresRepo struct {
+	db *sql.DB
+}
+
+func NewPostgresRepo(db *sql.DB) *PostgresRepo {
+	return &PostgresRepo{db: db}
+}
+
+// selectCollections reads collections with their live sources, oldest
+// member first.
+const selectCollections = `SELECT c.id, c.name, c.description, c.created_at, c.updated_at,
+          COALESCE((SELECT array_agg(cs.source_id::text ORDER BY cs.added_at) FROM collection_sources cs
+          JOIN sources s ON s.id = cs.source_id AND s.deleted_at IS NULL WHERE cs.collection_id = c.id), '{}')
+          FROM collections c`
+
+func scanCollection(row interface{ Scan(...any) error }) (*Collection, error) {
+	c := &Collection{}
+	if err := row.Scan(&c.ID, &c.Name, &c.Description, &c.CreatedAt, &c.UpdatedAt, pq.Array(&c.SourceIDs)); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (r *PostgresRepo) Save(ctx context.Context, c *Collection) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO collections (name, description) VALUES ($1, $2) RETURNING id, created_at, updated_at`
+	if err := tx.QueryRowContext(ctx, query, c.Name, c.Description).Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return err
+	}
+	if len(c.SourceIDs) > 0 {
+		if err := addSources(ctx, tx, c.ID, c.SourceIDs); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *PostgresRepo) List(ctx context.Context) ([]Collection, error) {
+	rows, err := r.db.QueryContext(ctx, selectCollections+` ORDER BY c.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collections := []Collection{}
+	for rows.Next() {
+		c, err := scanCollection(rows)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, *c)
+	}
+	return collections, rows.Err()
+}
+
+func (r *PostgresRepo) Get(ctx context.Context, id string) (*Collection, error) {
+	return scanCollection(r.db.QueryRowContext(ctx, selectCollections+` WHERE c.id = $1`, id))
+}
+
+func (r *PostgresRepo) GetByName(ctx context.Context, name string) (*Collection, error) {
+	return scanCollection(r.db.QueryRowContext(ctx, selectCollections+` WHERE c.name = $1`, name))
+}
+
+func (r *PostgresRepo) Update(ctx context.Context, c *Collection) error {
+	query := `UPDATE collections SET name = $1, description = $2, updated_at = NOW() WHERE id = $3 RETURNING updated_at`
+	return r.db.QueryRowContext(ctx, query, c.Name, c.Description, c.ID).Scan(&c.UpdatedAt)
+}
+
+func (r *PostgresRepo) Delete(ctx context.Context, id string) error {
+	return expectRow(r.db.ExecContext(ctx, `DELETE FROM collections WHERE id = $1`, id))
+}
+
+func (r *PostgresRepo) AddSources(ctx context.Context, id string, sourceIDs []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := addSources(ctx, tx, id, sourceIDs); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE collections SET updated_at = NOW() WHERE id = $1`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func addSources(ctx context.Context, tx *sql.Tx, id string, sourceIDs []string) error {
+	query := `INSERT INTO collection_sources (collection_id, source_id) SELECT $1, unnest($2::uuid[])
+	          ON CONFLICT DO NOTHING`
+	_, err := tx.ExecContext(ctx, query, id, pq.Array(sourceIDs))
+	return err
+}
+
+func (r *PostgresRepo) RemoveSource(ctx context.Context, id, sourceID string) error {
+	query := `DELETE FROM collection_sources WHERE collection_id = $1 AND source_id = $2`
+	return expectRow(r.db.ExecContext(ctx, query, id, sourceID))
+}
+
+func (r *PostgresRepo) CountSources(ctx context.Context, ids []string) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM sources WHERE id = ANY($1::uuid[]) AND deleted_at IS NULL`
+	err := r.db.QueryRowContext(ctx, query, pq.Array(ids)).Scan(&count)
+	return count, err
+}
+
+// expectRow turns a statement that touched no rows into sql.ErrNoRows.
+func expectRow(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
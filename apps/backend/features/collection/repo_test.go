@@ -0,0 +1,94 @@
+package collection_test
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"qurio/apps/backend/features/collection"
+)
+
+func TestPostgresRepo_Save(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := collection.NewPostgresRepo(db)
+	c := &collection.Collection{Name: "payments", Description: "Payments team docs", SourceIDs: []string{sourceA}}
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO collections (name, description) VALUES ($1, $2) RETURNING id, created_at, updated_at")).
+		WithArgs("payments", "Payments team docs").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(collectionID, now, now))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO collection_sources (collection_id, source_id) SELECT $1, unnest($2::uuid[])")).
+		WithArgs(collectionID, pq.Array([]string{sourceA})).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.Save(context.Background(), c))
+	assert.Equal(t, collectionID, c.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresRepo_GetByName(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := collection.NewPostgresRepo(db)
+	query := regexp.QuoteMeta("SELECT c.id, c.name, c.description, c.created_at, c.updated_at") + ".+" + regexp.QuoteMeta("WHERE c.name = $1")
+
+	t.Run("Found", func(t *testing.T) {
+		mock.ExpectQuery(query).
+			WithArgs("payments").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "created_at", "updated_at", "source_ids"}).
+				AddRow(collectionID, "payments", "", time.Now(), time.Now(), "{"+sourceA+","+sourceB+"}"))
+
+		c, err := repo.GetByName(context.Background(), "payments")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{sourceA, sourceB}, c.SourceIDs)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		mock.ExpectQuery(query).WithArgs("nope").WillReturnError(sql.ErrNoRows)
+
+		_, err := repo.GetByName(context.Background(), "nope")
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+}
+
+func TestPostgresRepo_RemoveSource(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := collection.NewPostgresRepo(db)
+	query := regexp.QuoteMeta("DELETE FROM collection_sources WHERE collection_id = $1 AND source_id = $2")
+
+	mock.ExpectExec(query).WithArgs(collectionID, sourceA).WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.RemoveSource(context.Background(), collectionID, sourceA))
+
+	mock.ExpectExec(query).WithArgs(collectionID, sourceB).WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, repo.RemoveSource(context.Background(), collectionID, sourceB), sql.ErrNoRows)
+}
+
+func TestPostgresRepo_CountSources(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := collection.NewPostgresRepo(db)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM sources WHERE id = ANY($1::uuid[]) AND deleted_at IS NULL")).
+		WithArgs(pq.Array([]string{sourceA, sourceB})).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	n, err := repo.CountSources(context.Background(), []string{sourceA, sourceB})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
@@ -5,6 +5,14 @@ import (
 	"time"
 )
 
+// HandlerEmbedder marks jobs dead-lettered by the embedder consumer. Their
+// payload is an embed task and is retried on the embed topic.
+const HandlerEmbedder = "embedder"
+
+// HandlerResult marks jobs dead-lettered by the result consumer. Their
+// payload is an ingestion result and is retried on the result topic.
+const HandlerResult = "result"
+
 type Job struct {
 	ID        string          `json:"id"`
 	SourceID  string          `json:"source_id"`
@@ -46,7 +46,11 @@ func (s *Service) Retry(ctx context.Context, id string) error {
 	}
 
 	topic := config.TopicIngestWeb
-	if t, ok := payloadMap["type"].(string); ok && t == "file" {
+	if job.Handler == HandlerEmbedder {
+		topic = config.TopicIngestEmbed
+	} else if job.Handler == HandlerResult {
+		topic = config.TopicIngestResult
+	} else if t, ok := payloadMap["type"].(string); ok && t == "file" {
 		topic = config.TopicIngestFile
 	}
 
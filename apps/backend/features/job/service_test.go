@@ -138,3 +138,42 @@ func TestRetry_TopicSelection(t *testing.T) {
 		t.Errorf("Expected topic %s, got %s", config.TopicIngestFile, pub.LastTopic)
 	}
 }
+
+func TestRetry_EmbedderRoutesToEmbedTopic(t *testing.T) {
+	pub := &MockPublisher{}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := &MockEmbedJobRepo{}
+	service := NewService(repo, pub, logger)
+
+	err := service.Retry(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Equal(t, config.TopicIngestEmbed, pub.LastTopic)
+}
+
+func TestRetry_ResultRoutesToResultTopic(t *testing.T) {
+	pub := &MockPublisher{}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := &MockJobRepoForTopic{}
+	repo.Payload = []byte(`{"source_id": "src1", "url": "http://example.com", "type": "file"}`)
+	service := NewService(&resultJobRepo{repo}, pub, logger)
+
+	err := service.Retry(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Equal(t, config.TopicIngestResult, pub.LastTopic)
+}
+
+type resultJobRepo struct {
+	*MockJobRepoForTopic
+}
+
+func (m *resultJobRepo) Get(ctx context.Context, id string) (*Job, error) {
+	return &Job{ID: id, Handler: HandlerResult, Payload: m.Payload}, nil
+}
+
+type MockEmbedJobRepo struct {
+	MockJobRepoForTopic
+}
+
+func (m *MockEmbedJobRepo) Get(ctx context.Context, id string) (*Job, error) {
+	return &Job{ID: id, Handler: HandlerEmbedder, Payload: []byte(`{"source_id": "src1", "content": "chunk"}`)}, nil
+}
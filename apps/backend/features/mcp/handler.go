@@ -3,13 +3,16 @@ package mcp
 import (
 	"context"
 	"crypto/sha256"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 
 	"qurio/apps/backend/features/source"
 	"qurio/apps/backend/internal/retrieval"
@@ -28,9 +31,15 @@ type SourceManager interface {
 	Upload(ctx context.Context, path string, hash string, name string) (*source.Source, error)
 }
 
+// CollectionResolver expands a collection name to its sources.
+type CollectionResolver interface {
+	SourceIDs(ctx context.Context, name string) ([]string, error)
+}
+
 type Handler struct {
-	retriever Retriever
-	sourceMgr SourceManager
+	retriever   Retriever
+	sourceMgr   SourceManager
+	collections CollectionResolver
 }
 
 func NewHandler(r Retriever, s SourceManager) *Handler {
@@ -40,6 +49,11 @@ func NewHandler(r Retriever, s SourceManager) *Handler {
 	}
 }
 
+// SetCollections enables scoping qurio_search to a collection.
+func (h *Handler) SetCollections(c CollectionResolver) {
+	h.collections = c
+}
+
 // JSON-RPC Request types
 type JSONRPCRequest struct {
 	JSONRPC string          `json:"jsonrpc"`
@@ -54,11 +68,13 @@ type CallParams struct {
 }
 
 type SearchArgs struct {
-	Query    string                 `json:"query"`
-	Alpha    *float32               `json:"alpha,omitempty"`
-	Limit    *int                   `json:"limit,omitempty"`
-	SourceID *string                `json:"source_id,omitempty"`
-	Filters  map[string]interface{} `json:"filters,omitempty"`
+	Query      string                 `json:"query"`
+	Alpha      *float32               `json:"alpha,omitempty"`
+	Limit      *int                   `json:"limit,omitempty"`
+	SourceID   *string                `json:"source_id,omitempty"`
+	Version    *string                `json:"version,omitempty"`
+	Collection *string                `json:"collection,omitempty"`
+	Filters    map[string]interface{} `json:"filters,omitempty"`
 }
 
 type FetchPageArgs struct {
@@ -150,13 +166,23 @@ ARGUMENT GUIDE:
 - Max: 50
 
 [Filters: Metadata Filtering]
-- type: Filter by content type (e.g., "code", "prose", "api", "config").
+- type: Filter by content type (e.g., "code", "prose", "api", "config", "table").
 - language: Filter by language (e.g., "go", "python", "json").
+- section: Filter by heading breadcrumb, or JSON path for JSON/YAML/OpenAPI uploads; matches chunks whose section contains all given words (e.g., "Webhooks Signatures").
+
+[Version: Versioned Sources]
+- Without a version only the current content of each source is searched.
+- version="v1.x" searches that version, including snapshots kept from earlier syncs. See qurio_list_sources for each source's current version.
+
+[Collection: Source Groups]
+- collection="backend" searches only the sources in that collection. See qurio_list_sources for the collections each source belongs to.
 
 USAGE EXAMPLES:
 - Specific: search(query="webhook signature", alpha=0.3)
 - Conceptual: search(query="how to handle errors", alpha=1.0)
-- Filtered: search(query="User struct", filters={"type": "code", "language": "go"})`,
+- Filtered: search(query="User struct", filters={"type": "code", "language": "go"})
+- Versioned: search(query="useEffect cleanup", source_id="src_react", version="v17")
+- Scoped: search(query="retry policy", collection="payments-team")`,
 						InputSchema: map[string]interface{}{
 							"type": "object",
 							"properties": map[string]interface{}{
@@ -180,9 +206,17 @@ USAGE EXAMPLES:
 									"type":        "string",
 									"description": "Filter results by source ID",
 								},
+								"version": map[string]string{
+									"type":        "string",
+									"description": "Search a specific source version (e.g. 'v1.x') instead of the current content",
+								},
+								"collection": map[string]string{
+									"type":        "string",
+									"description": "Search only the sources in this collection",
+								},
 								"filters": map[string]interface{}{
 									"type":        "object",
-									"description": "Metadata filters (e.g. type='code', language='go')",
+									"description": "Metadata filters (e.g. type='code', language='go', section='Webhooks')",
 								},
 							},
 							"required": []string{"query"},
@@ -305,6 +339,32 @@ qurio_ingest(content="# My Note\nImportant info...", name="meeting_notes_2024",
 				}
 				args.Filters["sourceId"] = *args.SourceID
 			}
+			if args.Version != nil && *args.Version != "" {
+				if args.Filters == nil {
+					args.Filters = make(map[string]interface{})
+				}
+				args.Filters["version"] = *args.Version
+			}
+
+			if args.Collection != nil && *args.Collection != "" {
+				ids, errResp := h.collectionSources(ctx, req.ID, *args.Collection, args.SourceID)
+				if errResp != nil {
+					return errResp
+				}
+				if len(ids) == 0 {
+					return &JSONRPCResponse{
+						JSONRPC: "2.0",
+						ID:      req.ID,
+						Result: ToolResult{
+							Content: []ToolContent{{Type: "text", Text: "No results found."}},
+						},
+					}
+				}
+				if args.Filters == nil {
+					args.Filters = make(map[string]interface{})
+				}
+				args.Filters["sourceId"] = ids
+			}
 
 			opts := &retrieval.SearchOptions{
 				Alpha:   args.Alpha,
@@ -334,9 +394,18 @@ qurio_ingest(content="# My Note\nImportant info...", name="meeting_notes_2024",
 					if res.Language != "" {
 						textResult += fmt.Sprintf("Language: %s\n", res.Language)
 					}
+					if res.Section != "" {
+						textResult += fmt.Sprintf("Section: %s\n", res.Section)
+					}
+					if res.Symbol != "" {
+						textResult += fmt.Sprintf("Symbol: %s\n", res.Symbol)
+					}
 					if res.SourceID != "" {
 						textResult += fmt.Sprintf("SourceID: %s\n", res.SourceID)
 					}
+					if res.Version != "" {
+						textResult += fmt.Sprintf("Version: %s\n", res.Version)
+					}
 
 					textResult += fmt.Sprintf("Content:\n%s\n", res.Content)
 
@@ -391,10 +460,13 @@ qurio_ingest(content="# My Note\nImportant info...", name="meeting_notes_2024",
 			}
 
 			type SimpleSource struct {
-				ID   string `json:"id"`
-				Name string `json:"name"`
-				Type string `json:"type"`
-				URL  string `json:"url"`
+				ID          string   `json:"id"`
+				Name        string   `json:"name"`
+				Type        string   `json:"type"`
+				URL         string   `json:"url"`
+				Version     string   `json:"version,omitempty"`
+				Tags        []string `json:"tags,omitempty"`
+				Collections []string `json:"collections,omitempty"`
 			}
 
 			simpleSources := make([]SimpleSource, len(sources))
@@ -404,10 +476,13 @@ qurio_ingest(content="# My Note\nImportant info...", name="meeting_notes_2024",
 					name = s.URL
 				}
 				simpleSources[i] = SimpleSource{
-					ID:   s.ID,
-					Name: name,
-					Type: s.Type,
-					URL:  s.URL,
+					ID:          s.ID,
+					Name:        name,
+					Type:        s.Type,
+					URL:         s.URL,
+					Version:     s.Version,
+					Tags:        s.Tags,
+					Collections: s.Collections,
 				}
 			}
 
@@ -676,6 +751,32 @@ qurio_ingest(content="# My Note\nImportant info...", name="meeting_notes_2024",
 	return &resp
 }
 
+// collectionSources resolves the sources a collection-scoped search covers;
+// with sourceID set, only that source if it belongs to the collection.
+func (h *Handler) collectionSources(ctx context.Context, id interface{}, name string, sourceID *string) ([]string, *JSONRPCResponse) {
+	if h.collections == nil {
+		resp := makeErrorResponse(id, ErrInvalidParams, "Collections are not available")
+		return nil, &resp
+	}
+	ids, err := h.collections.SourceIDs(ctx, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		resp := makeErrorResponse(id, ErrInvalidParams, "Collection not found: "+name)
+		return nil, &resp
+	}
+	if err != nil {
+		slog.Error("collection lookup failed", "error", err, "collection", name)
+		resp := makeErrorResponse(id, ErrInternal, "Search failed: "+err.Error())
+		return nil, &resp
+	}
+	if sourceID != nil && *sourceID != "" {
+		if !slices.Contains(ids, *sourceID) {
+			return nil, nil
+		}
+		return []string{*sourceID}, nil
+	}
+	return ids, nil
+}
+
 func makeErrorResponse(id interface{}, code int, message string) JSONRPCResponse {
 	return JSONRPCResponse{
 		JSONRPC: "2.0",
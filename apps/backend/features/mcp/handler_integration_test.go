@@ -65,7 +65,9 @@ func TestMCPHandler_Integration(t *testing.T) {
 	retrievalSvc := retrieval.NewService(embedder, vectorStore, nil, settingsSvc, nil)
 	sourceRepo := source.NewPostgresRepo(s.DB)
 
-	handler := mcp.NewHandler(retrievalSvc, sourceRepo)
+	sourceSvc := source.NewService(sourceRepo, nil, vectorStore, settingsSvc)
+
+	handler := mcp.NewHandler(retrievalSvc, sourceSvc)
 
 	// 2. Seed Data
 	src := &source.Source{
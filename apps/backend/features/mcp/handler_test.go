@@ -2,6 +2,7 @@ package mcp
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -29,6 +30,9 @@ func (m *mockSourceMgr) List(ctx context.Context) ([]source.Source, error) {
 func (m *mockSourceMgr) GetPages(ctx context.Context, id string) ([]source.SourcePage, error) {
 	return []source.SourcePage{}, nil
 }
+func (m *mockSourceMgr) Upload(ctx context.Context, path string, hash string, name string) (*source.Source, error) {
+	return &source.Source{}, nil
+}
 
 func TestServeHTTP_Streaming(t *testing.T) {
 	handler := NewHandler(&mockRetriever{}, &mockSourceMgr{})
@@ -83,3 +87,96 @@ func TestServeHTTP_Error(t *testing.T) {
 		t.Fail()
 	}
 }
+
+type recordingRetriever struct {
+	mockRetriever
+	opts *retrieval.SearchOptions
+}
+
+func (m *recordingRetriever) Search(ctx context.Context, query string, opts *retrieval.SearchOptions) ([]retrieval.SearchResult, error) {
+	m.opts = opts
+	return []retrieval.SearchResult{{Content: "useEffect", SourceID: "src1", Version: "v17"}}, nil
+}
+
+func TestSearch_VersionFilter(t *testing.T) {
+	r := &recordingRetriever{}
+	handler := NewHandler(r, &mockSourceMgr{})
+
+	resp := handler.processRequest(context.Background(), JSONRPCRequest{
+		JSONRPC: "2.0",
+		Method:  "tools/call",
+		Params:  json.RawMessage(`{"name":"qurio_search","arguments":{"query":"cleanup","source_id":"src1","version":"v17"}}`),
+		ID:      1,
+	})
+
+	assert.Nil(t, resp.Error)
+	assert.Equal(t, map[string]interface{}{"sourceId": "src1", "version": "v17"}, r.opts.Filters)
+	result := resp.Result.(ToolResult)
+	assert.Contains(t, result.Content[0].Text, "Version: v17")
+}
+
+type mockCollections map[string][]string
+
+func (m mockCollections) SourceIDs(ctx context.Context, name string) ([]string, error) {
+	ids, ok := m[name]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return ids, nil
+}
+
+func TestSearch_CollectionFilter(t *testing.T) {
+	call := func(h *Handler, args string) *JSONRPCResponse {
+		return h.processRequest(context.Background(), JSONRPCRequest{
+			JSONRPC: "2.0",
+			Method:  "tools/call",
+			Params:  json.RawMessage(`{"name":"qurio_search","arguments":` + args + `}`),
+			ID:      1,
+		})
+	}
+	collections := mockCollections{"payments": {"src1", "src2"}, "empty": {}}
+
+	t.Run("ExpandsToSources", func(t *testing.T) {
+		r := &recordingRetriever{}
+		h := NewHandler(r, &mockSourceMgr{})
+		h.SetCollections(collections)
+
+		resp := call(h, `{"query":"retry policy","collection":"payments"}`)
+		assert.Nil(t, resp.Error)
+		assert.Equal(t, []string{"src1", "src2"}, r.opts.Filters["sourceId"])
+	})
+
+	t.Run("NarrowedBySourceID", func(t *testing.T) {
+		r := &recordingRetriever{}
+		h := NewHandler(r, &mockSourceMgr{})
+		h.SetCollections(collections)
+
+		call(h, `{"query":"retry policy","collection":"payments","source_id":"src2"}`)
+		assert.Equal(t, []string{"src2"}, r.opts.Filters["sourceId"])
+
+		// A source outside the collection matches nothing
+		r.opts = nil
+		resp := call(h, `{"query":"retry policy","collection":"payments","source_id":"src9"}`)
+		assert.Nil(t, r.opts)
+		assert.Contains(t, resp.Result.(ToolResult).Content[0].Text, "No results found.")
+	})
+
+	t.Run("EmptyCollection", func(t *testing.T) {
+		r := &recordingRetriever{}
+		h := NewHandler(r, &mockSourceMgr{})
+		h.SetCollections(collections)
+
+		resp := call(h, `{"query":"retry policy","collection":"empty"}`)
+		assert.Nil(t, r.opts)
+		assert.Contains(t, resp.Result.(ToolResult).Content[0].Text, "No results found.")
+	})
+
+	t.Run("UnknownCollection", func(t *testing.T) {
+		h := NewHandler(&recordingRetriever{}, &mockSourceMgr{})
+		h.SetCollections(collections)
+
+		resp := call(h, `{"query":"retry policy","collection":"nope"}`)
+		assert.NotNil(t, resp.Error)
+		assert.Contains(t, resp.Error.(map[string]interface{})["message"], "Collection not found")
+	})
+}
@@ -0,0 +1,57 @@
+package reconcile
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"qurio/apps/backend/internal/middleware"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(s *Service) *Handler {
+	return &Handler{service: s}
+}
+
+// Report runs a dry-run comparison and returns the drift.
+func (h *Handler) Report(w http.ResponseWriter, r *http.Request) {
+	h.run(w, r, false)
+}
+
+// Repair runs the comparison and deletes orphaned chunks.
+func (h *Handler) Repair(w http.ResponseWriter, r *http.Request) {
+	h.run(w, r, true)
+}
+
+func (h *Handler) run(w http.ResponseWriter, r *http.Request, deleteOrphans bool) {
+	ctx := r.Context()
+
+	report, err := h.service.Run(ctx, deleteOrphans)
+	if err != nil {
+		slog.ErrorContext(ctx, "reconciliation failed", "error", err, "delete", deleteOrphans)
+		h.writeError(ctx, w, "INTERNAL_ERROR", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": report})
+}
+
+func (h *Handler) writeError(ctx context.Context, w http.ResponseWriter, code, message string, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	resp := map[string]interface{}{
+		"error": map[string]string{
+			"code":    code,
+			"message": message,
+		},
+		"correlationId": middleware.GetCorrelationID(ctx),
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,59 @@
+package reconcile
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"qurio/apps/backend/features/source"
+)
+
+func TestHandler_Report(t *testing.T) {
+	repo := new(MockSourceRepo)
+	idx := new(MockChunkIndex)
+	setupDrift(repo, idx)
+	h := NewHandler(NewService(repo, idx))
+
+	rec := httptest.NewRecorder()
+	h.Report(rec, httptest.NewRequest("GET", "/admin/reconcile", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var body map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	data := body["data"].(map[string]interface{})
+	assert.Len(t, data["orphan_sources"], 1)
+	assert.Len(t, data["orphan_pages"], 1)
+	assert.Equal(t, false, data["deleted"])
+}
+
+func TestHandler_Repair(t *testing.T) {
+	repo := new(MockSourceRepo)
+	idx := new(MockChunkIndex)
+	setupDrift(repo, idx)
+	idx.On("DeleteChunksBySourceID", mock.Anything, "deleted").Return(nil)
+	idx.On("DeleteChunksByURL", mock.Anything, "web-1", "https://example.com/gone").Return(nil)
+	h := NewHandler(NewService(repo, idx))
+
+	rec := httptest.NewRecorder()
+	h.Repair(rec, httptest.NewRequest("POST", "/admin/reconcile", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	idx.AssertExpectations(t)
+}
+
+func TestHandler_Report_Error(t *testing.T) {
+	repo := new(MockSourceRepo)
+	idx := new(MockChunkIndex)
+	repo.On("List", mock.Anything).Return([]source.Source{}, errors.New("db down"))
+	h := NewHandler(NewService(repo, idx))
+
+	rec := httptest.NewRecorder()
+	h.Report(rec, httptest.NewRequest("GET", "/admin/reconcile", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), "INTERNAL_ERROR")
+}
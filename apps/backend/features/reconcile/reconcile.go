@@ -0,0 +1,259 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"time"
+
+	"qurio/apps/backend/features/source"
+	"qurio/apps/backend/internal/worker"
+)
+
+// SourceRepo is the Postgres side of the comparison.
+type SourceRepo interface {
+	List(ctx context.Context) ([]source.Source, error)
+	GetPages(ctx context.Context, sourceID string) ([]source.SourcePage, error)
+	ListVersions(ctx context.Context, sourceID string) ([]source.Version, error)
+}
+
+// ChunkIndex is the Weaviate side of the comparison.
+type ChunkIndex interface {
+	CountChunksPerSource(ctx context.Context) (map[string]int, error)
+	CountChunksPerURL(ctx context.Context, sourceID string) (map[string]int, error)
+	CountVersionChunksPerSource(ctx context.Context) (map[string]int, error)
+	CountVersionChunksPerVersion(ctx context.Context, sourceID string) (map[string]int, error)
+	DeleteChunksBySourceID(ctx context.Context, sourceID string) error
+	DeleteChunksByURL(ctx context.Context, sourceID, url string) error
+	DeleteVersionChunks(ctx context.Context, sourceID, version string) error
+}
+
+// OrphanPage is a URL that still has chunks but no source_pages row.
+type OrphanPage struct {
+	SourceID string `json:"source_id"`
+	URL      string `json:"url"`
+	Chunks   int    `json:"chunks"`
+}
+
+// OrphanSource is a sourceId that still has chunks but no active sources row.
+type OrphanSource struct {
+	SourceID string `json:"source_id"`
+	Chunks   int    `json:"chunks"`
+}
+
+// OrphanVersion is a retained version that still has chunks but no
+// source_versions row, e.g. after pruning or deleting its source.
+type OrphanVersion struct {
+	SourceID string `json:"source_id"`
+	Version  string `json:"version"`
+	Chunks   int    `json:"chunks"`
+}
+
+// Report describes the drift between Postgres and the vector store.
+type Report struct {
+	CheckedAt      time.Time       `json:"checked_at"`
+	OrphanSources  []OrphanSource  `json:"orphan_sources"`
+	OrphanPages    []OrphanPage    `json:"orphan_pages"`
+	OrphanVersions []OrphanVersion `json:"orphan_versions"`
+	// EmptySources are completed sources without any chunks, e.g. after a
+	// delete that removed the chunks but failed to soft-delete the row.
+	EmptySources []string `json:"empty_sources"`
+	// UncheckedSources have more pages than the index can count at once, so
+	// their pages weren't compared.
+	UncheckedSources []string `json:"unchecked_sources"`
+	Deleted          bool     `json:"deleted"`
+}
+
+// HasDrift reports whether anything is out of sync.
+func (r *Report) HasDrift() bool {
+	return len(r.OrphanSources) > 0 || len(r.OrphanPages) > 0 || len(r.OrphanVersions) > 0 || len(r.EmptySources) > 0
+}
+
+type Service struct {
+	repo  SourceRepo
+	index ChunkIndex
+}
+
+func NewService(repo SourceRepo, index ChunkIndex) *Service {
+	return &Service{repo: repo, index: index}
+}
+
+// Run compares both stores and, if deleteOrphans is set, removes orphaned chunks.
+// Sources that are still being ingested are skipped at page level because their
+// page table is rebuilt while the crawl runs.
+func (s *Service) Run(ctx context.Context, deleteOrphans bool) (*Report, error) {
+	report := &Report{
+		CheckedAt:        time.Now(),
+		OrphanSources:    []OrphanSource{},
+		OrphanPages:      []OrphanPage{},
+		OrphanVersions:   []OrphanVersion{},
+		EmptySources:     []string{},
+		UncheckedSources: []string{},
+	}
+
+	sources, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	perSource, err := s.index.CountChunksPerSource(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	active := make(map[string]source.Source, len(sources))
+	for _, src := range sources {
+		active[src.ID] = src
+	}
+
+	// 1. Chunks belonging to deleted or unknown sources
+	for sourceID, count := range perSource {
+		if _, ok := active[sourceID]; !ok {
+			report.OrphanSources = append(report.OrphanSources, OrphanSource{SourceID: sourceID, Chunks: count})
+		}
+	}
+	sort.Slice(report.OrphanSources, func(i, j int) bool {
+		return report.OrphanSources[i].SourceID < report.OrphanSources[j].SourceID
+	})
+
+	// 2. Per source: empty completed sources and chunks of dropped pages
+	for _, src := range sources {
+		if src.Status == "in_progress" {
+			continue
+		}
+		if src.Status == "completed" && perSource[src.ID] == 0 {
+			report.EmptySources = append(report.EmptySources, src.ID)
+			continue
+		}
+		if perSource[src.ID] == 0 {
+			continue
+		}
+
+		pages, err := s.repo.GetPages(ctx, src.ID)
+		if err != nil {
+			return nil, err
+		}
+		known := make(map[string]bool, len(pages)+1)
+		for _, p := range pages {
+			known[p.URL] = true
+		}
+		// An uploaded file has no page rows, its chunks carry its path
+		if src.Type == "file" {
+			known[src.URL] = true
+		}
+
+		perURL, err := s.index.CountChunksPerURL(ctx, src.ID)
+		if errors.Is(err, worker.ErrTooManyGroups) {
+			slog.WarnContext(ctx, "too many pages to reconcile", "source_id", src.ID)
+			report.UncheckedSources = append(report.UncheckedSources, src.ID)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for url, count := range perURL {
+			if !known[url] {
+				report.OrphanPages = append(report.OrphanPages, OrphanPage{SourceID: src.ID, URL: url, Chunks: count})
+			}
+		}
+	}
+	sort.Slice(report.OrphanPages, func(i, j int) bool {
+		a, b := report.OrphanPages[i], report.OrphanPages[j]
+		if a.SourceID != b.SourceID {
+			return a.SourceID < b.SourceID
+		}
+		return a.URL < b.URL
+	})
+
+	// 3. Retained versions of deleted sources or pruned versions
+	if err := s.findOrphanVersions(ctx, report, active); err != nil {
+		return nil, err
+	}
+
+	if report.HasDrift() {
+		slog.WarnContext(ctx, "vector store drift detected",
+			"orphan_sources", len(report.OrphanSources),
+			"orphan_pages", len(report.OrphanPages),
+			"orphan_versions", len(report.OrphanVersions),
+			"empty_sources", len(report.EmptySources))
+	}
+
+	if deleteOrphans {
+		if err := s.deleteOrphans(ctx, report); err != nil {
+			return report, err
+		}
+		report.Deleted = true
+	}
+
+	return report, nil
+}
+
+// findOrphanVersions compares the retained chunks against source_versions.
+// Sources that are still being ingested are skipped, their new version is
+// being written.
+func (s *Service) findOrphanVersions(ctx context.Context, report *Report, active map[string]source.Source) error {
+	perSource, err := s.index.CountVersionChunksPerSource(ctx)
+	if err != nil {
+		return err
+	}
+	for sourceID := range perSource {
+		src, ok := active[sourceID]
+		if ok && src.Status == "in_progress" {
+			continue
+		}
+		known := map[string]bool{}
+		if ok {
+			versions, err := s.repo.ListVersions(ctx, sourceID)
+			if err != nil {
+				return err
+			}
+			for _, v := range versions {
+				known[v.Version] = true
+			}
+		}
+
+		perVersion, err := s.index.CountVersionChunksPerVersion(ctx, sourceID)
+		if err != nil {
+			return err
+		}
+		for version, count := range perVersion {
+			if !known[version] {
+				report.OrphanVersions = append(report.OrphanVersions, OrphanVersion{SourceID: sourceID, Version: version, Chunks: count})
+			}
+		}
+	}
+	sort.Slice(report.OrphanVersions, func(i, j int) bool {
+		a, b := report.OrphanVersions[i], report.OrphanVersions[j]
+		if a.SourceID != b.SourceID {
+			return a.SourceID < b.SourceID
+		}
+		return a.Version < b.Version
+	})
+	return nil
+}
+
+func (s *Service) deleteOrphans(ctx context.Context, report *Report) error {
+	for _, o := range report.OrphanSources {
+		if err := s.index.DeleteChunksBySourceID(ctx, o.SourceID); err != nil {
+			slog.ErrorContext(ctx, "failed to delete orphan source chunks", "error", err, "source_id", o.SourceID)
+			return err
+		}
+		slog.InfoContext(ctx, "deleted orphan source chunks", "source_id", o.SourceID, "chunks", o.Chunks)
+	}
+	for _, o := range report.OrphanPages {
+		if err := s.index.DeleteChunksByURL(ctx, o.SourceID, o.URL); err != nil {
+			slog.ErrorContext(ctx, "failed to delete orphan page chunks", "error", err, "source_id", o.SourceID, "url", o.URL)
+			return err
+		}
+		slog.InfoContext(ctx, "deleted orphan page chunks", "source_id", o.SourceID, "url", o.URL, "chunks", o.Chunks)
+	}
+	for _, o := range report.OrphanVersions {
+		if err := s.index.DeleteVersionChunks(ctx, o.SourceID, o.Version); err != nil {
+			slog.ErrorContext(ctx, "failed to delete orphan version chunks", "error", err, "source_id", o.SourceID, "version", o.Version)
+			return err
+		}
+		slog.InfoContext(ctx, "deleted orphan version chunks", "source_id", o.SourceID, "version", o.Version, "chunks", o.Chunks)
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"qurio/apps/backend/features/source"
+	"qurio/apps/backend/internal/worker"
+)
+
+type MockSourceRepo struct{ mock.Mock }
+
+func (m *MockSourceRepo) List(ctx context.Context) ([]source.Source, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]source.Source), args.Error(1)
+}
+
+func (m *MockSourceRepo) GetPages(ctx context.Context, sourceID string) ([]source.SourcePage, error) {
+	args := m.Called(ctx, sourceID)
+	return args.Get(0).([]source.SourcePage), args.Error(1)
+}
+
+func (m *MockSourceRepo) ListVersions(ctx context.Context, sourceID string) ([]source.Version, error) {
+	args := m.Called(ctx, sourceID)
+	return args.Get(0).([]source.Version), args.Error(1)
+}
+
+type MockChunkIndex struct{ mock.Mock }
+
+func (m *MockChunkIndex) CountChunksPerSource(ctx context.Context) (map[string]int, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]int), args.Error(1)
+}
+
+func (m *MockChunkIndex) CountChunksPerURL(ctx context.Context, sourceID string) (map[string]int, error) {
+	args := m.Called(ctx, sourceID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]int), args.Error(1)
+}
+
+func (m *MockChunkIndex) CountVersionChunksPerSource(ctx context.Context) (map[string]int, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(map[string]int), args.Error(1)
+}
+
+func (m *MockChunkIndex) CountVersionChunksPerVersion(ctx context.Context, sourceID string) (map[string]int, error) {
+	args := m.Called(ctx, sourceID)
+	return args.Get(0).(map[string]int), args.Error(1)
+}
+
+func (m *MockChunkIndex) DeleteChunksBySourceID(ctx context.Context, sourceID string) error {
+	return m.Called(ctx, sourceID).Error(0)
+}
+
+func (m *MockChunkIndex) DeleteChunksByURL(ctx context.Context, sourceID, url string) error {
+	return m.Called(ctx, sourceID, url).Error(0)
+}
+
+func (m *MockChunkIndex) DeleteVersionChunks(ctx context.Context, sourceID, version string) error {
+	return m.Called(ctx, sourceID, version).Error(0)
+}
+
+func setupDrift(repo *MockSourceRepo, idx *MockChunkIndex) {
+	repo.On("List", mock.Anything).Return([]source.Source{
+		{ID: "web-1", Type: "web", Status: "completed"},
+		{ID: "web-2", Type: "web", Status: "in_progress"},
+		{ID: "file-1", Type: "file", URL: "/uploads/api.md", Status: "completed"},
+		{ID: "empty-1", Type: "web", Status: "completed"},
+	}, nil)
+	idx.On("CountChunksPerSource", mock.Anything).Return(map[string]int{
+		"web-1":   10,
+		"web-2":   4,
+		"file-1":  3,
+		"deleted": 7,
+	}, nil)
+	repo.On("GetPages", mock.Anything, "web-1").Return([]source.SourcePage{
+		{URL: "https://example.com"},
+	}, nil)
+	idx.On("CountChunksPerURL", mock.Anything, "web-1").Return(map[string]int{
+		"https://example.com":      8,
+		"https://example.com/gone": 2,
+	}, nil)
+	repo.On("GetPages", mock.Anything, "file-1").Return([]source.SourcePage{}, nil)
+	idx.On("CountChunksPerURL", mock.Anything, "file-1").Return(map[string]int{"/uploads/api.md": 3}, nil)
+	idx.On("CountVersionChunksPerSource", mock.Anything).Return(map[string]int{}, nil)
+}
+
+func TestService_Run_DetectsDrift(t *testing.T) {
+	repo := new(MockSourceRepo)
+	idx := new(MockChunkIndex)
+	setupDrift(repo, idx)
+
+	report, err := NewService(repo, idx).Run(context.Background(), false)
+	assert.NoError(t, err)
+	assert.Equal(t, []OrphanSource{{SourceID: "deleted", Chunks: 7}}, report.OrphanSources)
+	assert.Equal(t, []OrphanPage{{SourceID: "web-1", URL: "https://example.com/gone", Chunks: 2}}, report.OrphanPages)
+	assert.Equal(t, []string{"empty-1"}, report.EmptySources)
+	assert.False(t, report.Deleted)
+
+	// In-progress sources are not inspected page by page
+	repo.AssertNotCalled(t, "GetPages", mock.Anything, "web-2")
+	idx.AssertNotCalled(t, "DeleteChunksBySourceID", mock.Anything, mock.Anything)
+	idx.AssertNotCalled(t, "DeleteChunksByURL", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_Run_FileSourcesDrift(t *testing.T) {
+	repo := new(MockSourceRepo)
+	idx := new(MockChunkIndex)
+	repo.On("List", mock.Anything).Return([]source.Source{
+		{ID: "git-1", Type: "git", Status: "completed"},
+		{ID: "dir-1", Type: "directory", URL: "/srv/qurio/directories/notes", Status: "completed"},
+		{ID: "file-1", Type: "file", URL: "/uploads/new.md", Status: "completed"},
+	}, nil)
+	idx.On("CountChunksPerSource", mock.Anything).Return(map[string]int{"git-1": 3, "dir-1": 2, "file-1": 2}, nil)
+	repo.On("GetPages", mock.Anything, "git-1").Return([]source.SourcePage{{URL: "/uploads/git/git-1/README.md"}}, nil)
+	idx.On("CountChunksPerURL", mock.Anything, "git-1").Return(map[string]int{
+		"/uploads/git/git-1/README.md":  2,
+		"/uploads/git/git-1/removed.md": 1,
+	}, nil)
+	repo.On("GetPages", mock.Anything, "dir-1").Return([]source.SourcePage{{URL: "/srv/qurio/directories/notes/adr-1.md"}}, nil)
+	idx.On("CountChunksPerURL", mock.Anything, "dir-1").Return(map[string]int{"/srv/qurio/directories/notes/adr-1.md": 2}, nil)
+	// Chunks stored under another path than the uploaded file
+	repo.On("GetPages", mock.Anything, "file-1").Return([]source.SourcePage{}, nil)
+	idx.On("CountChunksPerURL", mock.Anything, "file-1").Return(map[string]int{"/uploads/new.md": 1, "/uploads/old.md": 1}, nil)
+	idx.On("CountVersionChunksPerSource", mock.Anything).Return(map[string]int{}, nil)
+
+	report, err := NewService(repo, idx).Run(context.Background(), false)
+	assert.NoError(t, err)
+	assert.Equal(t, []OrphanPage{
+		{SourceID: "file-1", URL: "/uploads/old.md", Chunks: 1},
+		{SourceID: "git-1", URL: "/uploads/git/git-1/removed.md", Chunks: 1},
+	}, report.OrphanPages)
+}
+
+func TestService_Run_DeletesOrphans(t *testing.T) {
+	repo := new(MockSourceRepo)
+	idx := new(MockChunkIndex)
+	setupDrift(repo, idx)
+	idx.On("DeleteChunksBySourceID", mock.Anything, "deleted").Return(nil)
+	idx.On("DeleteChunksByURL", mock.Anything, "web-1", "https://example.com/gone").Return(nil)
+
+	report, err := NewService(repo, idx).Run(context.Background(), true)
+	assert.NoError(t, err)
+	assert.True(t, report.Deleted)
+	idx.AssertExpectations(t)
+}
+
+func TestService_Run_NoDrift(t *testing.T) {
+	repo := new(MockSourceRepo)
+	idx := new(MockChunkIndex)
+	repo.On("List", mock.Anything).Return([]source.Source{{ID: "web-1", Type: "web", Status: "completed"}}, nil)
+	idx.On("CountChunksPerSource", mock.Anything).Return(map[string]int{"web-1": 1}, nil)
+	repo.On("GetPages", mock.Anything, "web-1").Return([]source.SourcePage{{URL: "https://a.com"}}, nil)
+	idx.On("CountChunksPerURL", mock.Anything, "web-1").Return(map[string]int{"https://a.com": 1}, nil)
+	idx.On("CountVersionChunksPerSource", mock.Anything).Return(map[string]int{"web-1": 2}, nil)
+	repo.On("ListVersions", mock.Anything, "web-1").Return([]source.Version{{Version: "v1"}}, nil)
+	idx.On("CountVersionChunksPerVersion", mock.Anything, "web-1").Return(map[string]int{"v1": 2}, nil)
+
+	report, err := NewService(repo, idx).Run(context.Background(), true)
+	assert.NoError(t, err)
+	assert.False(t, report.HasDrift())
+	idx.AssertNotCalled(t, "DeleteChunksBySourceID", mock.Anything, mock.Anything)
+}
+
+func TestService_Run_OrphanVersions(t *testing.T) {
+	repo := new(MockSourceRepo)
+	idx := new(MockChunkIndex)
+	repo.On("List", mock.Anything).Return([]source.Source{
+		{ID: "web-1", Type: "web", Status: "completed"},
+		{ID: "web-2", Type: "web", Status: "in_progress"},
+	}, nil)
+	idx.On("CountChunksPerSource", mock.Anything).Return(map[string]int{"web-1": 1}, nil)
+	repo.On("GetPages", mock.Anything, "web-1").Return([]source.SourcePage{{URL: "https://a.com"}}, nil)
+	idx.On("CountChunksPerURL", mock.Anything, "web-1").Return(map[string]int{"https://a.com": 1}, nil)
+
+	// v1 was pruned; the deleted source left only retained chunks behind
+	idx.On("CountVersionChunksPerSource", mock.Anything).Return(map[string]int{"web-1": 5, "web-2": 4, "deleted": 3}, nil)
+	repo.On("ListVersions", mock.Anything, "web-1").Return([]source.Version{{Version: "v2"}}, nil)
+	idx.On("CountVersionChunksPerVersion", mock.Anything, "web-1").Return(map[string]int{"v1": 2, "v2": 3}, nil)
+	idx.On("CountVersionChunksPerVersion", mock.Anything, "deleted").Return(map[string]int{"v7": 3}, nil)
+	idx.On("DeleteVersionChunks", mock.Anything, "deleted", "v7").Return(nil)
+	idx.On("DeleteVersionChunks", mock.Anything, "web-1", "v1").Return(nil)
+
+	report, err := NewService(repo, idx).Run(context.Background(), true)
+	assert.NoError(t, err)
+	assert.Equal(t, []OrphanVersion{
+		{SourceID: "deleted", Version: "v7", Chunks: 3},
+		{SourceID: "web-1", Version: "v1", Chunks: 2},
+	}, report.OrphanVersions)
+	assert.Empty(t, report.OrphanSources)
+	// The syncing source may be writing its new version
+	idx.AssertNotCalled(t, "CountVersionChunksPerVersion", mock.Anything, "web-2")
+	idx.AssertExpectations(t)
+}
+
+func TestService_Run_TooManyPages(t *testing.T) {
+	repo := new(MockSourceRepo)
+	idx := new(MockChunkIndex)
+	repo.On("List", mock.Anything).Return([]source.Source{{ID: "web-1", Type: "web", Status: "completed"}}, nil)
+	idx.On("CountChunksPerSource", mock.Anything).Return(map[string]int{"web-1": 20000}, nil)
+	repo.On("GetPages", mock.Anything, "web-1").Return([]source.SourcePage{}, nil)
+	idx.On("CountChunksPerURL", mock.Anything, "web-1").Return(nil, fmt.Errorf("%w: DocumentChunk by url", worker.ErrTooManyGroups))
+	idx.On("CountVersionChunksPerSource", mock.Anything).Return(map[string]int{}, nil)
+
+	report, err := NewService(repo, idx).Run(context.Background(), false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web-1"}, report.UncheckedSources)
+	assert.Empty(t, report.OrphanPages, "uncounted pages aren't reported missing")
+}
+
+func TestService_Run_IndexError(t *testing.T) {
+	repo := new(MockSourceRepo)
+	idx := new(MockChunkIndex)
+	repo.On("List", mock.Anything).Return([]source.Source{}, nil)
+	idx.On("CountChunksPerSource", mock.Anything).Return(nil, errors.New("weaviate down"))
+
+	_, err := NewService(repo, idx).Run(context.Background(), false)
+	assert.Error(t, err)
+}
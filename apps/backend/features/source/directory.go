@@ -0,0 +1,185 @@
+package source
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SetDirectoryRoots sets the directories directory sources may read below;
+// without any, directory sources are rejected.
+func (s *Service) SetDirectoryRoots(roots []string) {
+	s.directoryRoots = roots
+}
+
+// validateDirectory checks the settings only directory sources have. The
+// path is stored with its symlinks resolved.
+func validateDirectory(src *Source, roots []string) error {
+	if src.Type != "directory" {
+		return nil
+	}
+	if src.URL == "" || !filepath.IsAbs(src.URL) {
+		return fmt.Errorf("%w: directory must be an absolute path: %q", ErrInvalidCrawlScope, src.URL)
+	}
+	dir, err := resolveDirectory(filepath.Clean(src.URL), roots)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCrawlScope, err)
+	}
+	src.URL = dir
+	return validateGlobs(src)
+}
+
+// resolveDirectory resolves the symlinks of path and checks that it is a
+// directory below one of roots, so sources can't read the rest of the host.
+func resolveDirectory(path string, roots []string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("not a directory: %s", path)
+	}
+	for _, root := range roots {
+		root, err := filepath.EvalSymlinks(root)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(root, resolved)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("directory is outside the allowed roots: %s", path)
+}
+
+// directoryFile is a file of a directory source found by a scan.
+type directoryFile struct {
+	path    string
+	modTime time.Time
+}
+
+// scanDirectory walks a directory source's path for the files matching its
+// include globs and none of its exclude globs or exclusions, which match the
+// path relative to the directory. Excluded directories aren't descended into.
+func scanDirectory(src *Source) ([]directoryFile, error) {
+	info, err := os.Stat(src.URL)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("not a directory: %s", src.URL)
+	}
+	include, err := newGlobMatcher(src.IncludeGlobs)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := newGlobMatcher(src.ExcludeGlobs)
+	if err != nil {
+		return nil, err
+	}
+	exclusions := compileExclusions(src.Exclusions)
+
+	var files []directoryFile
+	err = filepath.WalkDir(src.URL, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable entries are skipped, the root itself was checked above
+			slog.Warn("failed to read directory entry", "source_id", src.ID, "path", path, "error", err)
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(src.URL, path)
+		if err != nil || rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if exclude.Match(rel) || exclude.Match(rel+"/") {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !include.Match(rel) || exclude.Match(rel) || matchesAny(exclusions, rel) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		files = append(files, directoryFile{path: path, modTime: info.ModTime()})
+		return nil
+	})
+	return files, err
+}
+
+// hashFile returns the SHA-256 of a file's content.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// syncDirectory scans a directory source and publishes a file task per
+// matching file. A file that completed before is kept completed without
+// being read again when its modification time is unchanged, or else when its
+// hash is; files that are gone stay stale and are removed, with their
+// chunks, once the sync completes.
+func (s *Service) syncDirectory(ctx context.Context, src *Source, previous []SourcePage) error {
+	// The roots or a symlink may have changed since the source was added
+	if _, err := resolveDirectory(src.URL, s.directoryRoots); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCrawlScope, err)
+	}
+	files, err := scanDirectory(src)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCrawlScope, err)
+	}
+	completed := make(map[string]SourcePage)
+	for _, p := range previous {
+		if p.Status == "completed" && p.FileHash != "" && src.chunkedAlike(p) {
+			completed[p.URL] = p
+		}
+	}
+
+	var pages []SourcePage
+	for _, f := range files {
+		// Page times round-trip through Postgres, which keeps microseconds
+		modTime := f.modTime.UTC().Truncate(time.Microsecond)
+		page := SourcePage{SourceID: src.ID, URL: f.path, Status: "processing", Depth: 1, ModTime: &modTime}
+		prev, done := completed[f.path]
+		if done && prev.ModTime != nil && prev.ModTime.Equal(modTime) {
+			page.Status, page.Change, page.FileHash = "completed", PageUnchanged, prev.FileHash
+			pages = append(pages, page)
+			continue
+		}
+		hash, err := hashFile(f.path)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to hash file, skipping it", "source_id", src.ID, "path", f.path, "error", err)
+			continue
+		}
+		page.FileHash = hash
+		if done && prev.FileHash == hash {
+			page.Status, page.Change = "completed", PageUnchanged
+		}
+		pages = append(pages, page)
+	}
+	return s.syncFiles(ctx, src, previous, pages)
+}
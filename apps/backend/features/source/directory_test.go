@@ -0,0 +1,222 @@
+package source
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"qurio/apps/backend/internal/config"
+	"qurio/apps/backend/internal/text"
+)
+
+// tempDir is t.TempDir with its symlinks resolved, as directory sources
+// store their path.
+func tempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	return dir
+}
+
+// writeFiles creates files under dir, with slash-separated names.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+func TestScanDirectory(t *testing.T) {
+	dir := tempDir(t)
+	writeFiles(t, dir, map[string]string{
+		"README.md":             "# Notes",
+		"adr/0001-use-go.md":    "# Use Go",
+		"adr/0002-draft.md":     "# Draft",
+		"adr/index.rst":         "ADRs",
+		"drafts/idea.md":        "# Idea",
+		"scratch.txt":           "todo",
+		"node_modules/x/doc.md": "# Vendored",
+	})
+	src := &Source{Type: "directory", URL: dir, ExcludeGlobs: []string{"drafts/**", "node_modules"}, Exclusions: []string{"draft"}}
+	require.NoError(t, validateDirectory(src, []string{dir}))
+
+	files, err := scanDirectory(src)
+	require.NoError(t, err)
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, f.path)
+		assert.False(t, f.modTime.IsZero())
+	}
+	assert.ElementsMatch(t, []string{
+		filepath.Join(dir, "README.md"),
+		filepath.Join(dir, "adr", "0001-use-go.md"),
+		filepath.Join(dir, "adr", "index.rst"),
+	}, paths)
+
+	_, err = scanDirectory(&Source{Type: "directory", URL: filepath.Join(dir, "missing"), IncludeGlobs: DefaultIncludeGlobs})
+	assert.Error(t, err)
+}
+
+func TestValidateDirectory(t *testing.T) {
+	root := tempDir(t)
+	outside := tempDir(t)
+	writeFiles(t, root, map[string]string{"notes/a.md": "# A", "file.md": "# File"})
+	roots := []string{root}
+
+	src := &Source{Type: "directory", URL: filepath.Join(root, "notes") + "/"}
+	assert.NoError(t, validateDirectory(src, roots))
+	assert.Equal(t, filepath.Join(root, "notes"), src.URL)
+	assert.Equal(t, DefaultIncludeGlobs, src.IncludeGlobs)
+
+	// Symlinks are resolved: in the roots is fine, out of them isn't
+	require.NoError(t, os.Symlink(filepath.Join(root, "notes"), filepath.Join(root, "alias")))
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "escape")))
+	src = &Source{Type: "directory", URL: filepath.Join(root, "alias")}
+	assert.NoError(t, validateDirectory(src, roots))
+	assert.Equal(t, filepath.Join(root, "notes"), src.URL)
+	assert.ErrorIs(t, validateDirectory(&Source{Type: "directory", URL: filepath.Join(root, "escape")}, roots), ErrInvalidCrawlScope)
+
+	for _, src := range []*Source{
+		{Type: "directory", URL: "notes"},
+		{Type: "directory", URL: outside},
+		{Type: "directory", URL: filepath.Join(root, "notes", "..", "..")},
+		{Type: "directory", URL: filepath.Join(root, "missing")},
+		{Type: "directory", URL: filepath.Join(root, "file.md")},
+		{Type: "directory", URL: root, ExcludeGlobs: []string{""}},
+	} {
+		assert.ErrorIs(t, validateDirectory(src, roots), ErrInvalidCrawlScope, src.URL)
+	}
+	// Without roots directory sources are disabled
+	assert.ErrorIs(t, validateDirectory(&Source{Type: "directory", URL: root}, nil), ErrInvalidCrawlScope)
+}
+
+func TestService_Create_Directory(t *testing.T) {
+	dir := tempDir(t)
+	writeFiles(t, dir, map[string]string{"a.md": "# A", "b.md": "# B", "c.go": "package c"})
+
+	mockRepo := new(MockRepository)
+	mockPub := new(MockPublisher)
+	svc := NewService(mockRepo, mockPub, nil, nil)
+	svc.SetDirectoryRoots([]string{dir})
+
+	mockRepo.On("ExistsByHash", mock.Anything, mock.Anything).Return(false, nil)
+	mockRepo.On("Save", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(1).(*Source).ID = "src1"
+	}).Return(nil)
+	mockRepo.On("BulkCreatePages", mock.Anything, mock.MatchedBy(func(pages []SourcePage) bool {
+		for _, p := range pages {
+			if p.Status != "processing" || p.ModTime == nil || len(p.FileHash) != 64 {
+				return false
+			}
+		}
+		return len(pages) == 2
+	})).Return([]string{filepath.Join(dir, "a.md"), filepath.Join(dir, "b.md")}, nil)
+	mockPub.On("Publish", config.TopicIngestFile, mock.Anything).Return(nil)
+	mockPub.On("Publish", config.TopicSourceSync, mock.Anything).Return(nil).Once()
+
+	src := &Source{Type: "directory", URL: dir, Name: "Notes"}
+	err := svc.Create(context.Background(), src)
+	assert.NoError(t, err)
+	// The scan waits for the queued sync
+	mockRepo.AssertNotCalled(t, "BulkCreatePages", mock.Anything, mock.Anything)
+
+	mockRepo.On("Get", mock.Anything, "src1").Return(src, nil)
+	err = svc.runSync(context.Background(), "src1", false)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{filepath.Join(dir, "a.md"), filepath.Join(dir, "b.md")}, publishedPaths(mockPub))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_Create_DirectoryMissing(t *testing.T) {
+	dir := tempDir(t)
+	mockRepo := new(MockRepository)
+	svc := NewService(mockRepo, nil, nil, nil)
+	svc.SetDirectoryRoots([]string{dir})
+
+	// Rejected before anything is saved
+	err := svc.Create(context.Background(), &Source{Type: "directory", URL: filepath.Join(dir, "missing"), Name: "Notes"})
+	assert.ErrorIs(t, err, ErrInvalidCrawlScope)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestService_RunSync_DirectoryOutsideRoots(t *testing.T) {
+	dir := tempDir(t)
+	mockRepo := new(MockRepository)
+	svc := NewService(mockRepo, nil, nil, nil)
+	svc.SetDirectoryRoots([]string{tempDir(t)})
+
+	// Added before the roots changed
+	mockRepo.On("Get", mock.Anything, "src1").Return(&Source{ID: "src1", Type: "directory", URL: dir, IncludeGlobs: DefaultIncludeGlobs}, nil)
+
+	err := svc.runSync(context.Background(), "src1", false)
+	assert.ErrorIs(t, err, ErrInvalidCrawlScope)
+	mockRepo.AssertNotCalled(t, "BulkCreatePages", mock.Anything, mock.Anything)
+}
+
+func TestService_ReSync_DirectoryIncremental(t *testing.T) {
+	dir := tempDir(t)
+	writeFiles(t, dir, map[string]string{"same.md": "same", "touched.md": "touched", "edited.md": "edited v2", "added.md": "new", "rechunked.md": "rechunked"})
+	path := func(name string) string { return filepath.Join(dir, name) }
+	modTime := func(name string) *time.Time {
+		info, err := os.Stat(path(name))
+		require.NoError(t, err)
+		mt := info.ModTime().UTC().Truncate(time.Microsecond)
+		return &mt
+	}
+	touchedHash, err := hashFile(path("touched.md"))
+	require.NoError(t, err)
+	earlier := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	key := text.DefaultChunkProfile().Key()
+
+	mockRepo := new(MockRepository)
+	mockPub := new(MockPublisher)
+	svc := NewService(mockRepo, mockPub, nil, nil)
+	svc.SetDirectoryRoots([]string{dir})
+
+	mockRepo.On("Get", mock.Anything, "src1").Return(&Source{ID: "src1", Type: "directory", URL: dir, IncludeGlobs: DefaultIncludeGlobs}, nil)
+	mockRepo.On("StartCrawl", mock.Anything, "src1").Return(nil)
+	mockRepo.On("GetPages", mock.Anything, "src1").Return([]SourcePage{
+		// Same mtime: not even hashed
+		{URL: path("same.md"), Status: "completed", ModTime: modTime("same.md"), FileHash: "stored", ChunkKey: key},
+		// Touched but the same content
+		{URL: path("touched.md"), Status: "completed", ModTime: &earlier, FileHash: touchedHash, ChunkKey: key},
+		{URL: path("edited.md"), Status: "completed", ModTime: &earlier, FileHash: "old", ChunkKey: key},
+		{URL: path("removed.md"), Status: "completed", ModTime: &earlier, FileHash: "gone", ChunkKey: key},
+		// The same file, chunked under another profile
+		{URL: path("rechunked.md"), Status: "completed", ModTime: modTime("rechunked.md"), FileHash: "stored", ChunkKey: "paragraph/512/50"},
+	}, nil)
+	mockRepo.On("MarkPagesStale", mock.Anything, "src1").Return(nil)
+	mockRepo.On("BulkCreatePages", mock.Anything, mock.MatchedBy(func(pages []SourcePage) bool {
+		byURL := make(map[string]SourcePage)
+		for _, p := range pages {
+			byURL[p.URL] = p
+		}
+		return len(pages) == 5 &&
+			byURL[path("same.md")].Status == "completed" && byURL[path("same.md")].FileHash == "stored" &&
+			byURL[path("touched.md")].Status == "completed" && byURL[path("touched.md")].ModTime.Equal(*modTime("touched.md")) &&
+			byURL[path("edited.md")].Status == "processing" &&
+			byURL[path("added.md")].Status == "processing" &&
+			byURL[path("rechunked.md")].Status == "processing"
+	})).Return([]string{path("same.md"), path("touched.md"), path("edited.md"), path("added.md"), path("rechunked.md")}, nil)
+	mockPub.On("Publish", config.TopicIngestFile, mock.Anything).Return(nil)
+	mockPub.On("Publish", config.TopicSourceSync, mock.Anything).Return(nil).Once()
+
+	// The scan waits for the queued sync
+	err = svc.ReSync(context.Background(), "src1")
+	assert.NoError(t, err)
+	mockRepo.AssertNotCalled(t, "GetPages", mock.Anything, mock.Anything)
+
+	err = svc.runSync(context.Background(), "src1", true)
+	assert.NoError(t, err)
+	// removed.md stays stale and its chunks go when the sync completes
+	assert.ElementsMatch(t, []string{path("edited.md"), path("added.md"), path("rechunked.md")}, publishedPaths(mockPub))
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,250 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"qurio/apps/backend/internal/config"
+	"qurio/apps/backend/internal/worker"
+)
+
+// ArchiveVersion is bumped whenever the archive layout changes incompatibly.
+const ArchiveVersion = 1
+
+const exportBatchSize = 500
+
+// ErrIncompatibleArchive is returned when an archive cannot be restored on this
+// instance, e.g. because its vectors come from a different embedding model.
+var ErrIncompatibleArchive = errors.New("incompatible archive")
+
+// ArchiveManifest is the first record of every archive.
+type ArchiveManifest struct {
+	Version        int       `json:"version"`
+	EmbeddingModel string    `json:"embedding_model"`
+	Dimensions     int       `json:"dimensions"`
+	Chunks         int       `json:"chunks"`
+	ExportedAt     time.Time `json:"exported_at"`
+}
+
+// ArchivedSource carries the hash that the API representation hides.
+type ArchivedSource struct {
+	Source
+	ContentHash string `json:"content_hash"`
+}
+
+// ArchiveRecord is one JSONL line of an archive: a manifest, followed by the
+// source, its pages and finally its chunks with vectors.
+type ArchiveRecord struct {
+	Kind     string           `json:"kind"` // manifest, source, page, chunk
+	Manifest *ArchiveManifest `json:"manifest,omitempty"`
+	Source   *ArchivedSource  `json:"source,omitempty"`
+	Page     *SourcePage      `json:"page,omitempty"`
+	Chunk    *worker.Chunk    `json:"chunk,omitempty"`
+}
+
+// Export streams a portable JSONL archive of the source to w. The source is
+// looked up before anything is written so not-found errors can still be
+// reported by the caller.
+//
+// Only the current version is archived: earlier versions kept by
+// keep_versions, their source_versions rows and DocumentChunkVersion chunks
+// are left out, and the imported source starts its history afresh.
+func (s *Service) Export(ctx context.Context, id string, w io.Writer) error {
+	src, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	pages, err := s.repo.GetPages(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	total, err := s.chunkStore.CountChunksBySource(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// The first batch tells us the vector dimensions for the manifest.
+	batch, err := s.chunkStore.GetChunksWithVectorsAfter(ctx, id, "", exportBatchSize)
+	if err != nil {
+		return err
+	}
+	dims := 0
+	if len(batch) > 0 {
+		dims = len(batch[0].Vector)
+	}
+
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(ArchiveRecord{Kind: "manifest", Manifest: &ArchiveManifest{
+		Version:        ArchiveVersion,
+		EmbeddingModel: config.EmbeddingModel,
+		Dimensions:     dims,
+		Chunks:         total,
+		ExportedAt:     time.Now().UTC(),
+	}}); err != nil {
+		return err
+	}
+
+	archived := &ArchivedSource{Source: *src, ContentHash: src.ContentHash}
+	if err := enc.Encode(ArchiveRecord{Kind: "source", Source: archived}); err != nil {
+		return err
+	}
+
+	for i := range pages {
+		if err := enc.Encode(ArchiveRecord{Kind: "page", Page: &pages[i]}); err != nil {
+			return err
+		}
+	}
+
+	written := 0
+	for len(batch) > 0 {
+		for i := range batch {
+			if err := enc.Encode(ArchiveRecord{Kind: "chunk", Chunk: &batch[i]}); err != nil {
+				return err
+			}
+		}
+		written += len(batch)
+		if len(batch) < exportBatchSize {
+			break
+		}
+		after := batch[len(batch)-1].ID
+		if batch, err = s.chunkStore.GetChunksWithVectorsAfter(ctx, id, after, exportBatchSize); err != nil {
+			return err
+		}
+	}
+
+	slog.InfoContext(ctx, "source exported", "source_id", id, "pages", len(pages), "chunks", written)
+	return nil
+}
+
+// Import restores an archive produced by Export as a new source. Chunks are
+// written with their stored vectors, so nothing is re-crawled or re-embedded.
+// The source is validated like Create does before anything is saved.
+func (s *Service) Import(ctx context.Context, r io.Reader) (*Source, error) {
+	dec := json.NewDecoder(r)
+
+	var rec ArchiveRecord
+	if err := dec.Decode(&rec); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIncompatibleArchive, err)
+	}
+	if rec.Kind != "manifest" || rec.Manifest == nil {
+		return nil, fmt.Errorf("%w: archive must start with a manifest", ErrIncompatibleArchive)
+	}
+	manifest := rec.Manifest
+	if manifest.Version != ArchiveVersion {
+		return nil, fmt.Errorf("%w: unsupported archive version %d", ErrIncompatibleArchive, manifest.Version)
+	}
+	if manifest.EmbeddingModel != config.EmbeddingModel {
+		return nil, fmt.Errorf("%w: archive embedded with %q, this instance uses %q",
+			ErrIncompatibleArchive, manifest.EmbeddingModel, config.EmbeddingModel)
+	}
+
+	rec = ArchiveRecord{}
+	if err := dec.Decode(&rec); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIncompatibleArchive, err)
+	}
+	if rec.Kind != "source" || rec.Source == nil {
+		return nil, fmt.Errorf("%w: missing source record", ErrIncompatibleArchive)
+	}
+
+	src := rec.Source.Source
+	src.ContentHash = rec.Source.ContentHash
+	src.ID = ""
+	// Archives from before chunking profiles carry none, which validating
+	// fills in
+	if err := s.validate(&src); err != nil {
+		return nil, err
+	}
+
+	exists, err := s.repo.ExistsByHash(ctx, src.ContentHash)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("Duplicate detected")
+	}
+
+	if err := s.repo.Save(ctx, &src); err != nil {
+		return nil, err
+	}
+
+	if err := s.restore(ctx, dec, &src, manifest); err != nil {
+		slog.ErrorContext(ctx, "import failed, rolling back", "error", err, "source_id", src.ID)
+		if delErr := s.chunkStore.DeleteChunksBySourceID(ctx, src.ID); delErr != nil {
+			slog.ErrorContext(ctx, "failed to clean up imported chunks", "error", delErr, "source_id", src.ID)
+		}
+		if delErr := s.repo.SoftDelete(ctx, src.ID); delErr != nil {
+			slog.ErrorContext(ctx, "failed to clean up imported source", "error", delErr, "source_id", src.ID)
+		}
+		return nil, err
+	}
+
+	src.Status = "completed"
+	if err := s.repo.UpdateStatus(ctx, src.ID, src.Status); err != nil {
+		return nil, err
+	}
+
+	return &src, nil
+}
+
+func (s *Service) restore(ctx context.Context, dec *json.Decoder, src *Source, manifest *ArchiveManifest) error {
+	var pages []SourcePage
+	chunks := 0
+
+	for {
+		var rec ArchiveRecord
+		if err := dec.Decode(&rec); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("%w: %v", ErrIncompatibleArchive, err)
+		}
+
+		switch rec.Kind {
+		case "page":
+			if rec.Page == nil {
+				return fmt.Errorf("%w: empty page record", ErrIncompatibleArchive)
+			}
+			p := *rec.Page
+			p.SourceID = src.ID
+			pages = append(pages, p)
+		case "chunk":
+			if rec.Chunk == nil {
+				return fmt.Errorf("%w: empty chunk record", ErrIncompatibleArchive)
+			}
+			// Pages precede chunks, so flush them before the first chunk.
+			if len(pages) > 0 {
+				if _, err := s.repo.BulkCreatePages(ctx, pages); err != nil {
+					return err
+				}
+				pages = nil
+			}
+			c := *rec.Chunk
+			if len(c.Vector) == 0 || len(c.Vector) != manifest.Dimensions {
+				return fmt.Errorf("%w: chunk %d of %s has %d dimensions, expected %d",
+					ErrIncompatibleArchive, c.ChunkIndex, c.SourceURL, len(c.Vector), manifest.Dimensions)
+			}
+			c.SourceID = src.ID
+			if err := s.chunkStore.StoreChunk(ctx, c); err != nil {
+				return err
+			}
+			chunks++
+		default:
+			return fmt.Errorf("%w: unexpected record kind %q", ErrIncompatibleArchive, rec.Kind)
+		}
+	}
+
+	if len(pages) > 0 {
+		if _, err := s.repo.BulkCreatePages(ctx, pages); err != nil {
+			return err
+		}
+	}
+
+	slog.InfoContext(ctx, "source imported", "source_id", src.ID, "chunks", chunks)
+	return nil
+}
@@ -0,0 +1,207 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"qurio/apps/backend/internal/config"
+	"qurio/apps/backend/internal/worker"
+)
+
+func decodeArchive(t *testing.T, data []byte) []ArchiveRecord {
+	var records []ArchiveRecord
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		var rec ArchiveRecord
+		assert.NoError(t, dec.Decode(&rec))
+		records = append(records, rec)
+	}
+	return records
+}
+
+func TestService_Export(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockChunk := new(MockChunkStore)
+	svc := NewService(mockRepo, nil, mockChunk, nil)
+
+	src := &Source{ID: "src-1", Type: "web", URL: "https://example.com", Name: "Example", ContentHash: "hash-1", Status: "completed"}
+	mockRepo.On("Get", mock.Anything, "src-1").Return(src, nil)
+	mockRepo.On("GetPages", mock.Anything, "src-1").Return([]SourcePage{
+		{SourceID: "src-1", URL: "https://example.com", Status: "completed"},
+	}, nil)
+	mockChunk.On("CountChunksBySource", mock.Anything, "src-1").Return(2, nil)
+	mockChunk.On("GetChunksWithVectorsAfter", mock.Anything, "src-1", "", exportBatchSize).Return([]worker.Chunk{
+		{Content: "a", SourceID: "src-1", SourceURL: "https://example.com", Vector: []float32{0.1, 0.2}},
+		{Content: "b", SourceID: "src-1", SourceURL: "https://example.com", ChunkIndex: 1, Vector: []float32{0.3, 0.4}},
+	}, nil)
+
+	var buf bytes.Buffer
+	err := svc.Export(context.Background(), "src-1", &buf)
+	assert.NoError(t, err)
+
+	records := decodeArchive(t, buf.Bytes())
+	assert.Len(t, records, 5)
+	assert.Equal(t, "manifest", records[0].Kind)
+	assert.Equal(t, config.EmbeddingModel, records[0].Manifest.EmbeddingModel)
+	assert.Equal(t, 2, records[0].Manifest.Dimensions)
+	assert.Equal(t, 2, records[0].Manifest.Chunks)
+	assert.Equal(t, "source", records[1].Kind)
+	assert.Equal(t, "hash-1", records[1].Source.ContentHash)
+	assert.Equal(t, "page", records[2].Kind)
+	assert.Equal(t, "chunk", records[3].Kind)
+	assert.Equal(t, []float32{0.3, 0.4}, records[4].Chunk.Vector)
+}
+
+func TestService_Export_LeavesOutVersionHistory(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockChunk := new(MockChunkStore)
+	svc := NewService(mockRepo, nil, mockChunk, nil)
+
+	src := &Source{ID: "src-1", Type: "web", URL: "https://example.com", Version: "v2", KeepVersions: 3}
+	mockRepo.On("Get", mock.Anything, "src-1").Return(src, nil)
+	mockRepo.On("GetPages", mock.Anything, "src-1").Return([]SourcePage{}, nil)
+	mockChunk.On("CountChunksBySource", mock.Anything, "src-1").Return(1, nil)
+	mockChunk.On("GetChunksWithVectorsAfter", mock.Anything, "src-1", "", exportBatchSize).
+		Return([]worker.Chunk{{Content: "a", Version: "v2", Vector: []float32{0.1}}}, nil)
+
+	var buf bytes.Buffer
+	assert.NoError(t, svc.Export(context.Background(), "src-1", &buf))
+
+	records := decodeArchive(t, buf.Bytes())
+	assert.Len(t, records, 3)
+	assert.Equal(t, "v2", records[1].Source.Version)
+	mockRepo.AssertNotCalled(t, "ListVersions", mock.Anything, mock.Anything)
+}
+
+func TestService_Export_PagesByCursor(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockChunk := new(MockChunkStore)
+	svc := NewService(mockRepo, nil, mockChunk, nil)
+
+	mockRepo.On("Get", mock.Anything, "src-1").Return(&Source{ID: "src-1", Type: "web", URL: "https://example.com"}, nil)
+	mockRepo.On("GetPages", mock.Anything, "src-1").Return([]SourcePage{}, nil)
+	mockChunk.On("CountChunksBySource", mock.Anything, "src-1").Return(exportBatchSize+1, nil)
+	// Each batch starts after the last chunk of the one before
+	full := make([]worker.Chunk, exportBatchSize)
+	full[len(full)-1].ID = "chunk-500"
+	mockChunk.On("GetChunksWithVectorsAfter", mock.Anything, "src-1", "", exportBatchSize).Return(full, nil)
+	mockChunk.On("GetChunksWithVectorsAfter", mock.Anything, "src-1", "chunk-500", exportBatchSize).
+		Return([]worker.Chunk{{ID: "chunk-501", Content: "last"}}, nil)
+
+	var buf bytes.Buffer
+	err := svc.Export(context.Background(), "src-1", &buf)
+	assert.NoError(t, err)
+
+	records := decodeArchive(t, buf.Bytes())
+	assert.Len(t, records, 2+exportBatchSize+1)
+	assert.Equal(t, "last", records[len(records)-1].Chunk.Content)
+	mockChunk.AssertExpectations(t)
+}
+
+func archiveFixture(t *testing.T, model string, chunkVector []float32) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.Encode(ArchiveRecord{Kind: "manifest", Manifest: &ArchiveManifest{Version: ArchiveVersion, EmbeddingModel: model, Dimensions: 2, Chunks: 1}})
+	enc.Encode(ArchiveRecord{Kind: "source", Source: &ArchivedSource{
+		Source:      Source{ID: "old-id", Type: "web", URL: "https://example.com", Name: "Example", Status: "completed"},
+		ContentHash: "hash-1",
+	}})
+	enc.Encode(ArchiveRecord{Kind: "page", Page: &SourcePage{SourceID: "old-id", URL: "https://example.com", Status: "completed"}})
+	enc.Encode(ArchiveRecord{Kind: "chunk", Chunk: &worker.Chunk{Content: "a", SourceID: "old-id", SourceURL: "https://example.com", Vector: chunkVector}})
+	return buf.String()
+}
+
+func TestService_Import_Success(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockChunk := new(MockChunkStore)
+	svc := NewService(mockRepo, nil, mockChunk, nil)
+
+	mockRepo.On("ExistsByHash", mock.Anything, "hash-1").Return(false, nil)
+	mockRepo.On("Save", mock.Anything, mock.MatchedBy(func(s *Source) bool {
+		return s.ID == "" && s.ContentHash == "hash-1" && s.Name == "Example"
+	})).Run(func(args mock.Arguments) {
+		args.Get(1).(*Source).ID = "new-id"
+	}).Return(nil)
+	mockRepo.On("BulkCreatePages", mock.Anything, mock.MatchedBy(func(pages []SourcePage) bool {
+		return len(pages) == 1 && pages[0].SourceID == "new-id" && pages[0].Status == "completed"
+	})).Return([]string{"https://example.com"}, nil)
+	mockChunk.On("StoreChunk", mock.Anything, mock.MatchedBy(func(c worker.Chunk) bool {
+		return c.SourceID == "new-id" && len(c.Vector) == 2
+	})).Return(nil)
+	mockRepo.On("UpdateStatus", mock.Anything, "new-id", "completed").Return(nil)
+
+	src, err := svc.Import(context.Background(), strings.NewReader(archiveFixture(t, config.EmbeddingModel, []float32{0.1, 0.2})))
+	assert.NoError(t, err)
+	assert.Equal(t, "new-id", src.ID)
+	assert.Equal(t, "completed", src.Status)
+	mockRepo.AssertExpectations(t)
+	mockChunk.AssertExpectations(t)
+}
+
+func TestService_Import_ModelMismatch(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := NewService(mockRepo, nil, nil, nil)
+
+	_, err := svc.Import(context.Background(), strings.NewReader(archiveFixture(t, "text-embedding-004", []float32{0.1, 0.2})))
+	assert.True(t, errors.Is(err, ErrIncompatibleArchive))
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestService_Import_Duplicate(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := NewService(mockRepo, nil, nil, nil)
+
+	mockRepo.On("ExistsByHash", mock.Anything, "hash-1").Return(true, nil)
+
+	_, err := svc.Import(context.Background(), strings.NewReader(archiveFixture(t, config.EmbeddingModel, []float32{0.1, 0.2})))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Duplicate")
+}
+
+func TestService_Import_Invalid(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := NewService(mockRepo, nil, nil, nil)
+	svc.SetDirectoryRoots([]string{"/srv/docs"})
+
+	archive := func(src Source) string {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.Encode(ArchiveRecord{Kind: "manifest", Manifest: &ArchiveManifest{Version: ArchiveVersion, EmbeddingModel: config.EmbeddingModel}})
+		enc.Encode(ArchiveRecord{Kind: "source", Source: &ArchivedSource{Source: src, ContentHash: "hash-1"}})
+		return buf.String()
+	}
+
+	_, err := svc.Import(context.Background(), strings.NewReader(archive(Source{Type: "web", URL: "https://example.com", Exclusions: []string{"["}})))
+	assert.ErrorIs(t, err, ErrInvalidCrawlScope)
+
+	_, err = svc.Import(context.Background(), strings.NewReader(archive(Source{Type: "directory", URL: "/etc"})))
+	assert.ErrorIs(t, err, ErrInvalidCrawlScope)
+
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestService_Import_DimensionMismatchRollsBack(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockChunk := new(MockChunkStore)
+	svc := NewService(mockRepo, nil, mockChunk, nil)
+
+	mockRepo.On("ExistsByHash", mock.Anything, "hash-1").Return(false, nil)
+	mockRepo.On("Save", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(1).(*Source).ID = "new-id"
+	}).Return(nil)
+	mockRepo.On("BulkCreatePages", mock.Anything, mock.Anything).Return([]string{"https://example.com"}, nil)
+	mockChunk.On("DeleteChunksBySourceID", mock.Anything, "new-id").Return(nil)
+	mockRepo.On("SoftDelete", mock.Anything, "new-id").Return(nil)
+
+	_, err := svc.Import(context.Background(), strings.NewReader(archiveFixture(t, config.EmbeddingModel, []float32{0.1, 0.2, 0.3})))
+	assert.True(t, errors.Is(err, ErrIncompatibleArchive))
+	mockChunk.AssertNotCalled(t, "StoreChunk", mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+	mockChunk.AssertExpectations(t)
+}
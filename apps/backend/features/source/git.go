@@ -0,0 +1,198 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var ErrGitUnavailable = errors.New("git sources are not enabled")
+var ErrGitCheckout = errors.New("failed to check out repository")
+
+// DefaultIncludeGlobs are the files git and directory sources ingest when
+// they name none.
+var DefaultIncludeGlobs = []string{"**/*.md", "**/*.rst"}
+
+// GitRepository keeps the working copies of git sources, see the git adapter.
+type GitRepository interface {
+	Dir(sourceID string) string
+	// Checkout clones or fetches remote and checks out ref, returning the
+	// SHA of the commit checked out.
+	Checkout(ctx context.Context, sourceID, remote, ref string) (string, error)
+	// Files lists the checked out files as slash-separated relative paths.
+	Files(ctx context.Context, sourceID string) ([]string, error)
+	// Diff lists the files added or modified between two commits.
+	Diff(ctx context.Context, sourceID, from, to string) ([]string, error)
+	Remove(sourceID string) error
+}
+
+// SetGitRepository enables git sources.
+func (s *Service) SetGitRepository(g GitRepository) {
+	s.git = g
+}
+
+// AllowLocalGitRemotes lets git sources clone paths and file:// URLs on the
+// backend's host; by default only https and ssh remotes are accepted.
+func (s *Service) AllowLocalGitRemotes(allow bool) {
+	s.gitAllowLocal = allow
+}
+
+// discard drops a source whose first sync couldn't start, so adding it again
+// isn't rejected as a duplicate.
+func (s *Service) discard(ctx context.Context, id string) {
+	if err := s.repo.SoftDelete(ctx, id); err != nil {
+		slog.WarnContext(ctx, "failed to discard source", "source_id", id, "error", err)
+	}
+}
+
+// scpRemote matches the scp-like syntax of ssh remotes, [user@]host:path.
+var scpRemote = regexp.MustCompile(`^([A-Za-z0-9._-]+@)?[A-Za-z0-9.-]+:.`)
+
+// gitRemoteAllowed reports whether remote is an https or ssh remote, or, if
+// allowLocal, a path or file:// URL. Other transports, such as ext::, run
+// commands or reach services a user of the API must not.
+func gitRemoteAllowed(remote string, allowLocal bool) bool {
+	if strings.Contains(remote, "::") {
+		return false
+	}
+	if strings.Contains(remote, "://") {
+		u, err := url.Parse(remote)
+		if err != nil {
+			return false
+		}
+		switch u.Scheme {
+		case "https", "ssh":
+			return u.Host != ""
+		case "file":
+			return allowLocal
+		}
+		return false
+	}
+	if scpRemote.MatchString(remote) {
+		return true
+	}
+	return allowLocal
+}
+
+// validateGit checks the settings only git sources have.
+func validateGit(src *Source, allowLocal bool) error {
+	if src.Type != "git" {
+		return nil
+	}
+	src.URL = strings.TrimSpace(src.URL)
+	src.GitRef = strings.TrimSpace(src.GitRef)
+	if src.URL == "" || strings.HasPrefix(src.URL, "-") {
+		return fmt.Errorf("%w: invalid repository: %q", ErrInvalidCrawlScope, src.URL)
+	}
+	if !gitRemoteAllowed(src.URL, allowLocal) {
+		return fmt.Errorf("%w: repository must be an https or ssh remote: %q", ErrInvalidCrawlScope, src.URL)
+	}
+	if strings.HasPrefix(src.GitRef, "-") {
+		return fmt.Errorf("%w: invalid git ref: %q", ErrInvalidCrawlScope, src.GitRef)
+	}
+	return validateGlobs(src)
+}
+
+// validateGlobs checks the include and exclude globs of a file-backed
+// source, defaulting the include globs.
+func validateGlobs(src *Source) error {
+	if len(src.IncludeGlobs) == 0 {
+		src.IncludeGlobs = append([]string(nil), DefaultIncludeGlobs...)
+	}
+	for _, globs := range [][]string{src.IncludeGlobs, src.ExcludeGlobs} {
+		if _, err := newGlobMatcher(globs); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidCrawlScope, err)
+		}
+	}
+	return nil
+}
+
+// gitFiles returns the files of the checkout matching the source's include
+// globs and none of its exclude globs or exclusions, which match the
+// relative path.
+func gitFiles(src *Source, files []string) []string {
+	include, err := newGlobMatcher(src.IncludeGlobs)
+	if err != nil {
+		return nil
+	}
+	excludeGlobs, err := newGlobMatcher(src.ExcludeGlobs)
+	if err != nil {
+		return nil
+	}
+	exclude := compileExclusions(src.Exclusions)
+	var matched []string
+	for _, f := range files {
+		if !include.Match(f) || excludeGlobs.Match(f) || matchesAny(exclude, f) {
+			continue
+		}
+		matched = append(matched, f)
+	}
+	return matched
+}
+
+// syncGit checks out a git source and publishes a file task per matching
+// file; its pages are the files' paths in the working copy. On resync, files
+// that completed before and haven't changed since the last synced commit are
+// kept completed without being read again; files that are gone stay stale
+// and are removed once the sync completes.
+func (s *Service) syncGit(ctx context.Context, src *Source, previous []SourcePage) error {
+	if s.git == nil {
+		return ErrGitUnavailable
+	}
+	// The operator may have disallowed local remotes since the source was added
+	if !gitRemoteAllowed(src.URL, s.gitAllowLocal) {
+		return fmt.Errorf("%w: repository must be an https or ssh remote: %q", ErrInvalidCrawlScope, src.URL)
+	}
+	sha, err := s.git.Checkout(ctx, src.ID, src.URL, src.GitRef)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrGitCheckout, err)
+	}
+	files, err := s.git.Files(ctx, src.ID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrGitCheckout, err)
+	}
+
+	// changed is nil when every file has to be read
+	var changed map[string]bool
+	if src.CommitSHA != "" && len(previous) > 0 {
+		changed = make(map[string]bool)
+		if src.CommitSHA != sha {
+			diff, err := s.git.Diff(ctx, src.ID, src.CommitSHA, sha)
+			if err != nil {
+				slog.WarnContext(ctx, "failed to diff commits, reading every file", "source_id", src.ID, "from", src.CommitSHA, "to", sha, "error", err)
+				changed = nil
+			}
+			for _, f := range diff {
+				changed[f] = true
+			}
+		}
+	}
+	completed := make(map[string]bool)
+	for _, p := range previous {
+		if p.Status == "completed" && src.chunkedAlike(p) {
+			completed[p.URL] = true
+		}
+	}
+
+	dir := s.git.Dir(src.ID)
+	var pages []SourcePage
+	for _, f := range gitFiles(src, files) {
+		page := SourcePage{SourceID: src.ID, URL: filepath.Join(dir, filepath.FromSlash(f)), Status: "processing", Depth: 1}
+		if changed != nil && !changed[f] && completed[page.URL] {
+			page.Status, page.Change = "completed", PageUnchanged
+		}
+		pages = append(pages, page)
+	}
+
+	if err := s.repo.SetCommitSHA(ctx, src.ID, sha); err != nil {
+		return err
+	}
+	src.CommitSHA = sha
+	slog.InfoContext(ctx, "checked out git source", "source_id", src.ID, "commit", sha, "files", len(pages))
+	return s.syncFiles(ctx, src, previous, pages)
+}
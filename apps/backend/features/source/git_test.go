@@ -0,0 +1,299 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"qurio/apps/backend/internal/config"
+	"qurio/apps/backend/internal/text"
+)
+
+type MockGit struct {
+	mock.Mock
+}
+
+func (m *MockGit) Dir(sourceID string) string {
+	return "/uploads/git/" + sourceID
+}
+
+func (m *MockGit) Checkout(ctx context.Context, sourceID, remote, ref string) (string, error) {
+	args := m.Called(ctx, sourceID, remote, ref)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockGit) Files(ctx context.Context, sourceID string) ([]string, error) {
+	args := m.Called(ctx, sourceID)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockGit) Diff(ctx context.Context, sourceID, from, to string) ([]string, error) {
+	args := m.Called(ctx, sourceID, from, to)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockGit) Remove(sourceID string) error {
+	return m.Called(sourceID).Error(0)
+}
+
+// publishedPaths collects the paths of the file tasks published.
+func publishedPaths(pub *MockPublisher) []string {
+	var paths []string
+	for _, call := range pub.Calls {
+		if call.Arguments.String(0) != config.TopicIngestFile {
+			continue
+		}
+		var task map[string]interface{}
+		json.Unmarshal(call.Arguments.Get(1).([]byte), &task)
+		paths = append(paths, task["path"].(string))
+	}
+	return paths
+}
+
+func pageStatuses(pages []SourcePage) map[string]string {
+	status := make(map[string]string)
+	for _, p := range pages {
+		status[p.URL] = p.Status
+	}
+	return status
+}
+
+func TestService_Create_Git(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockPub := new(MockPublisher)
+	mockGit := new(MockGit)
+	svc := NewService(mockRepo, mockPub, nil, nil)
+	svc.SetGitRepository(mockGit)
+	svc.AllowLocalGitRemotes(true)
+
+	src := &Source{Type: "git", URL: "file:///srv/repos/docs.git", GitRef: " v1.0 ", Name: "Docs", Exclusions: []string{"^docs/internal/"}}
+
+	mockRepo.On("ExistsByHash", mock.Anything, mock.Anything).Return(false, nil)
+	mockRepo.On("Save", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(1).(*Source).ID = "src1"
+	}).Return(nil)
+	mockGit.On("Checkout", mock.Anything, "src1", "file:///srv/repos/docs.git", "v1.0").Return("abc123", nil)
+	mockGit.On("Files", mock.Anything, "src1").Return([]string{"README.md", "docs/guide.rst", "docs/internal/notes.md", "main.go"}, nil)
+	mockRepo.On("BulkCreatePages", mock.Anything, mock.MatchedBy(func(pages []SourcePage) bool {
+		status := pageStatuses(pages)
+		return len(pages) == 2 && status["/uploads/git/src1/README.md"] == "processing" &&
+			status["/uploads/git/src1/docs/guide.rst"] == "processing"
+	})).Return([]string{"/uploads/git/src1/README.md", "/uploads/git/src1/docs/guide.rst"}, nil)
+	mockRepo.On("SetCommitSHA", mock.Anything, "src1", "abc123").Return(nil)
+	mockPub.On("Publish", config.TopicIngestFile, mock.Anything).Return(nil)
+	mockPub.On("Publish", config.TopicSourceSync, mock.Anything).Return(nil).Once()
+
+	err := svc.Create(context.Background(), src)
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultIncludeGlobs, src.IncludeGlobs)
+	// The clone waits for the queued sync
+	mockGit.AssertNotCalled(t, "Checkout", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	mockRepo.On("Get", mock.Anything, "src1").Return(src, nil)
+	err = svc.runSync(context.Background(), "src1", false)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", src.CommitSHA)
+	assert.ElementsMatch(t, []string{"/uploads/git/src1/README.md", "/uploads/git/src1/docs/guide.rst"}, publishedPaths(mockPub))
+	mockRepo.AssertExpectations(t)
+	mockGit.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "MarkPagesStale", mock.Anything, mock.Anything)
+}
+
+func TestService_RunSync_GitCheckoutFails(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockGit := new(MockGit)
+	svc := NewService(mockRepo, nil, nil, nil)
+	svc.SetGitRepository(mockGit)
+
+	mockRepo.On("Get", mock.Anything, "src1").Return(&Source{ID: "src1", Type: "git", URL: "https://example.com/missing.git"}, nil)
+	mockGit.On("Checkout", mock.Anything, "src1", "https://example.com/missing.git", "").Return("", errors.New("repository not found"))
+
+	err := svc.runSync(context.Background(), "src1", false)
+	assert.ErrorIs(t, err, ErrGitCheckout)
+	assert.ErrorContains(t, err, "repository not found")
+	mockRepo.AssertExpectations(t)
+	mockGit.AssertExpectations(t)
+}
+
+func TestService_RunSync_GitLocalRemoteDisallowed(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockGit := new(MockGit)
+	svc := NewService(mockRepo, nil, nil, nil)
+	svc.SetGitRepository(mockGit)
+
+	// Added while local remotes were allowed
+	mockRepo.On("Get", mock.Anything, "src1").Return(&Source{ID: "src1", Type: "git", URL: "/srv/docs"}, nil)
+
+	err := svc.runSync(context.Background(), "src1", false)
+	assert.ErrorIs(t, err, ErrInvalidCrawlScope)
+	mockGit.AssertNotCalled(t, "Checkout", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_Create_GitInvalid(t *testing.T) {
+	svc := NewService(new(MockRepository), nil, nil, nil)
+
+	err := svc.Create(context.Background(), &Source{Type: "git", URL: "--upload-pack=evil", Name: "Docs"})
+	assert.ErrorIs(t, err, ErrInvalidCrawlScope)
+
+	err = svc.Create(context.Background(), &Source{Type: "git", URL: "https://example.com/docs.git", IncludeGlobs: []string{""}, Name: "Docs"})
+	assert.ErrorIs(t, err, ErrInvalidCrawlScope)
+
+	// Local remotes need the operator's opt-in
+	err = svc.Create(context.Background(), &Source{Type: "git", URL: "/srv/docs", Name: "Docs"})
+	assert.ErrorIs(t, err, ErrInvalidCrawlScope)
+
+	// Without a git client git sources can't be created
+	mockRepo := new(MockRepository)
+	svc = NewService(mockRepo, nil, nil, nil)
+	err = svc.Create(context.Background(), &Source{Type: "git", URL: "https://example.com/docs.git", Name: "Docs"})
+	assert.ErrorIs(t, err, ErrGitUnavailable)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestGitRemoteAllowed(t *testing.T) {
+	tests := []struct {
+		remote     string
+		allowLocal bool
+		want       bool
+	}{
+		{"https://github.com/org/docs.git", false, true},
+		{"ssh://git@github.com/org/docs.git", false, true},
+		{"git@github.com:org/docs.git", false, true},
+		{"git@github.com:/srv/docs.git", false, true},
+		{"http://example.com/docs.git", false, false},
+		{"git://example.com/docs.git", false, false},
+		{"https:///docs.git", false, false},
+		{"ext::sh -c touch% /tmp/pwned", true, false},
+		{"fd::17", true, false},
+		{"file:///srv/docs.git", false, false},
+		{"file:///srv/docs.git", true, true},
+		{"/srv/docs", false, false},
+		{"/srv/docs", true, true},
+		{"../docs", false, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, gitRemoteAllowed(tt.remote, tt.allowLocal), "%s (allow local: %v)", tt.remote, tt.allowLocal)
+	}
+}
+
+func TestService_ReSync_GitIncremental(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockPub := new(MockPublisher)
+	mockGit := new(MockGit)
+	svc := NewService(mockRepo, mockPub, nil, nil)
+	svc.SetGitRepository(mockGit)
+
+	src := &Source{ID: "src1", Type: "git", URL: "https://example.com/docs.git", GitRef: "main", CommitSHA: "old", IncludeGlobs: []string{"**/*.md"}}
+
+	mockRepo.On("Get", mock.Anything, "src1").Return(src, nil)
+	mockRepo.On("StartCrawl", mock.Anything, "src1").Return(nil)
+	mockRepo.On("GetPages", mock.Anything, "src1").Return([]SourcePage{
+		{URL: "/uploads/git/src1/same.md", Status: "completed", ChunkKey: text.DefaultChunkProfile().Key()},
+		{URL: "/uploads/git/src1/edited.md", Status: "completed", ChunkKey: text.DefaultChunkProfile().Key()},
+		{URL: "/uploads/git/src1/failed.md", Status: "failed"},
+		{URL: "/uploads/git/src1/deleted.md", Status: "completed", ChunkKey: text.DefaultChunkProfile().Key()},
+	}, nil)
+	mockGit.On("Checkout", mock.Anything, "src1", "https://example.com/docs.git", "main").Return("new", nil)
+	mockGit.On("Files", mock.Anything, "src1").Return([]string{"same.md", "edited.md", "failed.md", "added.md"}, nil)
+	mockGit.On("Diff", mock.Anything, "src1", "old", "new").Return([]string{"edited.md", "added.md"}, nil)
+	mockRepo.On("MarkPagesStale", mock.Anything, "src1").Return(nil)
+	mockRepo.On("BulkCreatePages", mock.Anything, mock.MatchedBy(func(pages []SourcePage) bool {
+		status := pageStatuses(pages)
+		return len(pages) == 4 && status["/uploads/git/src1/same.md"] == "completed" &&
+			status["/uploads/git/src1/edited.md"] == "processing" &&
+			status["/uploads/git/src1/failed.md"] == "processing" &&
+			status["/uploads/git/src1/added.md"] == "processing"
+	})).Return([]string{"/uploads/git/src1/same.md", "/uploads/git/src1/edited.md", "/uploads/git/src1/failed.md", "/uploads/git/src1/added.md"}, nil)
+	mockRepo.On("SetCommitSHA", mock.Anything, "src1", "new").Return(nil)
+	mockPub.On("Publish", config.TopicIngestFile, mock.MatchedBy(func(body []byte) bool {
+		var task map[string]interface{}
+		json.Unmarshal(body, &task)
+		return task["resync"] == true && task["type"] == "file"
+	})).Return(nil)
+
+	mockPub.On("Publish", config.TopicSourceSync, mock.Anything).Return(nil).Once()
+
+	// The fetch waits for the queued sync
+	err := svc.ReSync(context.Background(), "src1")
+	assert.NoError(t, err)
+	mockGit.AssertNotCalled(t, "Checkout", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	err = svc.runSync(context.Background(), "src1", true)
+	assert.NoError(t, err)
+	// deleted.md stays stale and is removed when the sync completes
+	assert.ElementsMatch(t, []string{"/uploads/git/src1/edited.md", "/uploads/git/src1/failed.md", "/uploads/git/src1/added.md"}, publishedPaths(mockPub))
+	mockRepo.AssertExpectations(t)
+	mockGit.AssertExpectations(t)
+}
+
+func TestService_RunSync_GitUnchanged(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockPub := new(MockPublisher)
+	mockGit := new(MockGit)
+	svc := NewService(mockRepo, mockPub, nil, nil)
+	svc.SetGitRepository(mockGit)
+
+	src := &Source{ID: "src1", Type: "git", URL: "https://example.com/docs.git", CommitSHA: "abc", IncludeGlobs: DefaultIncludeGlobs}
+
+	mockRepo.On("Get", mock.Anything, "src1").Return(src, nil)
+	mockRepo.On("GetPages", mock.Anything, "src1").Return([]SourcePage{{URL: "/uploads/git/src1/README.md", Status: "completed", ChunkKey: text.DefaultChunkProfile().Key()}}, nil)
+	mockGit.On("Checkout", mock.Anything, "src1", "https://example.com/docs.git", "").Return("abc", nil)
+	mockGit.On("Files", mock.Anything, "src1").Return([]string{"README.md"}, nil)
+	mockRepo.On("MarkPagesStale", mock.Anything, "src1").Return(nil)
+	mockRepo.On("BulkCreatePages", mock.Anything, []SourcePage{
+		{SourceID: "src1", URL: "/uploads/git/src1/README.md", Status: "completed", Depth: 1, Change: PageUnchanged},
+	}).Return([]string{"/uploads/git/src1/README.md"}, nil)
+	mockRepo.On("SetCommitSHA", mock.Anything, "src1", "abc").Return(nil)
+	// Nothing to read, the sync completes right away
+	mockRepo.On("FinishSync", mock.Anything, "src1").Return(&SyncReport{SourceID: "src1", PagesUnchanged: 1}, nil, nil)
+
+	err := svc.runSync(context.Background(), "src1", true)
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockGit.AssertNotCalled(t, "Diff", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockPub.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+}
+
+func TestService_RunSync_GitFetchFails(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockGit := new(MockGit)
+	svc := NewService(mockRepo, nil, nil, nil)
+	svc.SetGitRepository(mockGit)
+
+	mockRepo.On("Get", mock.Anything, "src1").Return(&Source{ID: "src1", Type: "git", URL: "https://example.com/docs.git"}, nil)
+	mockRepo.On("GetPages", mock.Anything, "src1").Return([]SourcePage{}, nil)
+	mockGit.On("Checkout", mock.Anything, "src1", "https://example.com/docs.git", "").Return("", errors.New("could not read from remote"))
+
+	// The SyncConsumer marks the source failed
+	err := svc.runSync(context.Background(), "src1", true)
+	assert.ErrorIs(t, err, ErrGitCheckout)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCompileGlob(t *testing.T) {
+	tests := []struct {
+		glob  string
+		match []string
+		miss  []string
+	}{
+		{"**/*.md", []string{"README.md", "docs/a/b.md"}, []string{"README.mdx", "docs/md"}},
+		{"docs/*.rst", []string{"docs/index.rst"}, []string{"docs/api/index.rst", "index.rst"}},
+		{"src/**", []string{"src/a.go", "src/pkg/b.go"}, []string{"lib/src/a.go"}},
+		{"file?.txt", []string{"file1.txt"}, []string{"file10.txt", "file/.txt"}},
+		{"a+b.md", []string{"a+b.md"}, []string{"aab.md"}},
+	}
+	for _, tt := range tests {
+		re, err := compileGlob(tt.glob)
+		assert.NoError(t, err)
+		for _, path := range tt.match {
+			assert.True(t, re.MatchString(path), "%s should match %s", tt.glob, path)
+		}
+		for _, path := range tt.miss {
+			assert.False(t, re.MatchString(path), "%s should not match %s", tt.glob, path)
+		}
+	}
+}
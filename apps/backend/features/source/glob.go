@@ -0,0 +1,83 @@
+package source
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// compileGlob turns a glob over slash-separated paths into a regexp: "*"
+// and "?" stay within one path segment, "**" spans segments and "**/"
+// also matches no directory at all, so "**/*.md" matches "README.md".
+func compileGlob(glob string) (*regexp.Regexp, error) {
+	if glob == "" {
+		return nil, fmt.Errorf("empty glob")
+	}
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if strings.HasPrefix(glob[i:], "**/") {
+				b.WriteString("(?:.*/)?")
+				i += 2
+			} else if strings.HasPrefix(glob[i:], "**") {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// globMatcher reports whether a path matches any of its globs.
+type globMatcher []*regexp.Regexp
+
+func newGlobMatcher(globs []string) (globMatcher, error) {
+	var m globMatcher
+	for _, glob := range globs {
+		re, err := compileGlob(strings.TrimPrefix(glob, "/"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %v", glob, err)
+		}
+		m = append(m, re)
+	}
+	return m, nil
+}
+
+func (m globMatcher) Match(path string) bool {
+	for _, re := range m {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// compileExclusions compiles a source's exclusion regexes, skipping invalid
+// ones; they were validated when the source was saved.
+func compileExclusions(patterns []string) []*regexp.Regexp {
+	var res []*regexp.Regexp
+	for _, pattern := range patterns {
+		if re, err := regexp.Compile(pattern); err == nil {
+			res = append(res, re)
+		}
+	}
+	return res
+}
+
+func matchesAny(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
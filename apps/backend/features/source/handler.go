@@ -12,6 +12,8 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -20,10 +22,20 @@ import (
 
 type Handler struct {
 	service *Service
+
+	// closing is closed by CloseStreams to end open progress streams
+	closing   chan struct{}
+	closeOnce sync.Once
 }
 
 func NewHandler(service *Service) *Handler {
-	return &Handler{service: service}
+	return &Handler{service: service, closing: make(chan struct{})}
+}
+
+// CloseStreams ends the open progress streams and refuses new ones. Server
+// shutdown waits for active requests, which a stream never stops being.
+func (h *Handler) CloseStreams() {
+	h.closeOnce.Do(func() { close(h.closing) })
 }
 
 func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
@@ -33,6 +45,33 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 		MaxDepth   int      `json:"max_depth"`
 		Exclusions []string `json:"exclusions"`
 		Name       string   `json:"name"`
+
+		ChunkStrategy  string `json:"chunk_strategy"`
+		ChunkMaxTokens int    `json:"chunk_max_tokens"`
+		ChunkOverlap   int    `json:"chunk_overlap"`
+
+		IncludePatterns []string `json:"include_patterns"`
+		PathPrefix      string   `json:"path_prefix"`
+		KeepQueryParams []string `json:"keep_query_params"`
+		DropQueryParams []string `json:"drop_query_params"`
+		SeedURLs        []string `json:"seed_urls"`
+		AllowedHosts    []string `json:"allowed_hosts"`
+
+		MaxPages        int `json:"max_pages"`
+		MaxCrawlMinutes int `json:"max_crawl_minutes"`
+
+		SyncEnabled  bool   `json:"sync_enabled"`
+		SyncSchedule string `json:"sync_schedule"`
+
+		Version      string `json:"version"`
+		KeepVersions int    `json:"keep_versions"`
+
+		Tags []string `json:"tags"`
+
+		GitRef       string   `json:"git_ref"`
+		IncludeGlobs []string `json:"include_globs"`
+		ExcludeGlobs []string `json:"exclude_globs"`
+		Watch        bool     `json:"watch"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.writeError(r.Context(), w, "VALIDATION_ERROR", err.Error(), http.StatusBadRequest)
@@ -55,12 +94,43 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 		MaxDepth:   req.MaxDepth,
 		Exclusions: req.Exclusions,
 		Name:       req.Name,
+
+		ChunkStrategy:  req.ChunkStrategy,
+		ChunkMaxTokens: req.ChunkMaxTokens,
+		ChunkOverlap:   req.ChunkOverlap,
+
+		IncludePatterns: req.IncludePatterns,
+		PathPrefix:      req.PathPrefix,
+		KeepQueryParams: req.KeepQueryParams,
+		DropQueryParams: req.DropQueryParams,
+		SeedURLs:        req.SeedURLs,
+		AllowedHosts:    req.AllowedHosts,
+
+		MaxPages:        req.MaxPages,
+		MaxCrawlMinutes: req.MaxCrawlMinutes,
+
+		SyncEnabled:  req.SyncEnabled,
+		SyncSchedule: req.SyncSchedule,
+
+		Version:      req.Version,
+		KeepVersions: req.KeepVersions,
+
+		Tags: req.Tags,
+
+		GitRef:       req.GitRef,
+		IncludeGlobs: req.IncludeGlobs,
+		ExcludeGlobs: req.ExcludeGlobs,
+		Watch:        req.Watch,
 	}
 	if err := h.service.Create(r.Context(), src); err != nil {
 		if err.Error() == "Duplicate detected" {
 			h.writeError(r.Context(), w, "CONFLICT", err.Error(), http.StatusConflict)
 			return
 		}
+		if isValidationError(err) {
+			h.writeError(r.Context(), w, "VALIDATION_ERROR", err.Error(), http.StatusBadRequest)
+			return
+		}
 		// Log the actual error for debugging
 		slog.Error("operation failed", "error", err, "url", req.URL)
 		h.writeError(r.Context(), w, "INTERNAL_ERROR", "Internal Server Error", http.StatusInternalServerError)
@@ -71,6 +141,50 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]interface{}{"data": src})
 }
 
+// Update edits a source's name, crawl and sync settings. With ?resync=true
+// a source whose crawl settings changed is resynced right away.
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var req SourceUpdate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(r.Context(), w, "VALIDATION_ERROR", err.Error(), http.StatusBadRequest)
+		return
+	}
+	resync := r.URL.Query().Get("resync") == "true"
+
+	src, resynced, err := h.service.Update(r.Context(), id, req, resync)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			h.writeError(r.Context(), w, "NOT_FOUND", "Source not found", http.StatusNotFound)
+		case isValidationError(err) || errors.Is(err, ErrNameRequired):
+			h.writeError(r.Context(), w, "VALIDATION_ERROR", err.Error(), http.StatusBadRequest)
+		case errors.Is(err, ErrSyncInProgress):
+			h.writeError(r.Context(), w, "CONFLICT", "Source is syncing, retry once it completes", http.StatusConflict)
+		case err.Error() == "Duplicate detected":
+			h.writeError(r.Context(), w, "CONFLICT", err.Error(), http.StatusConflict)
+		default:
+			slog.Error("operation failed", "error", err, "id", id)
+			h.writeError(r.Context(), w, "INTERNAL_ERROR", "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": src,
+		"meta": map[string]bool{"resynced": resynced},
+	})
+}
+
+// isValidationError reports whether err comes from checking a source's
+// settings.
+func isValidationError(err error) bool {
+	return errors.Is(err, ErrInvalidChunkProfile) || errors.Is(err, ErrInvalidCrawlScope) ||
+		errors.Is(err, ErrInvalidVersion) || errors.Is(err, ErrInvalidSchedule) ||
+		errors.Is(err, ErrInvalidTag) || errors.Is(err, ErrGitCheckout) || errors.Is(err, ErrGitUnavailable)
+}
+
 func (h *Handler) Upload(w http.ResponseWriter, r *http.Request) {
 	// 50 MB limit (enforced at reader level)
 	r.Body = http.MaxBytesReader(w, r.Body, 50<<20)
@@ -97,7 +211,7 @@ func (h *Handler) Upload(w http.ResponseWriter, r *http.Request) {
 	// Validate File Extension/MIME
 	ext := filepath.Ext(header.Filename)
 	validExts := map[string]bool{
-		".pdf": true, ".md": true, ".txt": true, ".json": true, ".csv": true,
+		".pdf": true, ".md": true, ".txt": true, ".json": true, ".csv": true, ".yaml": true, ".yml": true,
 	}
 	if !validExts[ext] {
 		h.writeError(r.Context(), w, "BAD_REQUEST", "Unsupported file type", http.StatusBadRequest)
@@ -203,6 +317,125 @@ func (h *Handler) ReSync(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// SetTags replaces the tags of a source.
+func (h *Handler) SetTags(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var req struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(r.Context(), w, "VALIDATION_ERROR", err.Error(), http.StatusBadRequest)
+		return
+	}
+	tags, err := h.service.SetTags(r.Context(), id, req.Tags)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			h.writeError(r.Context(), w, "NOT_FOUND", "Source not found", http.StatusNotFound)
+		case errors.Is(err, ErrInvalidTag):
+			h.writeError(r.Context(), w, "VALIDATION_ERROR", err.Error(), http.StatusBadRequest)
+		default:
+			h.writeError(r.Context(), w, "INTERNAL_ERROR", err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": tags})
+}
+
+// ListTags lists every tag with the number of sources carrying it.
+func (h *Handler) ListTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.service.ListTags(r.Context())
+	if err != nil {
+		h.writeError(r.Context(), w, "INTERNAL_ERROR", err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": tags,
+		"meta": map[string]int{"count": len(tags)},
+	})
+}
+
+// DeleteTag removes a tag from every source.
+func (h *Handler) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteTag(r.Context(), r.PathValue("name")); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeError(r.Context(), w, "NOT_FOUND", "Tag not found", http.StatusNotFound)
+			return
+		}
+		h.writeError(r.Context(), w, "INTERNAL_ERROR", err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// SetVersion moves a source to a new version label and resyncs it.
+func (h *Handler) SetVersion(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var req struct {
+		Version string `json:"version"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(r.Context(), w, "VALIDATION_ERROR", err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.service.SetVersion(r.Context(), id, req.Version); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			h.writeError(r.Context(), w, "NOT_FOUND", "Source not found", http.StatusNotFound)
+		case errors.Is(err, ErrInvalidVersion):
+			h.writeError(r.Context(), w, "VALIDATION_ERROR", err.Error(), http.StatusBadRequest)
+		case errors.Is(err, ErrSyncInProgress):
+			h.writeError(r.Context(), w, "CONFLICT", "Source is syncing, retry once it completes", http.StatusConflict)
+		default:
+			h.writeError(r.Context(), w, "INTERNAL_ERROR", err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// GetVersions lists the current version of a source and its snapshots.
+func (h *Handler) GetVersions(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	versions, err := h.service.ListVersions(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeError(r.Context(), w, "NOT_FOUND", "Source not found", http.StatusNotFound)
+			return
+		}
+		h.writeError(r.Context(), w, "INTERNAL_ERROR", err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": versions,
+		"meta": map[string]int{"count": len(versions)},
+	})
+}
+
+// DiffVersions lists the pages added, removed and changed between the
+// versions given by the from and to query parameters.
+func (h *Handler) DiffVersions(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	q := r.URL.Query()
+	diff, err := h.service.DiffVersions(r.Context(), id, q.Get("from"), q.Get("to"))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			h.writeError(r.Context(), w, "NOT_FOUND", "Source or version not found", http.StatusNotFound)
+		case errors.Is(err, ErrInvalidVersion):
+			h.writeError(r.Context(), w, "VALIDATION_ERROR", err.Error(), http.StatusBadRequest)
+		default:
+			h.writeError(r.Context(), w, "INTERNAL_ERROR", err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": diff})
+}
+
 func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 
@@ -233,6 +466,69 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]interface{}{"data": detail})
 }
 
+func (h *Handler) Export(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	// Headers are only committed on the first write, so lookup errors from
+	// Export can still be reported as JSON.
+	hw := &lazyHeaderWriter{ResponseWriter: w, onFirstWrite: func() {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="source-%s.jsonl"`, id))
+	}}
+
+	if err := h.service.Export(r.Context(), id, hw); err != nil {
+		if hw.written {
+			// Too late for an error envelope; the client sees a truncated archive.
+			slog.ErrorContext(r.Context(), "export aborted", "error", err, "source_id", id)
+			return
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeError(r.Context(), w, "NOT_FOUND", "Source not found", http.StatusNotFound)
+			return
+		}
+		h.writeError(r.Context(), w, "INTERNAL_ERROR", err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) Import(w http.ResponseWriter, r *http.Request) {
+	// 1 GB limit; archives carry full vectors
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<30)
+
+	src, err := h.service.Import(r.Context(), r.Body)
+	if err != nil {
+		if errors.Is(err, ErrIncompatibleArchive) || isValidationError(err) {
+			h.writeError(r.Context(), w, "VALIDATION_ERROR", err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err.Error() == "Duplicate detected" {
+			h.writeError(r.Context(), w, "CONFLICT", err.Error(), http.StatusConflict)
+			return
+		}
+		slog.ErrorContext(r.Context(), "import failed", "error", err)
+		h.writeError(r.Context(), w, "INTERNAL_ERROR", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": src})
+}
+
+type lazyHeaderWriter struct {
+	http.ResponseWriter
+	onFirstWrite func()
+	written      bool
+}
+
+func (l *lazyHeaderWriter) Write(p []byte) (int, error) {
+	if !l.written {
+		l.written = true
+		l.onFirstWrite()
+	}
+	return l.ResponseWriter.Write(p)
+}
+
 func (h *Handler) GetPages(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 	pages, err := h.service.GetPages(r.Context(), id)
@@ -250,6 +546,102 @@ func (h *Handler) GetPages(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (h *Handler) GetProgress(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	p, err := h.service.GetProgress(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeError(r.Context(), w, "NOT_FOUND", "Source not found", http.StatusNotFound)
+			return
+		}
+		h.writeError(r.Context(), w, "INTERNAL_ERROR", err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": p})
+}
+
+// GetSyncs lists the recent syncs of a source with their page counts.
+func (h *Handler) GetSyncs(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	syncs, err := h.service.ListSyncs(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeError(r.Context(), w, "NOT_FOUND", "Source not found", http.StatusNotFound)
+			return
+		}
+		h.writeError(r.Context(), w, "INTERNAL_ERROR", err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": syncs,
+		"meta": map[string]int{"count": len(syncs)},
+	})
+}
+
+// progressPollInterval is how often StreamProgress checks for changes.
+// Embeddings are stored by separate worker processes, so the stream polls
+// the database rather than listening for in-process events.
+const progressPollInterval = time.Second
+
+// StreamProgress sends a "progress" Server-Sent Event whenever the progress of
+// the source changes and a final "done" event once it has completed or failed.
+func (h *Handler) StreamProgress(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := r.PathValue("id")
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		h.writeError(ctx, w, "INTERNAL_ERROR", "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	p, err := h.service.GetProgress(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeError(ctx, w, "NOT_FOUND", "Source not found", http.StatusNotFound)
+			return
+		}
+		h.writeError(ctx, w, "INTERNAL_ERROR", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event string, p *Progress) {
+		data, _ := json.Marshal(p)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+		flusher.Flush()
+	}
+
+	send("progress", p)
+	ticker := time.NewTicker(progressPollInterval)
+	defer ticker.Stop()
+	for !p.Done() {
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.closing:
+			return
+		case <-ticker.C:
+		}
+		next, err := h.service.GetProgress(ctx, id)
+		if err != nil {
+			slog.ErrorContext(ctx, "progress stream aborted", "error", err, "source_id", id)
+			return
+		}
+		if *next != *p {
+			send("progress", next)
+		}
+		p = next
+	}
+	send("done", p)
+}
+
 func (h *Handler) writeError(ctx context.Context, w http.ResponseWriter, code, message string, status int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -2,13 +2,22 @@ package source_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
 
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"qurio/apps/backend/features/source"
+	"qurio/apps/backend/internal/adapter/git"
+	"qurio/apps/backend/internal/bus"
+	"qurio/apps/backend/internal/config"
 )
 
 func TestCreateSource_MissingName(t *testing.T) {
@@ -28,3 +37,80 @@ func TestCreateSource_MissingName(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
+
+func TestCreateSource_Git(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	// A repository on disk stands in for the remote
+	remote := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "Test"},
+	} {
+		require.NoError(t, exec.Command("git", append([]string{"-C", remote}, args...)...).Run())
+	}
+	require.NoError(t, os.MkdirAll(filepath.Join(remote, "docs"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(remote, "docs", "guide.md"), []byte("# Guide"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(remote, "main.go"), []byte("package main"), 0o644))
+	require.NoError(t, exec.Command("git", "-C", remote, "add", "-A").Run())
+	require.NoError(t, exec.Command("git", "-C", remote, "commit", "--quiet", "-m", "docs").Run())
+
+	mockRepo := new(MockRepo)
+	mockPub := new(MockPublisher)
+	svc := source.NewService(mockRepo, mockPub, nil, nil)
+	checkouts := t.TempDir()
+	svc.SetGitRepository(git.NewClient(checkouts))
+	svc.AllowLocalGitRemotes(true)
+	handler := source.NewHandler(svc)
+
+	guide := filepath.Join(checkouts, "src1", "docs", "guide.md")
+	mockRepo.On("ExistsByHash", mock.Anything, mock.Anything).Return(false, nil)
+	mockRepo.On("Save", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(1).(*source.Source).ID = "src1"
+	}).Return(nil)
+	mockRepo.On("BulkCreatePages", mock.Anything, mock.MatchedBy(func(pages []source.SourcePage) bool {
+		return len(pages) == 1 && pages[0].URL == guide
+	})).Return([]string{guide}, nil)
+	mockRepo.On("SetCommitSHA", mock.Anything, "src1", mock.AnythingOfType("string")).Return(nil)
+	mockPub.On("Publish", config.TopicIngestFile, mock.Anything).Return(nil)
+	var syncTask []byte
+	mockPub.On("Publish", config.TopicSourceSync, mock.Anything).Run(func(args mock.Arguments) {
+		syncTask = args.Get(1).([]byte)
+	}).Return(nil)
+
+	body := []byte(`{"type":"git","url":"file://` + remote + `","name":"Docs","include_globs":["docs/**/*.md"]}`)
+	w := httptest.NewRecorder()
+	handler.Create(w, httptest.NewRequest("POST", "/sources", bytes.NewBuffer(body)))
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var resp struct {
+		Data source.Source `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "in_progress", resp.Data.Status)
+	assert.NoFileExists(t, guide)
+
+	// The clone runs when the queued sync is consumed
+	require.NotNil(t, syncTask)
+	mockRepo.On("Get", mock.Anything, "src1").Return(&resp.Data, nil)
+	require.NoError(t, source.NewSyncConsumer(svc).HandleMessage(&bus.Message{Body: syncTask}))
+	assert.FileExists(t, guide)
+	mockRepo.AssertExpectations(t)
+	mockPub.AssertExpectations(t)
+}
+
+func TestCreateSource_GitUnavailable(t *testing.T) {
+	mockRepo := new(MockRepo)
+	svc := source.NewService(mockRepo, nil, nil, nil)
+	handler := source.NewHandler(svc)
+
+	// Without a git client the source is rejected before it is saved
+	body := []byte(`{"type":"git","url":"/srv/docs","name":"Docs"}`)
+	w := httptest.NewRecorder()
+	handler.Create(w, httptest.NewRequest("POST", "/sources", bytes.NewBuffer(body)))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
@@ -30,6 +30,25 @@ func (m *MockRepo) Save(ctx context.Context, src *source.Source) error {
 	args := m.Called(ctx, src)
 	return args.Error(0)
 }
+func (m *MockRepo) Update(ctx context.Context, src *source.Source, withTags bool) error {
+	args := m.Called(ctx, src, withTags)
+	return args.Error(0)
+}
+func (m *MockRepo) SetTags(ctx context.Context, sourceID string, tags []string) error {
+	args := m.Called(ctx, sourceID, tags)
+	return args.Error(0)
+}
+func (m *MockRepo) ListTags(ctx context.Context) ([]source.Tag, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]source.Tag), args.Error(1)
+}
+func (m *MockRepo) DeleteTag(ctx context.Context, name string) error {
+	args := m.Called(ctx, name)
+	return args.Error(0)
+}
 func (m *MockRepo) List(ctx context.Context) ([]source.Source, error) {
 	args := m.Called(ctx)
 	if args.Get(0) == nil {
@@ -56,8 +75,8 @@ func (m *MockRepo) UpdateStatus(ctx context.Context, id, status string) error {
 	args := m.Called(ctx, id, status)
 	return args.Error(0)
 }
-func (m *MockRepo) UpdateBodyHash(ctx context.Context, id, hash string) error {
-	args := m.Called(ctx, id, hash)
+func (m *MockRepo) SetCommitSHA(ctx context.Context, id, sha string) error {
+	args := m.Called(ctx, id, sha)
 	return args.Error(0)
 }
 func (m *MockRepo) Count(ctx context.Context) (int, error) {
@@ -87,10 +106,107 @@ func (m *MockRepo) CountPendingPages(ctx context.Context, sourceID string) (int,
 	args := m.Called(ctx, sourceID)
 	return args.Int(0), args.Error(1)
 }
+func (m *MockRepo) SetPageChunks(ctx context.Context, sourceID, url string, depth, expected int) error {
+	args := m.Called(ctx, sourceID, url, depth, expected)
+	return args.Error(0)
+}
+func (m *MockRepo) PendingPages(ctx context.Context, perSource int) ([]source.SourcePage, error) {
+	args := m.Called(ctx, perSource)
+	return args.Get(0).([]source.SourcePage), args.Error(1)
+}
+func (m *MockRepo) ClaimPage(ctx context.Context, sourceID, url string) (bool, error) {
+	args := m.Called(ctx, sourceID, url)
+	return args.Bool(0), args.Error(1)
+}
+func (m *MockRepo) CountReleasedPages(ctx context.Context, sourceID string) (int, error) {
+	args := m.Called(ctx, sourceID)
+	return args.Int(0), args.Error(1)
+}
+func (m *MockRepo) SkipPendingPages(ctx context.Context, sourceID, reason string) (int64, error) {
+	args := m.Called(ctx, sourceID, reason)
+	return args.Get(0).(int64), args.Error(1)
+}
+func (m *MockRepo) StartCrawl(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+func (m *MockRepo) MarkPagesStale(ctx context.Context, sourceID string) error {
+	args := m.Called(ctx, sourceID)
+	return args.Error(0)
+}
+func (m *MockRepo) RecordPageContent(ctx context.Context, sourceID, url, hash, chunkKey, etag, lastModified string) (string, error) {
+	args := m.Called(ctx, sourceID, url, hash, chunkKey, etag, lastModified)
+	return args.String(0), args.Error(1)
+}
+func (m *MockRepo) FinishSync(ctx context.Context, sourceID string) (*source.SyncReport, []string, error) {
+	args := m.Called(ctx, sourceID)
+	var report *source.SyncReport
+	if r := args.Get(0); r != nil {
+		report = r.(*source.SyncReport)
+	}
+	var removed []string
+	if r := args.Get(1); r != nil {
+		removed = r.([]string)
+	}
+	return report, removed, args.Error(2)
+}
+func (m *MockRepo) ListSyncs(ctx context.Context, sourceID string) ([]source.SyncReport, error) {
+	args := m.Called(ctx, sourceID)
+	return args.Get(0).([]source.SyncReport), args.Error(1)
+}
+func (m *MockRepo) SaveVersion(ctx context.Context, sourceID, current, next string, keep int) ([]string, error) {
+	args := m.Called(ctx, sourceID, current, next, keep)
+	return args.Get(0).([]string), args.Error(1)
+}
+func (m *MockRepo) ListVersions(ctx context.Context, sourceID string) ([]source.Version, error) {
+	args := m.Called(ctx, sourceID)
+	return args.Get(0).([]source.Version), args.Error(1)
+}
+func (m *MockRepo) PageHashes(ctx context.Context, sourceID, version string) (map[string]string, error) {
+	args := m.Called(ctx, sourceID, version)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]string), args.Error(1)
+}
+func (m *MockRepo) MarkChunkEmbedded(ctx context.Context, sourceID, url string, chunkIndex int) (bool, error) {
+	args := m.Called(ctx, sourceID, url, chunkIndex)
+	return args.Bool(0), args.Error(1)
+}
+func (m *MockRepo) GetProgress(ctx context.Context, sourceID string) (*source.Progress, error) {
+	args := m.Called(ctx, sourceID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*source.Progress), args.Error(1)
+}
 func (m *MockRepo) ResetStuckPages(ctx context.Context, timeout time.Duration) (int64, error) {
 	args := m.Called(ctx, timeout)
 	return args.Get(0).(int64), args.Error(1)
 }
+func (m *MockRepo) FailStuckEmbeddingPages(ctx context.Context, timeout time.Duration) ([]string, error) {
+	args := m.Called(ctx, timeout)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockRepo) FailStuckFilePages(ctx context.Context, timeout time.Duration) ([]string, error) {
+	args := m.Called(ctx, timeout)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+func (m *MockRepo) ListSyncDue(ctx context.Context) ([]source.Source, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]source.Source), args.Error(1)
+}
+func (m *MockRepo) UpdateLastSyncedAt(ctx context.Context, id string, t time.Time) error {
+	args := m.Called(ctx, id, t)
+	return args.Error(0)
+}
 
 // MockChunkStore
 type MockChunkStore struct {
@@ -105,11 +221,39 @@ func (m *MockChunkStore) GetChunks(ctx context.Context, sourceID string, limit,
 	return args.Get(0).([]worker.Chunk), args.Error(1)
 }
 
+func (m *MockChunkStore) GetChunksWithVectorsAfter(ctx context.Context, sourceID, after string, limit int) ([]worker.Chunk, error) {
+	args := m.Called(ctx, sourceID, after, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]worker.Chunk), args.Error(1)
+}
+
+func (m *MockChunkStore) StoreChunk(ctx context.Context, chunk worker.Chunk) error {
+	args := m.Called(ctx, chunk)
+	return args.Error(0)
+}
+
 func (m *MockChunkStore) DeleteChunksBySourceID(ctx context.Context, sourceID string) error {
 	args := m.Called(ctx, sourceID)
 	return args.Error(0)
 }
 
+func (m *MockChunkStore) DeleteChunksByURL(ctx context.Context, sourceID, url string) error {
+	args := m.Called(ctx, sourceID, url)
+	return args.Error(0)
+}
+
+func (m *MockChunkStore) StoreVersionChunks(ctx context.Context, chunks []worker.Chunk) error {
+	args := m.Called(ctx, chunks)
+	return args.Error(0)
+}
+
+func (m *MockChunkStore) DeleteVersionChunks(ctx context.Context, sourceID, version string) error {
+	args := m.Called(ctx, sourceID, version)
+	return args.Error(0)
+}
+
 func (m *MockChunkStore) CountChunksBySource(ctx context.Context, sourceID string) (int, error) {
 	args := m.Called(ctx, sourceID)
 	return args.Int(0), args.Error(1)
@@ -195,6 +339,103 @@ func TestHandler_Create(t *testing.T) {
 
 		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
 	})
+	t.Run("ChunkProfile", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockPub := new(MockPublisher)
+		mockSettings := new(MockSettingsService)
+		svc := source.NewService(mockRepo, mockPub, nil, mockSettings)
+		handler := source.NewHandler(svc)
+
+		mockRepo.On("ExistsByHash", mock.Anything, mock.Anything).Return(false, nil)
+		mockRepo.On("Save", mock.Anything, mock.MatchedBy(func(s *source.Source) bool {
+			return s.ChunkStrategy == "fixed-window" && s.ChunkMaxTokens == 128 && s.ChunkOverlap == 0
+		})).Return(nil)
+		mockRepo.On("BulkCreatePages", mock.Anything, mock.Anything).Return([]string{}, nil)
+		mockSettings.On("Get", mock.Anything).Return(&settings.Settings{}, nil)
+		mockPub.On("Publish", config.TopicIngestWeb, mock.Anything).Return(nil)
+
+		reqBody := `{"type": "web", "url": "http://example.com/api", "name": "API", "chunk_strategy": "fixed-window", "chunk_max_tokens": 128}`
+		req := httptest.NewRequest("POST", "/sources", strings.NewReader(reqBody))
+		w := httptest.NewRecorder()
+
+		handler.Create(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("InvalidChunkProfile", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		svc := source.NewService(mockRepo, nil, nil, nil)
+		handler := source.NewHandler(svc)
+
+		reqBody := `{"type": "web", "url": "http://example.com", "name": "Test", "chunk_strategy": "sentences"}`
+		req := httptest.NewRequest("POST", "/sources", strings.NewReader(reqBody))
+		w := httptest.NewRecorder()
+
+		handler.Create(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+		assert.Contains(t, w.Body.String(), "unknown chunk strategy")
+	})
+
+	t.Run("CrawlScope", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockPub := new(MockPublisher)
+		mockSettings := new(MockSettingsService)
+		svc := source.NewService(mockRepo, mockPub, nil, mockSettings)
+		handler := source.NewHandler(svc)
+
+		mockRepo.On("ExistsByHash", mock.Anything, mock.Anything).Return(false, nil)
+		mockRepo.On("Save", mock.Anything, mock.MatchedBy(func(s *source.Source) bool {
+			return s.PathPrefix == "/docs" && len(s.IncludePatterns) == 1 && len(s.KeepQueryParams) == 1
+		})).Return(nil)
+		mockRepo.On("BulkCreatePages", mock.Anything, mock.MatchedBy(func(pages []source.SourcePage) bool {
+			return len(pages) == 1 && pages[0].URL == "http://example.com/docs?page=1"
+		})).Return([]string{}, nil)
+		mockSettings.On("Get", mock.Anything).Return(&settings.Settings{}, nil)
+		mockPub.On("Publish", config.TopicIngestWeb, mock.Anything).Return(nil)
+
+		reqBody := `{"type": "web", "url": "http://Example.com/docs/?page=1&utm_source=x", "name": "Docs", "path_prefix": "docs", "include_patterns": ["/guide/"], "keep_query_params": ["page"]}`
+		req := httptest.NewRequest("POST", "/sources", strings.NewReader(reqBody))
+		w := httptest.NewRecorder()
+
+		handler.Create(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("InvalidIncludePattern", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		svc := source.NewService(mockRepo, nil, nil, nil)
+		handler := source.NewHandler(svc)
+
+		reqBody := `{"type": "web", "url": "http://example.com", "name": "Test", "include_patterns": ["("]}`
+		req := httptest.NewRequest("POST", "/sources", strings.NewReader(reqBody))
+		w := httptest.NewRecorder()
+
+		handler.Create(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+		assert.Contains(t, w.Body.String(), "invalid include regex")
+	})
+
+	t.Run("InvalidExclusion", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		svc := source.NewService(mockRepo, nil, nil, nil)
+		handler := source.NewHandler(svc)
+
+		reqBody := `{"type": "web", "url": "http://example.com", "name": "Test", "exclusions": ["["]}`
+		req := httptest.NewRequest("POST", "/sources", strings.NewReader(reqBody))
+		w := httptest.NewRecorder()
+
+		handler.Create(w, req)
+
+		// A bad exclusion is the caller's mistake, like a bad include pattern
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+		assert.Contains(t, w.Body.String(), "invalid exclusion regex")
+	})
 }
 
 func TestHandler_Upload(t *testing.T) {
@@ -235,11 +476,8 @@ func TestHandler_ReSync(t *testing.T) {
 
 	t.Run("Success", func(t *testing.T) {
 		mockRepo.On("Get", mock.Anything, "1").Return(&source.Source{ID: "1", Type: "web", URL: "http://example.com"}, nil)
-		mockRepo.On("UpdateStatus", mock.Anything, "1", "in_progress").Return(nil)
-		mockRepo.On("DeletePages", mock.Anything, "1").Return(nil)
-		mockRepo.On("BulkCreatePages", mock.Anything, mock.Anything).Return([]string{}, nil)
-		mockSettings.On("Get", mock.Anything).Return(&settings.Settings{}, nil)
-		mockPub.On("Publish", config.TopicIngestWeb, mock.Anything).Return(nil)
+		mockRepo.On("StartCrawl", mock.Anything, "1").Return(nil)
+		mockPub.On("Publish", config.TopicSourceSync, mock.Anything).Return(nil)
 
 		req := httptest.NewRequest("POST", "/sources/1/resync", nil)
 		req.SetPathValue("id", "1")
@@ -395,6 +633,75 @@ func TestHandler_GetPages(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
 }
 
+func TestHandler_Export_NotFound(t *testing.T) {
+	mockRepo := new(MockRepo)
+	svc := source.NewService(mockRepo, nil, new(MockChunkStore), nil)
+	handler := source.NewHandler(svc)
+
+	mockRepo.On("Get", mock.Anything, "99").Return(nil, sql.ErrNoRows)
+
+	req := httptest.NewRequest("GET", "/sources/99/export", nil)
+	req.SetPathValue("id", "99")
+	w := httptest.NewRecorder()
+
+	handler.Export(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+}
+
+func TestHandler_Export(t *testing.T) {
+	mockRepo := new(MockRepo)
+	mockChunkStore := new(MockChunkStore)
+	svc := source.NewService(mockRepo, nil, mockChunkStore, nil)
+	handler := source.NewHandler(svc)
+
+	mockRepo.On("Get", mock.Anything, "1").Return(&source.Source{ID: "1", URL: "http://example.com"}, nil)
+	mockRepo.On("GetPages", mock.Anything, "1").Return([]source.SourcePage{}, nil)
+	mockChunkStore.On("CountChunksBySource", mock.Anything, "1").Return(0, nil)
+	mockChunkStore.On("GetChunksWithVectorsAfter", mock.Anything, "1", "", 500).Return([]worker.Chunk{}, nil)
+
+	req := httptest.NewRequest("GET", "/sources/1/export", nil)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	handler.Export(w, req)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "source-1.jsonl")
+	assert.Equal(t, 2, strings.Count(w.Body.String(), "\n"))
+}
+
+func TestHandler_Import_Incompatible(t *testing.T) {
+	mockRepo := new(MockRepo)
+	svc := source.NewService(mockRepo, nil, nil, nil)
+	handler := source.NewHandler(svc)
+
+	body := `{"kind":"manifest","manifest":{"version":1,"embedding_model":"other-model","dimensions":768}}`
+	req := httptest.NewRequest("POST", "/sources/import", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.Import(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	assert.Contains(t, w.Body.String(), "VALIDATION_ERROR")
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestHandler_Import_InvalidSource(t *testing.T) {
+	mockRepo := new(MockRepo)
+	svc := source.NewService(mockRepo, nil, nil, nil)
+	handler := source.NewHandler(svc)
+
+	body := `{"kind":"manifest","manifest":{"version":1,"embedding_model":"` + config.EmbeddingModel + `","dimensions":768}}
+{"kind":"source","source":{"type":"web","url":"https://example.com","exclusions":["["],"content_hash":"hash-1"}}`
+	req := httptest.NewRequest("POST", "/sources/import", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.Import(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	assert.Contains(t, w.Body.String(), "VALIDATION_ERROR")
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
 func TestHandler_Upload_DefaultDirectory(t *testing.T) {
 	// Ensure environment variable is unset to trigger fallback
 	oldEnv := os.Getenv("QURIO_UPLOAD_DIR")
@@ -445,4 +752,304 @@ func TestHandler_Upload_DefaultDirectory(t *testing.T) {
 
 	// Cleanup
 	os.RemoveAll("./uploads")
-}
\ No newline at end of file
+}
+func TestHandler_GetProgress(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		svc := source.NewService(mockRepo, nil, nil, nil)
+		handler := source.NewHandler(svc)
+
+		mockRepo.On("Get", mock.Anything, "src1").Return(&source.Source{ID: "src1", Status: "in_progress"}, nil)
+		mockRepo.On("GetProgress", mock.Anything, "src1").Return(&source.Progress{
+			SourceID: "src1", PagesTotal: 4, PagesCompleted: 1, PagesFailed: 1, PagesPending: 2,
+			ChunksExpected: 10, ChunksEmbedded: 10,
+		}, nil)
+
+		req := httptest.NewRequest("GET", "/sources/src1/progress", nil)
+		req.SetPathValue("id", "src1")
+		w := httptest.NewRecorder()
+
+		handler.GetProgress(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Data source.Progress `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "in_progress", resp.Data.Status)
+		assert.Equal(t, 50.0, resp.Data.Percent)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		svc := source.NewService(mockRepo, nil, nil, nil)
+		handler := source.NewHandler(svc)
+
+		mockRepo.On("Get", mock.Anything, "missing").Return(nil, sql.ErrNoRows)
+
+		req := httptest.NewRequest("GET", "/sources/missing/progress", nil)
+		req.SetPathValue("id", "missing")
+		w := httptest.NewRecorder()
+
+		handler.GetProgress(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestHandler_StreamProgress(t *testing.T) {
+	mockRepo := new(MockRepo)
+	svc := source.NewService(mockRepo, nil, nil, nil)
+	handler := source.NewHandler(svc)
+
+	mockRepo.On("Get", mock.Anything, "src1").Return(&source.Source{ID: "src1", Status: "in_progress"}, nil).Once()
+	mockRepo.On("Get", mock.Anything, "src1").Return(&source.Source{ID: "src1", Status: "completed"}, nil)
+	mockRepo.On("GetProgress", mock.Anything, "src1").Return(&source.Progress{SourceID: "src1", PagesTotal: 1, PagesEmbedding: 1}, nil).Once()
+	mockRepo.On("GetProgress", mock.Anything, "src1").Return(&source.Progress{SourceID: "src1", PagesTotal: 1, PagesCompleted: 1}, nil)
+
+	req := httptest.NewRequest("GET", "/sources/src1/progress/stream", nil)
+	req.SetPathValue("id", "src1")
+	w := httptest.NewRecorder()
+
+	handler.StreamProgress(w, req)
+
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	events := strings.Split(strings.TrimSpace(w.Body.String()), "\n\n")
+	if assert.Len(t, events, 3) {
+		assert.Contains(t, events[0], "event: progress\ndata: ")
+		assert.Contains(t, events[0], `"percent":0`)
+		assert.Contains(t, events[1], `"percent":100`)
+		assert.Contains(t, events[2], "event: done\ndata: ")
+	}
+}
+
+func TestHandler_StreamProgress_ClosedOnShutdown(t *testing.T) {
+	mockRepo := new(MockRepo)
+	svc := source.NewService(mockRepo, nil, nil, nil)
+	handler := source.NewHandler(svc)
+
+	// The source never finishes
+	mockRepo.On("Get", mock.Anything, "src1").Return(&source.Source{ID: "src1", Status: "in_progress"}, nil)
+	mockRepo.On("GetProgress", mock.Anything, "src1").Return(&source.Progress{SourceID: "src1", PagesTotal: 1, PagesEmbedding: 1}, nil)
+
+	req := httptest.NewRequest("GET", "/sources/src1/progress/stream", nil)
+	req.SetPathValue("id", "src1")
+	w := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		handler.StreamProgress(w, req)
+		close(done)
+	}()
+	handler.CloseStreams()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream still open after CloseStreams")
+	}
+	assert.NotContains(t, w.Body.String(), "event: done")
+}
+
+func TestHandler_GetSyncs(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		svc := source.NewService(mockRepo, nil, nil, nil)
+		handler := source.NewHandler(svc)
+
+		mockRepo.On("Get", mock.Anything, "src1").Return(&source.Source{ID: "src1"}, nil)
+		mockRepo.On("ListSyncs", mock.Anything, "src1").Return([]source.SyncReport{
+			{ID: "s2", SourceID: "src1", PagesAdded: 1, PagesChanged: 2, PagesUnchanged: 10, PagesRemoved: 3},
+		}, nil)
+
+		req := httptest.NewRequest("GET", "/sources/src1/syncs", nil)
+		req.SetPathValue("id", "src1")
+		w := httptest.NewRecorder()
+
+		handler.GetSyncs(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Data []source.SyncReport `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Len(t, resp.Data, 1)
+		assert.Equal(t, 3, resp.Data[0].PagesRemoved)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		svc := source.NewService(mockRepo, nil, nil, nil)
+		handler := source.NewHandler(svc)
+
+		mockRepo.On("Get", mock.Anything, "missing").Return(nil, sql.ErrNoRows)
+
+		req := httptest.NewRequest("GET", "/sources/missing/syncs", nil)
+		req.SetPathValue("id", "missing")
+		w := httptest.NewRecorder()
+
+		handler.GetSyncs(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestHandler_SetVersion(t *testing.T) {
+	t.Run("Busy", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		svc := source.NewService(mockRepo, nil, nil, nil)
+		handler := source.NewHandler(svc)
+
+		mockRepo.On("Get", mock.Anything, "src1").Return(&source.Source{ID: "src1", Version: "v1.x", Status: "in_progress"}, nil)
+
+		req := httptest.NewRequest("POST", "/sources/src1/versions", strings.NewReader(`{"version":"v2.x"}`))
+		req.SetPathValue("id", "src1")
+		w := httptest.NewRecorder()
+
+		handler.SetVersion(w, req)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("MissingVersion", func(t *testing.T) {
+		handler := source.NewHandler(source.NewService(new(MockRepo), nil, nil, nil))
+
+		req := httptest.NewRequest("POST", "/sources/src1/versions", strings.NewReader(`{}`))
+		req.SetPathValue("id", "src1")
+		w := httptest.NewRecorder()
+
+		handler.SetVersion(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestHandler_DiffVersions(t *testing.T) {
+	mockRepo := new(MockRepo)
+	svc := source.NewService(mockRepo, nil, nil, nil)
+	handler := source.NewHandler(svc)
+
+	mockRepo.On("Get", mock.Anything, "src1").Return(&source.Source{ID: "src1", Version: "v2.x"}, nil)
+	mockRepo.On("PageHashes", mock.Anything, "src1", "v1.x").Return(map[string]string{"http://example.com/old": "h1"}, nil)
+	mockRepo.On("PageHashes", mock.Anything, "src1", "").Return(map[string]string{"http://example.com/new": "h2"}, nil)
+
+	req := httptest.NewRequest("GET", "/sources/src1/versions/diff?from=v1.x&to=v2.x", nil)
+	req.SetPathValue("id", "src1")
+	w := httptest.NewRecorder()
+
+	handler.DiffVersions(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data source.VersionDiff `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []string{"http://example.com/new"}, resp.Data.Added)
+	assert.Equal(t, []string{"http://example.com/old"}, resp.Data.Removed)
+	assert.Empty(t, resp.Data.Changed)
+}
+
+func TestHandler_Update(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		handler := source.NewHandler(source.NewService(mockRepo, nil, nil, nil))
+
+		mockRepo.On("Get", mock.Anything, "src1").Return(&source.Source{ID: "src1", Type: "web", Name: "Docs", Status: "completed"}, nil)
+		mockRepo.On("Update", mock.Anything, mock.MatchedBy(func(s *source.Source) bool {
+			return s.SyncEnabled && s.SyncSchedule == "@hourly" && s.Name == "Docs"
+		}), false).Return(nil)
+
+		req := httptest.NewRequest("PATCH", "/sources/src1", strings.NewReader(`{"sync_enabled":true,"sync_schedule":"@hourly"}`))
+		req.SetPathValue("id", "src1")
+		w := httptest.NewRecorder()
+
+		handler.Update(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Data source.Source   `json:"data"`
+			Meta map[string]bool `json:"meta"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.True(t, resp.Data.SyncEnabled)
+		assert.False(t, resp.Meta["resynced"])
+	})
+
+	t.Run("InvalidRegex", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		handler := source.NewHandler(source.NewService(mockRepo, nil, nil, nil))
+		mockRepo.On("Get", mock.Anything, "src1").Return(&source.Source{ID: "src1", Type: "web", Name: "Docs"}, nil)
+
+		req := httptest.NewRequest("PATCH", "/sources/src1", strings.NewReader(`{"include_patterns":["("]}`))
+		req.SetPathValue("id", "src1")
+		w := httptest.NewRecorder()
+
+		handler.Update(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		handler := source.NewHandler(source.NewService(mockRepo, nil, nil, nil))
+		mockRepo.On("Get", mock.Anything, "missing").Return(nil, sql.ErrNoRows)
+
+		req := httptest.NewRequest("PATCH", "/sources/missing", strings.NewReader(`{"name":"x"}`))
+		req.SetPathValue("id", "missing")
+		w := httptest.NewRecorder()
+
+		handler.Update(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestHandler_SetTags(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		handler := source.NewHandler(source.NewService(mockRepo, nil, nil, nil))
+
+		mockRepo.On("Get", mock.Anything, "src1").Return(&source.Source{ID: "src1"}, nil)
+		mockRepo.On("SetTags", mock.Anything, "src1", []string{"backend", "go"}).Return(nil)
+
+		req := httptest.NewRequest("PUT", "/sources/src1/tags", strings.NewReader(`{"tags":["Go","backend"]}`))
+		req.SetPathValue("id", "src1")
+		w := httptest.NewRecorder()
+
+		handler.SetTags(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Data []string `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, []string{"backend", "go"}, resp.Data)
+	})
+
+	t.Run("InvalidTag", func(t *testing.T) {
+		handler := source.NewHandler(source.NewService(new(MockRepo), nil, nil, nil))
+
+		req := httptest.NewRequest("PUT", "/sources/src1/tags", strings.NewReader(`{"tags":[""]}`))
+		req.SetPathValue("id", "src1")
+		w := httptest.NewRecorder()
+
+		handler.SetTags(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestHandler_ListTags(t *testing.T) {
+	mockRepo := new(MockRepo)
+	handler := source.NewHandler(source.NewService(mockRepo, nil, nil, nil))
+	mockRepo.On("ListTags", mock.Anything).Return([]source.Tag{{Name: "go", SourceCount: 2}}, nil)
+
+	req := httptest.NewRequest("GET", "/tags", nil)
+	w := httptest.NewRecorder()
+
+	handler.ListTags(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"source_count":2`)
+}
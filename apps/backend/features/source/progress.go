@@ -0,0 +1,52 @@
+package source
+
+import (
+	"context"
+	"math"
+)
+
+// Progress summarises the ingestion of a source from its pages. A page counts
+// as done once it is completed, failed or skipped; pages in 'embedding' count by the
+// share of their chunks already stored.
+type Progress struct {
+	SourceID       string  `json:"source_id"`
+	Status         string  `json:"status"`
+	Percent        float64 `json:"percent"`
+	PagesTotal     int     `json:"pages_total"`
+	PagesPending   int     `json:"pages_pending"` // pending or processing
+	PagesEmbedding int     `json:"pages_embedding"`
+	PagesCompleted int     `json:"pages_completed"`
+	PagesFailed    int     `json:"pages_failed"`
+	PagesSkipped   int     `json:"pages_skipped"` // over the crawl budget
+	ChunksExpected int     `json:"chunks_expected"`
+	ChunksEmbedded int     `json:"chunks_embedded"`
+
+	embeddingPages float64 // sum of the embedded share of pages in 'embedding'
+}
+
+// Done reports whether the source has stopped ingesting.
+func (p *Progress) Done() bool {
+	return p.Status == "completed" || p.Status == "failed"
+}
+
+func (s *Service) GetProgress(ctx context.Context, id string) (*Progress, error) {
+	src, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := s.repo.GetProgress(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	p.Status = src.Status
+
+	switch {
+	case p.Status == "completed":
+		p.Percent = 100
+	case p.PagesTotal > 0:
+		done := float64(p.PagesCompleted+p.PagesFailed+p.PagesSkipped) + p.embeddingPages
+		p.Percent = math.Round(1000*done/float64(p.PagesTotal)) / 10
+	}
+	return p, nil
+}
@@ -26,15 +26,68 @@ func (r *PostgresRepo) ExistsByHash(ctx context.Context, hash string) (bool, err
 	return exists, nil
 }
 
+// tagsColumn and collectionsColumn select a source's tags and the names of
+// its collections as sorted arrays.
+const (
+	tagsColumn = `COALESCE((SELECT array_agg(t.name ORDER BY t.name) FROM source_tags st
+	          JOIN tags t ON t.id = st.tag_id WHERE st.source_id = sources.id), '{}')`
+	collectionsColumn = `COALESCE((SELECT array_agg(c.name ORDER BY c.name) FROM collection_sources cs
+	          JOIN collections c ON c.id = cs.collection_id WHERE cs.source_id = sources.id), '{}')`
+)
+
 func (r *PostgresRepo) Save(ctx context.Context, src *Source) error {
-	query := `INSERT INTO sources (type, url, content_hash, max_depth, exclusions, name, sync_enabled, sync_schedule, last_synced_at) 
-	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`
+	query := `INSERT INTO sources (type, url, content_hash, max_depth, exclusions, name, sync_enabled, sync_schedule, last_synced_at,
+	          chunk_strategy, chunk_max_tokens, chunk_overlap, include_patterns, path_prefix, keep_query_params, drop_query_params,
+	          seed_urls, allowed_hosts, max_pages, max_crawl_minutes, crawl_started_at, version, keep_versions, git_ref, include_globs, exclude_globs, watch) 
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27) RETURNING id`
 	return r.db.QueryRowContext(ctx, query,
 		src.Type, src.URL, src.ContentHash, src.MaxDepth, pq.Array(src.Exclusions), src.Name,
 		src.SyncEnabled, src.SyncSchedule, src.LastSyncedAt,
+		src.ChunkStrategy, src.ChunkMaxTokens, src.ChunkOverlap,
+		pq.Array(src.IncludePatterns), src.PathPrefix, pq.Array(src.KeepQueryParams), pq.Array(src.DropQueryParams),
+		pq.Array(src.SeedURLs), pq.Array(src.AllowedHosts), src.MaxPages, src.MaxCrawlMinutes, src.CrawlStartedAt,
+		src.Version, src.KeepVersions, src.GitRef, pq.Array(src.IncludeGlobs), pq.Array(src.ExcludeGlobs), src.Watch,
 	).Scan(&src.ID)
 }
 
+// Update saves the editable settings and content hash of a source. With
+// withTags set, src.Tags replace its tags in the same transaction.
+func (r *PostgresRepo) Update(ctx context.Context, src *Source, withTags bool) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE sources SET name = $1, max_depth = $2, exclusions = $3, sync_enabled = $4, sync_schedule = $5,
+	          include_patterns = $6, path_prefix = $7, keep_query_params = $8, drop_query_params = $9,
+	          seed_urls = $10, allowed_hosts = $11, max_pages = $12, max_crawl_minutes = $13, keep_versions = $14,
+	          git_ref = $15, include_globs = $16, exclude_globs = $17, watch = $18, content_hash = NULLIF($19, ''), updated_at = NOW()
+	          WHERE id = $20 AND deleted_at IS NULL`
+	res, err := tx.ExecContext(ctx, query,
+		src.Name, src.MaxDepth, pq.Array(src.Exclusions), src.SyncEnabled, src.SyncSchedule,
+		pq.Array(src.IncludePatterns), src.PathPrefix, pq.Array(src.KeepQueryParams), pq.Array(src.DropQueryParams),
+		pq.Array(src.SeedURLs), pq.Array(src.AllowedHosts), src.MaxPages, src.MaxCrawlMinutes, src.KeepVersions,
+		src.GitRef, pq.Array(src.IncludeGlobs), pq.Array(src.ExcludeGlobs), src.Watch, src.ContentHash, src.ID,
+	)
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	if withTags {
+		if err := setTags(ctx, tx, src.ID, src.Tags); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
 func (r *PostgresRepo) UpdateStatus(ctx context.Context, id, status string) error {
 	query := `UPDATE sources SET status = $1, updated_at = NOW() WHERE id = $2`
 	_, err := r.db.ExecContext(ctx, query, status, id)
@@ -42,7 +95,10 @@ func (r *PostgresRepo) UpdateStatus(ctx context.Context, id, status string) erro
 }
 
 func (r *PostgresRepo) List(ctx context.Context) ([]Source, error) {
-	query := `SELECT id, type, url, status, max_depth, exclusions, name, sync_enabled, sync_schedule, last_synced_at, updated_at 
+	query := `SELECT id, type, url, status, max_depth, exclusions, name, sync_enabled, sync_schedule, last_synced_at, updated_at,
+	          chunk_strategy, chunk_max_tokens, chunk_overlap, include_patterns, path_prefix, keep_query_params, drop_query_params,
+	          seed_urls, allowed_hosts, max_pages, max_crawl_minutes, crawl_started_at, version, keep_versions,
+	          git_ref, commit_sha, include_globs, exclude_globs, watch, ` + tagsColumn + `, ` + collectionsColumn + `
 	          FROM sources WHERE deleted_at IS NULL ORDER BY created_at DESC`
 	rows, err := r.db.QueryContext(ctx, query)
 	if err != nil {
@@ -56,6 +112,11 @@ func (r *PostgresRepo) List(ctx context.Context) ([]Source, error) {
 		if err := rows.Scan(
 			&s.ID, &s.Type, &s.URL, &s.Status, &s.MaxDepth, pq.Array(&s.Exclusions),
 			&s.Name, &s.SyncEnabled, &s.SyncSchedule, &s.LastSyncedAt, &s.UpdatedAt,
+			&s.ChunkStrategy, &s.ChunkMaxTokens, &s.ChunkOverlap,
+			pq.Array(&s.IncludePatterns), &s.PathPrefix, pq.Array(&s.KeepQueryParams), pq.Array(&s.DropQueryParams),
+			pq.Array(&s.SeedURLs), pq.Array(&s.AllowedHosts), &s.MaxPages, &s.MaxCrawlMinutes, &s.CrawlStartedAt,
+			&s.Version, &s.KeepVersions, &s.GitRef, &s.CommitSHA, pq.Array(&s.IncludeGlobs), pq.Array(&s.ExcludeGlobs), &s.Watch,
+			pq.Array(&s.Tags), pq.Array(&s.Collections),
 		); err != nil {
 			return nil, err
 		}
@@ -66,11 +127,20 @@ func (r *PostgresRepo) List(ctx context.Context) ([]Source, error) {
 
 func (r *PostgresRepo) Get(ctx context.Context, id string) (*Source, error) {
 	s := &Source{}
-	query := `SELECT id, type, url, status, max_depth, exclusions, name, sync_enabled, sync_schedule, last_synced_at, updated_at 
+	query := `SELECT id, type, url, status, max_depth, exclusions, name, sync_enabled, sync_schedule, last_synced_at, updated_at,
+	          COALESCE(content_hash, ''), chunk_strategy, chunk_max_tokens, chunk_overlap,
+	          include_patterns, path_prefix, keep_query_params, drop_query_params,
+	          seed_urls, allowed_hosts, max_pages, max_crawl_minutes, crawl_started_at, version, keep_versions,
+	          git_ref, commit_sha, include_globs, exclude_globs, watch, ` + tagsColumn + `, ` + collectionsColumn + `
 	          FROM sources WHERE id = $1 AND deleted_at IS NULL`
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&s.ID, &s.Type, &s.URL, &s.Status, &s.MaxDepth, pq.Array(&s.Exclusions),
 		&s.Name, &s.SyncEnabled, &s.SyncSchedule, &s.LastSyncedAt, &s.UpdatedAt,
+		&s.ContentHash, &s.ChunkStrategy, &s.ChunkMaxTokens, &s.ChunkOverlap,
+		pq.Array(&s.IncludePatterns), &s.PathPrefix, pq.Array(&s.KeepQueryParams), pq.Array(&s.DropQueryParams),
+		pq.Array(&s.SeedURLs), pq.Array(&s.AllowedHosts), &s.MaxPages, &s.MaxCrawlMinutes, &s.CrawlStartedAt,
+		&s.Version, &s.KeepVersions, &s.GitRef, &s.CommitSHA, pq.Array(&s.IncludeGlobs), pq.Array(&s.ExcludeGlobs), &s.Watch,
+		pq.Array(&s.Tags), pq.Array(&s.Collections),
 	)
 	if err != nil {
 		return nil, err
@@ -94,9 +164,16 @@ func (r *PostgresRepo) SoftDelete(ctx context.Context, id string) error {
 	return nil
 }
 
-func (r *PostgresRepo) UpdateBodyHash(ctx context.Context, id, hash string) error {
-	query := `UPDATE sources SET body_hash = $1, updated_at = NOW() WHERE id = $2`
-	_, err := r.db.ExecContext(ctx, query, hash, id)
+func (r *PostgresRepo) StartCrawl(ctx context.Context, id string) error {
+	query := `UPDATE sources SET status = 'in_progress', crawl_started_at = NOW(), updated_at = NOW() WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// SetCommitSHA records the commit a git source was last synced at.
+func (r *PostgresRepo) SetCommitSHA(ctx context.Context, id, sha string) error {
+	query := `UPDATE sources SET commit_sha = $1, updated_at = NOW() WHERE id = $2`
+	_, err := r.db.ExecContext(ctx, query, sha, id)
 	return err
 }
 
@@ -112,9 +189,15 @@ func (r *PostgresRepo) BulkCreatePages(ctx context.Context, pages []SourcePage)
 		return nil, nil
 	}
 
-	query := `INSERT INTO source_pages (source_id, url, status, depth) 
-              VALUES ($1, $2, $3, $4) 
-              ON CONFLICT (source_id, url) DO NOTHING
+	// Pages left stale by a resync come back when found again, keeping
+	// their content hash so unchanged pages aren't embedded twice.
+	query := `INSERT INTO source_pages (source_id, url, status, depth, lastmod, change, mtime, file_hash, correlation_id) 
+              VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, NULLIF($8, ''), $9) 
+              ON CONFLICT (source_id, url) DO UPDATE
+              SET status = EXCLUDED.status, depth = EXCLUDED.depth, lastmod = EXCLUDED.lastmod,
+                  change = EXCLUDED.change, mtime = EXCLUDED.mtime, file_hash = EXCLUDED.file_hash,
+                  correlation_id = EXCLUDED.correlation_id, error = NULL, updated_at = NOW()
+              WHERE source_pages.status = 'stale'
               RETURNING url`
 
 	tx, err := r.db.BeginTx(ctx, nil)
@@ -132,7 +215,7 @@ func (r *PostgresRepo) BulkCreatePages(ctx context.Context, pages []SourcePage)
 	var newURLs []string
 	for _, p := range pages {
 		var u string
-		err := stmt.QueryRowContext(ctx, p.SourceID, p.URL, p.Status, p.Depth).Scan(&u)
+		err := stmt.QueryRowContext(ctx, p.SourceID, p.URL, p.Status, p.Depth, p.LastMod, p.Change, p.ModTime, p.FileHash, p.CorrelationID).Scan(&u)
 		if err == nil {
 			newURLs = append(newURLs, u)
 		} else if err != sql.ErrNoRows {
@@ -156,8 +239,68 @@ func (r *PostgresRepo) UpdatePageStatus(ctx context.Context, sourceID, url, stat
 	return err
 }
 
+// SetPageChunks records that expected chunks of a page were queued for
+// embedding. Pages without a row yet, such as uploaded files, are created.
+func (r *PostgresRepo) SetPageChunks(ctx context.Context, sourceID, url string, depth, expected int) error {
+	query := `INSERT INTO source_pages (source_id, url, status, depth, chunks_expected, chunks_embedded)
+              VALUES ($1, $2, 'embedding', $3, $4, 0)
+              ON CONFLICT (source_id, url) DO UPDATE
+              SET status = 'embedding', chunks_expected = EXCLUDED.chunks_expected, chunks_embedded = 0,
+                  embedded_chunks = '{}', error = NULL, updated_at = NOW()`
+	_, err := r.db.ExecContext(ctx, query, sourceID, url, depth, expected)
+	return err
+}
+
+// MarkChunkEmbedded counts one stored chunk of a page and completes the page
+// once all of its chunks are stored. It reports whether the page was
+// completed by this call; a chunk already counted changes nothing, so
+// redelivered embed messages are harmless.
+func (r *PostgresRepo) MarkChunkEmbedded(ctx context.Context, sourceID, url string, chunkIndex int) (bool, error) {
+	query := `UPDATE source_pages
+              SET embedded_chunks = array_append(embedded_chunks, $3::int),
+                  chunks_embedded = chunks_embedded + 1,
+                  status = CASE WHEN status = 'embedding' AND chunks_embedded + 1 >= chunks_expected THEN 'completed' ELSE status END,
+                  updated_at = NOW()
+              WHERE source_id = $1 AND url = $2 AND NOT ($3::int = ANY(embedded_chunks))
+              RETURNING status`
+	var status string
+	err := r.db.QueryRowContext(ctx, query, sourceID, url, chunkIndex).Scan(&status)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return status == "completed", nil
+}
+
+func (r *PostgresRepo) GetProgress(ctx context.Context, sourceID string) (*Progress, error) {
+	query := `SELECT COUNT(*),
+                     COUNT(*) FILTER (WHERE status IN ('pending', 'processing')),
+                     COUNT(*) FILTER (WHERE status = 'embedding'),
+                     COUNT(*) FILTER (WHERE status = 'completed'),
+                     COUNT(*) FILTER (WHERE status = 'failed'),
+                     COUNT(*) FILTER (WHERE status = 'skipped'),
+                     COALESCE(SUM(chunks_expected), 0),
+                     COALESCE(SUM(chunks_embedded), 0),
+                     COALESCE(SUM(CASE WHEN status = 'embedding' AND chunks_expected > 0
+                                       THEN LEAST(chunks_embedded, chunks_expected)::float / chunks_expected
+                                       ELSE 0 END), 0)
+              FROM source_pages
+              WHERE source_id = $1 AND status <> 'stale'`
+	p := &Progress{SourceID: sourceID}
+	err := r.db.QueryRowContext(ctx, query, sourceID).Scan(
+		&p.PagesTotal, &p.PagesPending, &p.PagesEmbedding, &p.PagesCompleted, &p.PagesFailed, &p.PagesSkipped,
+		&p.ChunksExpected, &p.ChunksEmbedded, &p.embeddingPages)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
 func (r *PostgresRepo) GetPages(ctx context.Context, sourceID string) ([]SourcePage, error) {
-	query := `SELECT id, source_id, url, status, depth, COALESCE(error, ''), created_at, updated_at 
+	query := `SELECT id, source_id, url, status, depth, COALESCE(error, ''), chunks_expected, chunks_embedded, lastmod, COALESCE(change, ''),
+                     mtime, COALESCE(file_hash, ''), COALESCE(chunk_key, ''), created_at, updated_at 
               FROM source_pages 
               WHERE source_id = $1 
               ORDER BY created_at ASC`
@@ -170,7 +313,7 @@ func (r *PostgresRepo) GetPages(ctx context.Context, sourceID string) ([]SourceP
 	var pages []SourcePage
 	for rows.Next() {
 		var p SourcePage
-		if err := rows.Scan(&p.ID, &p.SourceID, &p.URL, &p.Status, &p.Depth, &p.Error, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		if err := rows.Scan(&p.ID, &p.SourceID, &p.URL, &p.Status, &p.Depth, &p.Error, &p.ChunksExpected, &p.ChunksEmbedded, &p.LastMod, &p.Change, &p.ModTime, &p.FileHash, &p.ChunkKey, &p.CreatedAt, &p.UpdatedAt); err != nil {
 			return nil, err
 		}
 		pages = append(pages, p)
@@ -187,15 +330,290 @@ func (r *PostgresRepo) DeletePages(ctx context.Context, sourceID string) error {
 func (r *PostgresRepo) CountPendingPages(ctx context.Context, sourceID string) (int, error) {
 	var count int
 	query := `SELECT COUNT(*) FROM source_pages 
-              WHERE source_id = $1 AND (status = 'pending' OR status = 'processing')`
+              WHERE source_id = $1 AND (status = 'pending' OR status = 'processing' OR status = 'embedding')`
+	err := r.db.QueryRowContext(ctx, query, sourceID).Scan(&count)
+	return count, err
+}
+
+func (r *PostgresRepo) PendingPages(ctx context.Context, perSource int) ([]SourcePage, error) {
+	query := `SELECT source_id, url, status, depth, correlation_id FROM (
+                SELECT p.source_id, p.url, p.status, p.depth, p.correlation_id,
+                       ROW_NUMBER() OVER (PARTITION BY p.source_id ORDER BY p.depth, p.created_at) AS rn
+                FROM source_pages p
+                JOIN sources s ON s.id = p.source_id AND s.deleted_at IS NULL AND s.type = 'web'
+                WHERE p.status = 'pending'
+              ) ranked
+              WHERE rn <= $1
+              ORDER BY source_id, rn`
+	rows, err := r.db.QueryContext(ctx, query, perSource)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pages []SourcePage
+	for rows.Next() {
+		var p SourcePage
+		if err := rows.Scan(&p.SourceID, &p.URL, &p.Status, &p.Depth, &p.CorrelationID); err != nil {
+			return nil, err
+		}
+		pages = append(pages, p)
+	}
+	return pages, rows.Err()
+}
+
+func (r *PostgresRepo) ClaimPage(ctx context.Context, sourceID, url string) (bool, error) {
+	query := `UPDATE source_pages SET status = 'processing', updated_at = NOW()
+              WHERE source_id = $1 AND url = $2 AND status = 'pending'`
+	res, err := r.db.ExecContext(ctx, query, sourceID, url)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (r *PostgresRepo) CountReleasedPages(ctx context.Context, sourceID string) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM source_pages 
+              WHERE source_id = $1 AND status NOT IN ('pending', 'skipped', 'stale')`
 	err := r.db.QueryRowContext(ctx, query, sourceID).Scan(&count)
 	return count, err
 }
 
+func (r *PostgresRepo) SkipPendingPages(ctx context.Context, sourceID, reason string) (int64, error) {
+	query := `UPDATE source_pages SET status = 'skipped', error = $2, updated_at = NOW()
+              WHERE source_id = $1 AND status = 'pending'`
+	res, err := r.db.ExecContext(ctx, query, sourceID, reason)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// MarkPagesStale starts a resync: every page is stale until the crawl finds
+// it again.
+func (r *PostgresRepo) MarkPagesStale(ctx context.Context, sourceID string) error {
+	query := `UPDATE source_pages SET status = 'stale', change = NULL, error = NULL, updated_at = NOW()
+              WHERE source_id = $1`
+	_, err := r.db.ExecContext(ctx, query, sourceID)
+	return err
+}
+
+// RecordPageContent stores the hash, chunk key and HTTP validators of a
+// crawled page and returns how it compares to the previous crawl: added,
+// changed or unchanged. A page whose chunks weren't all stored last time, or
+// were made under another chunk key, counts as changed, and a redelivered
+// result keeps the verdict it got the first time.
+func (r *PostgresRepo) RecordPageContent(ctx context.Context, sourceID, url, hash, chunkKey, etag, lastModified string) (string, error) {
+	query := `UPDATE source_pages
+              SET change = CASE WHEN change IN ('added', 'changed') THEN change
+                                WHEN content_hash IS NULL THEN 'added'
+                                WHEN content_hash = $3 AND chunk_key = $4 AND chunks_embedded >= chunks_expected THEN 'unchanged'
+                                ELSE 'changed' END,
+                  content_hash = $3, chunk_key = $4, etag = NULLIF($5, ''), last_modified = NULLIF($6, ''), updated_at = NOW()
+              WHERE source_id = $1 AND url = $2
+              RETURNING change`
+	var change string
+	err := r.db.QueryRowContext(ctx, query, sourceID, url, hash, chunkKey, etag, lastModified).Scan(&change)
+	if err == sql.ErrNoRows {
+		// No page row, e.g. uploaded files: nothing to compare with
+		return PageAdded, nil
+	}
+	return change, err
+}
+
+// FinishSync completes a source: pages still stale have disappeared and are
+// deleted, and the sync's counts are recorded. It returns the report and the
+// removed URLs, or nil if the source was completed already.
+func (r *PostgresRepo) FinishSync(ctx context.Context, sourceID string) (*SyncReport, []string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE sources SET status = 'completed', updated_at = NOW() WHERE id = $1 AND status <> 'completed'`, sourceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return nil, nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `DELETE FROM source_pages WHERE source_id = $1 AND status = 'stale' RETURNING url`, sourceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var removed []string
+	for rows.Next() {
+		var u string
+		if err := rows.Scan(&u); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		removed = append(removed, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	report := &SyncReport{SourceID: sourceID, PagesRemoved: len(removed)}
+	query := `INSERT INTO source_syncs (source_id, started_at, pages_added, pages_changed, pages_unchanged, pages_removed)
+              SELECT $1, (SELECT crawl_started_at FROM sources WHERE id = $1),
+                     COUNT(*) FILTER (WHERE change = 'added'),
+                     COUNT(*) FILTER (WHERE change = 'changed'),
+                     COUNT(*) FILTER (WHERE change = 'unchanged'),
+                     $2
+              FROM source_pages WHERE source_id = $1
+              RETURNING id, started_at, finished_at, pages_added, pages_changed, pages_unchanged`
+	if err := tx.QueryRowContext(ctx, query, sourceID, len(removed)).Scan(
+		&report.ID, &report.StartedAt, &report.FinishedAt, &report.PagesAdded, &report.PagesChanged, &report.PagesUnchanged,
+	); err != nil {
+		return nil, nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	return report, removed, nil
+}
+
+func (r *PostgresRepo) ListSyncs(ctx context.Context, sourceID string) ([]SyncReport, error) {
+	query := `SELECT id, source_id, started_at, finished_at, pages_added, pages_changed, pages_unchanged, pages_removed
+              FROM source_syncs WHERE source_id = $1
+              ORDER BY finished_at DESC LIMIT 50`
+	rows, err := r.db.QueryContext(ctx, query, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	syncs := []SyncReport{}
+	for rows.Next() {
+		var s SyncReport
+		if err := rows.Scan(&s.ID, &s.SourceID, &s.StartedAt, &s.FinishedAt, &s.PagesAdded, &s.PagesChanged, &s.PagesUnchanged, &s.PagesRemoved); err != nil {
+			return nil, err
+		}
+		syncs = append(syncs, s)
+	}
+	return syncs, rows.Err()
+}
+
+func (r *PostgresRepo) SaveVersion(ctx context.Context, sourceID, current, next string, keep int) ([]string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if keep > 0 && current != "" {
+		var versionID string
+		query := `INSERT INTO source_versions (source_id, version, page_count)
+                  SELECT $1, $2, COUNT(*) FROM source_pages WHERE source_id = $1 AND status = 'completed'
+                  ON CONFLICT (source_id, version) DO UPDATE SET page_count = EXCLUDED.page_count, created_at = NOW()
+                  RETURNING id`
+		if err := tx.QueryRowContext(ctx, query, sourceID, current).Scan(&versionID); err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM source_version_pages WHERE version_id = $1`, versionID); err != nil {
+			return nil, err
+		}
+		query = `INSERT INTO source_version_pages (version_id, url, content_hash)
+                 SELECT $1, url, content_hash FROM source_pages WHERE source_id = $2 AND status = 'completed'`
+		if _, err := tx.ExecContext(ctx, query, versionID, sourceID); err != nil {
+			return nil, err
+		}
+	}
+
+	// A snapshot of the next version is superseded by the live content, and
+	// only the newest keep snapshots are retained.
+	query := `DELETE FROM source_versions WHERE source_id = $1 AND (version = $2 OR id NOT IN (
+                  SELECT id FROM source_versions WHERE source_id = $1 AND version <> $2 ORDER BY created_at DESC LIMIT $3))
+              RETURNING version`
+	rows, err := tx.QueryContext(ctx, query, sourceID, next, keep)
+	if err != nil {
+		return nil, err
+	}
+	var dropped []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		dropped = append(dropped, v)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE sources SET version = $1, updated_at = NOW() WHERE id = $2`, next, sourceID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return dropped, nil
+}
+
+func (r *PostgresRepo) ListVersions(ctx context.Context, sourceID string) ([]Version, error) {
+	query := `SELECT id, source_id, version, page_count, created_at
+              FROM source_versions WHERE source_id = $1
+              ORDER BY created_at DESC`
+	rows, err := r.db.QueryContext(ctx, query, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []Version{}
+	for rows.Next() {
+		var v Version
+		if err := rows.Scan(&v.ID, &v.SourceID, &v.Version, &v.PageCount, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+func (r *PostgresRepo) PageHashes(ctx context.Context, sourceID, version string) (map[string]string, error) {
+	var rows *sql.Rows
+	var err error
+	if version == "" {
+		rows, err = r.db.QueryContext(ctx, `SELECT url, COALESCE(content_hash, '') FROM source_pages WHERE source_id = $1 AND status = 'completed'`, sourceID)
+	} else {
+		var versionID string
+		if err := r.db.QueryRowContext(ctx, `SELECT id FROM source_versions WHERE source_id = $1 AND version = $2`, sourceID, version).Scan(&versionID); err != nil {
+			return nil, err
+		}
+		rows, err = r.db.QueryContext(ctx, `SELECT url, COALESCE(content_hash, '') FROM source_version_pages WHERE version_id = $1`, versionID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hashes := make(map[string]string)
+	for rows.Next() {
+		var u, hash string
+		if err := rows.Scan(&u, &hash); err != nil {
+			return nil, err
+		}
+		hashes[u] = hash
+	}
+	return hashes, rows.Err()
+}
+
+// ResetStuckPages hands crawl pages that got lost back to the dispatcher;
+// pages of other sources are published directly and never dispatched.
 func (r *PostgresRepo) ResetStuckPages(ctx context.Context, timeout time.Duration) (int64, error) {
 	query := `UPDATE source_pages 
               SET status = 'pending', updated_at = NOW(), error = 'timeout_reset' 
-              WHERE status = 'processing' AND updated_at < $1`
+              WHERE status = 'processing' AND updated_at < $1
+                AND source_id IN (SELECT id FROM sources WHERE type = 'web')`
 
 	cutoff := time.Now().Add(-timeout)
 
@@ -206,11 +624,60 @@ func (r *PostgresRepo) ResetStuckPages(ctx context.Context, timeout time.Duratio
 	return result.RowsAffected()
 }
 
+// FailStuckEmbeddingPages fails the pages that stored no chunk for timeout,
+// their embed messages having been lost, and returns their sources.
+func (r *PostgresRepo) FailStuckEmbeddingPages(ctx context.Context, timeout time.Duration) ([]string, error) {
+	query := `UPDATE source_pages
+              SET status = 'failed', updated_at = NOW(), error = 'embedding timed out'
+              WHERE status = 'embedding' AND updated_at < $1
+              RETURNING source_id`
+	return r.failStuckPages(ctx, query, timeout)
+}
+
+// FailStuckFilePages fails the file pages of git and directory sources that
+// got no result for timeout, their file tasks or results having been lost,
+// and returns their sources. Unlike crawled pages they aren't requeued: the
+// dispatcher only releases web pages.
+func (r *PostgresRepo) FailStuckFilePages(ctx context.Context, timeout time.Duration) ([]string, error) {
+	query := `UPDATE source_pages
+              SET status = 'failed', updated_at = NOW(), error = 'file processing timed out'
+              WHERE status = 'processing' AND updated_at < $1
+                AND source_id IN (SELECT id FROM sources WHERE type <> 'web')
+              RETURNING source_id`
+	return r.failStuckPages(ctx, query, timeout)
+}
+
+// failStuckPages runs a query failing stuck pages and returns the distinct
+// sources of the pages it returned.
+func (r *PostgresRepo) failStuckPages(ctx context.Context, query string, timeout time.Duration) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, query, time.Now().Add(-timeout))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seen := make(map[string]bool)
+	var sourceIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		if !seen[id] {
+			seen[id] = true
+			sourceIDs = append(sourceIDs, id)
+		}
+	}
+	return sourceIDs, rows.Err()
+}
+
 func (r *PostgresRepo) ListSyncDue(ctx context.Context) ([]Source, error) {
 	// Select sources that are enabled and not deleted
 	// Checking the schedule logic here in SQL is tricky because the interval varies per row.
 	// Simpler approach: Fetch ALL enabled sources and filter in Go.
-	query := `SELECT id, type, url, status, max_depth, exclusions, name, sync_enabled, sync_schedule, last_synced_at, updated_at 
+	query := `SELECT id, type, url, status, max_depth, exclusions, name, sync_enabled, sync_schedule, last_synced_at, updated_at,
+	          chunk_strategy, chunk_max_tokens, chunk_overlap, include_patterns, path_prefix, keep_query_params, drop_query_params,
+	          seed_urls, allowed_hosts, max_pages, max_crawl_minutes, crawl_started_at, version, keep_versions
 	          FROM sources WHERE sync_enabled = true AND deleted_at IS NULL`
 
 	rows, err := r.db.QueryContext(ctx, query)
@@ -225,6 +692,10 @@ func (r *PostgresRepo) ListSyncDue(ctx context.Context) ([]Source, error) {
 		if err := rows.Scan(
 			&s.ID, &s.Type, &s.URL, &s.Status, &s.MaxDepth, pq.Array(&s.Exclusions),
 			&s.Name, &s.SyncEnabled, &s.SyncSchedule, &s.LastSyncedAt, &s.UpdatedAt,
+			&s.ChunkStrategy, &s.ChunkMaxTokens, &s.ChunkOverlap,
+			pq.Array(&s.IncludePatterns), &s.PathPrefix, pq.Array(&s.KeepQueryParams), pq.Array(&s.DropQueryParams),
+			pq.Array(&s.SeedURLs), pq.Array(&s.AllowedHosts), &s.MaxPages, &s.MaxCrawlMinutes, &s.CrawlStartedAt,
+			&s.Version, &s.KeepVersions,
 		); err != nil {
 			return nil, err
 		}
@@ -238,3 +709,73 @@ func (r *PostgresRepo) UpdateLastSyncedAt(ctx context.Context, id string, t time
 	_, err := r.db.ExecContext(ctx, query, t, id)
 	return err
 }
+
+func (r *PostgresRepo) SetTags(ctx context.Context, sourceID string, tags []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setTags(ctx, tx, sourceID, tags); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func setTags(ctx context.Context, tx *sql.Tx, sourceID string, tags []string) error {
+	if len(tags) > 0 {
+		query := `INSERT INTO tags (name) SELECT unnest($1::text[]) ON CONFLICT (name) DO NOTHING`
+		if _, err := tx.ExecContext(ctx, query, pq.Array(tags)); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM source_tags WHERE source_id = $1`, sourceID); err != nil {
+		return err
+	}
+	if len(tags) > 0 {
+		query := `INSERT INTO source_tags (source_id, tag_id) SELECT $1, id FROM tags WHERE name = ANY($2)`
+		if _, err := tx.ExecContext(ctx, query, sourceID, pq.Array(tags)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListTags returns every tag with the number of live sources carrying it.
+func (r *PostgresRepo) ListTags(ctx context.Context) ([]Tag, error) {
+	query := `SELECT t.name, COUNT(s.id) FROM tags t
+	          LEFT JOIN source_tags st ON st.tag_id = t.id
+	          LEFT JOIN sources s ON s.id = st.source_id AND s.deleted_at IS NULL
+	          GROUP BY t.name ORDER BY t.name`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []Tag{}
+	for rows.Next() {
+		var t Tag
+		if err := rows.Scan(&t.Name, &t.SourceCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+func (r *PostgresRepo) DeleteTag(ctx context.Context, name string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM tags WHERE name = $1`, name)
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
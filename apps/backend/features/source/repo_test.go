@@ -2,6 +2,7 @@ package source_test
 
 import (
 	"context"
+	"database/sql"
 	"regexp"
 	"testing"
 	"time"
@@ -45,10 +46,23 @@ func TestPostgresRepo_Save(t *testing.T) {
 			MaxDepth:    2,
 			Exclusions:  []string{},
 			Name:        "Example",
+
+			ChunkStrategy:  "paragraph",
+			ChunkMaxTokens: 256,
+
+			IncludePatterns: []string{"/docs/"},
+			PathPrefix:      "/docs",
+			AllowedHosts:    []string{"*.example.com"},
+			MaxPages:        500,
+
+			Version:      "v2.x",
+			KeepVersions: 3,
 		}
 
-		mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO sources (type, url, content_hash, max_depth, exclusions, name) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id")).
-			WithArgs(src.Type, src.URL, src.ContentHash, src.MaxDepth, pq.Array(src.Exclusions), src.Name).
+		mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO sources (type, url, content_hash, max_depth, exclusions, name, sync_enabled, sync_schedule, last_synced_at, chunk_strategy, chunk_max_tokens, chunk_overlap, include_patterns, path_prefix, keep_query_params, drop_query_params, seed_urls, allowed_hosts, max_pages, max_crawl_minutes, crawl_started_at, version, keep_versions, git_ref, include_globs, exclude_globs, watch) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27) RETURNING id")).
+			WithArgs(src.Type, src.URL, src.ContentHash, src.MaxDepth, pq.Array(src.Exclusions), src.Name, false, "", nil, "paragraph", 256, 0,
+				pq.Array([]string{"/docs/"}), "/docs", pq.Array([]string(nil)), pq.Array([]string(nil)),
+				pq.Array([]string(nil)), pq.Array([]string{"*.example.com"}), 500, 0, sqlmock.AnyArg(), "v2.x", 3, "", pq.Array([]string(nil)), pq.Array([]string(nil)), false).
 			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("1"))
 
 		err := repo.Save(context.Background(), src)
@@ -65,16 +79,32 @@ func TestPostgresRepo_Get(t *testing.T) {
 	repo := source.NewPostgresRepo(db)
 
 	t.Run("Success", func(t *testing.T) {
-		rows := sqlmock.NewRows([]string{"id", "type", "url", "status", "max_depth", "exclusions", "name", "updated_at"}).
-			AddRow("1", "web", "http://example.com", "pending", 2, pq.Array([]string{}), "Example", time.Now())
+		rows := sqlmock.NewRows([]string{"id", "type", "url", "status", "max_depth", "exclusions", "name", "sync_enabled", "sync_schedule", "last_synced_at", "updated_at", "content_hash", "chunk_strategy", "chunk_max_tokens", "chunk_overlap", "include_patterns", "path_prefix", "keep_query_params", "drop_query_params", "seed_urls", "allowed_hosts", "max_pages", "max_crawl_minutes", "crawl_started_at", "version", "keep_versions", "git_ref", "commit_sha", "include_globs", "exclude_globs", "watch", "tags", "collections"}).
+			AddRow("1", "web", "http://example.com", "pending", 2, pq.Array([]string{}), "Example", false, "", nil, time.Now(), "hash", "fixed-window", 128, 16, "{/docs/}", "/docs", "{}", "{sid}", "{http://api.example.com}", "{*.example.com}", 200, 30, time.Now(), "v2.x", 3, "", "", "{}", "{}", false, "{backend,go}", "{payments}")
 
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT id, type, url, status, max_depth, exclusions, name, updated_at FROM sources WHERE id = $1 AND deleted_at IS NULL")).
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id, type, url, status, max_depth, exclusions, name, sync_enabled, sync_schedule, last_synced_at, updated_at, COALESCE(content_hash, ''), chunk_strategy, chunk_max_tokens, chunk_overlap, include_patterns, path_prefix, keep_query_params, drop_query_params, seed_urls, allowed_hosts, max_pages, max_crawl_minutes, crawl_started_at, version, keep_versions, git_ref, commit_sha, include_globs, exclude_globs, watch, COALESCE((SELECT array_agg(t.name ORDER BY t.name) FROM source_tags st") + ".+" + regexp.QuoteMeta("FROM sources WHERE id = $1 AND deleted_at IS NULL")).
 			WithArgs("1").
 			WillReturnRows(rows)
 
 		s, err := repo.Get(context.Background(), "1")
 		assert.NoError(t, err)
 		assert.Equal(t, "1", s.ID)
+		assert.Equal(t, "hash", s.ContentHash)
+		assert.Equal(t, "fixed-window", s.ChunkStrategy)
+		assert.Equal(t, 128, s.ChunkMaxTokens)
+		assert.Equal(t, 16, s.ChunkOverlap)
+		assert.Equal(t, []string{"/docs/"}, s.IncludePatterns)
+		assert.Equal(t, "/docs", s.PathPrefix)
+		assert.Equal(t, []string{"sid"}, s.DropQueryParams)
+		assert.Equal(t, []string{"http://api.example.com"}, s.SeedURLs)
+		assert.Equal(t, []string{"*.example.com"}, s.AllowedHosts)
+		assert.Equal(t, 200, s.MaxPages)
+		assert.Equal(t, 30, s.MaxCrawlMinutes)
+		assert.NotNil(t, s.CrawlStartedAt)
+		assert.Equal(t, "v2.x", s.Version)
+		assert.Equal(t, 3, s.KeepVersions)
+		assert.Equal(t, []string{"backend", "go"}, s.Tags)
+		assert.Equal(t, []string{"payments"}, s.Collections)
 	})
 }
 
@@ -86,15 +116,20 @@ func TestPostgresRepo_List(t *testing.T) {
 	repo := source.NewPostgresRepo(db)
 
 	t.Run("Success", func(t *testing.T) {
-		rows := sqlmock.NewRows([]string{"id", "type", "url", "status", "max_depth", "exclusions", "name", "updated_at"}).
-			AddRow("1", "website", "http://example.com", "pending", 2, pq.Array([]string{}), "Example", time.Now())
+		rows := sqlmock.NewRows([]string{"id", "type", "url", "status", "max_depth", "exclusions", "name", "sync_enabled", "sync_schedule", "last_synced_at", "updated_at", "chunk_strategy", "chunk_max_tokens", "chunk_overlap", "include_patterns", "path_prefix", "keep_query_params", "drop_query_params", "seed_urls", "allowed_hosts", "max_pages", "max_crawl_minutes", "crawl_started_at", "version", "keep_versions", "git_ref", "commit_sha", "include_globs", "exclude_globs", "watch", "tags", "collections"}).
+			AddRow("1", "website", "http://example.com", "pending", 2, pq.Array([]string{}), "Example", false, "", nil, time.Now(), "markdown-structural", 512, 50, "{}", "", "{}", "{}", "{}", "{}", 0, 0, nil, "", 0, "main", "abc123", "{docs/**/*.md}", "{drafts/**}", true, "{}", "{}")
 
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT id, type, url, status, max_depth, exclusions, name, updated_at FROM sources WHERE deleted_at IS NULL ORDER BY created_at DESC")).
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id, type, url, status, max_depth, exclusions, name, sync_enabled, sync_schedule, last_synced_at, updated_at, chunk_strategy, chunk_max_tokens, chunk_overlap, include_patterns, path_prefix, keep_query_params, drop_query_params, seed_urls, allowed_hosts, max_pages, max_crawl_minutes, crawl_started_at, version, keep_versions, git_ref, commit_sha, include_globs, exclude_globs, watch, COALESCE((SELECT array_agg(t.name ORDER BY t.name) FROM source_tags st") + ".+" + regexp.QuoteMeta("FROM sources WHERE deleted_at IS NULL ORDER BY created_at DESC")).
 			WillReturnRows(rows)
 
 		sources, err := repo.List(context.Background())
 		assert.NoError(t, err)
 		assert.Len(t, sources, 1)
+		assert.Equal(t, "main", sources[0].GitRef)
+		assert.Equal(t, "abc123", sources[0].CommitSHA)
+		assert.Equal(t, []string{"docs/**/*.md"}, sources[0].IncludeGlobs)
+		assert.Equal(t, []string{"drafts/**"}, sources[0].ExcludeGlobs)
+		assert.True(t, sources[0].Watch)
 	})
 }
 
@@ -106,20 +141,25 @@ func TestPostgresRepo_BulkCreatePages(t *testing.T) {
 	repo := source.NewPostgresRepo(db)
 
 	t.Run("Success", func(t *testing.T) {
+		mtime := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
 		pages := []source.SourcePage{
-			{SourceID: "src1", URL: "http://example.com/1", Status: "pending", Depth: 1},
+			{SourceID: "src1", URL: "http://example.com/1", Status: "pending", Depth: 1, CorrelationID: "req-1"},
+			{SourceID: "src1", URL: "/notes/adr-1.md", Status: "processing", Depth: 1, ModTime: &mtime, FileHash: "h1"},
 		}
 
 		mock.ExpectBegin()
 		stmt := mock.ExpectPrepare(regexp.QuoteMeta("INSERT INTO source_pages"))
 		stmt.ExpectQuery().
-			WithArgs("src1", "http://example.com/1", "pending", 1).
+			WithArgs("src1", "http://example.com/1", "pending", 1, nil, "", nil, "", "req-1").
 			WillReturnRows(sqlmock.NewRows([]string{"url"}).AddRow("http://example.com/1"))
+		stmt.ExpectQuery().
+			WithArgs("src1", "/notes/adr-1.md", "processing", 1, nil, "", &mtime, "h1", "").
+			WillReturnRows(sqlmock.NewRows([]string{"url"}).AddRow("/notes/adr-1.md"))
 		mock.ExpectCommit()
 
 		urls, err := repo.BulkCreatePages(context.Background(), pages)
 		assert.NoError(t, err)
-		assert.Len(t, urls, 1)
+		assert.Len(t, urls, 2)
 	})
 }
 
@@ -153,21 +193,6 @@ func TestPostgresRepo_SoftDelete(t *testing.T) {
 	assert.NoError(t, err)
 }
 
-func TestPostgresRepo_UpdateBodyHash(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	repo := source.NewPostgresRepo(db)
-
-	mock.ExpectExec(regexp.QuoteMeta("UPDATE sources SET body_hash = $1, updated_at = NOW() WHERE id = $2")).
-		WithArgs("hash", "src1").
-		WillReturnResult(sqlmock.NewResult(1, 1))
-
-	err = repo.UpdateBodyHash(context.Background(), "src1", "hash")
-	assert.NoError(t, err)
-}
-
 func TestPostgresRepo_Count(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
@@ -204,17 +229,27 @@ func TestPostgresRepo_GetPages(t *testing.T) {
 	defer db.Close()
 
 	repo := source.NewPostgresRepo(db)
+	lastmod := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
 
-	rows := sqlmock.NewRows([]string{"id", "source_id", "url", "status", "depth", "error", "created_at", "updated_at"}).
-		AddRow("p1", "src1", "http://u.rl", "pending", 0, "", time.Now(), time.Now())
+	rows := sqlmock.NewRows([]string{"id", "source_id", "url", "status", "depth", "error", "chunks_expected", "chunks_embedded", "lastmod", "change", "mtime", "file_hash", "chunk_key", "created_at", "updated_at"}).
+		AddRow("p1", "src1", "http://u.rl", "embedding", 0, "", 4, 1, lastmod, "changed", nil, "", "", time.Now(), time.Now()).
+		AddRow("p2", "src1", "/notes/adr-1.md", "completed", 1, "", 2, 2, nil, "", lastmod, "h1", "markdown-structural/512/50", time.Now(), time.Now())
 
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, source_id, url, status, depth, COALESCE(error, ''), created_at, updated_at FROM source_pages")).
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, source_id, url, status, depth, COALESCE(error, ''), chunks_expected, chunks_embedded, lastmod, COALESCE(change, ''), mtime, COALESCE(file_hash, ''), COALESCE(chunk_key, ''), created_at, updated_at FROM source_pages")).
 		WithArgs("src1").
 		WillReturnRows(rows)
 
 	pages, err := repo.GetPages(context.Background(), "src1")
 	assert.NoError(t, err)
-	assert.Len(t, pages, 1)
+	assert.Len(t, pages, 2)
+	assert.Equal(t, 4, pages[0].ChunksExpected)
+	assert.Equal(t, 1, pages[0].ChunksEmbedded)
+	assert.Equal(t, lastmod, *pages[0].LastMod)
+	assert.Equal(t, "changed", pages[0].Change)
+	assert.Nil(t, pages[0].ModTime)
+	assert.Equal(t, lastmod, *pages[1].ModTime)
+	assert.Equal(t, "h1", pages[1].FileHash)
+	assert.Equal(t, "markdown-structural/512/50", pages[1].ChunkKey)
 }
 
 func TestPostgresRepo_DeletePages(t *testing.T) {
@@ -239,7 +274,7 @@ func TestPostgresRepo_CountPendingPages(t *testing.T) {
 
 	repo := source.NewPostgresRepo(db)
 
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM source_pages WHERE source_id = $1 AND (status = 'pending' OR status = 'processing')")).
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM source_pages WHERE source_id = $1 AND (status = 'pending' OR status = 'processing' OR status = 'embedding')")).
 		WithArgs("src1").
 		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
 
@@ -248,6 +283,98 @@ func TestPostgresRepo_CountPendingPages(t *testing.T) {
 	assert.Equal(t, 3, count)
 }
 
+func TestPostgresRepo_SetPageChunks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := source.NewPostgresRepo(db)
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO source_pages (source_id, url, status, depth, chunks_expected, chunks_embedded) VALUES ($1, $2, 'embedding', $3, $4, 0) ON CONFLICT (source_id, url) DO UPDATE")+".+"+regexp.QuoteMeta("embedded_chunks = '{}'")).
+		WithArgs("src1", "http://u.rl", 1, 12).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = repo.SetPageChunks(context.Background(), "src1", "http://u.rl", 1, 12)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresRepo_MarkChunkEmbedded(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := source.NewPostgresRepo(db)
+	query := regexp.QuoteMeta("UPDATE source_pages SET embedded_chunks = array_append(embedded_chunks, $3::int), chunks_embedded = chunks_embedded + 1,") +
+		".+" + regexp.QuoteMeta("WHERE source_id = $1 AND url = $2 AND NOT ($3::int = ANY(embedded_chunks))")
+
+	t.Run("LastChunk", func(t *testing.T) {
+		mock.ExpectQuery(query).
+			WithArgs("src1", "http://u.rl", 4).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("completed"))
+
+		done, err := repo.MarkChunkEmbedded(context.Background(), "src1", "http://u.rl", 4)
+		assert.NoError(t, err)
+		assert.True(t, done)
+	})
+
+	t.Run("MoreToCome", func(t *testing.T) {
+		mock.ExpectQuery(query).
+			WithArgs("src1", "http://u.rl", 2).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("embedding"))
+
+		done, err := repo.MarkChunkEmbedded(context.Background(), "src1", "http://u.rl", 2)
+		assert.NoError(t, err)
+		assert.False(t, done)
+	})
+
+	t.Run("AlreadyCounted", func(t *testing.T) {
+		// A redelivered message matches no row and doesn't complete the page again
+		mock.ExpectQuery(query).
+			WithArgs("src1", "http://u.rl", 4).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}))
+
+		done, err := repo.MarkChunkEmbedded(context.Background(), "src1", "http://u.rl", 4)
+		assert.NoError(t, err)
+		assert.False(t, done)
+	})
+
+	t.Run("UnknownPage", func(t *testing.T) {
+		mock.ExpectQuery(query).
+			WithArgs("src1", "http://gone", 0).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}))
+
+		done, err := repo.MarkChunkEmbedded(context.Background(), "src1", "http://gone", 0)
+		assert.NoError(t, err)
+		assert.False(t, done)
+	})
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresRepo_GetProgress(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := source.NewPostgresRepo(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*), COUNT(*) FILTER (WHERE status IN ('pending', 'processing')),")).
+		WithArgs("src1").
+		WillReturnRows(sqlmock.NewRows([]string{"total", "pending", "embedding", "completed", "failed", "skipped", "expected", "embedded", "embedding_share"}).
+			AddRow(10, 3, 2, 3, 1, 1, 40, 25, 0.5))
+
+	p, err := repo.GetProgress(context.Background(), "src1")
+	assert.NoError(t, err)
+	assert.Equal(t, 10, p.PagesTotal)
+	assert.Equal(t, 3, p.PagesPending)
+	assert.Equal(t, 2, p.PagesEmbedding)
+	assert.Equal(t, 3, p.PagesCompleted)
+	assert.Equal(t, 1, p.PagesFailed)
+	assert.Equal(t, 1, p.PagesSkipped)
+	assert.Equal(t, 40, p.ChunksExpected)
+	assert.Equal(t, 25, p.ChunksEmbedded)
+}
+
 func TestPostgresRepo_ResetStuckPages(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
@@ -255,10 +382,385 @@ func TestPostgresRepo_ResetStuckPages(t *testing.T) {
 
 	repo := source.NewPostgresRepo(db)
 
-	mock.ExpectExec(regexp.QuoteMeta("UPDATE source_pages SET status = 'pending', updated_at = NOW(), error = 'timeout_reset' WHERE status = 'processing' AND updated_at < $1")).
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE source_pages SET status = 'pending', updated_at = NOW(), error = 'timeout_reset' WHERE status = 'processing' AND updated_at < $1 AND source_id IN (SELECT id FROM sources WHERE type = 'web')")).
 		WillReturnResult(sqlmock.NewResult(5, 5))
 
 	affected, err := repo.ResetStuckPages(context.Background(), time.Minute)
 	assert.NoError(t, err)
 	assert.Equal(t, int64(5), affected)
 }
+
+func TestPostgresRepo_FailStuckEmbeddingPages(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := source.NewPostgresRepo(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta("UPDATE source_pages SET status = 'failed', updated_at = NOW(), error = 'embedding timed out' WHERE status = 'embedding' AND updated_at < $1 RETURNING source_id")).
+		WillReturnRows(sqlmock.NewRows([]string{"source_id"}).AddRow("src1").AddRow("src2").AddRow("src1"))
+
+	sourceIDs, err := repo.FailStuckEmbeddingPages(context.Background(), time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"src1", "src2"}, sourceIDs)
+}
+
+func TestPostgresRepo_FailStuckFilePages(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := source.NewPostgresRepo(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta("UPDATE source_pages SET status = 'failed', updated_at = NOW(), error = 'file processing timed out' WHERE status = 'processing' AND updated_at < $1 AND source_id IN (SELECT id FROM sources WHERE type <> 'web') RETURNING source_id")).
+		WillReturnRows(sqlmock.NewRows([]string{"source_id"}).AddRow("git1").AddRow("git1"))
+
+	sourceIDs, err := repo.FailStuckFilePages(context.Background(), time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"git1"}, sourceIDs)
+}
+
+func TestPostgresRepo_StartCrawl(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := source.NewPostgresRepo(db)
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE sources SET status = 'in_progress', crawl_started_at = NOW(), updated_at = NOW() WHERE id = $1")).
+		WithArgs("src1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err = repo.StartCrawl(context.Background(), "src1")
+	assert.NoError(t, err)
+}
+
+func TestPostgresRepo_PendingPages(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := source.NewPostgresRepo(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta("ROW_NUMBER() OVER (PARTITION BY p.source_id ORDER BY p.depth, p.created_at)")).
+		WithArgs(100).
+		WillReturnRows(sqlmock.NewRows([]string{"source_id", "url", "status", "depth", "correlation_id"}).
+			AddRow("src1", "http://example.com/a", "pending", 1, "").
+			AddRow("src2", "http://other.com/b", "pending", 2, "req-2"))
+
+	pages, err := repo.PendingPages(context.Background(), 100)
+	assert.NoError(t, err)
+	assert.Len(t, pages, 2)
+	assert.Equal(t, "http://other.com/b", pages[1].URL)
+	assert.Equal(t, 2, pages[1].Depth)
+	assert.Equal(t, "req-2", pages[1].CorrelationID)
+}
+
+func TestPostgresRepo_ClaimPage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := source.NewPostgresRepo(db)
+	query := regexp.QuoteMeta("UPDATE source_pages SET status = 'processing', updated_at = NOW()")
+
+	t.Run("Claimed", func(t *testing.T) {
+		mock.ExpectExec(query).WithArgs("src1", "http://example.com/a").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		ok, err := repo.ClaimPage(context.Background(), "src1", "http://example.com/a")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("NoLongerPending", func(t *testing.T) {
+		mock.ExpectExec(query).WithArgs("src1", "http://example.com/a").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		ok, err := repo.ClaimPage(context.Background(), "src1", "http://example.com/a")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
+
+func TestPostgresRepo_SkipPendingPages(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := source.NewPostgresRepo(db)
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE source_pages SET status = 'skipped', error = $2, updated_at = NOW()")).
+		WithArgs("src1", "page budget reached").
+		WillReturnResult(sqlmock.NewResult(0, 7))
+
+	n, err := repo.SkipPendingPages(context.Background(), "src1", "page budget reached")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), n)
+}
+
+func TestPostgresRepo_MarkPagesStale(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := source.NewPostgresRepo(db)
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE source_pages SET status = 'stale', change = NULL")).
+		WithArgs("src1").
+		WillReturnResult(sqlmock.NewResult(0, 12))
+
+	err = repo.MarkPagesStale(context.Background(), "src1")
+	assert.NoError(t, err)
+}
+
+func TestPostgresRepo_RecordPageContent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := source.NewPostgresRepo(db)
+	query := regexp.QuoteMeta("SET change = CASE WHEN change IN ('added', 'changed') THEN change")
+
+	t.Run("Unchanged", func(t *testing.T) {
+		mock.ExpectQuery(query).
+			WithArgs("src1", "http://example.com/a", "abc", "markdown-structural/512/50", "\"v1\"", "").
+			WillReturnRows(sqlmock.NewRows([]string{"change"}).AddRow("unchanged"))
+
+		change, err := repo.RecordPageContent(context.Background(), "src1", "http://example.com/a", "abc", "markdown-structural/512/50", "\"v1\"", "")
+		assert.NoError(t, err)
+		assert.Equal(t, source.PageUnchanged, change)
+	})
+
+	t.Run("NoPageRow", func(t *testing.T) {
+		mock.ExpectQuery(query).
+			WithArgs("src1", "/uploads/a.pdf", "abc", "markdown-structural/512/50", "", "").
+			WillReturnError(sql.ErrNoRows)
+
+		change, err := repo.RecordPageContent(context.Background(), "src1", "/uploads/a.pdf", "abc", "markdown-structural/512/50", "", "")
+		assert.NoError(t, err)
+		assert.Equal(t, source.PageAdded, change)
+	})
+}
+
+func TestPostgresRepo_FinishSync(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := source.NewPostgresRepo(db)
+	complete := regexp.QuoteMeta("UPDATE sources SET status = 'completed', updated_at = NOW() WHERE id = $1 AND status <> 'completed'")
+
+	t.Run("Success", func(t *testing.T) {
+		finished := time.Now()
+		mock.ExpectBegin()
+		mock.ExpectExec(complete).WithArgs("src1").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(regexp.QuoteMeta("DELETE FROM source_pages WHERE source_id = $1 AND status = 'stale' RETURNING url")).
+			WithArgs("src1").
+			WillReturnRows(sqlmock.NewRows([]string{"url"}).AddRow("http://example.com/gone"))
+		mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO source_syncs")).
+			WithArgs("src1", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "started_at", "finished_at", "pages_added", "pages_changed", "pages_unchanged"}).
+				AddRow("sync1", nil, finished, 2, 3, 40))
+		mock.ExpectCommit()
+
+		report, removed, err := repo.FinishSync(context.Background(), "src1")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"http://example.com/gone"}, removed)
+		assert.Equal(t, "sync1", report.ID)
+		assert.Equal(t, 2, report.PagesAdded)
+		assert.Equal(t, 3, report.PagesChanged)
+		assert.Equal(t, 40, report.PagesUnchanged)
+		assert.Equal(t, 1, report.PagesRemoved)
+	})
+
+	t.Run("AlreadyCompleted", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(complete).WithArgs("src1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		report, removed, err := repo.FinishSync(context.Background(), "src1")
+		assert.NoError(t, err)
+		assert.Nil(t, report)
+		assert.Nil(t, removed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresRepo_ListSyncs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := source.NewPostgresRepo(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM source_syncs WHERE source_id = $1")).
+		WithArgs("src1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "source_id", "started_at", "finished_at", "pages_added", "pages_changed", "pages_unchanged", "pages_removed"}).
+			AddRow("sync1", "src1", time.Now(), time.Now(), 1, 2, 3, 4))
+
+	syncs, err := repo.ListSyncs(context.Background(), "src1")
+	assert.NoError(t, err)
+	assert.Len(t, syncs, 1)
+	assert.Equal(t, 4, syncs[0].PagesRemoved)
+}
+
+func TestPostgresRepo_SaveVersion(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := source.NewPostgresRepo(db)
+	prune := regexp.QuoteMeta("DELETE FROM source_versions WHERE source_id = $1 AND (version = $2 OR id NOT IN (")
+	relabel := regexp.QuoteMeta("UPDATE sources SET version = $1, updated_at = NOW() WHERE id = $2")
+
+	t.Run("Snapshot", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO source_versions (source_id, version, page_count)")).
+			WithArgs("src1", "v1.x").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("ver1"))
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM source_version_pages WHERE version_id = $1")).
+			WithArgs("ver1").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO source_version_pages (version_id, url, content_hash)")).
+			WithArgs("ver1", "src1").
+			WillReturnResult(sqlmock.NewResult(0, 42))
+		mock.ExpectQuery(prune).
+			WithArgs("src1", "v2.x", 2).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("v0.x"))
+		mock.ExpectExec(relabel).WithArgs("v2.x", "src1").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		dropped, err := repo.SaveVersion(context.Background(), "src1", "v1.x", "v2.x", 2)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"v0.x"}, dropped)
+	})
+
+	t.Run("FirstLabel", func(t *testing.T) {
+		// An unlabelled source has nothing to snapshot
+		mock.ExpectBegin()
+		mock.ExpectQuery(prune).
+			WithArgs("src1", "v1.x", 2).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}))
+		mock.ExpectExec(relabel).WithArgs("v1.x", "src1").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		dropped, err := repo.SaveVersion(context.Background(), "src1", "", "v1.x", 2)
+		assert.NoError(t, err)
+		assert.Empty(t, dropped)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresRepo_PageHashes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := source.NewPostgresRepo(db)
+
+	t.Run("Current", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("FROM source_pages WHERE source_id = $1 AND status = 'completed'")).
+			WithArgs("src1").
+			WillReturnRows(sqlmock.NewRows([]string{"url", "content_hash"}).AddRow("http://example.com/a", "h1"))
+
+		hashes, err := repo.PageHashes(context.Background(), "src1", "")
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"http://example.com/a": "h1"}, hashes)
+	})
+
+	t.Run("Snapshot", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM source_versions WHERE source_id = $1 AND version = $2")).
+			WithArgs("src1", "v1.x").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("ver1"))
+		mock.ExpectQuery(regexp.QuoteMeta("FROM source_version_pages WHERE version_id = $1")).
+			WithArgs("ver1").
+			WillReturnRows(sqlmock.NewRows([]string{"url", "content_hash"}).AddRow("http://example.com/a", "h0"))
+
+		hashes, err := repo.PageHashes(context.Background(), "src1", "v1.x")
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"http://example.com/a": "h0"}, hashes)
+	})
+
+	t.Run("UnknownVersion", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM source_versions")).
+			WithArgs("src1", "v9").
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := repo.PageHashes(context.Background(), "src1", "v9")
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+}
+
+func TestPostgresRepo_Update(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := source.NewPostgresRepo(db)
+	src := &source.Source{ID: "src1", Name: "Docs", MaxDepth: 2, SyncEnabled: true, SyncSchedule: "@daily", PathPrefix: "/docs", MaxPages: 500, ContentHash: "abc"}
+	query := regexp.QuoteMeta("UPDATE sources SET name = $1, max_depth = $2, exclusions = $3, sync_enabled = $4, sync_schedule = $5")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(query).
+		WithArgs("Docs", 2, sqlmock.AnyArg(), true, "@daily", sqlmock.AnyArg(), "/docs", sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), sqlmock.AnyArg(), 500, 0, 0, "", sqlmock.AnyArg(), sqlmock.AnyArg(), false, "abc", "src1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	assert.NoError(t, repo.Update(context.Background(), src, false))
+
+	// Tags are replaced in the same transaction
+	src.Tags = []string{"go"}
+	mock.ExpectBegin()
+	mock.ExpectExec(query).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO tags (name)")).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM source_tags WHERE source_id = $1")).
+		WithArgs("src1").
+		WillReturnError(assert.AnError)
+	mock.ExpectRollback()
+	assert.ErrorIs(t, repo.Update(context.Background(), src, true), assert.AnError)
+
+	// Deleted or unknown sources aren't updated
+	mock.ExpectBegin()
+	mock.ExpectExec(query).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	assert.ErrorIs(t, repo.Update(context.Background(), src, false), sql.ErrNoRows)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresRepo_SetCommitSHA(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := source.NewPostgresRepo(db)
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE sources SET commit_sha = $1, updated_at = NOW() WHERE id = $2")).
+		WithArgs("abc123", "src1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, repo.SetCommitSHA(context.Background(), "src1", "abc123"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresRepo_SetTags(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := source.NewPostgresRepo(db)
+	tags := []string{"backend", "go"}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO tags (name) SELECT unnest($1::text[]) ON CONFLICT (name) DO NOTHING")).
+		WithArgs(pq.Array(tags)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM source_tags WHERE source_id = $1")).
+		WithArgs("src1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO source_tags (source_id, tag_id) SELECT $1, id FROM tags WHERE name = ANY($2)")).
+		WithArgs("src1", pq.Array(tags)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.SetTags(context.Background(), "src1", tags))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
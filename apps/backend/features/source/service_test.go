@@ -2,6 +2,7 @@ package source
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"testing"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"qurio/apps/backend/internal/bus"
 	"qurio/apps/backend/internal/settings"
 	"qurio/apps/backend/internal/worker"
 	"qurio/apps/backend/internal/config"
@@ -44,16 +46,125 @@ func (m *MockRepository) CountPendingPages(ctx context.Context, sourceID string)
 	args := m.Called(ctx, sourceID)
 	return args.Int(0), args.Error(1)
 }
+func (m *MockRepository) SetPageChunks(ctx context.Context, sourceID, url string, depth, expected int) error {
+	args := m.Called(ctx, sourceID, url, depth, expected)
+	return args.Error(0)
+}
+func (m *MockRepository) PendingPages(ctx context.Context, perSource int) ([]SourcePage, error) {
+	args := m.Called(ctx, perSource)
+	return args.Get(0).([]SourcePage), args.Error(1)
+}
+func (m *MockRepository) ClaimPage(ctx context.Context, sourceID, url string) (bool, error) {
+	args := m.Called(ctx, sourceID, url)
+	return args.Bool(0), args.Error(1)
+}
+func (m *MockRepository) CountReleasedPages(ctx context.Context, sourceID string) (int, error) {
+	args := m.Called(ctx, sourceID)
+	return args.Int(0), args.Error(1)
+}
+func (m *MockRepository) SkipPendingPages(ctx context.Context, sourceID, reason string) (int64, error) {
+	args := m.Called(ctx, sourceID, reason)
+	return args.Get(0).(int64), args.Error(1)
+}
+func (m *MockRepository) StartCrawl(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+func (m *MockRepository) MarkPagesStale(ctx context.Context, sourceID string) error {
+	args := m.Called(ctx, sourceID)
+	return args.Error(0)
+}
+func (m *MockRepository) RecordPageContent(ctx context.Context, sourceID, url, hash, chunkKey, etag, lastModified string) (string, error) {
+	args := m.Called(ctx, sourceID, url, hash, chunkKey, etag, lastModified)
+	return args.String(0), args.Error(1)
+}
+func (m *MockRepository) FinishSync(ctx context.Context, sourceID string) (*SyncReport, []string, error) {
+	args := m.Called(ctx, sourceID)
+	var report *SyncReport
+	if r := args.Get(0); r != nil {
+		report = r.(*SyncReport)
+	}
+	var removed []string
+	if r := args.Get(1); r != nil {
+		removed = r.([]string)
+	}
+	return report, removed, args.Error(2)
+}
+func (m *MockRepository) ListSyncs(ctx context.Context, sourceID string) ([]SyncReport, error) {
+	args := m.Called(ctx, sourceID)
+	return args.Get(0).([]SyncReport), args.Error(1)
+}
+func (m *MockRepository) SaveVersion(ctx context.Context, sourceID, current, next string, keep int) ([]string, error) {
+	args := m.Called(ctx, sourceID, current, next, keep)
+	return args.Get(0).([]string), args.Error(1)
+}
+func (m *MockRepository) ListVersions(ctx context.Context, sourceID string) ([]Version, error) {
+	args := m.Called(ctx, sourceID)
+	return args.Get(0).([]Version), args.Error(1)
+}
+func (m *MockRepository) PageHashes(ctx context.Context, sourceID, version string) (map[string]string, error) {
+	args := m.Called(ctx, sourceID, version)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]string), args.Error(1)
+}
+func (m *MockRepository) MarkChunkEmbedded(ctx context.Context, sourceID, url string, chunkIndex int) (bool, error) {
+	args := m.Called(ctx, sourceID, url, chunkIndex)
+	return args.Bool(0), args.Error(1)
+}
+func (m *MockRepository) GetProgress(ctx context.Context, sourceID string) (*Progress, error) {
+	args := m.Called(ctx, sourceID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Progress), args.Error(1)
+}
 
 func (m *MockRepository) ResetStuckPages(ctx context.Context, timeout time.Duration) (int64, error) {
 	args := m.Called(ctx, timeout)
 	return args.Get(0).(int64), args.Error(1)
 }
 
+func (m *MockRepository) FailStuckEmbeddingPages(ctx context.Context, timeout time.Duration) ([]string, error) {
+	args := m.Called(ctx, timeout)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockRepository) FailStuckFilePages(ctx context.Context, timeout time.Duration) ([]string, error) {
+	args := m.Called(ctx, timeout)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
 func (m *MockRepository) Save(ctx context.Context, src *Source) error {
 	args := m.Called(ctx, src)
 	return args.Error(0)
 }
+func (m *MockRepository) Update(ctx context.Context, src *Source, withTags bool) error {
+	args := m.Called(ctx, src, withTags)
+	return args.Error(0)
+}
+func (m *MockRepository) SetTags(ctx context.Context, sourceID string, tags []string) error {
+	args := m.Called(ctx, sourceID, tags)
+	return args.Error(0)
+}
+func (m *MockRepository) ListTags(ctx context.Context) ([]Tag, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]Tag), args.Error(1)
+}
+func (m *MockRepository) DeleteTag(ctx context.Context, name string) error {
+	args := m.Called(ctx, name)
+	return args.Error(0)
+}
 
 func (m *MockRepository) ExistsByHash(ctx context.Context, hash string) (bool, error) {
 	args := m.Called(ctx, hash)
@@ -78,8 +189,8 @@ func (m *MockRepository) UpdateStatus(ctx context.Context, id, status string) er
 	return args.Error(0)
 }
 
-func (m *MockRepository) UpdateBodyHash(ctx context.Context, id, hash string) error {
-	args := m.Called(ctx, id, hash)
+func (m *MockRepository) SetCommitSHA(ctx context.Context, id, sha string) error {
+	args := m.Called(ctx, id, sha)
 	return args.Error(0)
 }
 
@@ -93,6 +204,16 @@ func (m *MockRepository) Count(ctx context.Context) (int, error) {
 	return args.Int(0), args.Error(1)
 }
 
+func (m *MockRepository) ListSyncDue(ctx context.Context) ([]Source, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]Source), args.Error(1)
+}
+
+func (m *MockRepository) UpdateLastSyncedAt(ctx context.Context, id string, t time.Time) error {
+	args := m.Called(ctx, id, t)
+	return args.Error(0)
+}
+
 type MockPublisher struct {
 	mock.Mock
 }
@@ -111,11 +232,36 @@ func (m *MockChunkStore) GetChunks(ctx context.Context, sourceID string, limit,
 	return args.Get(0).([]worker.Chunk), args.Error(1)
 }
 
+func (m *MockChunkStore) GetChunksWithVectorsAfter(ctx context.Context, sourceID, after string, limit int) ([]worker.Chunk, error) {
+	args := m.Called(ctx, sourceID, after, limit)
+	return args.Get(0).([]worker.Chunk), args.Error(1)
+}
+
+func (m *MockChunkStore) StoreChunk(ctx context.Context, chunk worker.Chunk) error {
+	args := m.Called(ctx, chunk)
+	return args.Error(0)
+}
+
 func (m *MockChunkStore) DeleteChunksBySourceID(ctx context.Context, sourceID string) error {
 	args := m.Called(ctx, sourceID)
 	return args.Error(0)
 }
 
+func (m *MockChunkStore) DeleteChunksByURL(ctx context.Context, sourceID, url string) error {
+	args := m.Called(ctx, sourceID, url)
+	return args.Error(0)
+}
+
+func (m *MockChunkStore) StoreVersionChunks(ctx context.Context, chunks []worker.Chunk) error {
+	args := m.Called(ctx, chunks)
+	return args.Error(0)
+}
+
+func (m *MockChunkStore) DeleteVersionChunks(ctx context.Context, sourceID, version string) error {
+	args := m.Called(ctx, sourceID, version)
+	return args.Error(0)
+}
+
 func (m *MockChunkStore) CountChunksBySource(ctx context.Context, sourceID string) (int, error) {
 	args := m.Called(ctx, sourceID)
 	return args.Int(0), args.Error(1)
@@ -156,9 +302,9 @@ func TestService_Create_Success(t *testing.T) {
 		return s.Status == "in_progress" && s.Type == "web"
 	})).Return(nil)
 
-	// 3. Create Seed Page
+	// 3. Create Seed Page under the canonical URL
 	mockRepo.On("BulkCreatePages", mock.Anything, mock.MatchedBy(func(pages []SourcePage) bool {
-		return len(pages) == 1 && pages[0].URL == "https://example.com"
+		return len(pages) == 1 && pages[0].URL == "https://example.com/"
 	})).Return([]string{"page-1"}, nil)
 
 	// 4. Get Settings
@@ -217,31 +363,274 @@ func TestService_ReSync(t *testing.T) {
 	// 1. Get Source
 	mockRepo.On("Get", mock.Anything, id).Return(src, nil)
 
-	// 2. Update Status
-	mockRepo.On("UpdateStatus", mock.Anything, id, "in_progress").Return(nil)
+	// 2. Restart the crawl clock
+	mockRepo.On("StartCrawl", mock.Anything, id).Return(nil)
 
-	// 3. Delete Pages
-	mockRepo.On("DeletePages", mock.Anything, id).Return(nil)
+	// 3. Queue the sync
+	mockPub.On("Publish", config.TopicSourceSync, mock.MatchedBy(func(body []byte) bool {
+		var task syncTask
+		json.Unmarshal(body, &task)
+		return task.SourceID == id && task.Resync
+	})).Return(nil)
+
+	err := svc.ReSync(context.Background(), id)
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockPub.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "MarkPagesStale", mock.Anything, mock.Anything)
+}
+
+func TestService_RunSync_ResyncWeb(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockPub := new(MockPublisher)
+	mockSettings := new(MockSettingsService)
+	svc := NewService(mockRepo, mockPub, nil, mockSettings)
 
-	// 4. Create Seed Page
+	id := "src-1"
+	src := &Source{ID: id, URL: "https://example.com", Type: "web"}
+
+	// 1. Get Source
+	mockRepo.On("Get", mock.Anything, id).Return(src, nil)
+
+	// 2. Mark Pages Stale
+	mockRepo.On("MarkPagesStale", mock.Anything, id).Return(nil)
+
+	// 3. Create Seed Page
 	mockRepo.On("BulkCreatePages", mock.Anything, mock.Anything).Return([]string{"p1"}, nil)
 
-	// 5. Settings
+	// 4. Settings
 	mockSettings.On("Get", mock.Anything).Return(nil, errors.New("no settings")) // Fallback to empty key
 
-	// 6. Publish
+	// 5. Publish
 	mockPub.On("Publish", config.TopicIngestWeb, mock.MatchedBy(func(body []byte) bool {
 		var m map[string]interface{}
 		json.Unmarshal(body, &m)
 		return m["resync"] == true
 	})).Return(nil)
 
-	err := svc.ReSync(context.Background(), id)
+	err := svc.runSync(context.Background(), id, true)
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockPub.AssertExpectations(t)
+}
+
+func TestSyncConsumer_HandleMessage(t *testing.T) {
+	body, _ := json.Marshal(syncTask{SourceID: "src-1", Resync: true, CorrelationID: "req-1"})
+
+	t.Run("FailureMarksSourceFailed", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := NewService(mockRepo, new(MockPublisher), nil, new(MockSettingsService))
+		mockRepo.On("Get", mock.Anything, "src-1").Return(nil, errors.New("db down"))
+		mockRepo.On("UpdateStatus", mock.Anything, "src-1", "failed").Return(nil)
+
+		err := NewSyncConsumer(svc).HandleMessage(&bus.Message{Body: body})
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("DeletedSourceIgnored", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := NewService(mockRepo, new(MockPublisher), nil, new(MockSettingsService))
+		mockRepo.On("Get", mock.Anything, "src-1").Return(nil, sql.ErrNoRows)
+
+		err := NewSyncConsumer(svc).HandleMessage(&bus.Message{Body: body})
+		assert.NoError(t, err)
+		mockRepo.AssertNotCalled(t, "UpdateStatus", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestService_Create_MultiSeed(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockPub := new(MockPublisher)
+	mockSettings := new(MockSettingsService)
+	svc := NewService(mockRepo, mockPub, nil, mockSettings)
+
+	src := &Source{
+		URL:          "https://docs.example.com",
+		SeedURLs:     []string{"https://api.example.com/ref/", "https://docs.example.com/"},
+		AllowedHosts: []string{" *.Example.com "},
+	}
+
+	mockRepo.On("ExistsByHash", mock.Anything, mock.Anything).Return(false, nil)
+	mockRepo.On("Save", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("BulkCreatePages", mock.Anything, []SourcePage{
+		{URL: "https://docs.example.com/", Status: "processing"},
+		{URL: "https://api.example.com/ref", Status: "processing"},
+	}).Return([]string{"p1", "p2"}, nil)
+	mockSettings.On("Get", mock.Anything).Return(&settings.Settings{}, nil)
+	for _, seed := range []string{"https://docs.example.com/", "https://api.example.com/ref"} {
+		mockPub.On("Publish", config.TopicIngestWeb, mock.MatchedBy(func(body []byte) bool {
+			var m map[string]interface{}
+			json.Unmarshal(body, &m)
+			return m["url"] == seed
+		})).Return(nil).Once()
+	}
+
+	err := svc.Create(context.Background(), src)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"*.example.com"}, src.AllowedHosts)
+	assert.Equal(t, []string{"*.example.com", "docs.example.com", "api.example.com"}, src.CrawlScope().AllowedHosts)
+	mockRepo.AssertExpectations(t)
+	mockPub.AssertExpectations(t)
+}
+
+// sitemapStub serves sitemaps from memory.
+type sitemapStub map[string]string
+
+func (f sitemapStub) FetchSitemap(ctx context.Context, url string) ([]byte, error) {
+	if body, ok := f[url]; ok {
+		return []byte(body), nil
+	}
+	return nil, errors.New("not found")
+}
+
+func TestService_Create_SitemapSeeds(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockPub := new(MockPublisher)
+	mockSettings := new(MockSettingsService)
+	svc := NewService(mockRepo, mockPub, nil, mockSettings)
+	svc.SetSitemapDiscoverer(worker.NewSitemapDiscoverer(sitemapStub{
+		"https://example.com/sitemap.xml": `<urlset>
+  <url><loc>https://example.com/docs/a</loc><lastmod>2025-01-02</lastmod></url>
+  <url><loc>https://example.com/docs/private/b</loc></url>
+  <url><loc>https://example.com/blog/c</loc></url>
+</urlset>`,
+	}))
+
+	src := &Source{
+		ID:         "src1",
+		URL:        "https://example.com/docs/",
+		MaxDepth:   1,
+		PathPrefix: "/docs",
+		Exclusions: []string{"/private/"},
+	}
+
+	mockRepo.On("ExistsByHash", mock.Anything, mock.Anything).Return(false, nil)
+	mockRepo.On("Save", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("BulkCreatePages", mock.Anything, []SourcePage{
+		{SourceID: "src1", URL: "https://example.com/docs", Status: "processing"},
+	}).Return([]string{"https://example.com/docs"}, nil)
+	mockRepo.On("BulkCreatePages", mock.Anything, mock.MatchedBy(func(pages []SourcePage) bool {
+		return len(pages) == 1 && pages[0].URL == "https://example.com/docs/a" &&
+			pages[0].Status == "pending" && pages[0].Depth == 1 && pages[0].LastMod != nil
+	})).Return([]string{"https://example.com/docs/a"}, nil)
+	mockSettings.On("Get", mock.Anything).Return(&settings.Settings{}, nil)
+	mockPub.On("Publish", config.TopicIngestWeb, mock.Anything).Return(nil).Once()
+	mockPub.On("Publish", config.TopicSourceSync, mock.Anything).Return(nil).Once()
+
+	err := svc.Create(context.Background(), src)
+	assert.NoError(t, err)
+
+	// The sitemap is read by the queued sync
+	src.Type = "web"
+	mockRepo.On("Get", mock.Anything, "src1").Return(src, nil)
+	err = svc.runSync(context.Background(), "src1", false)
 	assert.NoError(t, err)
 	mockRepo.AssertExpectations(t)
 	mockPub.AssertExpectations(t)
 }
 
+func TestService_Create_QueueFailureDiscards(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockPub := new(MockPublisher)
+	svc := NewService(mockRepo, mockPub, nil, nil)
+	svc.SetSitemapDiscoverer(worker.NewSitemapDiscoverer(sitemapStub{}))
+
+	src := &Source{ID: "src1", URL: "https://example.com/docs/"}
+
+	mockRepo.On("ExistsByHash", mock.Anything, mock.Anything).Return(false, nil)
+	mockRepo.On("Save", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("BulkCreatePages", mock.Anything, mock.Anything).Return([]string{"https://example.com/docs"}, nil)
+	mockPub.On("Publish", config.TopicSourceSync, mock.Anything).Return(errors.New("nsq down"))
+	// Dropped, so adding it again isn't rejected as a duplicate
+	mockRepo.On("SoftDelete", mock.Anything, "src1").Return(nil)
+
+	err := svc.Create(context.Background(), src)
+	assert.Error(t, err)
+	mockRepo.AssertExpectations(t)
+	mockPub.AssertNotCalled(t, "Publish", config.TopicIngestWeb, mock.Anything)
+}
+
+func TestService_ReSync_SitemapPagesRecrawled(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockPub := new(MockPublisher)
+	mockSettings := new(MockSettingsService)
+	svc := NewService(mockRepo, mockPub, nil, mockSettings)
+	svc.SetSitemapDiscoverer(worker.NewSitemapDiscoverer(sitemapStub{
+		"https://example.com/sitemap.xml": `<urlset>
+  <url><loc>https://example.com/same</loc><lastmod>2025-01-02</lastmod></url>
+  <url><loc>https://example.com/newer</loc><lastmod>2025-03-01</lastmod></url>
+  <url><loc>https://example.com/failed</loc><lastmod>2025-01-02</lastmod></url>
+</urlset>`,
+	}))
+
+	// The user gave the sitemap itself: its pages are the seeds
+	src := &Source{ID: "src1", Type: "web", URL: "https://example.com/sitemap.xml"}
+
+	mockRepo.On("Get", mock.Anything, "src1").Return(src, nil)
+	mockRepo.On("MarkPagesStale", mock.Anything, "src1").Return(nil)
+	mockRepo.On("BulkCreatePages", mock.Anything, []SourcePage(nil)).Return([]string(nil), nil)
+	mockRepo.On("BulkCreatePages", mock.Anything, mock.MatchedBy(func(pages []SourcePage) bool {
+		status := map[string]string{}
+		for _, p := range pages {
+			status[p.URL] = p.Status
+			if p.Depth != 0 {
+				return false
+			}
+		}
+		// Pages whose lastmod hasn't moved are crawled too, for their links
+		return len(pages) == 3 && status["https://example.com/same"] == "pending" &&
+			status["https://example.com/newer"] == "pending" && status["https://example.com/failed"] == "pending"
+	})).Return([]string{"https://example.com/same", "https://example.com/newer", "https://example.com/failed"}, nil)
+	mockSettings.On("Get", mock.Anything).Return(&settings.Settings{}, nil)
+
+	err := svc.runSync(context.Background(), "src1", true)
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "UpdateStatus", mock.Anything, mock.Anything, mock.Anything)
+	mockPub.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+}
+
+func TestService_Create_UnreadableSitemapFails(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockPub := new(MockPublisher)
+	mockSettings := new(MockSettingsService)
+	svc := NewService(mockRepo, mockPub, nil, mockSettings)
+	svc.SetSitemapDiscoverer(worker.NewSitemapDiscoverer(sitemapStub{}))
+
+	src := &Source{ID: "src1", URL: "https://example.com/sitemap.xml"}
+
+	mockRepo.On("ExistsByHash", mock.Anything, mock.Anything).Return(false, nil)
+	mockRepo.On("Save", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("BulkCreatePages", mock.Anything, mock.Anything).Return([]string(nil), nil)
+	mockRepo.On("Get", mock.Anything, "src1").Return(src, nil)
+	mockRepo.On("UpdateStatus", mock.Anything, "src1", "failed").Return(nil)
+	mockSettings.On("Get", mock.Anything).Return(&settings.Settings{}, nil)
+	mockPub.On("Publish", config.TopicSourceSync, mock.Anything).Return(nil).Once()
+
+	err := svc.Create(context.Background(), src)
+	assert.NoError(t, err)
+	err = svc.runSync(context.Background(), "src1", false)
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockPub.AssertNotCalled(t, "Publish", config.TopicIngestWeb, mock.Anything)
+}
+
+func TestService_Create_InvalidSeedsAndHosts(t *testing.T) {
+	svc := NewService(new(MockRepository), nil, nil, nil)
+
+	for _, src := range []*Source{
+		{URL: "https://example.com", SeedURLs: []string{"/relative"}},
+		{URL: "https://example.com", SeedURLs: []string{"ftp://example.com"}},
+		{URL: "https://example.com", AllowedHosts: []string{"example.com/docs"}},
+		{URL: "https://example.com", AllowedHosts: []string{"api.*.example.com"}},
+		{URL: "https://example.com", AllowedHosts: []string{""}},
+	} {
+		err := svc.Create(context.Background(), src)
+		assert.ErrorIs(t, err, ErrInvalidCrawlScope)
+	}
+}
+
 func TestService_Get_Pagination(t *testing.T) {
 	mockRepo := new(MockRepository)
 	mockChunk := new(MockChunkStore)
@@ -335,8 +724,64 @@ func TestService_ResetStuckPages(t *testing.T) {
 	svc := NewService(mockRepo, nil, nil, nil)
 
 	mockRepo.On("ResetStuckPages", mock.Anything, 5*time.Minute).Return(int64(5), nil)
+	mockRepo.On("FailStuckFilePages", mock.Anything, fileTimeout).Return([]string{"git1"}, nil)
+	mockRepo.On("FailStuckEmbeddingPages", mock.Anything, embeddingTimeout).Return([]string{"src1", "src2"}, nil)
+	// src1 and git1 have nothing left and complete, src2 is still crawling
+	mockRepo.On("CountPendingPages", mock.Anything, "src1").Return(0, nil)
+	mockRepo.On("CountPendingPages", mock.Anything, "src2").Return(3, nil)
+	mockRepo.On("CountPendingPages", mock.Anything, "git1").Return(0, nil)
+	mockRepo.On("FinishSync", mock.Anything, "src1").Return(&SyncReport{SourceID: "src1"}, []string{}, nil)
+	mockRepo.On("FinishSync", mock.Anything, "git1").Return(&SyncReport{SourceID: "git1"}, []string{}, nil)
 
 	err := svc.ResetStuckPages(context.Background())
 	assert.NoError(t, err)
 	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "FinishSync", mock.Anything, "src2")
+}
+
+func TestService_GetProgress(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := NewService(mockRepo, nil, nil, nil)
+
+	mockRepo.On("Get", mock.Anything, "src1").Return(&Source{ID: "src1", Status: "in_progress"}, nil)
+	// 1 of 4 pages completed, 2 embedding with 50% and 25% of their chunks stored
+	mockRepo.On("GetProgress", mock.Anything, "src1").Return(&Progress{
+		SourceID: "src1", PagesTotal: 4, PagesCompleted: 1, PagesEmbedding: 2, PagesPending: 1,
+		embeddingPages: 0.75,
+	}, nil)
+
+	p, err := svc.GetProgress(context.Background(), "src1")
+	assert.NoError(t, err)
+	assert.Equal(t, "in_progress", p.Status)
+	assert.Equal(t, 43.8, p.Percent)
+	assert.False(t, p.Done())
+}
+
+func TestService_CompleteSync(t *testing.T) {
+	t.Run("RemovesChunksOfGonePages", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockChunk := new(MockChunkStore)
+		svc := NewService(mockRepo, nil, mockChunk, nil)
+
+		mockRepo.On("FinishSync", mock.Anything, "src1").Return(&SyncReport{SourceID: "src1", PagesRemoved: 2},
+			[]string{"https://example.com/old", "https://example.com/gone"}, nil)
+		mockChunk.On("DeleteChunksByURL", mock.Anything, "src1", "https://example.com/old").Return(nil)
+		mockChunk.On("DeleteChunksByURL", mock.Anything, "src1", "https://example.com/gone").Return(errors.New("weaviate down"))
+
+		err := svc.CompleteSync(context.Background(), "src1")
+		assert.NoError(t, err) // chunk cleanup failures are logged
+		mockChunk.AssertExpectations(t)
+	})
+
+	t.Run("AlreadyCompleted", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockChunk := new(MockChunkStore)
+		svc := NewService(mockRepo, nil, mockChunk, nil)
+
+		mockRepo.On("FinishSync", mock.Anything, "src1").Return(nil, nil, nil)
+
+		err := svc.CompleteSync(context.Background(), "src1")
+		assert.NoError(t, err)
+		mockChunk.AssertNotCalled(t, "DeleteChunksByURL", mock.Anything, mock.Anything, mock.Anything)
+	})
 }
@@ -4,14 +4,19 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/url"
 	"regexp"
+	"slices"
+	"strings"
 	"time"
 
 	"qurio/apps/backend/internal/config"
 	"qurio/apps/backend/internal/middleware"
 	"qurio/apps/backend/internal/settings"
+	"qurio/apps/backend/internal/text"
 	"qurio/apps/backend/internal/worker"
 )
 
@@ -20,7 +25,6 @@ type Source struct {
 	Type         string     `json:"type"`
 	URL          string     `json:"url"`
 	ContentHash  string     `json:"-"`
-	BodyHash     string     `json:"-"`
 	Status       string     `json:"status"`
 	MaxDepth     int        `json:"max_depth"`
 	Exclusions   []string   `json:"exclusions"`
@@ -29,17 +33,193 @@ type Source struct {
 	SyncSchedule string     `json:"sync_schedule"` // minute, hourly, daily
 	LastSyncedAt *time.Time `json:"last_synced_at"`
 	UpdatedAt    string     `json:"updated_at"`
+
+	// Chunking profile, see text.ChunkProfile
+	ChunkStrategy  string `json:"chunk_strategy"`
+	ChunkMaxTokens int    `json:"chunk_max_tokens"`
+	ChunkOverlap   int    `json:"chunk_overlap"`
+
+	// Crawl scope, see worker.CrawlScope
+	IncludePatterns []string `json:"include_patterns"`
+	PathPrefix      string   `json:"path_prefix"`
+	KeepQueryParams []string `json:"keep_query_params"`
+	DropQueryParams []string `json:"drop_query_params"`
+
+	// SeedURLs are crawled alongside URL; AllowedHosts lets links leave the
+	// seeds' hosts, with "*.example.com" matching any subdomain.
+	SeedURLs     []string `json:"seed_urls"`
+	AllowedHosts []string `json:"allowed_hosts"`
+
+	// Crawl budget, zero means unlimited; see worker.CrawlBudget
+	MaxPages        int        `json:"max_pages"`
+	MaxCrawlMinutes int        `json:"max_crawl_minutes"`
+	CrawlStartedAt  *time.Time `json:"crawl_started_at"`
+
+	// Version labels the indexed content, e.g. "v2.x"; KeepVersions is how
+	// many replaced versions stay searchable, see SetVersion.
+	Version      string `json:"version"`
+	KeepVersions int    `json:"keep_versions"`
+
+	// Git sources check out GitRef, the remote's default branch when empty,
+	// and ingest the files matching IncludeGlobs; CommitSHA is the commit of
+	// the last sync, see syncGit.
+	GitRef       string   `json:"git_ref"`
+	CommitSHA    string   `json:"commit_sha"`
+	IncludeGlobs []string `json:"include_globs"`
+
+	// Directory sources walk the path in URL for files matching
+	// IncludeGlobs and none of ExcludeGlobs; with Watch set they resync when
+	// files change, see Watcher. The path must be readable by the worker.
+	ExcludeGlobs []string `json:"exclude_globs"`
+	Watch        bool     `json:"watch"`
+
+	// Tags label the source; Collections names the collections it belongs
+	// to and is read-only, see the collection feature.
+	Tags        []string `json:"tags"`
+	Collections []string `json:"collections"`
+}
+
+// ChunkProfile returns the source's chunking profile with defaults applied.
+func (s *Source) ChunkProfile() text.ChunkProfile {
+	return text.ChunkProfile{
+		Strategy:  s.ChunkStrategy,
+		MaxTokens: s.ChunkMaxTokens,
+		Overlap:   s.ChunkOverlap,
+	}.WithDefaults()
+}
+
+// chunkedAlike reports whether a page's chunks were made the way the source
+// chunks pages now, so a resync may keep them.
+func (s *Source) chunkedAlike(p SourcePage) bool {
+	return p.ChunkKey == worker.ChunkKey(s.ChunkProfile(), s.Version)
+}
+
+// CrawlScope returns the rules deciding which discovered links are crawled:
+// links may reach the allowed hosts and the hosts of every seed and sitemap.
+// The host of the page being crawled is left to the caller.
+func (s *Source) CrawlScope() worker.CrawlScope {
+	scope := s.canonicalizer()
+	hosts := append([]string(nil), s.AllowedHosts...)
+	for _, seed := range append(s.Seeds(), s.Sitemaps()...) {
+		if u, err := url.Parse(seed); err == nil && u.Host != "" && !slices.Contains(hosts, u.Host) {
+			hosts = append(hosts, u.Host)
+		}
+	}
+	return worker.CrawlScope{
+		AllowedHosts:  hosts,
+		PathPrefix:    s.PathPrefix,
+		Include:       s.IncludePatterns,
+		Exclude:       s.Exclusions,
+		Canonicalizer: scope,
+	}
+}
+
+// CrawlBudget returns the limits of the source's current crawl.
+func (s *Source) CrawlBudget() worker.CrawlBudget {
+	b := worker.CrawlBudget{
+		MaxPages:    s.MaxPages,
+		MaxDuration: time.Duration(s.MaxCrawlMinutes) * time.Minute,
+	}
+	if s.CrawlStartedAt != nil {
+		b.StartedAt = *s.CrawlStartedAt
+	}
+	return b
+}
+
+func (s *Source) canonicalizer() worker.Canonicalizer {
+	return worker.Canonicalizer{
+		KeepParams: s.KeepQueryParams,
+		DropParams: s.DropQueryParams,
+	}
+}
+
+// Seeds are the URLs a crawl starts from: the canonical forms of a web
+// source's URL and seed URLs, so seed pages match links pointing back to them.
+// Sitemaps given as seeds aren't crawled but expanded, see Sitemaps.
+func (s *Source) Seeds() []string {
+	if s.Type != "web" {
+		return []string{s.URL}
+	}
+	c := s.canonicalizer()
+	var seeds []string
+	for _, raw := range append([]string{s.URL}, s.SeedURLs...) {
+		if worker.IsSitemapURL(raw) {
+			continue
+		}
+		if seed := c.CanonicalizeOr(raw); !slices.Contains(seeds, seed) {
+			seeds = append(seeds, seed)
+		}
+	}
+	return seeds
+}
+
+// Sitemaps lists the sitemaps given as the source's URL or seed URLs.
+func (s *Source) Sitemaps() []string {
+	if s.Type != "web" {
+		return nil
+	}
+	c := s.canonicalizer()
+	var sitemaps []string
+	for _, raw := range append([]string{s.URL}, s.SeedURLs...) {
+		if !worker.IsSitemapURL(raw) {
+			continue
+		}
+		if sitemap := c.CanonicalizeOr(raw); !slices.Contains(sitemaps, sitemap) {
+			sitemaps = append(sitemaps, sitemap)
+		}
+	}
+	return sitemaps
+}
+
+// probedSitemaps are the /sitemap.xml of every seed host, looked up when the
+// crawl follows links anyway.
+func (s *Source) probedSitemaps() []string {
+	if s.MaxDepth < 1 {
+		return nil
+	}
+	var sitemaps []string
+	for _, seed := range s.Seeds() {
+		u, err := url.Parse(seed)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		sitemap := u.Scheme + "://" + u.Host + "/sitemap.xml"
+		if !slices.Contains(sitemaps, sitemap) && !slices.Contains(s.Sitemaps(), sitemap) {
+			sitemaps = append(sitemaps, sitemap)
+		}
+	}
+	return sitemaps
+}
+
+func (s *Source) setChunkProfile(p text.ChunkProfile) {
+	s.ChunkStrategy, s.ChunkMaxTokens, s.ChunkOverlap = p.Strategy, p.MaxTokens, p.Overlap
 }
 
 type SourcePage struct {
 	ID        string `json:"id"`
 	SourceID  string `json:"source_id"`
 	URL       string `json:"url"`
-	Status    string `json:"status"` // pending, processing, completed, failed
+	Status    string `json:"status"` // pending, processing, embedding, completed, failed, skipped, stale
 	Depth     int    `json:"depth"`
 	Error     string `json:"error,omitempty"`
 	CreatedAt string `json:"created_at"`
+	// LastMod is the page's sitemap lastmod
+	LastMod *time.Time `json:"lastmod,omitempty"`
+	// ModTime and FileHash describe the file of a directory source's page,
+	// used to skip it on resync
+	ModTime  *time.Time `json:"mtime,omitempty"`
+	FileHash string     `json:"file_hash,omitempty"`
+	// Change is how the current sync found the page: added, changed or unchanged
+	Change    string `json:"change,omitempty"`
 	UpdatedAt string `json:"updated_at"`
+	// ChunkKey is the chunk profile and version label the page's chunks were
+	// made with, see worker.ChunkKey; pages of another key aren't skipped
+	ChunkKey string `json:"-"`
+	// CorrelationID is that of the request whose crawl discovered the page
+	CorrelationID string `json:"-"`
+
+	ChunksExpected int `json:"chunks_expected"`
+	ChunksEmbedded int `json:"chunks_embedded"`
 }
 
 type Repository interface {
@@ -50,15 +230,48 @@ type Repository interface {
 	DeletePages(ctx context.Context, sourceID string) error
 	CountPendingPages(ctx context.Context, sourceID string) (int, error)
 	ResetStuckPages(ctx context.Context, timeout time.Duration) (int64, error)
+	FailStuckEmbeddingPages(ctx context.Context, timeout time.Duration) ([]string, error)
+	FailStuckFilePages(ctx context.Context, timeout time.Duration) ([]string, error)
+	SetPageChunks(ctx context.Context, sourceID, url string, depth, expected int) error
+	MarkChunkEmbedded(ctx context.Context, sourceID, url string, chunkIndex int) (bool, error)
+	GetProgress(ctx context.Context, sourceID string) (*Progress, error)
+	PendingPages(ctx context.Context, perSource int) ([]SourcePage, error)
+	ClaimPage(ctx context.Context, sourceID, url string) (bool, error)
+	CountReleasedPages(ctx context.Context, sourceID string) (int, error)
+	SkipPendingPages(ctx context.Context, sourceID, reason string) (int64, error)
+	MarkPagesStale(ctx context.Context, sourceID string) error
+	RecordPageContent(ctx context.Context, sourceID, url, hash, chunkKey, etag, lastModified string) (string, error)
+	FinishSync(ctx context.Context, sourceID string) (*SyncReport, []string, error)
+	ListSyncs(ctx context.Context, sourceID string) ([]SyncReport, error)
+
+	// Versions
+	// SaveVersion moves a source from its current version label to next. With
+	// keep > 0 a non-empty current version is recorded as a snapshot of the
+	// completed pages; it returns the labels of the snapshots dropped for it.
+	SaveVersion(ctx context.Context, sourceID, current, next string, keep int) ([]string, error)
+	ListVersions(ctx context.Context, sourceID string) ([]Version, error)
+	// PageHashes maps the URLs of a snapshot to their content hashes; an empty
+	// version means the source's current pages.
+	PageHashes(ctx context.Context, sourceID, version string) (map[string]string, error)
+
+	// Tags
+	// SetTags replaces a source's tags, creating tags that don't exist yet.
+	SetTags(ctx context.Context, sourceID string, tags []string) error
+	ListTags(ctx context.Context) ([]Tag, error)
+	DeleteTag(ctx context.Context, name string) error
 
 	// Sources
 
 	Save(ctx context.Context, src *Source) error
+	// Update saves the editable settings of a source, see SourceUpdate, and
+	// with withTags set its tags, all or nothing
+	Update(ctx context.Context, src *Source, withTags bool) error
 	ExistsByHash(ctx context.Context, hash string) (bool, error)
 	Get(ctx context.Context, id string) (*Source, error)
 	List(ctx context.Context) ([]Source, error)
 	UpdateStatus(ctx context.Context, id, status string) error
-	UpdateBodyHash(ctx context.Context, id, hash string) error
+	StartCrawl(ctx context.Context, id string) error
+	SetCommitSHA(ctx context.Context, id, sha string) error
 	SoftDelete(ctx context.Context, id string) error
 	Count(ctx context.Context) (int, error)
 	ListSyncDue(ctx context.Context) ([]Source, error)
@@ -67,8 +280,14 @@ type Repository interface {
 
 type ChunkStore interface {
 	GetChunks(ctx context.Context, sourceID string, limit, offset int) ([]worker.Chunk, error)
+	// GetChunksWithVectorsAfter pages by chunk ID, see the Weaviate store
+	GetChunksWithVectorsAfter(ctx context.Context, sourceID, after string, limit int) ([]worker.Chunk, error)
+	StoreChunk(ctx context.Context, chunk worker.Chunk) error
 	DeleteChunksBySourceID(ctx context.Context, sourceID string) error
+	DeleteChunksByURL(ctx context.Context, sourceID, url string) error
 	CountChunksBySource(ctx context.Context, sourceID string) (int, error)
+	StoreVersionChunks(ctx context.Context, chunks []worker.Chunk) error
+	DeleteVersionChunks(ctx context.Context, sourceID, version string) error
 }
 
 type EventPublisher interface {
@@ -84,23 +303,55 @@ type Service struct {
 	pub        EventPublisher
 	chunkStore ChunkStore
 	settings   SettingsService
+	sitemaps   *worker.SitemapDiscoverer
+	git        GitRepository
+	// gitAllowLocal permits local git remotes, see AllowLocalGitRemotes
+	gitAllowLocal bool
+	// directoryRoots bound directory sources, see SetDirectoryRoots
+	directoryRoots []string
+	// validateSchedule checks sync schedules, see SetScheduleValidator
+	validateSchedule func(spec string) error
 }
 
 func NewService(repo Repository, pub EventPublisher, chunkStore ChunkStore, settings SettingsService) *Service {
 	return &Service{repo: repo, pub: pub, chunkStore: chunkStore, settings: settings}
 }
 
+// SetSitemapDiscoverer enables seeding web sources from their sitemaps.
+func (s *Service) SetSitemapDiscoverer(d *worker.SitemapDiscoverer) {
+	s.sitemaps = d
+}
+
+// SetScheduleValidator sets the check sync schedules must pass, normally the
+// scheduler's parser; without one any schedule is accepted.
+func (s *Service) SetScheduleValidator(validate func(spec string) error) {
+	s.validateSchedule = validate
+}
+
+var ErrInvalidChunkProfile = errors.New("invalid chunking profile")
+var ErrInvalidCrawlScope = errors.New("invalid crawl scope")
+var ErrInvalidSchedule = errors.New("invalid sync schedule")
+
+// sourceHash returns the hash duplicate sources are detected by; a
+// repository may be added once per ref.
+func sourceHash(src *Source) string {
+	key := src.URL
+	if src.Type == "git" {
+		key += "@" + src.GitRef
+	}
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(key)))
+}
+
 func (s *Service) Create(ctx context.Context, src *Source) error {
-	// Validate Exclusions
-	for _, pattern := range src.Exclusions {
-		if _, err := regexp.Compile(pattern); err != nil {
-			return fmt.Errorf("invalid exclusion regex: %s", pattern)
-		}
+	if err := s.validate(src); err != nil {
+		return err
+	}
+	if src.Type == "git" && s.git == nil {
+		return ErrGitUnavailable
 	}
 
 	// 0. Compute Hash
-	hash := sha256.Sum256([]byte(src.URL))
-	src.ContentHash = fmt.Sprintf("%x", hash)
+	src.ContentHash = sourceHash(src)
 
 	// Default to web if empty
 	if src.Type == "" {
@@ -118,22 +369,40 @@ func (s *Service) Create(ctx context.Context, src *Source) error {
 
 	// 2. Set Status to in_progress (queued) and Save
 	src.Status = "in_progress"
+	now := time.Now()
+	src.CrawlStartedAt = &now
 	if err := s.repo.Save(ctx, src); err != nil {
 		return err
 	}
+	if len(src.Tags) > 0 {
+		if err := s.repo.SetTags(ctx, src.ID, src.Tags); err != nil {
+			return fmt.Errorf("failed to tag source: %w", err)
+		}
+	}
+
+	// Cloning and scanning can take minutes, the SyncConsumer does it
+	if src.Type == "git" || src.Type == "directory" {
+		if err := s.queueSync(ctx, src.ID, false); err != nil {
+			s.discard(ctx, src.ID)
+			return err
+		}
+		return nil
+	}
 
-	// 2.1 Create Seed Page (Crawl Frontier)
+	// 2.1 Create Seed Pages (Crawl Frontier)
 	if src.Type == "web" {
-		_, err = s.repo.BulkCreatePages(ctx, []SourcePage{{
-			SourceID: src.ID,
-			URL:      src.URL,
-			Status:   "pending",
-			Depth:    0,
-		}})
+		_, err = s.repo.BulkCreatePages(ctx, seedPages(src))
 		if err != nil {
-			// Log error but proceed? No, fail.
+			s.discard(ctx, src.ID)
 			return fmt.Errorf("failed to create seed page: %w", err)
 		}
+		// Reading sitemaps can take minutes, their pages are added in the background
+		if s.sitemaps != nil {
+			if err := s.queueSync(ctx, src.ID, false); err != nil {
+				s.discard(ctx, src.ID)
+				return err
+			}
+		}
 	}
 
 	// 3. Get Settings
@@ -143,32 +412,177 @@ func (s *Service) Create(ctx context.Context, src *Source) error {
 		apiKey = set.GeminiAPIKey
 	}
 
-	// 4. Publish to NSQ
-	payload, _ := json.Marshal(map[string]interface{}{
-		"type":           src.Type,
-		"url":            src.URL,
-		"id":             src.ID,
-		"depth":          0, // Seed depth
-		"max_depth":      src.MaxDepth,
-		"exclusions":     src.Exclusions,
-		"gemini_api_key": apiKey,
-		"correlation_id": middleware.GetCorrelationID(ctx),
-	})
-
+	// 4. Publish to NSQ, one task per seed
 	topic := config.TopicIngestWeb
 	if src.Type == "file" {
 		topic = config.TopicIngestFile
 	}
 
-	if err := s.pub.Publish(topic, payload); err != nil {
-		slog.Error("failed to publish ingest task", "error", err, "topic", topic)
-	} else {
-		slog.Info("published ingest task", "url", src.URL, "id", src.ID, "topic", topic)
+	for _, seed := range src.Seeds() {
+		payload, _ := json.Marshal(map[string]interface{}{
+			"type":           src.Type,
+			"url":            seed,
+			"id":             src.ID,
+			"depth":          0, // Seed depth
+			"max_depth":      src.MaxDepth,
+			"exclusions":     src.Exclusions,
+			"allowed_hosts":  src.CrawlScope().AllowedHosts,
+			"gemini_api_key": apiKey,
+			"correlation_id": middleware.GetCorrelationID(ctx),
+		})
+
+		if err := s.pub.Publish(topic, payload); err != nil {
+			slog.Error("failed to publish ingest task", "error", err, "topic", topic)
+		} else {
+			slog.Info("published ingest task", "url", seed, "id", src.ID, "topic", topic)
+		}
 	}
 
 	return nil
 }
 
+// validate checks the settings of a source being created or updated,
+// normalizing them in place.
+func (s *Service) validate(src *Source) error {
+	// Validate Exclusions
+	for _, pattern := range src.Exclusions {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("%w: invalid exclusion regex: %s", ErrInvalidCrawlScope, pattern)
+		}
+	}
+	if err := validateGit(src, s.gitAllowLocal); err != nil {
+		return err
+	}
+	if err := validateDirectory(src, s.directoryRoots); err != nil {
+		return err
+	}
+	for _, pattern := range src.IncludePatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("%w: invalid include regex: %s", ErrInvalidCrawlScope, pattern)
+		}
+	}
+	if src.PathPrefix != "" && !strings.HasPrefix(src.PathPrefix, "/") {
+		src.PathPrefix = "/" + src.PathPrefix
+	}
+	for _, seed := range src.SeedURLs {
+		if u, err := url.Parse(seed); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: invalid seed url: %s", ErrInvalidCrawlScope, seed)
+		}
+	}
+	if src.MaxDepth < 0 {
+		return fmt.Errorf("%w: max_depth must not be negative", ErrInvalidCrawlScope)
+	}
+	if src.MaxPages < 0 || src.MaxCrawlMinutes < 0 {
+		return fmt.Errorf("%w: crawl budget must not be negative", ErrInvalidCrawlScope)
+	}
+	if src.KeepVersions < 0 {
+		return fmt.Errorf("%w: keep_versions must not be negative", ErrInvalidVersion)
+	}
+	src.Version = strings.TrimSpace(src.Version)
+	src.SyncSchedule = strings.TrimSpace(src.SyncSchedule)
+	if s.validateSchedule != nil {
+		if err := s.validateSchedule(src.SyncSchedule); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+		}
+	}
+	for i, host := range src.AllowedHosts {
+		host = strings.ToLower(strings.TrimSpace(host))
+		if !validHostPattern(host) {
+			return fmt.Errorf("%w: invalid allowed host: %s", ErrInvalidCrawlScope, src.AllowedHosts[i])
+		}
+		src.AllowedHosts[i] = host
+	}
+
+	// Validate Chunking Profile
+	profile := src.ChunkProfile()
+	if err := profile.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidChunkProfile, err)
+	}
+	src.setChunkProfile(profile)
+
+	tags, err := normalizeTags(src.Tags)
+	if err != nil {
+		return err
+	}
+	src.Tags = tags
+	return nil
+}
+
+// seedPages returns one depth-0 page per seed of a web source. Seeds are
+// published right away rather than left to the dispatcher, so they start out
+// processing.
+func seedPages(src *Source) []SourcePage {
+	var pages []SourcePage
+	for _, seed := range src.Seeds() {
+		pages = append(pages, SourcePage{
+			SourceID: src.ID,
+			URL:      seed,
+			Status:   "processing",
+			Depth:    0,
+		})
+	}
+	return pages
+}
+
+// seedSitemapPages adds the in-scope pages of the source's sitemaps to the
+// frontier; the dispatcher releases them like discovered links. Every page
+// is crawled again on resync, however old its lastmod, since the pages only
+// its links reach would otherwise be removed as stale; an unchanged page
+// keeps its chunks. Sitemaps that can't be read are skipped, the crawl then
+// relies on links alone.
+func (s *Service) seedSitemapPages(ctx context.Context, src *Source) error {
+	if s.sitemaps == nil {
+		return nil
+	}
+
+	scope := src.CrawlScope()
+	var pages []SourcePage
+	add := func(sitemaps []string, depth int) {
+		for _, sitemap := range sitemaps {
+			entries, err := s.sitemaps.Discover(ctx, sitemap, scope.AllowedHosts)
+			if err != nil {
+				slog.WarnContext(ctx, "failed to read sitemap", "source_id", src.ID, "url", sitemap, "error", err)
+				continue
+			}
+			for _, p := range worker.SitemapPages(src.ID, scope, entries, depth) {
+				pages = append(pages, SourcePage{SourceID: p.SourceID, URL: p.URL, Status: p.Status, Depth: p.Depth, LastMod: p.LastMod,
+					CorrelationID: middleware.GetCorrelationID(ctx)})
+			}
+		}
+	}
+	// Pages listed by a sitemap the user gave stand in for seeds
+	add(src.Sitemaps(), 0)
+	add(src.probedSitemaps(), 1)
+
+	created, err := s.repo.BulkCreatePages(ctx, pages)
+	if err != nil {
+		return fmt.Errorf("failed to create sitemap pages: %w", err)
+	}
+	if len(created) > 0 {
+		slog.InfoContext(ctx, "seeded pages from sitemaps", "source_id", src.ID, "count", len(created))
+	}
+	if len(src.Seeds()) > 0 {
+		return nil
+	}
+	// Without seed pages no crawl result will complete the source
+	if len(created) == 0 {
+		// The sitemaps given were empty or unreadable
+		return s.repo.UpdateStatus(ctx, src.ID, "failed")
+	}
+	return nil
+}
+
+// validHostPattern accepts a host, optionally with a port, or "*." followed
+// by a domain.
+func validHostPattern(pattern string) bool {
+	host := strings.TrimPrefix(pattern, "*.")
+	if host == "" || strings.ContainsAny(host, "*/?#@ ") {
+		return false
+	}
+	u, err := url.Parse("http://" + host)
+	return err == nil && u.Host == host
+}
+
 func (s *Service) Upload(ctx context.Context, path string, hash string, name string) (*Source, error) {
 	// Check Duplicate
 	exists, err := s.repo.ExistsByHash(ctx, hash)
@@ -186,6 +600,7 @@ func (s *Service) Upload(ctx context.Context, path string, hash string, name str
 		Status:      "in_progress",
 		Name:        name,
 	}
+	src.setChunkProfile(text.DefaultChunkProfile())
 
 	if err := s.repo.Save(ctx, src); err != nil {
 		return nil, err
@@ -256,7 +671,16 @@ func (s *Service) Delete(ctx context.Context, id string) error {
 		return err
 	}
 	// 2. Soft Delete DB
-	return s.repo.SoftDelete(ctx, id)
+	if err := s.repo.SoftDelete(ctx, id); err != nil {
+		return err
+	}
+	// 3. Drop the working copy of a git source
+	if s.git != nil {
+		if err := s.git.Remove(id); err != nil {
+			slog.WarnContext(ctx, "failed to remove git working copy", "source_id", id, "error", err)
+		}
+	}
+	return nil
 }
 
 func (s *Service) ReSync(ctx context.Context, id string) error {
@@ -265,26 +689,44 @@ func (s *Service) ReSync(ctx context.Context, id string) error {
 		return err
 	}
 
-	// Update Status to in_progress
-	if err := s.repo.UpdateStatus(ctx, id, "in_progress"); err != nil {
+	// Update Status to in_progress, restarting the crawl budget
+	if err := s.repo.StartCrawl(ctx, id); err != nil {
 		return err
 	}
 
-	// Clean up pages for fresh start
-	if src.Type == "web" {
-		if err := s.repo.DeletePages(ctx, id); err != nil {
-			return fmt.Errorf("failed to clean up pages: %w", err)
-		}
-		// Re-create Seed Page
-		_, err = s.repo.BulkCreatePages(ctx, []SourcePage{{
-			SourceID: src.ID,
-			URL:      src.URL,
-			Status:   "pending",
-			Depth:    0,
-		}})
-		if err != nil {
-			return fmt.Errorf("failed to recreate seed page: %w", err)
-		}
+	// Marking pages stale, reading sitemaps, fetching repositories and
+	// scanning directories can take a while, the SyncConsumer does it
+	if src.Type == "web" || src.Type == "git" || src.Type == "directory" {
+		return s.queueSync(ctx, id, true)
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"type":           src.Type,
+		"id":             src.ID,
+		"path":           src.URL,
+		"resync":         true,
+		"correlation_id": middleware.GetCorrelationID(ctx),
+	})
+	if err := s.pub.Publish(config.TopicIngestFile, payload); err != nil {
+		slog.Error("failed to publish resync event", "error", err, "topic", config.TopicIngestFile)
+		return err
+	}
+	return nil
+}
+
+// resyncWeb restarts the crawl of a web source from its seeds and sitemaps.
+// Pages stay stale until the crawl finds them again; the ones it doesn't
+// are removed with their chunks once the sync completes.
+func (s *Service) resyncWeb(ctx context.Context, src *Source) error {
+	if err := s.repo.MarkPagesStale(ctx, src.ID); err != nil {
+		return fmt.Errorf("failed to mark pages stale: %w", err)
+	}
+	// Re-create Seed Pages
+	if _, err := s.repo.BulkCreatePages(ctx, seedPages(src)); err != nil {
+		return fmt.Errorf("failed to recreate seed page: %w", err)
+	}
+	if err := s.seedSitemapPages(ctx, src); err != nil {
+		return err
 	}
 
 	set, err := s.settings.Get(ctx)
@@ -297,29 +739,20 @@ func (s *Service) ReSync(ctx context.Context, id string) error {
 		"type":           src.Type,
 		"id":             src.ID,
 		"resync":         true,
+		"depth":          0, // Reset depth
+		"max_depth":      src.MaxDepth,
+		"exclusions":     src.Exclusions,
+		"allowed_hosts":  src.CrawlScope().AllowedHosts,
+		"gemini_api_key": apiKey,
 		"correlation_id": middleware.GetCorrelationID(ctx),
 	}
-
-	if src.Type == "file" {
-		payloadMap["path"] = src.URL
-	} else {
-		payloadMap["url"] = src.URL
-		payloadMap["depth"] = 0 // Reset depth
-		payloadMap["max_depth"] = src.MaxDepth
-		payloadMap["exclusions"] = src.Exclusions
-		payloadMap["gemini_api_key"] = apiKey
-	}
-
-	payload, _ := json.Marshal(payloadMap)
-
-	topic := config.TopicIngestWeb
-	if src.Type == "file" {
-		topic = config.TopicIngestFile
-	}
-
-	if err := s.pub.Publish(topic, payload); err != nil {
-		slog.Error("failed to publish resync event", "error", err, "topic", topic)
-		return err
+	for _, seed := range src.Seeds() {
+		payloadMap["url"] = seed
+		payload, _ := json.Marshal(payloadMap)
+		if err := s.pub.Publish(config.TopicIngestWeb, payload); err != nil {
+			slog.Error("failed to publish resync event", "error", err, "topic", config.TopicIngestWeb)
+			return err
+		}
 	}
 	return nil
 }
@@ -328,6 +761,18 @@ func (s *Service) GetPages(ctx context.Context, id string) ([]SourcePage, error)
 	return s.repo.GetPages(ctx, id)
 }
 
+// embeddingTimeout is how long a page may go without storing a chunk before
+// its embed messages are taken as lost. Embedding queues behind the rate
+// limit, so it is well above the crawl timeout.
+const embeddingTimeout = 30 * time.Minute
+
+// fileTimeout is how long a file page may wait for its result. The file
+// worker gives up converting a file after 30 minutes.
+const fileTimeout = 45 * time.Minute
+
+// ResetStuckPages requeues crawls that never reported back and fails file
+// pages without a result and pages whose chunks stopped being stored,
+// completing their sources if nothing else is left.
 func (s *Service) ResetStuckPages(ctx context.Context) error {
 	count, err := s.repo.ResetStuckPages(ctx, 5*time.Minute)
 	if err != nil {
@@ -337,5 +782,34 @@ func (s *Service) ResetStuckPages(ctx context.Context) error {
 	if count > 0 {
 		slog.Info("reset stuck pages", "count", count)
 	}
+
+	fileSourceIDs, err := s.repo.FailStuckFilePages(ctx, fileTimeout)
+	if err != nil {
+		slog.Error("failed to fail stuck file pages", "error", err)
+		return err
+	}
+	for _, id := range fileSourceIDs {
+		slog.Warn("failed file pages stuck processing", "source_id", id)
+	}
+	sourceIDs, err := s.repo.FailStuckEmbeddingPages(ctx, embeddingTimeout)
+	if err != nil {
+		slog.Error("failed to fail stuck embedding pages", "error", err)
+		return err
+	}
+	for _, id := range sourceIDs {
+		slog.Warn("failed pages stuck embedding", "source_id", id)
+	}
+	for _, id := range append(fileSourceIDs, sourceIDs...) {
+		pending, err := s.repo.CountPendingPages(ctx, id)
+		if err != nil {
+			slog.Error("failed to count pending pages", "source_id", id, "error", err)
+			continue
+		}
+		if pending == 0 {
+			if err := s.CompleteSync(ctx, id); err != nil {
+				slog.Error("failed to complete source", "source_id", id, "error", err)
+			}
+		}
+	}
 	return nil
 }
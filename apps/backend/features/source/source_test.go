@@ -38,10 +38,18 @@ func (m *TestRepo) UpdatePageStatus(ctx context.Context, sourceID, url, status,
 func (m *TestRepo) GetPages(ctx context.Context, sourceID string) ([]SourcePage, error) { return nil, nil }
 func (m *TestRepo) DeletePages(ctx context.Context, sourceID string) error { return nil }
 func (m *TestRepo) CountPendingPages(ctx context.Context, sourceID string) (int, error) { return 0, nil }
+func (m *TestRepo) SetPageChunks(ctx context.Context, sourceID, url string, depth, expected int) error {
+	return nil
+}
+func (m *TestRepo) MarkChunkEmbedded(ctx context.Context, sourceID, url string, chunkIndex int) (bool, error) {
+	return false, nil
+}
+func (m *TestRepo) GetProgress(ctx context.Context, sourceID string) (*Progress, error) {
+	return &Progress{SourceID: sourceID}, nil
+}
 func (m *TestRepo) Get(ctx context.Context, id string) (*Source, error) { return nil, nil }
 func (m *TestRepo) List(ctx context.Context) ([]Source, error) { return nil, nil }
 func (m *TestRepo) UpdateStatus(ctx context.Context, id, status string) error { return nil }
-func (m *TestRepo) UpdateBodyHash(ctx context.Context, id, hash string) error { return nil }
 func (m *TestRepo) SoftDelete(ctx context.Context, id string) error { return nil }
 
 type TestSettings struct { SettingsService }
@@ -98,8 +106,8 @@ func TestService_Create_InvalidRegex(t *testing.T) {
 	if err == nil {
 		t.Fatal("Expected error for invalid regex, got nil")
 	}
-	if err.Error() != "invalid exclusion regex: [" {
-		t.Errorf("Expected 'invalid exclusion regex: [', got '%v'", err)
+	if err.Error() != "invalid crawl scope: invalid exclusion regex: [" {
+		t.Errorf("Expected 'invalid crawl scope: invalid exclusion regex: [', got '%v'", err)
 	}
 }
 
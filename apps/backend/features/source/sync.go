@@ -0,0 +1,178 @@
+package source
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"qurio/apps/backend/internal/bus"
+	"qurio/apps/backend/internal/config"
+	"qurio/apps/backend/internal/middleware"
+)
+
+// How a crawled page compares to the previous sync, see SourcePage.Change.
+const (
+	PageAdded     = "added"
+	PageChanged   = "changed"
+	PageUnchanged = "unchanged"
+)
+
+// SyncReport counts what one sync of a source found.
+type SyncReport struct {
+	ID             string     `json:"id"`
+	SourceID       string     `json:"source_id"`
+	StartedAt      *time.Time `json:"started_at"`
+	FinishedAt     time.Time  `json:"finished_at"`
+	PagesAdded     int        `json:"pages_added"`
+	PagesChanged   int        `json:"pages_changed"`
+	PagesUnchanged int        `json:"pages_unchanged"`
+	PagesRemoved   int        `json:"pages_removed"`
+}
+
+// CompleteSync marks a source completed, dropping the pages and chunks the
+// crawl no longer found. Calls for a source already completed are no-ops, so
+// racing completion checks record the sync once.
+func (s *Service) CompleteSync(ctx context.Context, id string) error {
+	report, removed, err := s.repo.FinishSync(ctx, id)
+	if err != nil || report == nil {
+		return err
+	}
+	for _, u := range removed {
+		if err := s.chunkStore.DeleteChunksByURL(ctx, id, u); err != nil {
+			slog.WarnContext(ctx, "failed to delete chunks of removed page", "source_id", id, "url", u, "error", err)
+		}
+	}
+	slog.InfoContext(ctx, "source sync completed", "source_id", id,
+		"added", report.PagesAdded, "changed", report.PagesChanged, "unchanged", report.PagesUnchanged, "removed", report.PagesRemoved)
+	return nil
+}
+
+// syncTask asks the SyncConsumer to run the slow part of a source's sync.
+type syncTask struct {
+	SourceID      string `json:"id"`
+	Resync        bool   `json:"resync"`
+	CorrelationID string `json:"correlation_id"`
+}
+
+// queueSync hands the slow part of a sync to the SyncConsumer, so requests
+// return once the source is saved, like they do for the web crawl.
+func (s *Service) queueSync(ctx context.Context, id string, resync bool) error {
+	payload, _ := json.Marshal(syncTask{SourceID: id, Resync: resync, CorrelationID: middleware.GetCorrelationID(ctx)})
+	if err := s.pub.Publish(config.TopicSourceSync, payload); err != nil {
+		slog.ErrorContext(ctx, "failed to queue sync", "error", err, "source_id", id)
+		return err
+	}
+	return nil
+}
+
+// runSync runs the part of a sync queued by queueSync.
+func (s *Service) runSync(ctx context.Context, id string, resync bool) error {
+	src, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	switch {
+	case src.Type == "web" && resync:
+		return s.resyncWeb(ctx, src)
+	case src.Type == "web":
+		return s.seedSitemapPages(ctx, src)
+	case src.Type == "git" || src.Type == "directory":
+		var previous []SourcePage
+		if resync {
+			if previous, err = s.repo.GetPages(ctx, id); err != nil {
+				return fmt.Errorf("failed to load pages: %w", err)
+			}
+		}
+		if src.Type == "directory" {
+			return s.syncDirectory(ctx, src, previous)
+		}
+		return s.syncGit(ctx, src, previous)
+	}
+	return nil
+}
+
+// SyncConsumer runs the syncs queued on TopicSourceSync. A sync that fails
+// marks its source failed rather than being retried; resyncing it retries.
+type SyncConsumer struct {
+	service *Service
+}
+
+func NewSyncConsumer(service *Service) *SyncConsumer {
+	return &SyncConsumer{service: service}
+}
+
+func (c *SyncConsumer) HandleMessage(m *bus.Message) error {
+	var task syncTask
+	if err := json.Unmarshal(m.Body, &task); err != nil || task.SourceID == "" {
+		slog.Error("dropping invalid sync task", "error", err)
+		return nil
+	}
+	if task.CorrelationID == "" {
+		task.CorrelationID = uuid.New().String()
+	}
+	ctx := middleware.WithCorrelationID(context.Background(), task.CorrelationID)
+
+	err := c.service.runSync(ctx, task.SourceID, task.Resync)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		slog.InfoContext(ctx, "source deleted before its sync ran", "source_id", task.SourceID)
+	case err != nil:
+		slog.ErrorContext(ctx, "source sync failed", "source_id", task.SourceID, "error", err)
+		if statusErr := c.service.repo.UpdateStatus(ctx, task.SourceID, "failed"); statusErr != nil {
+			slog.WarnContext(ctx, "failed to update source status to failed", "source_id", task.SourceID, "error", statusErr)
+		}
+	}
+	return nil
+}
+
+// syncFiles replaces the pages of a git or directory source with pages, one
+// per file, and publishes a file task for each page left processing. Pages
+// of files that are gone stay stale until the sync completes; with nothing
+// to read it completes right away.
+func (s *Service) syncFiles(ctx context.Context, src *Source, previous, pages []SourcePage) error {
+	if len(previous) > 0 {
+		if err := s.repo.MarkPagesStale(ctx, src.ID); err != nil {
+			return fmt.Errorf("failed to mark pages stale: %w", err)
+		}
+	}
+	if _, err := s.repo.BulkCreatePages(ctx, pages); err != nil {
+		return fmt.Errorf("failed to create file pages: %w", err)
+	}
+
+	published := 0
+	for _, p := range pages {
+		if p.Status != "processing" {
+			continue
+		}
+		payload, _ := json.Marshal(map[string]interface{}{
+			"type":           "file",
+			"path":           p.URL,
+			"id":             src.ID,
+			"depth":          p.Depth,
+			"resync":         len(previous) > 0,
+			"correlation_id": middleware.GetCorrelationID(ctx),
+		})
+		if err := s.pub.Publish(config.TopicIngestFile, payload); err != nil {
+			slog.ErrorContext(ctx, "failed to publish ingest task", "error", err, "topic", config.TopicIngestFile)
+			return err
+		}
+		published++
+	}
+	slog.InfoContext(ctx, "published file tasks", "source_id", src.ID, "files", len(pages), "published", published)
+	if published == 0 {
+		return s.CompleteSync(ctx, src.ID)
+	}
+	return nil
+}
+
+func (s *Service) ListSyncs(ctx context.Context, id string) ([]SyncReport, error) {
+	if _, err := s.repo.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListSyncs(ctx, id)
+}
@@ -0,0 +1,61 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var ErrInvalidTag = errors.New("invalid tag")
+
+// maxTagLength bounds a tag name; tags are labels, not descriptions.
+const maxTagLength = 64
+
+// Tag is a tag with the number of sources carrying it.
+type Tag struct {
+	Name        string `json:"name"`
+	SourceCount int    `json:"source_count"`
+}
+
+// normalizeTags lowercases and trims tags, dropping duplicates, so "Go" and
+// "go " are the same tag.
+func normalizeTags(tags []string) ([]string, error) {
+	normalized := []string{}
+	for _, tag := range tags {
+		name := strings.ToLower(strings.TrimSpace(tag))
+		if name == "" || len(name) > maxTagLength {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidTag, tag)
+		}
+		if !slices.Contains(normalized, name) {
+			normalized = append(normalized, name)
+		}
+	}
+	slices.Sort(normalized)
+	return normalized, nil
+}
+
+// SetTags replaces a source's tags, returning them normalized.
+func (s *Service) SetTags(ctx context.Context, id string, tags []string) ([]string, error) {
+	normalized, err := normalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.repo.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetTags(ctx, id, normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+func (s *Service) ListTags(ctx context.Context) ([]Tag, error) {
+	return s.repo.ListTags(ctx)
+}
+
+// DeleteTag removes a tag from every source.
+func (s *Service) DeleteTag(ctx context.Context, name string) error {
+	return s.repo.DeleteTag(ctx, strings.ToLower(strings.TrimSpace(name)))
+}
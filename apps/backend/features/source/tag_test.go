@@ -0,0 +1,45 @@
+package source
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tags, err := normalizeTags([]string{"Go ", "backend", "go", "BACKEND"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"backend", "go"}, tags)
+
+	tags, err = normalizeTags(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, tags)
+
+	_, err = normalizeTags([]string{"go", " "})
+	assert.ErrorIs(t, err, ErrInvalidTag)
+}
+
+func TestService_SetTags(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := NewService(mockRepo, nil, nil, nil)
+
+	mockRepo.On("Get", mock.Anything, "src1").Return(&Source{ID: "src1"}, nil)
+	mockRepo.On("Get", mock.Anything, "missing").Return(nil, sql.ErrNoRows)
+	mockRepo.On("SetTags", mock.Anything, "src1", []string{"payments", "stripe"}).Return(nil)
+
+	tags, err := svc.SetTags(context.Background(), "src1", []string{"Stripe", "payments"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"payments", "stripe"}, tags)
+
+	_, err = svc.SetTags(context.Background(), "missing", []string{"go"})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	// Clearing tags is allowed
+	mockRepo.On("SetTags", mock.Anything, "src1", []string{}).Return(nil)
+	tags, err = svc.SetTags(context.Background(), "src1", nil)
+	assert.NoError(t, err)
+	assert.Empty(t, tags)
+}
@@ -0,0 +1,147 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var ErrNameRequired = errors.New("name is required")
+
+// SourceUpdate holds the settings a PATCH changes; nil fields are kept.
+type SourceUpdate struct {
+	Name         *string   `json:"name"`
+	MaxDepth     *int      `json:"max_depth"`
+	Exclusions   *[]string `json:"exclusions"`
+	SyncEnabled  *bool     `json:"sync_enabled"`
+	SyncSchedule *string   `json:"sync_schedule"`
+
+	IncludePatterns *[]string `json:"include_patterns"`
+	PathPrefix      *string   `json:"path_prefix"`
+	KeepQueryParams *[]string `json:"keep_query_params"`
+	DropQueryParams *[]string `json:"drop_query_params"`
+	SeedURLs        *[]string `json:"seed_urls"`
+	AllowedHosts    *[]string `json:"allowed_hosts"`
+
+	MaxPages        *int `json:"max_pages"`
+	MaxCrawlMinutes *int `json:"max_crawl_minutes"`
+	KeepVersions    *int `json:"keep_versions"`
+
+	Tags *[]string `json:"tags"`
+
+	GitRef       *string   `json:"git_ref"`
+	IncludeGlobs *[]string `json:"include_globs"`
+	ExcludeGlobs *[]string `json:"exclude_globs"`
+	Watch        *bool     `json:"watch"`
+}
+
+func (u SourceUpdate) apply(src *Source) {
+	set := func(dst *[]string, v *[]string) {
+		if v != nil {
+			*dst = slices.Clone(*v)
+		}
+	}
+	if u.Name != nil {
+		src.Name = strings.TrimSpace(*u.Name)
+	}
+	if u.MaxDepth != nil {
+		src.MaxDepth = *u.MaxDepth
+	}
+	set(&src.Exclusions, u.Exclusions)
+	if u.SyncEnabled != nil {
+		src.SyncEnabled = *u.SyncEnabled
+	}
+	if u.SyncSchedule != nil {
+		src.SyncSchedule = *u.SyncSchedule
+	}
+	set(&src.IncludePatterns, u.IncludePatterns)
+	if u.PathPrefix != nil {
+		src.PathPrefix = *u.PathPrefix
+	}
+	set(&src.KeepQueryParams, u.KeepQueryParams)
+	set(&src.DropQueryParams, u.DropQueryParams)
+	set(&src.SeedURLs, u.SeedURLs)
+	set(&src.AllowedHosts, u.AllowedHosts)
+	if u.MaxPages != nil {
+		src.MaxPages = *u.MaxPages
+	}
+	if u.MaxCrawlMinutes != nil {
+		src.MaxCrawlMinutes = *u.MaxCrawlMinutes
+	}
+	if u.KeepVersions != nil {
+		src.KeepVersions = *u.KeepVersions
+	}
+	set(&src.Tags, u.Tags)
+	if u.GitRef != nil {
+		src.GitRef = *u.GitRef
+	}
+	set(&src.IncludeGlobs, u.IncludeGlobs)
+	set(&src.ExcludeGlobs, u.ExcludeGlobs)
+	if u.Watch != nil {
+		src.Watch = *u.Watch
+	}
+}
+
+// crawlChanged reports whether a and b would crawl different pages.
+func crawlChanged(a, b *Source) bool {
+	return a.MaxDepth != b.MaxDepth ||
+		a.PathPrefix != b.PathPrefix ||
+		a.MaxPages != b.MaxPages ||
+		a.MaxCrawlMinutes != b.MaxCrawlMinutes ||
+		!slices.Equal(a.Exclusions, b.Exclusions) ||
+		!slices.Equal(a.IncludePatterns, b.IncludePatterns) ||
+		!slices.Equal(a.KeepQueryParams, b.KeepQueryParams) ||
+		!slices.Equal(a.DropQueryParams, b.DropQueryParams) ||
+		!slices.Equal(a.SeedURLs, b.SeedURLs) ||
+		!slices.Equal(a.AllowedHosts, b.AllowedHosts) ||
+		a.GitRef != b.GitRef ||
+		!slices.Equal(a.IncludeGlobs, b.IncludeGlobs) ||
+		!slices.Equal(a.ExcludeGlobs, b.ExcludeGlobs)
+}
+
+// Update applies u to a source after validating the result like Create
+// does. With resync set, a source whose crawl settings changed is resynced;
+// the returned bool reports whether it was.
+func (s *Service) Update(ctx context.Context, id string, u SourceUpdate, resync bool) (*Source, bool, error) {
+	src, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, false, err
+	}
+	before := *src
+	updated := *src
+	u.apply(&updated)
+	if updated.Name == "" {
+		return nil, false, ErrNameRequired
+	}
+	if err := s.validate(&updated); err != nil {
+		return nil, false, err
+	}
+	// A repository may be added once per ref
+	if updated.Type == "git" && updated.GitRef != before.GitRef {
+		updated.ContentHash = sourceHash(&updated)
+		exists, err := s.repo.ExistsByHash(ctx, updated.ContentHash)
+		if err != nil {
+			return nil, false, err
+		}
+		if exists {
+			return nil, false, fmt.Errorf("Duplicate detected")
+		}
+	}
+
+	resync = resync && crawlChanged(&before, &updated)
+	if resync && src.Status == "in_progress" {
+		return nil, false, ErrSyncInProgress
+	}
+	if err := s.repo.Update(ctx, &updated, u.Tags != nil); err != nil {
+		return nil, false, err
+	}
+	if resync {
+		if err := s.ReSync(ctx, id); err != nil {
+			return nil, false, err
+		}
+		updated.Status = "in_progress"
+	}
+	return &updated, resync, nil
+}
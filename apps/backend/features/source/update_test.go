@@ -0,0 +1,133 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"qurio/apps/backend/internal/config"
+)
+
+func TestService_Update(t *testing.T) {
+	existing := func() *Source {
+		return &Source{ID: "src1", Type: "web", URL: "https://example.com", Name: "Docs", Status: "completed", MaxDepth: 1}
+	}
+	strPtr := func(s string) *string { return &s }
+	// Stands in for the scheduler's parser
+	validateSchedule := func(spec string) error {
+		if spec == "every tuesday" {
+			return errors.New("expected exactly 5 fields")
+		}
+		return nil
+	}
+	intPtr := func(i int) *int { return &i }
+
+	t.Run("SyncSettingsOnly", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := NewService(mockRepo, nil, nil, nil)
+		svc.SetScheduleValidator(validateSchedule)
+
+		enabled := true
+		mockRepo.On("Get", mock.Anything, "src1").Return(existing(), nil)
+		mockRepo.On("Update", mock.Anything, mock.MatchedBy(func(s *Source) bool {
+			return s.Name == "API Docs" && s.SyncEnabled && s.SyncSchedule == "0 3 * * *" && s.MaxDepth == 1
+		}), false).Return(nil)
+
+		src, resynced, err := svc.Update(context.Background(), "src1", SourceUpdate{
+			Name:         strPtr(" API Docs "),
+			SyncEnabled:  &enabled,
+			SyncSchedule: strPtr("0 3 * * *"),
+		}, true)
+		assert.NoError(t, err)
+		assert.False(t, resynced, "sync settings don't change the crawl")
+		assert.Equal(t, "API Docs", src.Name)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("CrawlSettingsResync", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockPub := new(MockPublisher)
+		mockSettings := new(MockSettingsService)
+		svc := NewService(mockRepo, mockPub, nil, mockSettings)
+
+		mockRepo.On("Get", mock.Anything, "src1").Return(existing(), nil)
+		mockRepo.On("Update", mock.Anything, mock.MatchedBy(func(s *Source) bool {
+			return s.MaxDepth == 3 && len(s.Exclusions) == 1
+		}), false).Return(nil)
+		mockRepo.On("StartCrawl", mock.Anything, "src1").Return(nil)
+		mockPub.On("Publish", config.TopicSourceSync, mock.Anything).Return(nil)
+
+		src, resynced, err := svc.Update(context.Background(), "src1", SourceUpdate{
+			MaxDepth:   intPtr(3),
+			Exclusions: &[]string{"/blog/"},
+		}, true)
+		assert.NoError(t, err)
+		assert.True(t, resynced)
+		assert.Equal(t, "in_progress", src.Status)
+		mockPub.AssertExpectations(t)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := NewService(mockRepo, nil, nil, nil)
+		svc.SetScheduleValidator(validateSchedule)
+		mockRepo.On("Get", mock.Anything, "src1").Return(existing(), nil)
+
+		_, _, err := svc.Update(context.Background(), "src1", SourceUpdate{SyncSchedule: strPtr("every tuesday")}, false)
+		assert.ErrorIs(t, err, ErrInvalidSchedule)
+
+		_, _, err = svc.Update(context.Background(), "src1", SourceUpdate{Exclusions: &[]string{"["}}, false)
+		assert.ErrorIs(t, err, ErrInvalidCrawlScope)
+
+		_, _, err = svc.Update(context.Background(), "src1", SourceUpdate{MaxDepth: intPtr(-1)}, false)
+		assert.ErrorIs(t, err, ErrInvalidCrawlScope)
+
+		_, _, err = svc.Update(context.Background(), "src1", SourceUpdate{Name: strPtr("  ")}, false)
+		assert.ErrorIs(t, err, ErrNameRequired)
+
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("GitRefRehashes", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := NewService(mockRepo, nil, nil, nil)
+		repo := &Source{ID: "src1", Type: "git", URL: "https://github.com/acme/docs.git", GitRef: "main", Name: "Docs", Status: "completed"}
+		repo.ContentHash = sourceHash(repo)
+		release := *repo
+		release.GitRef = "v2"
+		mockRepo.On("Get", mock.Anything, "src1").Return(repo, nil)
+		mockRepo.On("ExistsByHash", mock.Anything, sourceHash(&release)).Return(false, nil)
+		mockRepo.On("Update", mock.Anything, mock.MatchedBy(func(s *Source) bool {
+			return s.GitRef == "v2" && s.ContentHash == sourceHash(&release)
+		}), true).Return(nil)
+
+		_, _, err := svc.Update(context.Background(), "src1", SourceUpdate{GitRef: strPtr("v2"), Tags: &[]string{"go"}}, false)
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("GitRefDuplicate", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := NewService(mockRepo, nil, nil, nil)
+		mockRepo.On("Get", mock.Anything, "src1").Return(&Source{ID: "src1", Type: "git", URL: "https://github.com/acme/docs.git", GitRef: "main", Name: "Docs"}, nil)
+		mockRepo.On("ExistsByHash", mock.Anything, mock.Anything).Return(true, nil)
+
+		_, _, err := svc.Update(context.Background(), "src1", SourceUpdate{GitRef: strPtr("v2")}, false)
+		assert.EqualError(t, err, "Duplicate detected")
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("ResyncWhileSyncing", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := NewService(mockRepo, nil, nil, nil)
+		busy := existing()
+		busy.Status = "in_progress"
+		mockRepo.On("Get", mock.Anything, "src1").Return(busy, nil)
+
+		_, _, err := svc.Update(context.Background(), "src1", SourceUpdate{MaxDepth: intPtr(2)}, true)
+		assert.ErrorIs(t, err, ErrSyncInProgress)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,173 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrInvalidVersion = errors.New("invalid version")
+var ErrSyncInProgress = errors.New("source is syncing")
+
+// snapshotBatchSize is how many chunks are copied into a snapshot at a time.
+const snapshotBatchSize = 100
+
+// Version is a retained snapshot of a source, or its current content.
+type Version struct {
+	ID        string     `json:"id,omitempty"`
+	SourceID  string     `json:"source_id"`
+	Version   string     `json:"version"`
+	PageCount int        `json:"page_count"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	Current   bool       `json:"current"`
+}
+
+// VersionDiff lists the pages that differ between two versions of a source.
+type VersionDiff struct {
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	Changed []string `json:"changed"`
+}
+
+// SetVersion moves a source to a new version label and resyncs it. With
+// KeepVersions set, the chunks of the version being replaced are copied into
+// a snapshot that stays searchable under its label; the oldest snapshots
+// beyond KeepVersions are dropped.
+func (s *Service) SetVersion(ctx context.Context, id, version string) error {
+	version = strings.TrimSpace(version)
+	if version == "" {
+		return fmt.Errorf("%w: version is required", ErrInvalidVersion)
+	}
+	src, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if src.Version == version {
+		return fmt.Errorf("%w: source is already at %s", ErrInvalidVersion, version)
+	}
+	if src.Status == "in_progress" {
+		return ErrSyncInProgress
+	}
+
+	if src.KeepVersions > 0 && src.Version != "" {
+		if err := s.snapshotChunks(ctx, src); err != nil {
+			return fmt.Errorf("failed to snapshot %s: %w", src.Version, err)
+		}
+	}
+	dropped, err := s.repo.SaveVersion(ctx, id, src.Version, version, src.KeepVersions)
+	if err != nil {
+		return err
+	}
+	for _, v := range dropped {
+		if err := s.chunkStore.DeleteVersionChunks(ctx, id, v); err != nil {
+			slog.WarnContext(ctx, "failed to delete snapshot chunks", "source_id", id, "version", v, "error", err)
+		}
+	}
+	slog.InfoContext(ctx, "source version changed", "source_id", id, "from", src.Version, "to", version, "dropped", len(dropped))
+
+	// Every page is re-embedded so the live chunks carry the new label: the
+	// label is part of the chunk key, so no page is skipped as unchanged
+	return s.ReSync(ctx, id)
+}
+
+// snapshotChunks copies the source's live chunks under its current label,
+// replacing an earlier snapshot of the same label.
+func (s *Service) snapshotChunks(ctx context.Context, src *Source) error {
+	if err := s.chunkStore.DeleteVersionChunks(ctx, src.ID, src.Version); err != nil {
+		return err
+	}
+	copied := 0
+	for after := ""; ; {
+		batch, err := s.chunkStore.GetChunksWithVectorsAfter(ctx, src.ID, after, snapshotBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		after = batch[len(batch)-1].ID
+		for i := range batch {
+			batch[i].Version = src.Version
+		}
+		if err := s.chunkStore.StoreVersionChunks(ctx, batch); err != nil {
+			return err
+		}
+		copied += len(batch)
+		if len(batch) < snapshotBatchSize {
+			break
+		}
+	}
+	slog.InfoContext(ctx, "source version snapshotted", "source_id", src.ID, "version", src.Version, "chunks", copied)
+	return nil
+}
+
+// ListVersions returns the source's current version followed by its
+// snapshots, newest first.
+func (s *Service) ListVersions(ctx context.Context, id string) ([]Version, error) {
+	src, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	snapshots, err := s.repo.ListVersions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.repo.PageHashes(ctx, id, "")
+	if err != nil {
+		return nil, err
+	}
+	versions := []Version{{SourceID: id, Version: src.Version, PageCount: len(current), Current: true}}
+	return append(versions, snapshots...), nil
+}
+
+// DiffVersions compares the pages of two versions by content hash. Either
+// may name the source's current version.
+func (s *Service) DiffVersions(ctx context.Context, id, from, to string) (*VersionDiff, error) {
+	src, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	pages := func(version string) (map[string]string, error) {
+		if version == "" {
+			return nil, fmt.Errorf("%w: from and to are required", ErrInvalidVersion)
+		}
+		if version == src.Version {
+			version = ""
+		}
+		return s.repo.PageHashes(ctx, id, version)
+	}
+	before, err := pages(from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := pages(to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &VersionDiff{From: from, To: to, Added: []string{}, Removed: []string{}, Changed: []string{}}
+	for u, hash := range after {
+		old, ok := before[u]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, u)
+		case old != hash:
+			diff.Changed = append(diff.Changed, u)
+		}
+	}
+	for u := range before {
+		if _, ok := after[u]; !ok {
+			diff.Removed = append(diff.Removed, u)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+	return diff, nil
+}
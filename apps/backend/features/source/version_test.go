@@ -0,0 +1,157 @@
+package source
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"qurio/apps/backend/internal/config"
+	"qurio/apps/backend/internal/text"
+	"qurio/apps/backend/internal/worker"
+)
+
+func TestService_SetVersion(t *testing.T) {
+	t.Run("SnapshotsPreviousVersion", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockChunk := new(MockChunkStore)
+		mockPub := new(MockPublisher)
+		mockSettings := new(MockSettingsService)
+		svc := NewService(mockRepo, mockPub, mockChunk, mockSettings)
+
+		src := &Source{ID: "src1", Type: "file", URL: "/uploads/api.md", Status: "completed", Version: "v1.x", KeepVersions: 2}
+		mockRepo.On("Get", mock.Anything, "src1").Return(src, nil)
+
+		// The live chunks are copied under the old label, one batch at a
+		// time, each starting after the last chunk of the one before
+		mockChunk.On("DeleteVersionChunks", mock.Anything, "src1", "v1.x").Return(nil).Once()
+		full := make([]worker.Chunk, snapshotBatchSize)
+		full[len(full)-1].ID = "chunk-100"
+		mockChunk.On("GetChunksWithVectorsAfter", mock.Anything, "src1", "", snapshotBatchSize).Return(full, nil)
+		mockChunk.On("GetChunksWithVectorsAfter", mock.Anything, "src1", "chunk-100", snapshotBatchSize).
+			Return([]worker.Chunk{{ID: "chunk-101", Content: "last", SourceID: "src1"}}, nil)
+		mockChunk.On("StoreVersionChunks", mock.Anything, mock.MatchedBy(func(chunks []worker.Chunk) bool {
+			for _, c := range chunks {
+				if c.Version != "v1.x" {
+					return false
+				}
+			}
+			return true
+		})).Return(nil).Twice()
+
+		// The oldest snapshot falls out of the two kept
+		mockRepo.On("SaveVersion", mock.Anything, "src1", "v1.x", "v2.x", 2).Return([]string{"v0.x"}, nil)
+		mockChunk.On("DeleteVersionChunks", mock.Anything, "src1", "v0.x").Return(nil).Once()
+
+		// Resync
+		mockRepo.On("StartCrawl", mock.Anything, "src1").Return(nil)
+		mockSettings.On("Get", mock.Anything).Return(nil, errors.New("no settings"))
+		mockPub.On("Publish", config.TopicIngestFile, mock.Anything).Return(nil)
+
+		err := svc.SetVersion(context.Background(), "src1", " v2.x ")
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+		mockChunk.AssertExpectations(t)
+		mockPub.AssertExpectations(t)
+	})
+
+	t.Run("NothingKept", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockChunk := new(MockChunkStore)
+		mockPub := new(MockPublisher)
+		mockSettings := new(MockSettingsService)
+		svc := NewService(mockRepo, mockPub, mockChunk, mockSettings)
+
+		src := &Source{ID: "src1", Type: "file", URL: "/uploads/api.md", Status: "completed", Version: "v1.x"}
+		mockRepo.On("Get", mock.Anything, "src1").Return(src, nil)
+		mockRepo.On("SaveVersion", mock.Anything, "src1", "v1.x", "v2.x", 0).Return([]string(nil), nil)
+		mockRepo.On("StartCrawl", mock.Anything, "src1").Return(nil)
+		mockSettings.On("Get", mock.Anything).Return(nil, errors.New("no settings"))
+		mockPub.On("Publish", config.TopicIngestFile, mock.Anything).Return(nil)
+
+		err := svc.SetVersion(context.Background(), "src1", "v2.x")
+		assert.NoError(t, err)
+		mockChunk.AssertNotCalled(t, "GetChunksWithVectorsAfter", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := NewService(mockRepo, nil, nil, nil)
+
+		mockRepo.On("Get", mock.Anything, "same").Return(&Source{ID: "same", Version: "v1.x", Status: "completed"}, nil)
+		mockRepo.On("Get", mock.Anything, "busy").Return(&Source{ID: "busy", Version: "v1.x", Status: "in_progress"}, nil)
+
+		assert.ErrorIs(t, svc.SetVersion(context.Background(), "same", ""), ErrInvalidVersion)
+		assert.ErrorIs(t, svc.SetVersion(context.Background(), "same", "v1.x"), ErrInvalidVersion)
+		assert.ErrorIs(t, svc.SetVersion(context.Background(), "busy", "v2.x"), ErrSyncInProgress)
+		mockRepo.AssertNotCalled(t, "SaveVersion", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestService_RunSync_RelabelledReembedsUnchanged(t *testing.T) {
+	dir := tempDir(t)
+	writeFiles(t, dir, map[string]string{"guide.md": "same"})
+	path := filepath.Join(dir, "guide.md")
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	modTime := info.ModTime().UTC().Truncate(time.Microsecond)
+	hash, err := hashFile(path)
+	assert.NoError(t, err)
+
+	mockRepo := new(MockRepository)
+	mockPub := new(MockPublisher)
+	svc := NewService(mockRepo, mockPub, nil, nil)
+	svc.SetDirectoryRoots([]string{dir})
+
+	// The file is untouched, but its chunks carry the previous label
+	mockRepo.On("Get", mock.Anything, "src1").Return(&Source{ID: "src1", Type: "directory", URL: dir, IncludeGlobs: DefaultIncludeGlobs, Version: "v2.x"}, nil)
+	mockRepo.On("GetPages", mock.Anything, "src1").Return([]SourcePage{
+		{URL: path, Status: "completed", ModTime: &modTime, FileHash: hash, ChunkKey: worker.ChunkKey(text.DefaultChunkProfile(), "v1.x")},
+	}, nil)
+	mockRepo.On("MarkPagesStale", mock.Anything, "src1").Return(nil)
+	mockRepo.On("BulkCreatePages", mock.Anything, mock.MatchedBy(func(pages []SourcePage) bool {
+		return len(pages) == 1 && pages[0].Status == "processing" && pages[0].FileHash == hash
+	})).Return([]string{path}, nil)
+	mockPub.On("Publish", config.TopicIngestFile, mock.Anything).Return(nil)
+
+	err = svc.runSync(context.Background(), "src1", true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{path}, publishedPaths(mockPub))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_DiffVersions(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := NewService(mockRepo, nil, nil, nil)
+
+	mockRepo.On("Get", mock.Anything, "src1").Return(&Source{ID: "src1", Version: "v2.x"}, nil)
+	mockRepo.On("PageHashes", mock.Anything, "src1", "v1.x").Return(map[string]string{
+		"https://example.com/a": "h1",
+		"https://example.com/b": "h2",
+		"https://example.com/c": "h3",
+	}, nil)
+	// The current version is read from the live pages
+	mockRepo.On("PageHashes", mock.Anything, "src1", "").Return(map[string]string{
+		"https://example.com/a": "h1",
+		"https://example.com/b": "h2-new",
+		"https://example.com/d": "h4",
+	}, nil)
+	mockRepo.On("PageHashes", mock.Anything, "src1", "v0.x").Return(nil, sql.ErrNoRows)
+
+	diff, err := svc.DiffVersions(context.Background(), "src1", "v1.x", "v2.x")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com/d"}, diff.Added)
+	assert.Equal(t, []string{"https://example.com/c"}, diff.Removed)
+	assert.Equal(t, []string{"https://example.com/b"}, diff.Changed)
+
+	_, err = svc.DiffVersions(context.Background(), "src1", "v0.x", "v2.x")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = svc.DiffVersions(context.Background(), "src1", "v1.x", "")
+	assert.ErrorIs(t, err, ErrInvalidVersion)
+}
@@ -0,0 +1,232 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watcher resyncs directory sources with Watch set shortly after files under
+// them change. The resync is queued like any other: it walks the whole tree,
+// hashes the files whose mtime changed, reprocesses those whose hash changed
+// too and drops the chunks of removed ones. fsnotify watches single
+// directories, so each directory of a source is watched and new ones are
+// added as they appear.
+type Watcher struct {
+	debounce time.Duration
+	list     func(ctx context.Context) ([]Source, error)
+	resync   func(ctx context.Context, id string) error
+
+	fs      *fsnotify.Watcher
+	sources map[string]Source    // by ID
+	dirs    map[string]string    // watched directory -> source ID
+	dirty   map[string]time.Time // source ID -> last change seen
+}
+
+// NewWatcher resyncs a source once its files have been left alone for
+// debounce.
+func NewWatcher(service *Service, debounce time.Duration) *Watcher {
+	return &Watcher{
+		debounce: debounce,
+		list:     service.List,
+		resync:   service.resyncIdle,
+		sources:  make(map[string]Source),
+		dirs:     make(map[string]string),
+		dirty:    make(map[string]time.Time),
+	}
+}
+
+// resyncIdle resyncs a source unless a sync of it is running.
+func (s *Service) resyncIdle(ctx context.Context, id string) error {
+	src, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if src.Status == "in_progress" {
+		return ErrSyncInProgress
+	}
+	return s.ReSync(ctx, id)
+}
+
+// Run watches until ctx is done, picking up watched sources added, changed
+// or deleted every refresh. Every watched source is resynced once at start,
+// catching up on changes made while nothing watched it.
+func (w *Watcher) Run(ctx context.Context, refresh time.Duration) error {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer fw.Close()
+	w.fs = fw
+
+	w.refresh(ctx, true)
+	refreshTicker := time.NewTicker(refresh)
+	defer refreshTicker.Stop()
+	flushTicker := time.NewTicker(w.debounce)
+	defer flushTicker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-fw.Events:
+			if !ok {
+				return nil
+			}
+			w.handle(ev)
+		case err, ok := <-fw.Errors:
+			if !ok {
+				return nil
+			}
+			slog.Warn("directory watch error", "error", err)
+		case <-refreshTicker.C:
+			w.refresh(ctx, false)
+		case <-flushTicker.C:
+			w.flush(ctx)
+		}
+	}
+}
+
+// refresh watches the sources that want it and stops watching the others.
+// A source whose directory or globs changed is watched afresh.
+func (w *Watcher) refresh(ctx context.Context, catchUp bool) {
+	sources, err := w.list(ctx)
+	if err != nil {
+		slog.Error("failed to list watched directories", "error", err)
+		return
+	}
+	want := make(map[string]Source)
+	for _, src := range sources {
+		if src.Type == "directory" && src.Watch {
+			want[src.ID] = src
+		}
+	}
+	for id, src := range w.sources {
+		if next, ok := want[id]; !ok || next.URL != src.URL || !slices.Equal(next.ExcludeGlobs, src.ExcludeGlobs) {
+			w.unwatch(id)
+		}
+	}
+	for id, src := range want {
+		_, watched := w.sources[id]
+		w.sources[id] = src
+		if watched {
+			continue
+		}
+		w.watchTree(src, src.URL)
+		if catchUp {
+			w.dirty[id] = time.Time{}
+		}
+		slog.Info("watching directory", "source_id", id, "path", src.URL)
+	}
+}
+
+// watchTree watches dir and the directories below it that the source
+// doesn't exclude.
+func (w *Watcher) watchTree(src Source, dir string) {
+	exclude, _ := newGlobMatcher(src.ExcludeGlobs)
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if rel, err := filepath.Rel(src.URL, path); err == nil && rel != "." {
+			rel = filepath.ToSlash(rel)
+			if exclude.Match(rel) || exclude.Match(rel+"/") {
+				return fs.SkipDir
+			}
+		}
+		if err := w.fs.Add(path); err != nil {
+			slog.Warn("failed to watch directory", "source_id", src.ID, "path", path, "error", err)
+			return nil
+		}
+		w.dirs[path] = src.ID
+		return nil
+	})
+}
+
+func (w *Watcher) unwatch(id string) {
+	for dir, sourceID := range w.dirs {
+		if sourceID == id {
+			w.fs.Remove(dir)
+			delete(w.dirs, dir)
+		}
+	}
+	delete(w.sources, id)
+	delete(w.dirty, id)
+}
+
+// handle marks the source of a changed file dirty, watching directories as
+// they are created. Changes to files the source doesn't ingest are ignored.
+func (w *Watcher) handle(ev fsnotify.Event) {
+	if ev.Op == fsnotify.Chmod {
+		return
+	}
+	id, ok := w.dirs[filepath.Dir(ev.Name)]
+	if !ok {
+		return
+	}
+	src := w.sources[id]
+	relevant := false
+	if ev.Has(fsnotify.Create) {
+		if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+			// A directory moved in may come with files
+			w.watchTree(src, ev.Name)
+			relevant = true
+		}
+	}
+	if ev.Has(fsnotify.Remove) || ev.Has(fsnotify.Rename) {
+		// A directory gone takes its watches and files along
+		for dir := range w.dirs {
+			if dir == ev.Name || strings.HasPrefix(dir, ev.Name+string(filepath.Separator)) {
+				delete(w.dirs, dir)
+				relevant = true
+			}
+		}
+	}
+	if relevant || ingests(src, ev.Name) {
+		w.dirty[id] = time.Now()
+	}
+}
+
+// ingests reports whether a file under a directory source would be ingested.
+func ingests(src Source, path string) bool {
+	rel, err := filepath.Rel(src.URL, path)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	include, err := newGlobMatcher(src.IncludeGlobs)
+	if err != nil {
+		return false
+	}
+	exclude, _ := newGlobMatcher(src.ExcludeGlobs)
+	return include.Match(rel) && !exclude.Match(rel) && !matchesAny(compileExclusions(src.Exclusions), rel)
+}
+
+// flush queues resyncs of the sources left alone for debounce since they
+// changed; the scan runs in the SyncConsumer, off the event loop. Sources
+// still syncing stay dirty and are resynced once that sync is done.
+func (w *Watcher) flush(ctx context.Context) {
+	now := time.Now()
+	for id, changed := range w.dirty {
+		if now.Sub(changed) < w.debounce {
+			continue
+		}
+		err := w.resync(ctx, id)
+		if errors.Is(err, ErrSyncInProgress) {
+			continue
+		}
+		delete(w.dirty, id)
+		if err != nil {
+			slog.Error("failed to resync watched directory", "source_id", id, "error", err)
+			continue
+		}
+		slog.Info("queued resync of watched directory", "source_id", id)
+	}
+}
@@ -0,0 +1,88 @@
+package source
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatcher_ResyncsChangedDirectories(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"notes/a.md": "# A", "build/out.md": "# Out"})
+
+	resynced := make(chan string, 10)
+	w := &Watcher{
+		debounce: 20 * time.Millisecond,
+		list: func(ctx context.Context) ([]Source, error) {
+			return []Source{
+				{ID: "watched", Type: "directory", URL: dir, Watch: true, IncludeGlobs: DefaultIncludeGlobs, ExcludeGlobs: []string{"build"}},
+				{ID: "unwatched", Type: "directory", URL: dir, IncludeGlobs: DefaultIncludeGlobs},
+				{ID: "web", Type: "web", URL: "https://example.com", Watch: true},
+			}, nil
+		},
+		resync: func(ctx context.Context, id string) error {
+			resynced <- id
+			return nil
+		},
+		sources: make(map[string]Source),
+		dirs:    make(map[string]string),
+		dirty:   make(map[string]time.Time),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.Run(ctx, time.Hour)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	expectResync := func(msg string) {
+		t.Helper()
+		select {
+		case id := <-resynced:
+			assert.Equal(t, "watched", id, msg)
+		case <-time.After(2 * time.Second):
+			t.Fatal(msg)
+		}
+	}
+	expectNoResync := func(msg string) {
+		t.Helper()
+		select {
+		case id := <-resynced:
+			t.Fatalf("%s: unexpected resync of %s", msg, id)
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+
+	expectResync("catch-up resync at start")
+
+	writeFiles(t, dir, map[string]string{"notes/b.go": "package b", "build/new.md": "# New"})
+	expectNoResync("files not ingested")
+
+	writeFiles(t, dir, map[string]string{"notes/a.md": "# A v2"})
+	expectResync("modified file")
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "adr"), 0o755))
+	expectResync("new directory")
+	writeFiles(t, dir, map[string]string{"adr/0001.md": "# ADR"})
+	expectResync("file in new directory")
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "notes", "a.md")))
+	expectResync("removed file")
+}
+
+func TestIngests(t *testing.T) {
+	src := Source{URL: "/srv/notes", IncludeGlobs: DefaultIncludeGlobs, ExcludeGlobs: []string{"drafts/**"}, Exclusions: []string{"^tmp"}}
+	assert.True(t, ingests(src, "/srv/notes/adr/0001.md"))
+	assert.False(t, ingests(src, "/srv/notes/adr/0001.go"))
+	assert.False(t, ingests(src, "/srv/notes/drafts/idea.md"))
+	assert.False(t, ingests(src, "/srv/notes/tmp.md"))
+}
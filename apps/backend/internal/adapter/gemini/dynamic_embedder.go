@@ -2,12 +2,20 @@ package gemini
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/google/generative-ai-go/genai"
+	"github.com/googleapis/gax-go/v2/apierror"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
+	"qurio/apps/backend/internal/config"
 	"qurio/apps/backend/internal/settings"
+	"qurio/apps/backend/internal/worker"
 )
 
 type DynamicEmbedder struct {
@@ -40,10 +48,10 @@ func (e *DynamicEmbedder) Embed(ctx context.Context, text string) ([]float32, er
 		return nil, err
 	}
 
-	model := client.EmbeddingModel("gemini-embedding-001")
+	model := client.EmbeddingModel(config.EmbeddingModel)
 	res, err := model.EmbedContent(ctx, genai.Text(text))
 	if err != nil {
-		return nil, err
+		return nil, asRateLimitError(err)
 	}
 
 	if len(res.Embedding.Values) == 0 {
@@ -83,3 +91,37 @@ func (e *DynamicEmbedder) getClient(ctx context.Context, key string) (*genai.Cli
 	e.currentKey = key
 	return client, nil
 }
+
+// asRateLimitError converts a 429 into a *worker.RateLimitError carrying the
+// Retry-After header, or the RetryInfo detail when the header is absent.
+func asRateLimitError(err error) error {
+	var gerr *googleapi.Error
+	if !errors.As(err, &gerr) || gerr.Code != http.StatusTooManyRequests {
+		return err
+	}
+	rl := &worker.RateLimitError{Err: err}
+	if gerr.Header != nil {
+		rl.RetryAfter = parseRetryAfter(gerr.Header.Get("Retry-After"), time.Now())
+	}
+	var ae *apierror.APIError
+	if rl.RetryAfter == 0 && errors.As(err, &ae) {
+		if info := ae.Details().RetryInfo; info != nil && info.GetRetryDelay() != nil {
+			rl.RetryAfter = info.GetRetryDelay().AsDuration()
+		}
+	}
+	return rl
+}
+
+// parseRetryAfter reads a Retry-After value in seconds or as an HTTP date.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
@@ -7,11 +7,13 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"google.golang.org/api/option"
 	"qurio/apps/backend/internal/settings"
+	"qurio/apps/backend/internal/worker"
 )
 
 // --- Mocks ---
@@ -225,4 +227,51 @@ func TestDynamicEmbedder_Embed_TooManyRequests(t *testing.T) {
 
 	_, err := embedder.Embed(context.Background(), "test")
 	assert.Error(t, err)
-}
\ No newline at end of file
+}
+func TestDynamicEmbedder_Embed_RetryAfter(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", "17")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error":{"code":429,"message":"quota exceeded","status":"RESOURCE_EXHAUSTED"}}`))
+	}))
+	defer ts.Close()
+
+	mockRepo := new(MockSettingsRepo)
+	mockRepo.On("Get", mock.Anything).Return(&settings.Settings{GeminiAPIKey: "test-key"}, nil)
+	embedder := NewDynamicEmbedder(settings.NewService(mockRepo), option.WithEndpoint(ts.URL))
+
+	_, err := embedder.Embed(context.Background(), "test")
+	var rl *worker.RateLimitError
+	if assert.ErrorAs(t, err, &rl) {
+		assert.Equal(t, 17*time.Second, rl.RetryAfter)
+	}
+}
+
+func TestDynamicEmbedder_Embed_RetryInfo(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error":{"code":429,"message":"quota exceeded","status":"RESOURCE_EXHAUSTED","details":[` +
+			`{"@type":"type.googleapis.com/google.rpc.RetryInfo","retryDelay":"42s"}]}}`))
+	}))
+	defer ts.Close()
+
+	mockRepo := new(MockSettingsRepo)
+	mockRepo.On("Get", mock.Anything).Return(&settings.Settings{GeminiAPIKey: "test-key"}, nil)
+	embedder := NewDynamicEmbedder(settings.NewService(mockRepo), option.WithEndpoint(ts.URL))
+
+	_, err := embedder.Embed(context.Background(), "test")
+	var rl *worker.RateLimitError
+	if assert.ErrorAs(t, err, &rl) {
+		assert.Equal(t, 42*time.Second, rl.RetryAfter)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, 5*time.Second, parseRetryAfter("5", now))
+	assert.Equal(t, 90*time.Second, parseRetryAfter(now.Add(90*time.Second).Format(http.TimeFormat), now))
+	assert.Zero(t, parseRetryAfter("", now))
+	assert.Zero(t, parseRetryAfter("soon", now))
+}
@@ -0,0 +1,142 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Client keeps one working copy per source under a base directory, using the
+// git binary. Working copies live where the ingestion worker can read them.
+type Client struct {
+	baseDir string
+	bin     string
+}
+
+func NewClient(baseDir string) *Client {
+	return &Client{baseDir: baseDir, bin: "git"}
+}
+
+// Dir is the working copy of a source.
+func (c *Client) Dir(sourceID string) string {
+	return filepath.Join(c.baseDir, sourceID)
+}
+
+// Checkout clones the remote, or fetches it into an existing working copy,
+// and checks out ref: a branch, a tag or a commit, the remote's default
+// branch when empty. It returns the SHA of the commit checked out.
+func (c *Client) Checkout(ctx context.Context, sourceID, remote, ref string) (string, error) {
+	if remote == "" || strings.HasPrefix(remote, "-") {
+		return "", fmt.Errorf("invalid remote: %q", remote)
+	}
+	dir := c.Dir(sourceID)
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		if err := os.MkdirAll(c.baseDir, 0o755); err != nil {
+			return "", err
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return "", err
+		}
+		if _, err := c.run(ctx, "", "clone", "--no-checkout", "--", remote, dir); err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+	} else {
+		if _, err := c.run(ctx, dir, "remote", "set-url", "origin", remote); err != nil {
+			return "", err
+		}
+		if _, err := c.run(ctx, dir, "fetch", "--force", "--prune", "--tags", "origin", "+refs/heads/*:refs/remotes/origin/*"); err != nil {
+			return "", err
+		}
+		// Follow a change of the remote's default branch; remotes without
+		// one are left as they are
+		c.run(ctx, dir, "remote", "set-head", "origin", "--auto")
+	}
+
+	sha, err := c.resolve(ctx, dir, ref)
+	if err != nil {
+		return "", err
+	}
+	if _, err := c.run(ctx, dir, "checkout", "--force", "--detach", sha); err != nil {
+		return "", err
+	}
+	return sha, nil
+}
+
+// resolve finds the commit of ref, preferring the remote's branches over its
+// tags over anything else git accepts, such as a SHA.
+func (c *Client) resolve(ctx context.Context, dir, ref string) (string, error) {
+	candidates := []string{"refs/remotes/origin/HEAD"}
+	if ref != "" {
+		if strings.HasPrefix(ref, "-") {
+			return "", fmt.Errorf("invalid ref: %q", ref)
+		}
+		candidates = []string{"refs/remotes/origin/" + ref, "refs/tags/" + ref, ref}
+	}
+	for _, candidate := range candidates {
+		out, err := c.run(ctx, dir, "rev-parse", "--verify", "--quiet", candidate+"^{commit}")
+		if err == nil {
+			return strings.TrimSpace(out), nil
+		}
+	}
+	if ref == "" {
+		return "", errors.New("remote has no default branch")
+	}
+	return "", fmt.Errorf("unknown ref: %s", ref)
+}
+
+// Files lists the files of the commit checked out, relative to the working
+// copy and slash-separated.
+func (c *Client) Files(ctx context.Context, sourceID string) ([]string, error) {
+	out, err := c.run(ctx, c.Dir(sourceID), "ls-tree", "-r", "--name-only", "-z", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	return splitNul(out), nil
+}
+
+// Diff lists the files added or modified between two commits; deleted files
+// are left out.
+func (c *Client) Diff(ctx context.Context, sourceID, from, to string) ([]string, error) {
+	out, err := c.run(ctx, c.Dir(sourceID), "diff", "--name-only", "--no-renames", "--diff-filter=d", "-z", from, to, "--")
+	if err != nil {
+		return nil, err
+	}
+	return splitNul(out), nil
+}
+
+// Remove deletes the working copy of a source.
+func (c *Client) Remove(sourceID string) error {
+	return os.RemoveAll(c.Dir(sourceID))
+}
+
+func (c *Client) run(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, c.bin, args...)
+	cmd.Dir = dir
+	// Never wait on a credential prompt
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %w: %s", args[0], err, msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
+
+func splitNul(out string) []string {
+	var names []string
+	for _, name := range strings.Split(out, "\x00") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
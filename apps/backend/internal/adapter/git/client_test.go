@@ -0,0 +1,129 @@
+package git_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"qurio/apps/backend/internal/adapter/git"
+)
+
+// upstream is a repository standing in for a remote.
+type upstream struct {
+	t   *testing.T
+	dir string
+}
+
+func newUpstream(t *testing.T) *upstream {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	u := &upstream{t: t, dir: t.TempDir()}
+	u.git("init", "--quiet", "--initial-branch=main")
+	u.git("config", "user.email", "test@example.com")
+	u.git("config", "user.name", "Test")
+	return u
+}
+
+func (u *upstream) git(args ...string) string {
+	u.t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = u.dir
+	out, err := cmd.CombinedOutput()
+	require.NoError(u.t, err, string(out))
+	return string(out)
+}
+
+func (u *upstream) commit(files map[string]string, removed ...string) string {
+	u.t.Helper()
+	for name, content := range files {
+		path := filepath.Join(u.dir, name)
+		require.NoError(u.t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(u.t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	for _, name := range removed {
+		require.NoError(u.t, os.Remove(filepath.Join(u.dir, name)))
+	}
+	u.git("add", "-A")
+	u.git("commit", "--quiet", "-m", "update")
+	return u.head()
+}
+
+func (u *upstream) head() string {
+	u.t.Helper()
+	out := u.git("rev-parse", "HEAD")
+	return out[:len(out)-1]
+}
+
+func TestClient_CheckoutAndDiff(t *testing.T) {
+	up := newUpstream(t)
+	first := up.commit(map[string]string{"README.md": "# Hello", "docs/guide.md": "guide", "main.go": "package main"})
+
+	c := git.NewClient(filepath.Join(t.TempDir(), "git"))
+	ctx := context.Background()
+
+	sha, err := c.Checkout(ctx, "src1", "file://"+up.dir, "")
+	require.NoError(t, err)
+	assert.Equal(t, first, sha)
+
+	files, err := c.Files(ctx, "src1")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"README.md", "docs/guide.md", "main.go"}, files)
+	content, err := os.ReadFile(filepath.Join(c.Dir("src1"), "docs/guide.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "guide", string(content))
+
+	second := up.commit(map[string]string{"docs/guide.md": "guide v2", "docs/new.md": "new"}, "main.go")
+
+	sha, err = c.Checkout(ctx, "src1", "file://"+up.dir, "main")
+	require.NoError(t, err)
+	assert.Equal(t, second, sha)
+
+	changed, err := c.Diff(ctx, "src1", first, second)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"docs/guide.md", "docs/new.md"}, changed)
+
+	require.NoError(t, c.Remove("src1"))
+	assert.NoDirExists(t, c.Dir("src1"))
+}
+
+func TestClient_CheckoutTag(t *testing.T) {
+	up := newUpstream(t)
+	tagged := up.commit(map[string]string{"README.md": "v1"})
+	up.git("tag", "v1.0")
+	up.commit(map[string]string{"README.md": "v2"})
+
+	c := git.NewClient(t.TempDir())
+	ctx := context.Background()
+
+	// A local path works as well as a file:// remote
+	sha, err := c.Checkout(ctx, "src1", up.dir, "v1.0")
+	require.NoError(t, err)
+	assert.Equal(t, tagged, sha)
+	content, err := os.ReadFile(filepath.Join(c.Dir("src1"), "README.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(content))
+}
+
+func TestClient_CheckoutErrors(t *testing.T) {
+	up := newUpstream(t)
+	up.commit(map[string]string{"README.md": "# Hello"})
+
+	c := git.NewClient(t.TempDir())
+	ctx := context.Background()
+
+	_, err := c.Checkout(ctx, "src1", up.dir, "missing")
+	assert.ErrorContains(t, err, "unknown ref: missing")
+
+	_, err = c.Checkout(ctx, "src2", "--upload-pack=touch /tmp/x", "")
+	assert.ErrorContains(t, err, "invalid remote")
+
+	_, err = c.Checkout(ctx, "src3", filepath.Join(t.TempDir(), "none"), "")
+	assert.Error(t, err)
+	assert.NoDirExists(t, c.Dir("src3"))
+}
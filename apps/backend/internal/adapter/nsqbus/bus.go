@@ -0,0 +1,155 @@
+// Package nsqbus implements bus.Bus on NSQ.
+package nsqbus
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/nsqio/go-nsq"
+	"qurio/apps/backend/internal/bus"
+)
+
+// maxRequeueDelay is the longest requeue delay nsqd accepts.
+const maxRequeueDelay = time.Hour
+
+type Bus struct {
+	nsqdHost  string
+	lookupd   string
+	producer  *nsq.Producer
+	mu        sync.Mutex
+	consumers []*nsq.Consumer
+}
+
+// New connects a producer to nsqdHost. Subscriptions discover nsqd through
+// lookupd when it is set, otherwise they connect to nsqdHost directly.
+func New(nsqdHost, lookupd string) (*Bus, error) {
+	producer, err := nsq.NewProducer(nsqdHost, nsq.NewConfig())
+	if err != nil {
+		return nil, fmt.Errorf("nsq producer error: %w", err)
+	}
+	return &Bus{nsqdHost: nsqdHost, lookupd: lookupd, producer: producer}, nil
+}
+
+func (b *Bus) Publish(topic string, body []byte) error {
+	return b.producer.Publish(topic, body)
+}
+
+// Ping checks the producer's connection to nsqd.
+func (b *Bus) Ping() error {
+	return b.producer.Ping()
+}
+
+func (b *Bus) Subscribe(topic string, opts bus.SubscribeOptions, h bus.Handler) (bus.Subscription, error) {
+	cfg := nsq.NewConfig()
+	concurrency := max(opts.Concurrency, 1)
+	cfg.MaxInFlight = concurrency
+	cfg.MaxAttempts = opts.MaxAttempts
+	delay := opts.MaxRequeueDelay
+	if delay <= 0 || delay > maxRequeueDelay {
+		delay = maxRequeueDelay
+	}
+	cfg.MaxRequeueDelay = delay
+	cfg.MaxBackoffDuration = delay
+	if cfg.DefaultRequeueDelay > delay {
+		cfg.DefaultRequeueDelay = delay
+	}
+
+	consumer, err := nsq.NewConsumer(topic, opts.Channel, cfg)
+	if err != nil {
+		return nil, err
+	}
+	consumer.AddConcurrentHandlers(handler(topic, h), concurrency)
+
+	if b.lookupd != "" {
+		if err := consumer.ConnectToNSQLookupd(b.lookupd); err != nil {
+			consumer.Stop()
+			return nil, fmt.Errorf("connect to nsqlookupd: %w", err)
+		}
+		slog.Info("NSQ consumer connected via Lookupd", "topic", topic, "channel", opts.Channel, "lookupd", b.lookupd, "concurrency", concurrency)
+	} else {
+		if err := consumer.ConnectToNSQD(b.nsqdHost); err != nil {
+			consumer.Stop()
+			return nil, fmt.Errorf("connect to nsqd: %w", err)
+		}
+		slog.Info("NSQ consumer connected via NSQD", "topic", topic, "channel", opts.Channel, "nsqd", b.nsqdHost, "concurrency", concurrency)
+	}
+
+	b.mu.Lock()
+	b.consumers = append(b.consumers, consumer)
+	b.mu.Unlock()
+	return &subscription{consumer: consumer, max: concurrency}, nil
+}
+
+// StopConsumers stops the consumers, waiting for in-flight messages; the
+// producer stays connected.
+func (b *Bus) StopConsumers() {
+	b.mu.Lock()
+	consumers := b.consumers
+	b.consumers = nil
+	b.mu.Unlock()
+
+	for _, c := range consumers {
+		c.Stop()
+		<-c.StopChan
+	}
+}
+
+func (b *Bus) Stop() {
+	b.StopConsumers()
+	b.producer.Stop()
+}
+
+// CreateTopics pre-creates topics through the nsqd HTTP API so messages
+// published before the first consumer connects aren't lost.
+func (b *Bus) CreateTopics(topics ...string) {
+	host, _, _ := net.SplitHostPort(b.nsqdHost)
+	if host == "" {
+		host = "nsqd"
+	}
+
+	create := func(topic string) {
+		url := fmt.Sprintf("http://%s:4151/topic/create?topic=%s", host, topic)
+		http.Post(url, "application/json", nil)
+	}
+
+	go func() {
+		time.Sleep(2 * time.Second)
+		for _, t := range topics {
+			create(t)
+		}
+	}()
+}
+
+// handler adapts a bus.Handler to NSQ. Errors carrying a requeue delay are
+// requeued with it instead of NSQ's default.
+func handler(topic string, h bus.Handler) nsq.Handler {
+	return nsq.HandlerFunc(func(m *nsq.Message) error {
+		msg := &bus.Message{ID: string(m.ID[:]), Topic: topic, Body: m.Body, Attempts: m.Attempts}
+		err := h.HandleMessage(msg)
+		if err != nil && !m.IsAutoResponseDisabled() {
+			if delay := bus.RequeueDelay(err); delay >= 0 {
+				m.DisableAutoResponse()
+				m.Requeue(delay)
+			}
+		}
+		return err
+	})
+}
+
+type subscription struct {
+	consumer *nsq.Consumer
+	max      int
+}
+
+func (s *subscription) ChangeMaxInFlight(n int) {
+	s.consumer.ChangeMaxInFlight(min(max(n, 1), s.max))
+}
+
+func (s *subscription) Stop() {
+	s.consumer.Stop()
+	<-s.consumer.StopChan
+}
@@ -0,0 +1,65 @@
+package nsqbus
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nsqio/go-nsq"
+	"github.com/stretchr/testify/assert"
+	"qurio/apps/backend/internal/bus"
+)
+
+type requeueRecorder struct {
+	requeued bool
+	delay    time.Duration
+}
+
+func (r *requeueRecorder) OnFinish(m *nsq.Message) {}
+func (r *requeueRecorder) OnRequeue(m *nsq.Message, delay time.Duration, backoff bool) {
+	r.requeued = true
+	r.delay = delay
+}
+func (r *requeueRecorder) OnTouch(m *nsq.Message) {}
+
+func TestHandler(t *testing.T) {
+	t.Run("ConvertsMessage", func(t *testing.T) {
+		var got *bus.Message
+		h := handler("ingest.result", bus.HandlerFunc(func(m *bus.Message) error {
+			got = m
+			return nil
+		}))
+		msg := &nsq.Message{ID: nsq.MessageID{'a', 'b'}, Body: []byte("body"), Attempts: 2, Delegate: &requeueRecorder{}}
+
+		assert.NoError(t, h.HandleMessage(msg))
+		assert.Equal(t, "ingest.result", got.Topic)
+		assert.Equal(t, []byte("body"), got.Body)
+		assert.Equal(t, uint16(2), got.Attempts)
+		assert.False(t, msg.IsAutoResponseDisabled())
+	})
+
+	t.Run("RequeuesWithRequestedDelay", func(t *testing.T) {
+		rec := &requeueRecorder{}
+		h := handler("t", bus.HandlerFunc(func(m *bus.Message) error {
+			return bus.Requeue(errors.New("boom"), 4*time.Second)
+		}))
+		msg := &nsq.Message{Attempts: 3, Delegate: rec}
+
+		assert.Error(t, h.HandleMessage(msg))
+		assert.True(t, rec.requeued)
+		assert.Equal(t, 4*time.Second, rec.delay)
+		assert.True(t, msg.IsAutoResponseDisabled())
+	})
+
+	t.Run("LeavesPlainErrorsToNSQ", func(t *testing.T) {
+		rec := &requeueRecorder{}
+		h := handler("t", bus.HandlerFunc(func(m *bus.Message) error {
+			return errors.New("boom")
+		}))
+		msg := &nsq.Message{Attempts: 1, Delegate: rec}
+
+		assert.Error(t, h.HandleMessage(msg))
+		assert.False(t, rec.requeued)
+		assert.False(t, msg.IsAutoResponseDisabled())
+	})
+}
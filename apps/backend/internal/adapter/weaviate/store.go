@@ -4,29 +4,93 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sort"
 	"qurio/apps/backend/internal/retrieval"
 	"qurio/apps/backend/internal/worker"
 	"qurio/apps/backend/internal/vector"
 	"github.com/weaviate/weaviate-go-client/v5/weaviate"
 	"github.com/weaviate/weaviate-go-client/v5/weaviate/filters"
 	"github.com/weaviate/weaviate-go-client/v5/weaviate/graphql"
+	"github.com/weaviate/weaviate/entities/models"
 )
 
 type Store struct {
-	client *weaviate.Client
+	client       *weaviate.Client
+	tokenization map[string]string
 }
 
 func NewStore(client *weaviate.Client) *Store {
 	return &Store{client: client}
 }
 
+// SetTokenization configures the per-property BM25 tokenization applied by EnsureSchema.
+func (s *Store) SetTokenization(tokenization map[string]string) {
+	s.tokenization = tokenization
+}
+
 func (s *Store) EnsureSchema(ctx context.Context) error {
 	wAdapter := vector.NewWeaviateClientAdapter(s.client)
-	return vector.EnsureSchema(ctx, wAdapter)
+	return vector.EnsureSchema(ctx, wAdapter, s.tokenization)
 }
 
 func (s *Store) StoreChunk(ctx context.Context, chunk worker.Chunk) error {
 	slog.DebugContext(ctx, "storing chunk", "source_id", chunk.SourceID, "chunk_index", chunk.ChunkIndex, "url", chunk.SourceURL)
+	_, err := s.client.Data().Creator().
+		WithClassName("DocumentChunk").
+		WithProperties(chunkProperties(chunk)).
+		WithVector(chunk.Vector).
+		Do(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to store chunk", "error", err, "source_id", chunk.SourceID, "chunk_index", chunk.ChunkIndex)
+	}
+	return err
+}
+
+// StoreVersionChunks writes chunks of a retained source version, labelled by
+// their Version, in one batch.
+func (s *Store) StoreVersionChunks(ctx context.Context, chunks []worker.Chunk) error {
+	batcher := s.client.Batch().ObjectsBatcher()
+	for _, chunk := range chunks {
+		batcher = batcher.WithObjects(&models.Object{
+			Class:      vector.VersionClass,
+			Properties: chunkProperties(chunk),
+			Vector:     chunk.Vector,
+		})
+	}
+	res, err := batcher.Do(ctx)
+	if err != nil {
+		return err
+	}
+	for _, r := range res {
+		if r.Result != nil && r.Result.Errors != nil && len(r.Result.Errors.Error) > 0 {
+			return fmt.Errorf("store version chunk: %s", r.Result.Errors.Error[0].Message)
+		}
+	}
+	return nil
+}
+
+// DeleteVersionChunks removes the chunks of a retained source version.
+func (s *Store) DeleteVersionChunks(ctx context.Context, sourceID, version string) error {
+	_, err := s.client.Batch().ObjectsBatchDeleter().
+		WithClassName(vector.VersionClass).
+		WithOutput("minimal").
+		WithWhere(filters.Where().
+			WithOperator(filters.And).
+			WithOperands([]*filters.WhereBuilder{
+				filters.Where().
+					WithPath([]string{"sourceId"}).
+					WithOperator(filters.Equal).
+					WithValueString(sourceID),
+				filters.Where().
+					WithPath([]string{"version"}).
+					WithOperator(filters.Equal).
+					WithValueString(version),
+			})).
+		Do(ctx)
+	return err
+}
+
+func chunkProperties(chunk worker.Chunk) map[string]interface{} {
 	properties := map[string]interface{}{
 		"content":    chunk.Content,
 		"url":        chunk.SourceURL,
@@ -40,6 +104,12 @@ func (s *Store) StoreChunk(ctx context.Context, chunk worker.Chunk) error {
 	if chunk.Language != "" {
 		properties["language"] = chunk.Language
 	}
+	if chunk.Symbol != "" {
+		properties["symbol"] = chunk.Symbol
+	}
+	if chunk.Section != "" {
+		properties["section"] = chunk.Section
+	}
 	if chunk.Title != "" {
 		properties["title"] = chunk.Title
 	}
@@ -55,16 +125,10 @@ func (s *Store) StoreChunk(ctx context.Context, chunk worker.Chunk) error {
 	if chunk.PageCount > 0 {
 		properties["pageCount"] = chunk.PageCount
 	}
-
-	_, err := s.client.Data().Creator().
-		WithClassName("DocumentChunk").
-		WithProperties(properties).
-		WithVector(chunk.Vector).
-		Do(ctx)
-	if err != nil {
-		slog.ErrorContext(ctx, "failed to store chunk", "error", err, "source_id", chunk.SourceID, "chunk_index", chunk.ChunkIndex)
+	if chunk.Version != "" {
+		properties["version"] = chunk.Version
 	}
-	return err
+	return properties
 }
 
 func (s *Store) DeleteChunksByURL(ctx context.Context, sourceID, url string) error {
@@ -87,25 +151,115 @@ func (s *Store) DeleteChunksByURL(ctx context.Context, sourceID, url string) err
 	return err
 }
 
+// DeleteChunksBySourceID removes a source's chunks, retained versions included.
 func (s *Store) DeleteChunksBySourceID(ctx context.Context, sourceID string) error {
-	_, err := s.client.Batch().ObjectsBatchDeleter().
-		WithClassName("DocumentChunk").
-		WithOutput("minimal").
-		WithWhere(filters.Where().
-			WithPath([]string{"sourceId"}).
+	for _, className := range []string{vector.ChunkClass, vector.VersionClass} {
+		_, err := s.client.Batch().ObjectsBatchDeleter().
+			WithClassName(className).
+			WithOutput("minimal").
+			WithWhere(filters.Where().
+				WithPath([]string{"sourceId"}).
+				WithOperator(filters.Equal).
+				WithValueString(sourceID)).
+			Do(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Search runs a hybrid search over the live chunks. A "version" filter also
+// searches retained versions; each query normalizes its own scores, so the
+// two are merged by rank, see fuseRanked.
+func (s *Store) Search(ctx context.Context, query string, vec []float32, alpha float32, limit int, properties []string, searchFilters map[string]interface{}) ([]retrieval.SearchResult, error) {
+	slog.DebugContext(ctx, "searching vector store", "query", query, "alpha", alpha, "limit", limit, "properties", properties)
+	results, err := s.search(ctx, vector.ChunkClass, query, vec, alpha, limit, properties, searchFilters)
+	if _, ok := searchFilters["version"]; !ok || err != nil {
+		return results, err
+	}
+	retained, err := s.search(ctx, vector.VersionClass, query, vec, alpha, limit, properties, searchFilters)
+	if err != nil {
+		return nil, err
+	}
+	return fuseRanked(limit, results, retained), nil
+}
+
+// rrfK damps how much the top ranks outweigh the rest in fuseRanked; 60 is
+// the usual choice for reciprocal rank fusion.
+const rrfK = 60
+
+// fuseRanked merges result lists ranked by separate queries into one ranking
+// by reciprocal rank fusion: a result scores 1/(rrfK+rank) in each list it
+// is in, and the fused score replaces its own. A chunk found in both lists,
+// e.g. the live copy of a version still being snapshotted, counts once.
+func fuseRanked(limit int, lists ...[]retrieval.SearchResult) []retrieval.SearchResult {
+	var fused []retrieval.SearchResult
+	index := make(map[string]int)
+	for _, list := range lists {
+		for rank, r := range list {
+			score := 1 / float32(rrfK+rank+1)
+			key := fmt.Sprintf("%s\x00%s\x00%v\x00%s", r.SourceID, r.URL, r.Metadata["chunkIndex"], r.Version)
+			if i, ok := index[key]; ok {
+				fused[i].Score += score
+				continue
+			}
+			r.Score = score
+			index[key] = len(fused)
+			fused = append(fused, r)
+		}
+	}
+	sort.SliceStable(fused, func(i, j int) bool { return fused[i].Score > fused[j].Score })
+	if len(fused) > limit {
+		fused = fused[:limit]
+	}
+	return fused
+}
+
+// filterValues reads a filter given as a list, e.g. the sources of a
+// collection; values that aren't strings are ignored.
+func filterValues(v interface{}) []string {
+	switch list := v.(type) {
+	case []string:
+		return list
+	case []interface{}:
+		var values []string
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// anyOf matches objects whose property equals one of values.
+func anyOf(property string, values []string) *filters.WhereBuilder {
+	operands := make([]*filters.WhereBuilder, len(values))
+	for i, v := range values {
+		operands[i] = filters.Where().
+			WithPath([]string{property}).
 			WithOperator(filters.Equal).
-			WithValueString(sourceID)).
-		Do(ctx)
-	return err
+			WithValueString(v)
+	}
+	if len(operands) == 1 {
+		return operands[0]
+	}
+	return filters.Where().WithOperator(filters.Or).WithOperands(operands)
 }
 
-func (s *Store) Search(ctx context.Context, query string, vector []float32, alpha float32, limit int, searchFilters map[string]interface{}) ([]retrieval.SearchResult, error) {
-	slog.DebugContext(ctx, "searching vector store", "query", query, "alpha", alpha, "limit", limit)
+func (s *Store) search(ctx context.Context, className, query string, vector []float32, alpha float32, limit int, properties []string, searchFilters map[string]interface{}) ([]retrieval.SearchResult, error) {
 	hybrid := s.client.GraphQL().HybridArgumentBuilder().
 		WithQuery(query).
 		WithVector(vector).
 		WithAlpha(alpha)
 
+	// Keyword weighting, e.g. ["title^3", "content"]
+	if len(properties) > 0 {
+		hybrid = hybrid.WithProperties(properties)
+	}
+
 	fields := []graphql.Field{
 		{Name: "content"},
 		{Name: "url"},
@@ -113,16 +267,19 @@ func (s *Store) Search(ctx context.Context, query string, vector []float32, alph
 		{Name: "chunkIndex"},
 		{Name: "type"},
 		{Name: "language"},
+		{Name: "symbol"},
+		{Name: "section"},
 		{Name: "title"},
 		{Name: "sourceName"},
 		{Name: "author"},
 		{Name: "createdAt"},
 		{Name: "pageCount"},
+		{Name: "version"},
 		{Name: "_additional", Fields: []graphql.Field{{Name: "score"}}},
 	}
 
 	queryBuilder := s.client.GraphQL().Get().
-		WithClassName("DocumentChunk").
+		WithClassName(className).
 		WithHybrid(hybrid).
 		WithLimit(limit).
 		WithFields(fields...)
@@ -135,6 +292,8 @@ func (s *Store) Search(ctx context.Context, query string, vector []float32, alph
 					WithPath([]string{k}).
 					WithOperator(filters.Equal).
 					WithValueString(sVal))
+			} else if values := filterValues(v); len(values) > 0 {
+				operands = append(operands, anyOf(k, values))
 			}
 		}
 		
@@ -162,7 +321,7 @@ func (s *Store) Search(ctx context.Context, query string, vector []float32, alph
 
 	var results []retrieval.SearchResult
 	if data, ok := res.Data["Get"].(map[string]interface{}); ok {
-		if chunks, ok := data["DocumentChunk"].([]interface{}); ok {
+		if chunks, ok := data[className].([]interface{}); ok {
 			for _, c := range chunks {
 				if props, ok := c.(map[string]interface{}); ok {
 					result := retrieval.SearchResult{
@@ -191,6 +350,14 @@ func (s *Store) Search(ctx context.Context, query string, vector []float32, alph
 						result.Language = langVal
 						result.Metadata["language"] = langVal
 					}
+					if symbol, ok := props["symbol"].(string); ok {
+						result.Symbol = symbol
+						result.Metadata["symbol"] = symbol
+					}
+					if section, ok := props["section"].(string); ok {
+						result.Section = section
+						result.Metadata["section"] = section
+					}
 					if titleVal, ok := props["title"].(string); ok {
 						result.Title = titleVal
 						result.Metadata["title"] = titleVal
@@ -211,6 +378,10 @@ func (s *Store) Search(ctx context.Context, query string, vector []float32, alph
 						result.PageCount = int(pageCount)
 						result.Metadata["pageCount"] = int(pageCount)
 					}
+					if version, ok := props["version"].(string); ok {
+						result.Version = version
+						result.Metadata["version"] = version
+					}
 					
 					// Extract score
 					if additional, ok := props["_additional"].(map[string]interface{}); ok {
@@ -233,6 +404,20 @@ func (s *Store) Search(ctx context.Context, query string, vector []float32, alph
 }
 
 func (s *Store) GetChunks(ctx context.Context, sourceID string, limit, offset int) ([]worker.Chunk, error) {
+	return s.getChunks(ctx, sourceID, limit, offset, nil, false)
+}
+
+// GetChunksWithVectorsAfter is GetChunks including the stored vectors and
+// all metadata, used for snapshots and export. It pages in ID order starting
+// after the chunk with ID after, or at the first when it is empty. Weaviate
+// refuses offsets past 10,000; a cursor reaches every chunk. Its own after
+// cursor can't be combined with a where filter, hence the filter on id.
+func (s *Store) GetChunksWithVectorsAfter(ctx context.Context, sourceID, after string, limit int) ([]worker.Chunk, error) {
+	return s.getChunks(ctx, sourceID, limit, 0, &after, true)
+}
+
+// getChunks pages by offset, or by ID after the cursor when one is given.
+func (s *Store) getChunks(ctx context.Context, sourceID string, limit, offset int, after *string, withVector bool) ([]worker.Chunk, error) {
 	fields := []graphql.Field{
 		{Name: "content"},
 		{Name: "url"},
@@ -240,22 +425,45 @@ func (s *Store) GetChunks(ctx context.Context, sourceID string, limit, offset in
 		{Name: "chunkIndex"},
 		{Name: "type"},
 		{Name: "language"},
+		{Name: "symbol"},
+		{Name: "section"},
 		{Name: "title"},
 		{Name: "sourceName"},
 	}
+	if withVector {
+		fields = append(fields,
+			graphql.Field{Name: "author"},
+			graphql.Field{Name: "createdAt"},
+			graphql.Field{Name: "pageCount"},
+			graphql.Field{Name: "version"},
+			graphql.Field{Name: "_additional", Fields: []graphql.Field{{Name: "id"}, {Name: "vector"}}},
+		)
+	}
 
 	where := filters.Where().
 		WithOperator(filters.Equal).
 		WithPath([]string{"sourceId"}).
 		WithValueString(sourceID)
+	if after != nil && *after != "" {
+		where = filters.Where().
+			WithOperator(filters.And).
+			WithOperands([]*filters.WhereBuilder{
+				where,
+				filters.Where().WithOperator(filters.GreaterThan).WithPath([]string{"id"}).WithValueText(*after),
+			})
+	}
 
-	res, err := s.client.GraphQL().Get().
+	query := s.client.GraphQL().Get().
 		WithClassName("DocumentChunk").
 		WithWhere(where).
 		WithLimit(limit).
-		WithOffset(offset).
-		WithFields(fields...).
-		Do(ctx)
+		WithFields(fields...)
+	if after != nil {
+		query = query.WithSort(graphql.Sort{Path: []string{"_id"}, Order: graphql.Asc})
+	} else {
+		query = query.WithOffset(offset)
+	}
+	res, err := query.Do(ctx)
 	
 	if err != nil {
 		return nil, err
@@ -292,12 +500,43 @@ func (s *Store) GetChunks(ctx context.Context, sourceID string, limit, offset in
 					if l, ok := props["language"].(string); ok {
 						chunk.Language = l
 					}
+					if symbol, ok := props["symbol"].(string); ok {
+						chunk.Symbol = symbol
+					}
+					if section, ok := props["section"].(string); ok {
+						chunk.Section = section
+					}
 					if title, ok := props["title"].(string); ok {
 						chunk.Title = title
 					}
 					if sourceName, ok := props["sourceName"].(string); ok {
 						chunk.SourceName = sourceName
 					}
+					if author, ok := props["author"].(string); ok {
+						chunk.Author = author
+					}
+					if createdAt, ok := props["createdAt"].(string); ok {
+						chunk.CreatedAt = createdAt
+					}
+					if pc, ok := props["pageCount"].(float64); ok {
+						chunk.PageCount = int(pc)
+					}
+					if version, ok := props["version"].(string); ok {
+						chunk.Version = version
+					}
+					if additional, ok := props["_additional"].(map[string]interface{}); ok {
+						if id, ok := additional["id"].(string); ok {
+							chunk.ID = id
+						}
+						if rawVec, ok := additional["vector"].([]interface{}); ok {
+							chunk.Vector = make([]float32, 0, len(rawVec))
+							for _, v := range rawVec {
+								if f, ok := v.(float64); ok {
+									chunk.Vector = append(chunk.Vector, float32(f))
+								}
+							}
+						}
+					}
 					chunks = append(chunks, chunk)
 				}
 			}
@@ -314,6 +553,8 @@ func (s *Store) GetChunksByURL(ctx context.Context, url string) ([]retrieval.Sea
 		{Name: "chunkIndex"},
 		{Name: "type"},
 		{Name: "language"},
+		{Name: "symbol"},
+		{Name: "section"},
 		{Name: "title"},
 		{Name: "sourceName"},
 		{Name: "author"},
@@ -375,6 +616,14 @@ func (s *Store) GetChunksByURL(ctx context.Context, url string) ([]retrieval.Sea
 						result.Language = l
 						result.Metadata["language"] = l
 					}
+					if symbol, ok := props["symbol"].(string); ok {
+						result.Symbol = symbol
+						result.Metadata["symbol"] = symbol
+					}
+					if section, ok := props["section"].(string); ok {
+						result.Section = section
+						result.Metadata["section"] = section
+					}
 					if title, ok := props["title"].(string); ok {
 						result.Title = title
 						result.Metadata["title"] = title
@@ -474,3 +723,91 @@ func (s *Store) CountChunksBySource(ctx context.Context, sourceID string) (int,
 	}
 	return 0, nil
 }
+
+// maxGroups bounds the number of distinct values returned by a grouped
+// aggregate. Weaviate can't page through groups, so reaching it fails the
+// count with worker.ErrTooManyGroups rather than dropping the rest.
+const maxGroups = 10000
+
+// CountChunksPerSource returns the number of stored chunks for every distinct sourceId.
+func (s *Store) CountChunksPerSource(ctx context.Context) (map[string]int, error) {
+	return s.countChunksGroupedBy(ctx, vector.ChunkClass, "sourceId", nil)
+}
+
+// CountChunksPerURL returns the number of stored chunks for every distinct url of a source.
+func (s *Store) CountChunksPerURL(ctx context.Context, sourceID string) (map[string]int, error) {
+	return s.countChunksGroupedBy(ctx, vector.ChunkClass, "url", sourceFilter(sourceID))
+}
+
+// CountVersionChunksPerSource is CountChunksPerSource for the chunks of
+// retained versions.
+func (s *Store) CountVersionChunksPerSource(ctx context.Context) (map[string]int, error) {
+	return s.countChunksGroupedBy(ctx, vector.VersionClass, "sourceId", nil)
+}
+
+// CountVersionChunksPerVersion returns the number of retained chunks for
+// every version of a source.
+func (s *Store) CountVersionChunksPerVersion(ctx context.Context, sourceID string) (map[string]int, error) {
+	return s.countChunksGroupedBy(ctx, vector.VersionClass, "version", sourceFilter(sourceID))
+}
+
+func sourceFilter(sourceID string) *filters.WhereBuilder {
+	return filters.Where().
+		WithOperator(filters.Equal).
+		WithPath([]string{"sourceId"}).
+		WithValueString(sourceID)
+}
+
+func (s *Store) countChunksGroupedBy(ctx context.Context, className, property string, where *filters.WhereBuilder) (map[string]int, error) {
+	builder := s.client.GraphQL().Aggregate().
+		WithClassName(className).
+		WithGroupBy(property).
+		WithLimit(maxGroups).
+		WithFields(
+			graphql.Field{Name: "groupedBy", Fields: []graphql.Field{{Name: "value"}}},
+			graphql.Field{Name: "meta", Fields: []graphql.Field{{Name: "count"}}},
+		)
+	if where != nil {
+		builder = builder.WithWhere(where)
+	}
+
+	res, err := builder.Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Errors) > 0 {
+		msg := ""
+		for _, e := range res.Errors {
+			msg += fmt.Sprintf("%s; ", e.Message)
+		}
+		return nil, fmt.Errorf("graphql error: %s", msg)
+	}
+
+	counts := make(map[string]int)
+	if data, ok := res.Data["Aggregate"].(map[string]interface{}); ok {
+		if groups, ok := data[className].([]interface{}); ok {
+			if len(groups) >= maxGroups {
+				return nil, fmt.Errorf("%w: %s by %s", worker.ErrTooManyGroups, className, property)
+			}
+			for _, g := range groups {
+				props, ok := g.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				grouped, _ := props["groupedBy"].(map[string]interface{})
+				value, ok := grouped["value"].(string)
+				if !ok {
+					continue
+				}
+				count := 0
+				if metaStats, ok := props["meta"].(map[string]interface{}); ok {
+					if c, ok := metaStats["count"].(float64); ok {
+						count = int(c)
+					}
+				}
+				counts[value] = count
+			}
+		}
+	}
+	return counts, nil
+}
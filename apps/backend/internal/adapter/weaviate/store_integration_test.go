@@ -2,6 +2,7 @@ package weaviate_test
 
 import (
 	"context"
+	"slices"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -45,7 +46,7 @@ func TestWeaviateStore_Integration(t *testing.T) {
 	require.NoError(t, err)
 
 	// Verify existence via Search
-	res, err := store.Search(ctx, "Postgres", nil, 0.0, 10, nil)
+	res, err := store.Search(ctx, "Postgres", nil, 0.0, 10, nil, nil)
 	require.NoError(t, err)
 	assert.NotEmpty(t, res)
 	assert.Equal(t, "Postgres is a database", res[0].Content)
@@ -55,7 +56,7 @@ func TestWeaviateStore_Integration(t *testing.T) {
 	require.NoError(t, err)
 
 	// Verify deletion
-	res, err = store.Search(ctx, "Postgres", nil, 0.0, 10, nil)
+	res, err = store.Search(ctx, "Postgres", nil, 0.0, 10, nil, nil)
 	require.NoError(t, err)
 	assert.Empty(t, res)
 
@@ -68,7 +69,7 @@ func TestWeaviateStore_Integration(t *testing.T) {
 	require.NoError(t, err)
 
 	// Search for "Postgres" with keyword preference (alpha 0.0)
-	res, err = store.Search(ctx, "Postgres", []float32{0.1, 0.1, 0.1}, 0.0, 10, nil)
+	res, err = store.Search(ctx, "Postgres", []float32{0.1, 0.1, 0.1}, 0.0, 10, nil, nil)
 	require.NoError(t, err)
 	require.NotEmpty(t, res)
 	assert.Equal(t, "Postgres", res[0].Content)
@@ -76,7 +77,7 @@ func TestWeaviateStore_Integration(t *testing.T) {
 
 	// Search with filter (Type=pdf)
 	filters := map[string]interface{}{"type": "pdf"}
-	res, err = store.Search(ctx, "Databases", []float32{0.2, 0.2, 0.2}, 0.5, 10, filters)
+	res, err = store.Search(ctx, "Databases", []float32{0.2, 0.2, 0.2}, 0.5, 10, nil, filters)
 	require.NoError(t, err)
 	require.NotEmpty(t, res)
 	assert.Equal(t, "Databases", res[0].Content)
@@ -161,3 +162,52 @@ func TestWeaviateStore_GetChunks(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, 15, count)
 }
+
+func TestWeaviateStore_GetChunksWithVectorsAfter(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	s := testutils.NewIntegrationSuite(t)
+	s.Setup()
+	defer s.Teardown()
+
+	store := weaviate.NewStore(s.Weaviate)
+	ctx := context.Background()
+	require.NoError(t, store.EnsureSchema(ctx))
+
+	sourceID := "src-export"
+	for i := 0; i < 7; i++ {
+		require.NoError(t, store.StoreChunk(ctx, worker.Chunk{
+			SourceID:   sourceID,
+			SourceURL:  "http://example.com/page",
+			Content:    "Content",
+			ChunkIndex: i,
+			Type:       "web",
+			Vector:     []float32{0.1, 0.2},
+		}))
+	}
+	// Another source's chunks must not leak into the pages
+	require.NoError(t, store.StoreChunk(ctx, worker.Chunk{SourceID: "src-other", SourceURL: "http://example.com/other", Content: "Other", Vector: []float32{0.1, 0.2}}))
+
+	// Page through three at a time, each page starting after the last ID of
+	// the one before, as Export does
+	var ids []string
+	after := ""
+	for page := 0; page < 5; page++ {
+		batch, err := store.GetChunksWithVectorsAfter(ctx, sourceID, after, 3)
+		require.NoError(t, err)
+		for _, c := range batch {
+			assert.Equal(t, sourceID, c.SourceID)
+			assert.Len(t, c.Vector, 2)
+			ids = append(ids, c.ID)
+		}
+		if len(batch) < 3 {
+			break
+		}
+		after = batch[len(batch)-1].ID
+	}
+
+	assert.Len(t, ids, 7)
+	assert.True(t, slices.IsSorted(ids), "chunks are paged in ID order")
+	assert.Len(t, slices.Compact(slices.Clone(ids)), 7, "no chunk is returned twice")
+}
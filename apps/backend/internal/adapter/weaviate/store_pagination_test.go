@@ -2,10 +2,14 @@ package weaviate
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"qurio/apps/backend/internal/worker"
 )
 
 func TestStore_CountChunksBySource(t *testing.T) {
@@ -42,3 +46,75 @@ func TestStore_GetChunks_Pagination(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, chunks)
 }
+
+func TestStore_CountChunksPerURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/meta" {
+			json.NewEncoder(w).Encode(map[string]interface{}{"version": "1.19.0"})
+			return
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		query := body["query"].(string)
+		assert.Contains(t, query, "Aggregate")
+		assert.Contains(t, query, `groupBy: "url"`)
+		assert.Contains(t, query, "sourceId")
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"Aggregate": map[string]interface{}{
+					"DocumentChunk": []interface{}{
+						map[string]interface{}{
+							"groupedBy": map[string]interface{}{"value": "http://example.com/a"},
+							"meta":      map[string]interface{}{"count": 3},
+						},
+						map[string]interface{}{
+							"groupedBy": map[string]interface{}{"value": "http://example.com/b"},
+							"meta":      map[string]interface{}{"count": 1},
+						},
+					},
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	store := newTestStore(t, server)
+
+	counts, err := store.CountChunksPerURL(context.Background(), "src-1")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"http://example.com/a": 3, "http://example.com/b": 1}, counts)
+}
+
+func TestStore_CountVersionChunksPerVersion_TooManyGroups(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/meta" {
+			json.NewEncoder(w).Encode(map[string]interface{}{"version": "1.19.0"})
+			return
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		query := body["query"].(string)
+		assert.Contains(t, query, "DocumentChunkVersion")
+		assert.Contains(t, query, `groupBy: "version"`)
+
+		groups := make([]interface{}, maxGroups)
+		for i := range groups {
+			groups[i] = map[string]interface{}{
+				"groupedBy": map[string]interface{}{"value": fmt.Sprintf("v%d", i)},
+				"meta":      map[string]interface{}{"count": 1},
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"Aggregate": map[string]interface{}{"DocumentChunkVersion": groups},
+			},
+		})
+	}))
+	defer server.Close()
+
+	store := newTestStore(t, server)
+
+	_, err := store.CountVersionChunksPerVersion(context.Background(), "src-1")
+	assert.ErrorIs(t, err, worker.ErrTooManyGroups)
+}
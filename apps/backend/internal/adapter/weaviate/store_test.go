@@ -9,6 +9,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/weaviate/weaviate-go-client/v5/weaviate"
+	"qurio/apps/backend/internal/retrieval"
 	"qurio/apps/backend/internal/worker"
 )
 
@@ -121,18 +122,34 @@ func TestStore_Search(t *testing.T) {
 
 	store := newTestStore(t, server)
 
-	results, err := store.Search(context.Background(), "test", nil, 0.5, 10, nil)
+	results, err := store.Search(context.Background(), "test", nil, 0.5, 10, nil, nil)
 	assert.NoError(t, err)
 	assert.Len(t, results, 1)
 	assert.Equal(t, "hello world", results[0].Content)
 }
 
+func TestStore_Search_WeightedProperties(t *testing.T) {
+	server := newMockWeaviateServer(t, func(r *http.Request, body map[string]interface{}) {
+		assert.Equal(t, "/v1/graphql", r.URL.Path)
+		query := body["query"].(string)
+		assert.Contains(t, query, "hybrid")
+		assert.Contains(t, query, `properties: ["title^3","content"]`)
+	})
+	defer server.Close()
+
+	store := newTestStore(t, server)
+
+	_, err := store.Search(context.Background(), "handle_web_task", nil, 0.3, 10, []string{"title^3", "content"}, nil)
+	assert.NoError(t, err)
+}
+
 func TestStore_DeleteChunksBySourceID(t *testing.T) {
+	var classes []interface{}
 	server := newMockWeaviateServer(t, func(r *http.Request, body map[string]interface{}) {
 		assert.Equal(t, "/v1/batch/objects", r.URL.Path)
 		assert.Equal(t, "DELETE", r.Method)
 		match := body["match"].(map[string]interface{})
-		assert.Equal(t, "DocumentChunk", match["class"])
+		classes = append(classes, match["class"])
 		where := match["where"].(map[string]interface{})
 		assert.Equal(t, "sourceId", where["path"].([]interface{})[0])
 	})
@@ -142,6 +159,114 @@ func TestStore_DeleteChunksBySourceID(t *testing.T) {
 
 	err := store.DeleteChunksBySourceID(context.Background(), "src-1")
 	assert.NoError(t, err)
+	// Retained versions go with the source
+	assert.Equal(t, []interface{}{"DocumentChunk", "DocumentChunkVersion"}, classes)
+}
+
+func TestStore_StoreVersionChunks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/meta" {
+			json.NewEncoder(w).Encode(map[string]interface{}{"version": "1.19.0"})
+			return
+		}
+		assert.Equal(t, "/v1/batch/objects", r.URL.Path)
+		assert.Equal(t, "POST", r.Method)
+		var body struct {
+			Objects []map[string]interface{} `json:"objects"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		assert.Len(t, body.Objects, 2)
+		for _, obj := range body.Objects {
+			assert.Equal(t, "DocumentChunkVersion", obj["class"])
+			props := obj["properties"].(map[string]interface{})
+			assert.Equal(t, "v1.x", props["version"])
+			assert.Equal(t, "src-1", props["sourceId"])
+		}
+		json.NewEncoder(w).Encode([]interface{}{})
+	}))
+	defer server.Close()
+
+	store := newTestStore(t, server)
+
+	err := store.StoreVersionChunks(context.Background(), []worker.Chunk{
+		{Content: "a", SourceID: "src-1", SourceURL: "http://example.com/a", Version: "v1.x", Vector: []float32{0.1}},
+		{Content: "b", SourceID: "src-1", SourceURL: "http://example.com/b", Version: "v1.x", Vector: []float32{0.2}},
+	})
+	assert.NoError(t, err)
+}
+
+func TestStore_DeleteVersionChunks(t *testing.T) {
+	server := newMockWeaviateServer(t, func(r *http.Request, body map[string]interface{}) {
+		assert.Equal(t, "/v1/batch/objects", r.URL.Path)
+		assert.Equal(t, "DELETE", r.Method)
+		match := body["match"].(map[string]interface{})
+		assert.Equal(t, "DocumentChunkVersion", match["class"])
+		where := match["where"].(map[string]interface{})
+		assert.Len(t, where["operands"], 2)
+	})
+	defer server.Close()
+
+	store := newTestStore(t, server)
+
+	err := store.DeleteVersionChunks(context.Background(), "src-1", "v1.x")
+	assert.NoError(t, err)
+}
+
+func TestStore_Search_VersionFilter(t *testing.T) {
+	var queries []string
+	server := newMockWeaviateServer(t, func(r *http.Request, body map[string]interface{}) {
+		query := body["query"].(string)
+		queries = append(queries, query)
+		assert.Contains(t, query, "version")
+		assert.Contains(t, query, "v1.x")
+	})
+	defer server.Close()
+
+	store := newTestStore(t, server)
+
+	results, err := store.Search(context.Background(), "hooks", nil, 0.5, 10, nil, map[string]interface{}{"version": "v1.x"})
+	assert.NoError(t, err)
+	// Live chunks and retained versions are both searched
+	assert.Len(t, queries, 2)
+	assert.Contains(t, queries[0], "{DocumentChunk (")
+	assert.Contains(t, queries[1], "{DocumentChunkVersion (")
+	assert.Len(t, results, 1) // the mock only answers for DocumentChunk
+}
+
+func TestFuseRanked(t *testing.T) {
+	result := func(url string, score float32) retrieval.SearchResult {
+		return retrieval.SearchResult{URL: url, SourceID: "src-1", Version: "v1.x", Score: score, Metadata: map[string]interface{}{"chunkIndex": 0}}
+	}
+	// Scores of separate queries aren't comparable: every list tops at 1
+	live := []retrieval.SearchResult{result("a", 1), result("b", 0.9), result("c", 0.1)}
+	retained := []retrieval.SearchResult{result("d", 1), result("b", 0.2), result("e", 0.1)}
+
+	fused := fuseRanked(4, live, retained)
+	var urls []string
+	for _, r := range fused {
+		urls = append(urls, r.URL)
+	}
+	// b is ranked by both queries, a and d top one each
+	assert.Equal(t, []string{"b", "a", "d", "c"}, urls)
+	assert.InDelta(t, 1.0/62+1.0/62, fused[0].Score, 1e-6)
+	assert.InDelta(t, 1.0/61, fused[1].Score, 1e-6)
+}
+
+func TestStore_Search_SourceList(t *testing.T) {
+	var query string
+	server := newMockWeaviateServer(t, func(r *http.Request, body map[string]interface{}) {
+		query = body["query"].(string)
+	})
+	defer server.Close()
+
+	store := newTestStore(t, server)
+
+	_, err := store.Search(context.Background(), "retries", nil, 0.5, 10, nil, map[string]interface{}{"sourceId": []string{"src1", "src2"}})
+	assert.NoError(t, err)
+	// A list matches any of its values
+	assert.Contains(t, query, "operator: Or")
+	assert.Contains(t, query, "src1")
+	assert.Contains(t, query, "src2")
 }
 
 func TestStore_Search_NetworkError(t *testing.T) {
@@ -160,7 +285,7 @@ func TestStore_Search_NetworkError(t *testing.T) {
 	store := NewStore(client)
 
 	// 3. Call Search
-	_, err := store.Search(context.Background(), "test", []float32{0.1}, 0.5, 10, nil)
+	_, err := store.Search(context.Background(), "test", []float32{0.1}, 0.5, 10, nil, nil)
 	
 	// 4. Expect Error
 	assert.Error(t, err)
@@ -188,7 +313,7 @@ func TestStore_Search_GraphQLError(t *testing.T) {
 	defer server.Close()
 
 	store := newTestStore(t, server)
-	_, err := store.Search(context.Background(), "test", nil, 0.5, 10, nil)
+	_, err := store.Search(context.Background(), "test", nil, 0.5, 10, nil, nil)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "syntax error")
 }
@@ -220,4 +345,92 @@ func TestStore_GetChunksByURL(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, results, 1)
 	assert.Equal(t, "hello world", results[0].Content)
-}
\ No newline at end of file
+}
+func TestStore_GetChunksWithVectors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/graphql" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		assert.Contains(t, body["query"].(string), "_additional{id vector}")
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"Get": map[string]interface{}{
+					"DocumentChunk": []interface{}{
+						map[string]interface{}{
+							"content":   "hello world",
+							"sourceId":  "src-1",
+							"pageCount": 3,
+							"_additional": map[string]interface{}{
+								"vector": []float64{0.5, -0.25},
+							},
+						},
+					},
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	store := newTestStore(t, server)
+
+	chunks, err := store.GetChunksWithVectorsAfter(context.Background(), "src-1", "", 100)
+	assert.NoError(t, err)
+	assert.Len(t, chunks, 1)
+	assert.Equal(t, []float32{0.5, -0.25}, chunks[0].Vector)
+	assert.Equal(t, 3, chunks[0].PageCount)
+}
+
+func TestStore_GetChunksWithVectorsAfter(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/graphql" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		queries = append(queries, body["query"].(string))
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"Get": map[string]interface{}{
+					"DocumentChunk": []interface{}{
+						map[string]interface{}{
+							"content":  "hello world",
+							"sourceId": "src-1",
+							"_additional": map[string]interface{}{
+								"id":     "00000000-0000-0000-0000-000000000002",
+								"vector": []float64{0.5, -0.25},
+							},
+						},
+					},
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	store := newTestStore(t, server)
+
+	chunks, err := store.GetChunksWithVectorsAfter(context.Background(), "src-1", "", 100)
+	assert.NoError(t, err)
+	assert.Len(t, chunks, 1)
+	assert.Equal(t, "00000000-0000-0000-0000-000000000002", chunks[0].ID)
+	assert.Equal(t, []float32{0.5, -0.25}, chunks[0].Vector)
+
+	_, err = store.GetChunksWithVectorsAfter(context.Background(), "src-1", chunks[0].ID, 100)
+	assert.NoError(t, err)
+
+	assert.Len(t, queries, 2)
+	// Pages are ordered by ID and never use an offset
+	for _, q := range queries {
+		assert.Contains(t, q, `sort:[{path:["_id"] order:asc}]`)
+		assert.NotContains(t, q, "offset")
+	}
+	assert.NotContains(t, queries[0], "GreaterThan")
+	assert.Contains(t, queries[1], `{operator: GreaterThan path: ["id"] valueText: "00000000-0000-0000-0000-000000000002"}`)
+}
@@ -6,17 +6,25 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"path/filepath"
+	"time"
 
+	"qurio/apps/backend/features/collection"
 	"qurio/apps/backend/features/job"
 	"qurio/apps/backend/features/mcp"
+	"qurio/apps/backend/features/reconcile"
 	"qurio/apps/backend/features/source"
 	"qurio/apps/backend/features/stats"
 	"qurio/apps/backend/internal/adapter/gemini"
+	"qurio/apps/backend/internal/adapter/git"
 	"qurio/apps/backend/internal/adapter/reranker"
 	"qurio/apps/backend/internal/config"
+	"qurio/apps/backend/internal/lifecycle"
 	"qurio/apps/backend/internal/middleware"
 	"qurio/apps/backend/internal/retrieval"
+	"qurio/apps/backend/internal/scheduler"
 	"qurio/apps/backend/internal/settings"
+	"qurio/apps/backend/internal/text"
 	"qurio/apps/backend/internal/worker"
 )
 
@@ -24,8 +32,14 @@ type App struct {
 	Handler          http.Handler
 	SourceService    *source.Service
 	SourceRepo       source.Repository
+	Reconciler       *reconcile.Service
+	Dispatcher       *worker.Dispatcher
 	ResultConsumer   *worker.ResultConsumer
 	EmbedderConsumer *worker.EmbedderConsumer
+	EmbedLimiter     *worker.RateLimitedEmbedder
+	Lifecycle        *lifecycle.Manager
+
+	server *http.Server
 }
 
 type Options struct {
@@ -97,8 +111,23 @@ func New(
 	// Feature: Source
 	sourceRepo := source.NewPostgresRepo(sqlDB)
 	sourceService := source.NewService(sourceRepo, taskPub, vecStore, settingsService)
+	sourceService.SetSitemapDiscoverer(worker.NewSitemapDiscoverer(worker.NewHTTPSitemapFetcher(30 * time.Second)))
+	sourceService.SetScheduleValidator(scheduler.ValidateSchedule)
+	// Working copies share the upload volume so the ingestion worker can read them
+	uploadDir := os.Getenv("QURIO_UPLOAD_DIR")
+	if uploadDir == "" {
+		uploadDir = "./uploads"
+	}
+	sourceService.SetGitRepository(git.NewClient(filepath.Join(uploadDir, "git")))
+	sourceService.AllowLocalGitRemotes(cfg.GitAllowLocalRemotes)
+	sourceService.SetDirectoryRoots(cfg.DirectoryRoots)
 	sourceHandler := source.NewHandler(sourceService)
 
+	// Feature: Collection
+	collectionRepo := collection.NewPostgresRepo(sqlDB)
+	collectionService := collection.NewService(collectionRepo)
+	collectionHandler := collection.NewHandler(collectionService)
+
 	// Feature: Job
 	jobRepo := job.NewPostgresRepo(sqlDB)
 	jobService := job.NewService(jobRepo, taskPub, logger)
@@ -107,6 +136,10 @@ func New(
 	// Feature: Stats
 	statsHandler := stats.NewHandler(sourceRepo, jobRepo, vecStore)
 
+	// Feature: Reconcile
+	reconcileService := reconcile.NewService(sourceRepo, vecStore)
+	reconcileHandler := reconcile.NewHandler(reconcileService)
+
 	// Adapters: Dynamic or Injected
 	var geminiEmbedder retrieval.Embedder
 	if opts != nil && opts.Embedder != nil {
@@ -126,7 +159,7 @@ func New(
 	enableCORS := func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
+			w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, PATCH, DELETE")
 			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 
 			if r.Method == "OPTIONS" {
@@ -142,11 +175,32 @@ func New(
 
 	mux.Handle("POST /sources", middleware.CorrelationID(enableCORS(sourceHandler.Create)))
 	mux.Handle("POST /sources/upload", middleware.CorrelationID(enableCORS(sourceHandler.Upload)))
+	mux.Handle("POST /sources/import", middleware.CorrelationID(enableCORS(sourceHandler.Import)))
 	mux.Handle("GET /sources", middleware.CorrelationID(enableCORS(sourceHandler.List)))
 	mux.Handle("GET /sources/{id}", middleware.CorrelationID(enableCORS(sourceHandler.Get)))
+	mux.Handle("PATCH /sources/{id}", middleware.CorrelationID(enableCORS(sourceHandler.Update)))
 	mux.Handle("DELETE /sources/{id}", middleware.CorrelationID(enableCORS(sourceHandler.Delete)))
 	mux.Handle("POST /sources/{id}/resync", middleware.CorrelationID(enableCORS(sourceHandler.ReSync)))
 	mux.Handle("GET /sources/{id}/pages", middleware.CorrelationID(enableCORS(sourceHandler.GetPages)))
+	mux.Handle("GET /sources/{id}/progress", middleware.CorrelationID(enableCORS(sourceHandler.GetProgress)))
+	mux.Handle("GET /sources/{id}/syncs", middleware.CorrelationID(enableCORS(sourceHandler.GetSyncs)))
+	mux.Handle("GET /sources/{id}/versions", middleware.CorrelationID(enableCORS(sourceHandler.GetVersions)))
+	mux.Handle("POST /sources/{id}/versions", middleware.CorrelationID(enableCORS(sourceHandler.SetVersion)))
+	mux.Handle("GET /sources/{id}/versions/diff", middleware.CorrelationID(enableCORS(sourceHandler.DiffVersions)))
+	mux.Handle("GET /sources/{id}/progress/stream", middleware.CorrelationID(enableCORS(sourceHandler.StreamProgress)))
+	mux.Handle("GET /sources/{id}/export", middleware.CorrelationID(enableCORS(sourceHandler.Export)))
+	mux.Handle("PUT /sources/{id}/tags", middleware.CorrelationID(enableCORS(sourceHandler.SetTags)))
+
+	mux.Handle("GET /tags", middleware.CorrelationID(enableCORS(sourceHandler.ListTags)))
+	mux.Handle("DELETE /tags/{name}", middleware.CorrelationID(enableCORS(sourceHandler.DeleteTag)))
+
+	mux.Handle("POST /collections", middleware.CorrelationID(enableCORS(collectionHandler.Create)))
+	mux.Handle("GET /collections", middleware.CorrelationID(enableCORS(collectionHandler.List)))
+	mux.Handle("GET /collections/{id}", middleware.CorrelationID(enableCORS(collectionHandler.Get)))
+	mux.Handle("PATCH /collections/{id}", middleware.CorrelationID(enableCORS(collectionHandler.Update)))
+	mux.Handle("DELETE /collections/{id}", middleware.CorrelationID(enableCORS(collectionHandler.Delete)))
+	mux.Handle("POST /collections/{id}/sources", middleware.CorrelationID(enableCORS(collectionHandler.AddSources)))
+	mux.Handle("DELETE /collections/{id}/sources/{sourceId}", middleware.CorrelationID(enableCORS(collectionHandler.RemoveSource)))
 
 	mux.Handle("GET /settings", middleware.CorrelationID(enableCORS(settingsHandler.GetSettings)))
 	mux.Handle("PUT /settings", middleware.CorrelationID(enableCORS(settingsHandler.UpdateSettings)))
@@ -156,6 +210,9 @@ func New(
 
 	mux.Handle("GET /stats", middleware.CorrelationID(enableCORS(statsHandler.GetStats)))
 
+	mux.Handle("GET /admin/reconcile", middleware.CorrelationID(enableCORS(reconcileHandler.Report)))
+	mux.Handle("POST /admin/reconcile", middleware.CorrelationID(enableCORS(reconcileHandler.Repair)))
+
 	// Feature: Retrieval & MCP
 	queryLogger, err := retrieval.NewFileQueryLogger("data/logs/query.log")
 	if err != nil {
@@ -165,6 +222,7 @@ func New(
 
 	retrievalService := retrieval.NewService(geminiEmbedder, vecStore, rerankerClient, settingsService, queryLogger)
 	mcpHandler := mcp.NewHandler(retrievalService, sourceService)
+	mcpHandler.SetCollections(collectionService)
 
 	// Unified Endpoint (Streaming)
 	mux.Handle("/mcp", middleware.CorrelationID(enableCORS(mcpHandler.ServeHTTP)))
@@ -175,42 +233,83 @@ func New(
 		w.Write([]byte(`{"status":"ok"}`))
 	})
 
+	// Readiness turns 503 as soon as shutdown starts, while /health stays up
+	lc := lifecycle.New(time.Duration(cfg.ShutdownDrainDelaySeconds) * time.Second)
+	mux.HandleFunc("/ready", lc.ReadyHandler)
+
 	// Worker (Result Consumer) Setup
 	sfAdapter := &sourceFetcherAdapter{repo: sourceRepo, settings: settingsService}
 	pmAdapter := &pageManagerAdapter{repo: sourceRepo}
-
-	resultConsumer := worker.NewResultConsumer(vecStore, sourceRepo, jobRepo, sfAdapter, pmAdapter, taskPub)
+	statusAdapter := &sourceStatusAdapter{repo: sourceRepo, service: sourceService}
+
+	resultConsumer := worker.NewResultConsumer(vecStore, statusAdapter, jobRepo, sfAdapter, pmAdapter, taskPub)
+	resultConsumer.SetRetryPolicy(worker.NewRetryPolicy(cfg.ResultMaxAttempts, cfg.ResultRetryInitialDelayMS, cfg.ConsumerRetryMaxDelayMS))
+	dispatcher := worker.NewDispatcher(pmAdapter, pmAdapter, sfAdapter, statusAdapter, taskPub, cfg.CrawlHostPagesPerMinute)
+	var tok text.Tokenizer
+	if cfg.TokenizerVocabPath != "" {
+		bpe, err := text.LoadBPETokenizer(cfg.TokenizerVocabPath)
+		if err != nil {
+			slog.Warn("failed to load tokenizer vocabulary, using estimate", "error", err, "path", cfg.TokenizerVocabPath)
+		} else {
+			tok = bpe
+			resultConsumer.SetTokenizer(tok)
+		}
+	}
 
 	var embedderConsumer *worker.EmbedderConsumer
+	var embedLimiter *worker.RateLimitedEmbedder
 	if cfg.EnableEmbedderWorker {
-		embedderConsumer = worker.NewEmbedderConsumer(geminiEmbedder, vecStore)
+		// Only ingestion is paced; search queries aren't held back by bulk embedding.
+		embedLimiter = worker.NewRateLimitedEmbedder(geminiEmbedder, func(ctx context.Context) (worker.RateLimits, error) {
+			set, err := settingsService.Get(ctx)
+			if err != nil {
+				return worker.RateLimits{}, err
+			}
+			return worker.RateLimits{RequestsPerMinute: set.EmbedRequestsPerMinute, TokensPerMinute: set.EmbedTokensPerMinute}, nil
+		})
+		if tok != nil {
+			embedLimiter.SetTokenizer(tok)
+		}
+		embedderConsumer = worker.NewEmbedderConsumer(embedLimiter, vecStore, jobRepo)
+		embedderConsumer.SetRetryPolicy(worker.NewRetryPolicy(cfg.EmbedMaxAttempts, cfg.EmbedRetryInitialDelayMS, cfg.ConsumerRetryMaxDelayMS))
+		embedderConsumer.SetProgressTracking(pmAdapter, statusAdapter)
 	}
 
+	server := &http.Server{
+		Addr:    ":8081",
+		Handler: mux,
+	}
+	server.RegisterOnShutdown(sourceHandler.CloseStreams)
+
 	return &App{
 		Handler:          mux,
 		SourceService:    sourceService,
 		SourceRepo:       sourceRepo,
+		Reconciler:       reconcileService,
+		Dispatcher:       dispatcher,
 		ResultConsumer:   resultConsumer,
 		EmbedderConsumer: embedderConsumer,
+		EmbedLimiter:     embedLimiter,
+		Lifecycle:        lc,
+		server:           server,
 	}, nil
 }
 
-func (a *App) Run(ctx context.Context) error {
-	srv := &http.Server{
-		Addr:    ":8081",
-		Handler: a.Handler,
+// Run serves HTTP until Shutdown is called.
+func (a *App) Run() error {
+	slog.Info("server starting", "port", 8081)
+	if err := a.server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
 	}
+	return nil
+}
 
-	go func() {
-		<-ctx.Done()
-		slog.Info("shutting down server...")
-		if err := srv.Shutdown(context.Background()); err != nil {
-			slog.Error("server shutdown failed", "error", err)
-		}
-	}()
-
-	slog.Info("server starting", "port", 8081)
-	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+// Shutdown stops accepting connections and waits for active requests, closing
+// whatever is left when ctx expires.
+func (a *App) Shutdown(ctx context.Context) error {
+	slog.Info("shutting down server...")
+	if err := a.server.Shutdown(ctx); err != nil {
+		a.server.Close()
 		return err
 	}
 	return nil
@@ -245,6 +344,61 @@ func (a *sourceFetcherAdapter) GetSourceConfig(ctx context.Context, id string) (
 	return s.MaxDepth, s.Exclusions, apiKey, s.Name, nil
 }
 
+func (a *sourceFetcherAdapter) GetChunkProfile(ctx context.Context, id string) (text.ChunkProfile, error) {
+	s, err := a.repo.Get(ctx, id)
+	if err != nil {
+		return text.ChunkProfile{}, err
+	}
+	return s.ChunkProfile(), nil
+}
+
+func (a *sourceFetcherAdapter) GetCrawlScope(ctx context.Context, id string) (worker.CrawlScope, error) {
+	s, err := a.repo.Get(ctx, id)
+	if err != nil {
+		return worker.CrawlScope{}, err
+	}
+	return s.CrawlScope(), nil
+}
+
+func (a *sourceFetcherAdapter) GetCrawlBudget(ctx context.Context, id string) (worker.CrawlBudget, error) {
+	s, err := a.repo.Get(ctx, id)
+	if err != nil {
+		return worker.CrawlBudget{}, err
+	}
+	return s.CrawlBudget(), nil
+}
+
+func (a *sourceFetcherAdapter) GetSourceVersion(ctx context.Context, id string) (string, error) {
+	s, err := a.repo.Get(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	return s.Version, nil
+}
+
+func (a *sourceFetcherAdapter) GetSeeds(ctx context.Context, id string) ([]string, error) {
+	s, err := a.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.Seeds(), nil
+}
+
+// Adapter for SourceStatusUpdater: completing a sync needs the service to
+// clean up the chunks of removed pages.
+type sourceStatusAdapter struct {
+	repo    source.Repository
+	service *source.Service
+}
+
+func (a *sourceStatusAdapter) UpdateStatus(ctx context.Context, id, status string) error {
+	return a.repo.UpdateStatus(ctx, id, status)
+}
+
+func (a *sourceStatusAdapter) CompleteSync(ctx context.Context, id string) error {
+	return a.service.CompleteSync(ctx, id)
+}
+
 // Adapter for PageManager
 type pageManagerAdapter struct {
 	repo source.Repository
@@ -259,6 +413,9 @@ func (a *pageManagerAdapter) BulkCreatePages(ctx context.Context, pages []worker
 			URL:      p.URL,
 			Status:   p.Status,
 			Depth:    p.Depth,
+			LastMod:  p.LastMod,
+
+			CorrelationID: p.CorrelationID,
 		})
 	}
 	return a.repo.BulkCreatePages(ctx, srcPages)
@@ -271,3 +428,39 @@ func (a *pageManagerAdapter) UpdatePageStatus(ctx context.Context, sourceID, url
 func (a *pageManagerAdapter) CountPendingPages(ctx context.Context, sourceID string) (int, error) {
 	return a.repo.CountPendingPages(ctx, sourceID)
 }
+
+func (a *pageManagerAdapter) SetPageChunks(ctx context.Context, sourceID, url string, depth, expected int) error {
+	return a.repo.SetPageChunks(ctx, sourceID, url, depth, expected)
+}
+
+func (a *pageManagerAdapter) MarkChunkEmbedded(ctx context.Context, sourceID, url string, chunkIndex int) (bool, error) {
+	return a.repo.MarkChunkEmbedded(ctx, sourceID, url, chunkIndex)
+}
+
+func (a *pageManagerAdapter) RecordPageContent(ctx context.Context, sourceID, url, hash, chunkKey, etag, lastModified string) (string, error) {
+	return a.repo.RecordPageContent(ctx, sourceID, url, hash, chunkKey, etag, lastModified)
+}
+
+func (a *pageManagerAdapter) PendingPages(ctx context.Context, perSource int) ([]worker.PageDTO, error) {
+	pages, err := a.repo.PendingPages(ctx, perSource)
+	if err != nil {
+		return nil, err
+	}
+	var res []worker.PageDTO
+	for _, p := range pages {
+		res = append(res, worker.PageDTO{SourceID: p.SourceID, URL: p.URL, Status: p.Status, Depth: p.Depth, CorrelationID: p.CorrelationID})
+	}
+	return res, nil
+}
+
+func (a *pageManagerAdapter) ClaimPage(ctx context.Context, sourceID, url string) (bool, error) {
+	return a.repo.ClaimPage(ctx, sourceID, url)
+}
+
+func (a *pageManagerAdapter) CountReleasedPages(ctx context.Context, sourceID string) (int, error) {
+	return a.repo.CountReleasedPages(ctx, sourceID)
+}
+
+func (a *pageManagerAdapter) SkipPendingPages(ctx context.Context, sourceID, reason string) (int64, error) {
+	return a.repo.SkipPendingPages(ctx, sourceID, reason)
+}
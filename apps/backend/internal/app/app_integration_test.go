@@ -9,7 +9,7 @@ import (
 	"testing"
 	"time"
 
-	"github.com/nsqio/go-nsq"
+	"qurio/apps/backend/internal/bus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -99,9 +99,9 @@ func TestApp_EndToEnd_Ingestion(t *testing.T) {
 	}
 	resultBody, _ := json.Marshal(resultPayload)
 	
-	msg := &nsq.Message{
+	msg := &bus.Message{
 		Body: resultBody,
-		ID:   nsq.MessageID{'1'},
+		ID:   "1",
 	}
 	
 	// Execute Result Consumer Logic
@@ -119,9 +119,9 @@ func TestApp_EndToEnd_Ingestion(t *testing.T) {
 	assert.Contains(t, embedPayload.Content, "This is the content")
 
 	// 7. Simulate Embed Worker
-	embedNsqMsg := &nsq.Message{
+	embedNsqMsg := &bus.Message{
 		Body: embedMsg.Body,
-		ID:   nsq.MessageID{'2'},
+		ID:   "2",
 	}
 
 	err = application.EmbedderConsumer.HandleMessage(embedNsqMsg)
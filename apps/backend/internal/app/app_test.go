@@ -63,6 +63,32 @@ func TestNew_Success(t *testing.T) {
 	}
 }
 
+func TestNew_Readiness(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	application, err := New(&config.Config{}, db, &MockVectorStore{}, &MockTaskPublisher{}, slog.New(slog.NewJSONHandler(os.Stdout, nil)), nil)
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(application.Handler)
+	defer ts.Close()
+
+	get := func() int {
+		resp, err := http.Get(ts.URL + "/ready")
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Not ready until main has wired the consumers
+	assert.Equal(t, http.StatusServiceUnavailable, get())
+	application.Lifecycle.SetReady(true)
+	assert.Equal(t, http.StatusOK, get())
+	require.NoError(t, application.Lifecycle.Shutdown(context.Background()))
+	assert.Equal(t, http.StatusServiceUnavailable, get())
+}
+
 type FakeDB struct{}
 
 func (f *FakeDB) PingContext(ctx context.Context) error { return nil }
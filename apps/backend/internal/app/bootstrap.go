@@ -5,25 +5,25 @@ import (
 	"database/sql"
 	"fmt"
 	"log/slog"
-	"net"
-	"net/http"
 	"time"
 
+	"qurio/apps/backend/internal/adapter/nsqbus"
+	"qurio/apps/backend/internal/bus"
 	"qurio/apps/backend/internal/config"
+	"qurio/apps/backend/internal/settings"
 	wstore "qurio/apps/backend/internal/adapter/weaviate"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	_ "github.com/lib/pq"
-	"github.com/nsqio/go-nsq"
 	"github.com/weaviate/weaviate-go-client/v5/weaviate"
 )
 
 type Dependencies struct {
 	DB          *sql.DB
 	VectorStore VectorStore
-	NSQProducer *nsq.Producer
+	Bus         bus.Bus
 }
 
 func Bootstrap(ctx context.Context, cfg *config.Config) (*Dependencies, error) {
@@ -73,48 +73,46 @@ func Bootstrap(ctx context.Context, cfg *config.Config) (*Dependencies, error) {
 		return nil, fmt.Errorf("weaviate client error: %w", err)
 	}
 	vecStore := wstore.NewStore(wClient)
+
+	// BM25 tokenization is fixed when properties are created, so read it before ensuring the schema
+	if set, err := settings.NewPostgresRepo(db).Get(ctx); err == nil {
+		vecStore.SetTokenization(set.Tokenization)
+	} else {
+		slog.Warn("failed to load settings for schema tokenization, using defaults", "error", err)
+	}
 	
 	// Ensure Schema Retry
 	if err := EnsureSchemaWithRetry(ctx, vecStore, cfg.BootstrapRetryAttempts, retryDelay); err != nil {
 		return nil, fmt.Errorf("weaviate schema error: %w", err)
 	}
 
-	// NSQ Producer
-	nsqCfg := nsq.NewConfig()
-	// nsqCfg.MaxMsgSize = cfg.NSQMaxMsgSize // Field undefined in go-nsq v1.1.0
-	producer, err := nsq.NewProducer(cfg.NSQDHost, nsqCfg)
+	msgBus, err := NewBus(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("nsq producer error: %w", err)
+		return nil, err
 	}
-    
-    // Topic pre-creation (Logic from main.go)
-    createTopics(cfg.NSQDHost)
 
 	return &Dependencies{
 		DB:          db,
 		VectorStore: vecStore,
-		NSQProducer: producer,
+		Bus:         msgBus,
 	}, nil
 }
 
-func createTopics(nsqdHost string) {
-	host, _, _ := net.SplitHostPort(nsqdHost)
-	if host == "" {
-		host = "nsqd"
-	}
-
-	create := func(topic string) {
-		url := fmt.Sprintf("http://%s:4151/topic/create?topic=%s", host, topic)
-		http.Post(url, "application/json", nil)
+// NewBus creates the message bus selected by cfg.BusDriver.
+func NewBus(cfg *config.Config) (bus.Bus, error) {
+	switch cfg.BusDriver {
+	case "memory":
+		slog.Info("using in-process message bus")
+		return bus.NewMemory(), nil
+	case "nsq", "":
+		b, err := nsqbus.New(cfg.NSQDHost, cfg.NSQLookupd)
+		if err != nil {
+			return nil, err
+		}
+		b.CreateTopics(config.TopicIngestWeb, config.TopicIngestFile, config.TopicIngestResult, config.TopicIngestEmbed, config.TopicSourceSync)
+		return b, nil
 	}
-
-	go func() {
-		time.Sleep(2 * time.Second)
-		create(config.TopicIngestWeb)
-		create(config.TopicIngestFile)
-		create(config.TopicIngestResult)
-		create(config.TopicIngestEmbed)
-	}()
+	return nil, fmt.Errorf("unknown bus driver %q", cfg.BusDriver)
 }
 
 // EnsureSchemaWithRetry delegates schema check to a helper with retry logic.
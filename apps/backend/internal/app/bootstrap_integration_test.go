@@ -7,6 +7,7 @@ import (
 	"runtime"
 	"fmt"
 
+	"qurio/apps/backend/internal/adapter/nsqbus"
 	"qurio/apps/backend/internal/app"
 	"qurio/apps/backend/internal/testutils"
 	"github.com/stretchr/testify/assert"
@@ -47,6 +48,8 @@ func TestBootstrap_Integration(t *testing.T) {
 	assert.NoError(t, err, "Weaviate connectivity check failed")
 
 	// Verify NSQ
-	err = deps.NSQProducer.Ping()
+	nsqBus, ok := deps.Bus.(*nsqbus.Bus)
+	require.True(t, ok)
+	err = nsqBus.Ping()
 	assert.NoError(t, err)
 }
@@ -22,11 +22,18 @@ type VectorStore interface {
 	StoreChunk(ctx context.Context, chunk worker.Chunk) error
 	DeleteChunksByURL(ctx context.Context, sourceID, url string) error
 	DeleteChunksBySourceID(ctx context.Context, sourceID string) error
-	Search(ctx context.Context, query string, vector []float32, alpha float32, limit int, searchFilters map[string]interface{}) ([]retrieval.SearchResult, error)
+	StoreVersionChunks(ctx context.Context, chunks []worker.Chunk) error
+	DeleteVersionChunks(ctx context.Context, sourceID, version string) error
+	Search(ctx context.Context, query string, vector []float32, alpha float32, limit int, properties []string, searchFilters map[string]interface{}) ([]retrieval.SearchResult, error)
 	GetChunks(ctx context.Context, sourceID string, limit, offset int) ([]worker.Chunk, error)
+	GetChunksWithVectorsAfter(ctx context.Context, sourceID, after string, limit int) ([]worker.Chunk, error)
 	GetChunksByURL(ctx context.Context, url string) ([]retrieval.SearchResult, error)
 	CountChunks(ctx context.Context) (int, error)
 	CountChunksBySource(ctx context.Context, sourceID string) (int, error)
+	CountChunksPerSource(ctx context.Context) (map[string]int, error)
+	CountChunksPerURL(ctx context.Context, sourceID string) (map[string]int, error)
+	CountVersionChunksPerSource(ctx context.Context) (map[string]int, error)
+	CountVersionChunksPerVersion(ctx context.Context, sourceID string) (map[string]int, error)
 	EnsureSchema(ctx context.Context) error
 }
 
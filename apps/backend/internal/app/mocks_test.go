@@ -35,7 +35,15 @@ func (m *MockVectorStore) DeleteChunksBySourceID(ctx context.Context, sourceID s
 	return m.DeleteChunksErr
 }
 
-func (m *MockVectorStore) Search(ctx context.Context, query string, vector []float32, alpha float32, limit int, searchFilters map[string]interface{}) ([]retrieval.SearchResult, error) {
+func (m *MockVectorStore) StoreVersionChunks(ctx context.Context, chunks []worker.Chunk) error {
+	return nil
+}
+
+func (m *MockVectorStore) DeleteVersionChunks(ctx context.Context, sourceID, version string) error {
+	return nil
+}
+
+func (m *MockVectorStore) Search(ctx context.Context, query string, vector []float32, alpha float32, limit int, properties []string, searchFilters map[string]interface{}) ([]retrieval.SearchResult, error) {
 	return m.SearchRes, m.SearchErr
 }
 
@@ -43,10 +51,30 @@ func (m *MockVectorStore) GetChunks(ctx context.Context, sourceID string, limit,
 	return m.GetChunksRes, m.GetChunksErr
 }
 
+func (m *MockVectorStore) GetChunksWithVectorsAfter(ctx context.Context, sourceID, after string, limit int) ([]worker.Chunk, error) {
+	return m.GetChunksRes, m.GetChunksErr
+}
+
 func (m *MockVectorStore) CountChunksBySource(ctx context.Context, sourceID string) (int, error) {
 	return 0, nil
 }
 
+func (m *MockVectorStore) CountChunksPerSource(ctx context.Context) (map[string]int, error) {
+	return map[string]int{}, nil
+}
+
+func (m *MockVectorStore) CountChunksPerURL(ctx context.Context, sourceID string) (map[string]int, error) {
+	return map[string]int{}, nil
+}
+
+func (m *MockVectorStore) CountVersionChunksPerSource(ctx context.Context) (map[string]int, error) {
+	return map[string]int{}, nil
+}
+
+func (m *MockVectorStore) CountVersionChunksPerVersion(ctx context.Context, sourceID string) (map[string]int, error) {
+	return map[string]int{}, nil
+}
+
 func (m *MockVectorStore) GetChunksByURL(ctx context.Context, url string) ([]retrieval.SearchResult, error) {
 	return m.GetChunksByURLRes, m.GetChunksByURLErr
 }
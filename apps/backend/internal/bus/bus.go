@@ -0,0 +1,96 @@
+// Package bus abstracts the message queue between the API, the ingestion
+// workers and the embedders. NSQ is one adapter (internal/adapter/nsqbus);
+// Memory keeps everything in-process for single-binary mode and tests.
+package bus
+
+import (
+	"errors"
+	"time"
+)
+
+// Message is one delivery of a published body.
+type Message struct {
+	ID       string
+	Topic    string
+	Body     []byte
+	Attempts uint16 // 1 on first delivery
+}
+
+// Handler processes a message. Returning nil acknowledges it; returning an
+// error requeues it, after the delay of a *RequeueError if it is one.
+type Handler interface {
+	HandleMessage(m *Message) error
+}
+
+// HandlerFunc adapts a function to Handler.
+type HandlerFunc func(m *Message) error
+
+func (f HandlerFunc) HandleMessage(m *Message) error {
+	return f(m)
+}
+
+// SubscribeOptions configure a subscription.
+type SubscribeOptions struct {
+	// Channel names the consumer group. Every channel of a topic receives
+	// each message once; subscribers sharing a channel split the messages.
+	Channel string
+	// Concurrency is the number of messages handled at once.
+	Concurrency int
+	// MaxAttempts drops a message after it failed this many times. 0 retries
+	// forever.
+	MaxAttempts uint16
+	// MaxRequeueDelay caps the requeue delays handlers ask for.
+	MaxRequeueDelay time.Duration
+}
+
+// Subscription is a running subscriber.
+type Subscription interface {
+	// ChangeMaxInFlight limits how many messages are handled at once, up to
+	// the subscription's concurrency.
+	ChangeMaxInFlight(n int)
+	Stop()
+}
+
+// Publisher publishes a body to a topic.
+type Publisher interface {
+	Publish(topic string, body []byte) error
+}
+
+// Bus publishes and subscribes to topics.
+type Bus interface {
+	Publisher
+	Subscribe(topic string, opts SubscribeOptions, h Handler) (Subscription, error)
+	// StopConsumers stops all subscriptions, returning once in-flight
+	// handlers finish. Publishing keeps working, so they can still publish.
+	StopConsumers()
+	// Stop stops all subscriptions and releases connections.
+	Stop()
+}
+
+// RequeueError asks the bus to redeliver the message after Delay.
+type RequeueError struct {
+	Delay time.Duration
+	Err   error
+}
+
+func (e *RequeueError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RequeueError) Unwrap() error {
+	return e.Err
+}
+
+// Requeue wraps err so the message is redelivered after delay.
+func Requeue(err error, delay time.Duration) error {
+	return &RequeueError{Delay: delay, Err: err}
+}
+
+// RequeueDelay returns the delay err asks for, or -1 for the bus default.
+func RequeueDelay(err error) time.Duration {
+	var re *RequeueError
+	if errors.As(err, &re) {
+		return re.Delay
+	}
+	return -1
+}
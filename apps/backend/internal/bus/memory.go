@@ -0,0 +1,272 @@
+package bus
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// defaultRequeueDelay is used when a handler fails without asking for a delay.
+const defaultRequeueDelay = time.Second
+
+// Memory is an in-process Bus with NSQ's delivery semantics: every channel of
+// a topic gets each message, messages published before a topic has channels
+// are kept for the first one, and failed messages are requeued until they run
+// out of attempts. Nothing survives a restart.
+type Memory struct {
+	mu      sync.Mutex
+	topics  map[string]*memTopic
+	subs    []*memSubscription
+	stopped bool
+}
+
+type memTopic struct {
+	channels map[string]*memChannel
+	backlog  []*Message
+}
+
+// memChannel is a queue shared by the subscriptions of one channel.
+type memChannel struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queue  []*Message
+	timers map[*time.Timer]struct{}
+	closed bool
+}
+
+func NewMemory() *Memory {
+	return &Memory{topics: make(map[string]*memTopic)}
+}
+
+func (b *Memory) Publish(topic string, body []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.stopped {
+		return fmt.Errorf("bus stopped")
+	}
+
+	t := b.topic(topic)
+	m := &Message{ID: uuid.NewString(), Topic: topic, Body: append([]byte(nil), body...)}
+	if len(t.channels) == 0 {
+		t.backlog = append(t.backlog, m)
+		return nil
+	}
+	for _, ch := range t.channels {
+		ch.push(m.clone())
+	}
+	return nil
+}
+
+func (b *Memory) Subscribe(topic string, opts SubscribeOptions, h Handler) (Subscription, error) {
+	if opts.Channel == "" {
+		return nil, fmt.Errorf("channel is required")
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.stopped {
+		return nil, fmt.Errorf("bus stopped")
+	}
+
+	t := b.topic(topic)
+	ch, ok := t.channels[opts.Channel]
+	if !ok {
+		ch = &memChannel{timers: make(map[*time.Timer]struct{})}
+		ch.cond = sync.NewCond(&ch.mu)
+		t.channels[opts.Channel] = ch
+		for _, m := range t.backlog {
+			ch.push(m.clone())
+		}
+		t.backlog = nil
+	}
+
+	s := newMemSubscription(ch, opts, h)
+	b.subs = append(b.subs, s)
+	return s, nil
+}
+
+// StopConsumers stops every subscription, keeping queued messages.
+func (b *Memory) StopConsumers() {
+	b.mu.Lock()
+	subs := b.subs
+	b.subs = nil
+	b.mu.Unlock()
+
+	for _, s := range subs {
+		s.Stop()
+	}
+}
+
+// Stop stops every subscription and discards queued messages.
+func (b *Memory) Stop() {
+	b.mu.Lock()
+	if b.stopped {
+		b.mu.Unlock()
+		return
+	}
+	b.stopped = true
+	subs := b.subs
+	b.subs = nil
+	topics := b.topics
+	b.mu.Unlock()
+
+	for _, s := range subs {
+		s.Stop()
+	}
+	for _, t := range topics {
+		for _, ch := range t.channels {
+			ch.close()
+		}
+	}
+}
+
+func (b *Memory) topic(name string) *memTopic {
+	t, ok := b.topics[name]
+	if !ok {
+		t = &memTopic{channels: make(map[string]*memChannel)}
+		b.topics[name] = t
+	}
+	return t
+}
+
+func (m *Message) clone() *Message {
+	c := *m
+	return &c
+}
+
+func (ch *memChannel) push(m *Message) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if ch.closed {
+		return
+	}
+	ch.queue = append(ch.queue, m)
+	ch.cond.Broadcast()
+}
+
+func (ch *memChannel) requeue(m *Message, delay time.Duration) {
+	if delay <= 0 {
+		ch.push(m)
+		return
+	}
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if ch.closed {
+		return
+	}
+	var t *time.Timer
+	t = time.AfterFunc(delay, func() {
+		ch.mu.Lock()
+		delete(ch.timers, t)
+		ch.mu.Unlock()
+		ch.push(m)
+	})
+	ch.timers[t] = struct{}{}
+}
+
+func (ch *memChannel) close() {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.closed = true
+	ch.queue = nil
+	for t := range ch.timers {
+		t.Stop()
+	}
+	ch.timers = nil
+	ch.cond.Broadcast()
+}
+
+type memSubscription struct {
+	ch   *memChannel
+	opts SubscribeOptions
+	h    Handler
+	wg   sync.WaitGroup
+
+	// Guarded by ch.mu
+	inFlight    int
+	maxInFlight int
+	stopped     bool
+}
+
+func newMemSubscription(ch *memChannel, opts SubscribeOptions, h Handler) *memSubscription {
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+	s := &memSubscription{ch: ch, opts: opts, h: h, maxInFlight: opts.Concurrency}
+	for i := 0; i < opts.Concurrency; i++ {
+		s.wg.Add(1)
+		go s.work()
+	}
+	return s
+}
+
+func (s *memSubscription) ChangeMaxInFlight(n int) {
+	s.ch.mu.Lock()
+	defer s.ch.mu.Unlock()
+	s.maxInFlight = min(max(n, 1), s.opts.Concurrency)
+	s.ch.cond.Broadcast()
+}
+
+// Stop waits for in-flight messages to finish.
+func (s *memSubscription) Stop() {
+	s.ch.mu.Lock()
+	s.stopped = true
+	s.ch.cond.Broadcast()
+	s.ch.mu.Unlock()
+	s.wg.Wait()
+}
+
+func (s *memSubscription) work() {
+	defer s.wg.Done()
+	for {
+		m := s.next()
+		if m == nil {
+			return
+		}
+		s.handle(m)
+
+		s.ch.mu.Lock()
+		s.inFlight--
+		s.ch.cond.Broadcast()
+		s.ch.mu.Unlock()
+	}
+}
+
+// next blocks until a message may be handled, returning nil once stopped.
+func (s *memSubscription) next() *Message {
+	ch := s.ch
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	for !s.stopped && !ch.closed && (len(ch.queue) == 0 || s.inFlight >= s.maxInFlight) {
+		ch.cond.Wait()
+	}
+	if s.stopped || ch.closed {
+		return nil
+	}
+	m := ch.queue[0]
+	ch.queue = ch.queue[1:]
+	s.inFlight++
+	return m
+}
+
+func (s *memSubscription) handle(m *Message) {
+	m.Attempts++
+	err := s.h.HandleMessage(m)
+	if err == nil {
+		return
+	}
+	if s.opts.MaxAttempts > 0 && m.Attempts >= s.opts.MaxAttempts {
+		slog.Warn("giving up on message", "topic", m.Topic, "channel", s.opts.Channel, "id", m.ID, "attempts", m.Attempts, "error", err)
+		return
+	}
+	delay := RequeueDelay(err)
+	if delay < 0 {
+		delay = defaultRequeueDelay
+	}
+	if s.opts.MaxRequeueDelay > 0 && delay > s.opts.MaxRequeueDelay {
+		delay = s.opts.MaxRequeueDelay
+	}
+	s.ch.requeue(m, delay)
+}
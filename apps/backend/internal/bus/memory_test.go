@@ -0,0 +1,169 @@
+package bus_test
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"qurio/apps/backend/internal/bus"
+)
+
+// collector records the bodies it handles.
+type collector struct {
+	mu     sync.Mutex
+	bodies []string
+}
+
+func (c *collector) HandleMessage(m *bus.Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.bodies = append(c.bodies, string(m.Body))
+	return nil
+}
+
+func (c *collector) received() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.bodies...)
+}
+
+func TestMemory_FanOutToChannels(t *testing.T) {
+	b := bus.NewMemory()
+	defer b.Stop()
+
+	a, c := &collector{}, &collector{}
+	_, err := b.Subscribe("topic", bus.SubscribeOptions{Channel: "a"}, a)
+	require.NoError(t, err)
+	_, err = b.Subscribe("topic", bus.SubscribeOptions{Channel: "c"}, c)
+	require.NoError(t, err)
+
+	require.NoError(t, b.Publish("topic", []byte("hello")))
+	require.NoError(t, b.Publish("other", []byte("ignored")))
+
+	assert.Eventually(t, func() bool {
+		return len(a.received()) == 1 && len(c.received()) == 1
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"hello"}, a.received())
+}
+
+func TestMemory_KeepsMessagesUntilFirstChannel(t *testing.T) {
+	b := bus.NewMemory()
+	defer b.Stop()
+
+	require.NoError(t, b.Publish("topic", []byte("early")))
+
+	col := &collector{}
+	_, err := b.Subscribe("topic", bus.SubscribeOptions{Channel: "late"}, col)
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool { return len(col.received()) == 1 }, time.Second, 5*time.Millisecond)
+}
+
+func TestMemory_RequeuesUntilMaxAttempts(t *testing.T) {
+	b := bus.NewMemory()
+	defer b.Stop()
+
+	var attempts []uint16
+	var mu sync.Mutex
+	h := bus.HandlerFunc(func(m *bus.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts = append(attempts, m.Attempts)
+		return bus.Requeue(errors.New("boom"), time.Millisecond)
+	})
+	_, err := b.Subscribe("topic", bus.SubscribeOptions{Channel: "ch", MaxAttempts: 3}, h)
+	require.NoError(t, err)
+	require.NoError(t, b.Publish("topic", []byte("x")))
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(attempts) == 3
+	}, time.Second, 5*time.Millisecond)
+
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []uint16{1, 2, 3}, attempts)
+}
+
+func TestMemory_ChangeMaxInFlight(t *testing.T) {
+	b := bus.NewMemory()
+	defer b.Stop()
+
+	var current, peak int32
+	release := make(chan struct{})
+	h := bus.HandlerFunc(func(m *bus.Message) error {
+		n := atomic.AddInt32(&current, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		<-release
+		atomic.AddInt32(&current, -1)
+		return nil
+	})
+	sub, err := b.Subscribe("topic", bus.SubscribeOptions{Channel: "ch", Concurrency: 4}, h)
+	require.NoError(t, err)
+	sub.ChangeMaxInFlight(2)
+
+	for i := 0; i < 6; i++ {
+		require.NoError(t, b.Publish("topic", []byte("x")))
+	}
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&current) == 2 }, time.Second, 5*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&peak))
+
+	close(release)
+	sub.Stop()
+}
+
+func TestMemory_StopConsumersKeepsPublishing(t *testing.T) {
+	b := bus.NewMemory()
+	defer b.Stop()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	var published error
+	_, err := b.Subscribe("topic", bus.SubscribeOptions{Channel: "a"}, bus.HandlerFunc(func(m *bus.Message) error {
+		close(started)
+		<-release
+		// A handler finishing during shutdown still publishes its follow-up
+		published = b.Publish("next", []byte("follow-up"))
+		return nil
+	}))
+	require.NoError(t, err)
+	require.NoError(t, b.Publish("topic", []byte("x")))
+	<-started
+
+	stopped := make(chan struct{})
+	go func() {
+		b.StopConsumers()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		t.Fatal("StopConsumers returned before the in-flight handler finished")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	<-stopped
+	assert.NoError(t, published)
+
+	// The follow-up waits for the next consumer
+	col := &collector{}
+	_, err = b.Subscribe("next", bus.SubscribeOptions{Channel: "a"}, col)
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return len(col.received()) == 1 }, time.Second, 5*time.Millisecond)
+}
+
+func TestMemory_StopRejectsPublish(t *testing.T) {
+	b := bus.NewMemory()
+	b.Stop()
+	assert.Error(t, b.Publish("topic", []byte("x")))
+}
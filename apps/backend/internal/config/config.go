@@ -21,6 +21,10 @@ type Config struct {
 	DoclingURL string `envconfig:"DOCLING_URL" default:"http://docling:8000"`
 	NSQLookupd string `envconfig:"NSQ_LOOKUPD" default:"nsqlookupd:4161"`
 	NSQDHost   string `envconfig:"NSQD_HOST" default:"nsqd:4150"`
+
+	// Message bus: "nsq", or "memory" to keep queues in-process (single binary,
+	// no external ingestion worker).
+	BusDriver string `envconfig:"BUS_DRIVER" default:"nsq"`
 	
 	EnableAPI            bool `envconfig:"ENABLE_API" default:"true"`
 	EnableEmbedderWorker bool `envconfig:"ENABLE_EMBEDDER_WORKER" default:"false"`
@@ -30,9 +34,45 @@ type Config struct {
 	RerankAPIKey string `envconfig:"RERANK_API_KEY"`
 	NSQMaxMsgSize int64 `envconfig:"NSQ_MAX_MSG_SIZE" default:"10485760"` // 10MB
 
+	// Chunking: BPE vocabulary (tiktoken format) used to measure chunk sizes.
+	// Empty falls back to a byte-based estimate.
+	TokenizerVocabPath string `envconfig:"TOKENIZER_VOCAB_PATH"`
+
+	// Crawl pacing: discovered pages are released to the crawler at most this
+	// many per host and minute, checked every dispatch interval.
+	CrawlHostPagesPerMinute      int `envconfig:"CRAWL_HOST_PAGES_PER_MINUTE" default:"60"`
+	CrawlDispatchIntervalSeconds int `envconfig:"CRAWL_DISPATCH_INTERVAL_SECONDS" default:"1"`
+
 	// Resilience
 	BootstrapRetryAttempts int `envconfig:"BOOTSTRAP_RETRY_ATTEMPTS" default:"10"`
 	BootstrapRetryDelaySeconds int `envconfig:"BOOTSTRAP_RETRY_DELAY_SECONDS" default:"2"`
+
+	// Consumer retries: a message is requeued with exponential backoff starting
+	// at the initial delay and capped at CONSUMER_RETRY_MAX_DELAY_MS. Embeddings
+	// that fail their last attempt are written to failed_jobs.
+	ResultMaxAttempts         int `envconfig:"RESULT_MAX_ATTEMPTS" default:"5"`
+	ResultRetryInitialDelayMS int `envconfig:"RESULT_RETRY_INITIAL_DELAY_MS" default:"1000"`
+	EmbedMaxAttempts          int `envconfig:"EMBED_MAX_ATTEMPTS" default:"5"`
+	EmbedRetryInitialDelayMS  int `envconfig:"EMBED_RETRY_INITIAL_DELAY_MS" default:"5000"`
+	ConsumerRetryMaxDelayMS   int `envconfig:"CONSUMER_RETRY_MAX_DELAY_MS" default:"300000"`
+
+	// Shutdown: /ready reports 503 for the drain delay before the HTTP server,
+	// consumers, background jobs and finally the producer are stopped within
+	// the timeout.
+	ShutdownDrainDelaySeconds int `envconfig:"SHUTDOWN_DRAIN_DELAY_SECONDS" default:"5"`
+	ShutdownTimeoutSeconds    int `envconfig:"SHUTDOWN_TIMEOUT_SECONDS" default:"30"`
+
+	// Git sources clone https and ssh remotes; local paths and file:// URLs
+	// reach the backend's own filesystem and need this opt-in.
+	GitAllowLocalRemotes bool `envconfig:"GIT_ALLOW_LOCAL_REMOTES" default:"false"`
+
+	// Directory sources may only read below these paths, comma-separated;
+	// none disables them. The file ingestion worker must see the same paths.
+	DirectoryRoots []string `envconfig:"QURIO_DIRECTORY_ROOTS"`
+
+	// Reconciliation (Postgres <-> Weaviate)
+	ReconcileIntervalMinutes int  `envconfig:"RECONCILE_INTERVAL_MINUTES" default:"60"` // 0 disables the periodic run; checked on the 5-minute janitor tick
+	ReconcileDeleteOrphans   bool `envconfig:"RECONCILE_DELETE_ORPHANS" default:"false"`
 }
 
 func Load() (*Config, error) {
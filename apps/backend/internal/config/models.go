@@ -0,0 +1,5 @@
+package config
+
+// EmbeddingModel is the Gemini model used for all stored vectors. Snapshot
+// archives record it so vectors are never mixed across incompatible models.
+const EmbeddingModel = "gemini-embedding-001"
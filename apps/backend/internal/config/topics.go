@@ -12,4 +12,8 @@ const (
 
 	// TopicIngestEmbed is the NSQ topic for embedding generation tasks.
 	TopicIngestEmbed = "ingest.embed"
+
+	// TopicSourceSync is the NSQ topic for the slow parts of starting a
+	// source sync, such as reading sitemaps, run by the backend itself.
+	TopicSourceSync = "source.sync"
 )
@@ -0,0 +1,114 @@
+// Package lifecycle coordinates graceful shutdown: readiness is withdrawn
+// first so load balancers stop routing, then registered stages (the HTTP
+// server, consumers, background jobs, the producer) are stopped in order
+// under one deadline.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type stage struct {
+	name string
+	stop func(ctx context.Context) error
+}
+
+type Manager struct {
+	ready      atomic.Bool
+	drainDelay time.Duration
+
+	mu     sync.Mutex
+	stages []stage
+}
+
+// New returns a manager that, on shutdown, waits drainDelay after reporting
+// not ready before it stops anything.
+func New(drainDelay time.Duration) *Manager {
+	return &Manager{drainDelay: drainDelay}
+}
+
+func (m *Manager) SetReady(ready bool) {
+	m.ready.Store(ready)
+}
+
+func (m *Manager) Ready() bool {
+	return m.ready.Load()
+}
+
+// OnShutdown registers a stage. Stages run in registration order.
+func (m *Manager) OnShutdown(name string, stop func(ctx context.Context) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stages = append(m.stages, stage{name: name, stop: stop})
+}
+
+// Shutdown reports not ready, waits out the drain delay and runs every stage.
+// A stage that fails or misses the deadline doesn't keep later stages from
+// running; their errors are joined.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.SetReady(false)
+	slog.Info("shutting down, readiness withdrawn", "drain_delay", m.drainDelay)
+
+	if m.drainDelay > 0 {
+		t := time.NewTimer(m.drainDelay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+		}
+	}
+
+	m.mu.Lock()
+	stages := append([]stage(nil), m.stages...)
+	m.mu.Unlock()
+
+	var errs []error
+	for _, s := range stages {
+		start := time.Now()
+		if err := s.stop(ctx); err != nil {
+			slog.Error("shutdown stage failed", "stage", s.name, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", s.name, err))
+			continue
+		}
+		slog.Info("shutdown stage complete", "stage", s.name, "duration", time.Since(start))
+	}
+	return errors.Join(errs...)
+}
+
+// Blocking adapts a stop function that can't be cancelled. The stage returns
+// once fn does, or with ctx's error when the deadline passes first; fn keeps
+// running in the background.
+func Blocking(fn func()) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			fn()
+		}()
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// ReadyHandler answers 200 while ready and 503 otherwise.
+func (m *Manager) ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !m.Ready() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"status":"unavailable"}`))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status":"ready"}`))
+}
@@ -0,0 +1,86 @@
+package lifecycle_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"qurio/apps/backend/internal/lifecycle"
+)
+
+func TestShutdown_RunsStagesInOrder(t *testing.T) {
+	m := lifecycle.New(0)
+	m.SetReady(true)
+
+	var order []string
+	var readyDuringStages []bool
+	for _, name := range []string{"consumers", "scheduler", "http"} {
+		name := name
+		m.OnShutdown(name, func(ctx context.Context) error {
+			order = append(order, name)
+			readyDuringStages = append(readyDuringStages, m.Ready())
+			return nil
+		})
+	}
+
+	assert.NoError(t, m.Shutdown(context.Background()))
+	assert.Equal(t, []string{"consumers", "scheduler", "http"}, order)
+	assert.Equal(t, []bool{false, false, false}, readyDuringStages)
+}
+
+func TestShutdown_ContinuesAfterFailure(t *testing.T) {
+	m := lifecycle.New(0)
+	ran := false
+	m.OnShutdown("consumers", func(ctx context.Context) error { return errors.New("boom") })
+	m.OnShutdown("http", func(ctx context.Context) error {
+		ran = true
+		return nil
+	})
+
+	err := m.Shutdown(context.Background())
+	assert.EqualError(t, err, "consumers: boom")
+	assert.True(t, ran)
+}
+
+func TestShutdown_DrainDelay(t *testing.T) {
+	m := lifecycle.New(30 * time.Millisecond)
+	var stoppedAfter time.Duration
+	start := time.Now()
+	m.OnShutdown("consumers", func(ctx context.Context) error {
+		stoppedAfter = time.Since(start)
+		return nil
+	})
+
+	assert.NoError(t, m.Shutdown(context.Background()))
+	assert.GreaterOrEqual(t, stoppedAfter, 30*time.Millisecond)
+}
+
+func TestBlocking_Deadline(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	stop := lifecycle.Blocking(func() { <-release })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, stop(ctx), context.DeadlineExceeded)
+
+	assert.NoError(t, lifecycle.Blocking(func() {})(context.Background()))
+}
+
+func TestReadyHandler(t *testing.T) {
+	m := lifecycle.New(0)
+
+	w := httptest.NewRecorder()
+	m.ReadyHandler(w, httptest.NewRequest("GET", "/ready", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	m.SetReady(true)
+	w = httptest.NewRecorder()
+	m.ReadyHandler(w, httptest.NewRequest("GET", "/ready", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ready"}`, w.Body.String())
+}
@@ -18,6 +18,9 @@ type SearchResult struct {
 	PageCount int                    `json:"pageCount,omitempty"` // New
 	Language  string                 `json:"language,omitempty"`  // New
 	Type      string                 `json:"type,omitempty"`      // New
+	Symbol    string                 `json:"symbol,omitempty"`
+	Section   string                 `json:"section,omitempty"`
+	Version   string                 `json:"version,omitempty"`
 	Metadata  map[string]interface{} `json:"metadata"`
 }
 
@@ -32,7 +35,7 @@ type Embedder interface {
 }
 
 type VectorStore interface {
-	Search(ctx context.Context, query string, vector []float32, alpha float32, limit int, filters map[string]interface{}) ([]SearchResult, error)
+	Search(ctx context.Context, query string, vector []float32, alpha float32, limit int, properties []string, filters map[string]interface{}) ([]SearchResult, error)
 	GetChunksByURL(ctx context.Context, url string) ([]SearchResult, error)
 }
 
@@ -77,6 +80,7 @@ func (s *Service) Search(ctx context.Context, query string, opts *SearchOptions)
 	// Resolve params
 	alpha := cfg.SearchAlpha
 	limit := cfg.SearchTopK
+	properties := cfg.SearchProperties
 	var filters map[string]interface{}
 
 	if opts != nil {
@@ -96,7 +100,7 @@ func (s *Service) Search(ctx context.Context, query string, opts *SearchOptions)
 	}
 
 	// 2. Hybrid Search (BM25 + Vector)
-	docs, err := s.store.Search(ctx, query, vec, alpha, limit, filters)
+	docs, err := s.store.Search(ctx, query, vec, alpha, limit, properties, filters)
 	if err != nil {
 		return nil, err
 	}
@@ -22,8 +22,8 @@ func (m *MockEmbedder) Embed(ctx context.Context, text string) ([]float32, error
 
 type MockStore struct{ mock.Mock }
 
-func (m *MockStore) Search(ctx context.Context, query string, vector []float32, alpha float32, limit int, filters map[string]interface{}) ([]retrieval.SearchResult, error) {
-	args := m.Called(ctx, query, vector, alpha, limit, filters)
+func (m *MockStore) Search(ctx context.Context, query string, vector []float32, alpha float32, limit int, properties []string, filters map[string]interface{}) ([]retrieval.SearchResult, error) {
+	args := m.Called(ctx, query, vector, alpha, limit, properties, filters)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -74,7 +74,7 @@ func TestService_Search(t *testing.T) {
 			setup: func(e *MockEmbedder, s *MockStore, r *MockReranker, set *MockSettingsRepo) {
 				set.On("Get", mock.Anything).Return(&settings.Settings{SearchAlpha: 0.5, SearchTopK: 10}, nil)
 				e.On("Embed", mock.Anything, "test").Return([]float32{0.1}, nil)
-				s.On("Search", mock.Anything, "test", []float32{0.1}, float32(0.5), 10, map[string]interface{}(nil)).
+				s.On("Search", mock.Anything, "test", []float32{0.1}, float32(0.5), 10, []string(nil), map[string]interface{}(nil)).
 					Return([]retrieval.SearchResult{{Content: "A", Score: 0.9}}, nil)
 			},
 			wantLen: 1,
@@ -86,7 +86,7 @@ func TestService_Search(t *testing.T) {
 			setup: func(e *MockEmbedder, s *MockStore, r *MockReranker, set *MockSettingsRepo) {
 				set.On("Get", mock.Anything).Return(&settings.Settings{SearchAlpha: 0.5, SearchTopK: 10}, nil)
 				e.On("Embed", mock.Anything, "test").Return([]float32{0.1}, nil)
-				s.On("Search", mock.Anything, "test", []float32{0.1}, float32(0.5), 10, map[string]interface{}(nil)).
+				s.On("Search", mock.Anything, "test", []float32{0.1}, float32(0.5), 10, []string(nil), map[string]interface{}(nil)).
 					Return([]retrieval.SearchResult{{Content: "A", Score: 0.8}, {Content: "B", Score: 0.9}}, nil)
 				r.On("Rerank", mock.Anything, "test", []string{"A", "B"}).Return([]int{1, 0}, nil)
 			},
@@ -107,11 +107,24 @@ func TestService_Search(t *testing.T) {
 			setup: func(e *MockEmbedder, s *MockStore, r *MockReranker, set *MockSettingsRepo) {
 				set.On("Get", mock.Anything).Return(&settings.Settings{SearchAlpha: 0.5, SearchTopK: 10}, nil)
 				e.On("Embed", mock.Anything, "test").Return([]float32{0.1}, nil)
-				s.On("Search", mock.Anything, "test", []float32{0.1}, float32(0.8), 5, map[string]interface{}{"type": "code"}).
+				s.On("Search", mock.Anything, "test", []float32{0.1}, float32(0.8), 5, []string(nil), map[string]interface{}{"type": "code"}).
 					Return([]retrieval.SearchResult{}, nil)
 			},
 			wantLen: 0,
 		},
+		{
+			name:  "Weighted Properties from Settings",
+			query: "test",
+			opts:  nil,
+			setup: func(e *MockEmbedder, s *MockStore, r *MockReranker, set *MockSettingsRepo) {
+				set.On("Get", mock.Anything).Return(&settings.Settings{SearchAlpha: 0.5, SearchTopK: 10, SearchProperties: []string{"title^3", "content"}}, nil)
+				e.On("Embed", mock.Anything, "test").Return([]float32{0.1}, nil)
+				s.On("Search", mock.Anything, "test", []float32{0.1}, float32(0.5), 10, []string{"title^3", "content"}, map[string]interface{}(nil)).
+					Return([]retrieval.SearchResult{{Content: "A", Score: 0.9}}, nil)
+			},
+			nilReranker: true,
+			wantLen:     1,
+		},
 		{
 			name:  "Embedder Error",
 			query: "test",
@@ -127,7 +140,7 @@ func TestService_Search(t *testing.T) {
 			setup: func(e *MockEmbedder, s *MockStore, r *MockReranker, set *MockSettingsRepo) {
 				set.On("Get", mock.Anything).Return(&settings.Settings{SearchAlpha: 0.5, SearchTopK: 10}, nil)
 				e.On("Embed", mock.Anything, "test").Return([]float32{0.1}, nil)
-				s.On("Search", mock.Anything, "test", []float32{0.1}, float32(0.5), 10, map[string]interface{}(nil)).
+				s.On("Search", mock.Anything, "test", []float32{0.1}, float32(0.5), 10, []string(nil), map[string]interface{}(nil)).
 					Return(nil, errors.New("store error"))
 			},
 			wantErr: true,
@@ -138,7 +151,7 @@ func TestService_Search(t *testing.T) {
 			setup: func(e *MockEmbedder, s *MockStore, r *MockReranker, set *MockSettingsRepo) {
 				set.On("Get", mock.Anything).Return(&settings.Settings{SearchAlpha: 0.5, SearchTopK: 10}, nil)
 				e.On("Embed", mock.Anything, "test").Return([]float32{0.1}, nil)
-				s.On("Search", mock.Anything, "test", []float32{0.1}, float32(0.5), 10, map[string]interface{}(nil)).
+				s.On("Search", mock.Anything, "test", []float32{0.1}, float32(0.5), 10, []string(nil), map[string]interface{}(nil)).
 					Return([]retrieval.SearchResult{{Content: "A"}}, nil)
 				r.On("Rerank", mock.Anything, "test", []string{"A"}).Return(nil, errors.New("rerank error"))
 			},
@@ -151,7 +164,7 @@ func TestService_Search(t *testing.T) {
 				set.On("Get", mock.Anything).Return((*settings.Settings)(nil), errors.New("settings error"))
 				e.On("Embed", mock.Anything, "test").Return([]float32{0.1}, nil)
 				// Expect defaults: Alpha 0.5, Limit 10
-				s.On("Search", mock.Anything, "test", []float32{0.1}, float32(0.5), 10, map[string]interface{}(nil)).
+				s.On("Search", mock.Anything, "test", []float32{0.1}, float32(0.5), 10, []string(nil), map[string]interface{}(nil)).
 					Return([]retrieval.SearchResult{}, nil)
 			},
 			wantLen: 0,
@@ -163,7 +176,7 @@ func TestService_Search(t *testing.T) {
 			setup: func(e *MockEmbedder, s *MockStore, r *MockReranker, set *MockSettingsRepo) {
 				set.On("Get", mock.Anything).Return(&settings.Settings{SearchAlpha: 0.5, SearchTopK: 10}, nil)
 				e.On("Embed", mock.Anything, "test").Return([]float32{0.1}, nil)
-				s.On("Search", mock.Anything, "test", []float32{0.1}, float32(0.5), 10, map[string]interface{}(nil)).
+				s.On("Search", mock.Anything, "test", []float32{0.1}, float32(0.5), 10, []string(nil), map[string]interface{}(nil)).
 					Return([]retrieval.SearchResult{
 						{Content: "A", Metadata: map[string]interface{}{"title": "My Title"}},
 					}, nil)
@@ -216,7 +229,7 @@ func TestService_Search_Logging(t *testing.T) {
 
 	setRepo.On("Get", mock.Anything).Return(&settings.Settings{SearchAlpha: 0.5, SearchTopK: 10}, nil)
 	e.On("Embed", mock.Anything, "test").Return([]float32{0.1}, nil)
-	s.On("Search", mock.Anything, "test", []float32{0.1}, float32(0.5), 10, map[string]interface{}(nil)).
+	s.On("Search", mock.Anything, "test", []float32{0.1}, float32(0.5), 10, []string(nil), map[string]interface{}(nil)).
 		Return([]retrieval.SearchResult{{Content: "A"}}, nil)
 
 	var buf bytes.Buffer
@@ -243,7 +256,7 @@ func TestService_Search_RerankerEdgeCases(t *testing.T) {
 
 		setRepo.On("Get", mock.Anything).Return(&settings.Settings{}, nil)
 		e.On("Embed", mock.Anything, "test").Return([]float32{0.1}, nil)
-		s.On("Search", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		s.On("Search", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 			Return([]retrieval.SearchResult{{Content: "A"}, {Content: "B"}}, nil)
 		
 		// Reranker returns index 5 which is out of bounds (len 2)
@@ -276,7 +289,7 @@ func TestService_Search_RerankerEdgeCases(t *testing.T) {
 
 		setRepo.On("Get", mock.Anything).Return(&settings.Settings{}, nil)
 		e.On("Embed", mock.Anything, "test").Return([]float32{0.1}, nil)
-		s.On("Search", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		s.On("Search", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 			Return([]retrieval.SearchResult{}, nil)
 
 		svc := retrieval.NewService(e, s, r, settings.NewService(setRepo), nil)
@@ -13,6 +13,7 @@ type Scheduler struct {
 	sourceRepo source.Repository
 	service    *source.Service
 	stop       chan struct{}
+	done       chan struct{}
 }
 
 func New(repo source.Repository, service *source.Service) *Scheduler {
@@ -20,6 +21,7 @@ func New(repo source.Repository, service *source.Service) *Scheduler {
 		sourceRepo: repo,
 		service:    service,
 		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
 	}
 }
 
@@ -27,6 +29,7 @@ func (s *Scheduler) Start() {
 	// Run every 30 seconds
 	ticker := time.NewTicker(30 * time.Second)
 	go func() {
+		defer close(s.done)
 		defer ticker.Stop()
 		slog.Info("Scheduler started")
 		for {
@@ -41,8 +44,10 @@ func (s *Scheduler) Start() {
 	}()
 }
 
+// Stop waits for a sync check in progress to finish.
 func (s *Scheduler) Stop() {
 	close(s.stop)
+	<-s.done
 }
 
 func (s *Scheduler) checkAndSync(ctx context.Context) {
@@ -78,27 +83,39 @@ func (s *Scheduler) isDue(src source.Source) bool {
 	last := *src.LastSyncedAt
 	now := time.Now()
 
-	scheduleStr := src.SyncSchedule
+	schedule, err := ParseSchedule(src.SyncSchedule)
+	if err != nil {
+		slog.Warn("Scheduler: invalid cron schedule, fallback to daily", "schedule", src.SyncSchedule, "error", err)
+		// Fallback to daily
+		return now.Sub(last) >= 24*time.Hour
+	}
+
+	nextSyncTime := schedule.Next(last)
+	return now.After(nextSyncTime)
+}
+
+// ParseSchedule parses a sync schedule: a five-field cron expression, a
+// descriptor such as "@hourly", or one of the legacy names minute, hourly
+// and daily. An empty schedule means daily.
+func ParseSchedule(spec string) (cron.Schedule, error) {
 	// Legacy mapping
-	switch scheduleStr {
+	switch spec {
 	case "minute":
-		scheduleStr = "* * * * *"
+		spec = "* * * * *"
 	case "hourly":
-		scheduleStr = "@hourly"
+		spec = "@hourly"
 	case "daily":
-		scheduleStr = "@daily"
+		spec = "@daily"
 	case "":
-		scheduleStr = "@daily" // Default
+		spec = "@daily" // Default
 	}
 
 	parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
-	schedule, err := parser.Parse(scheduleStr)
-	if err != nil {
-		slog.Warn("Scheduler: invalid cron schedule, fallback to daily", "schedule", scheduleStr, "error", err)
-		// Fallback to daily
-		return now.Sub(last) >= 24*time.Hour
-	}
+	return parser.Parse(spec)
+}
 
-	nextSyncTime := schedule.Next(last)
-	return now.After(nextSyncTime)
+// ValidateSchedule reports whether spec is a schedule ParseSchedule accepts.
+func ValidateSchedule(spec string) error {
+	_, err := ParseSchedule(spec)
+	return err
 }
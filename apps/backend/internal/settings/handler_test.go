@@ -119,4 +119,19 @@ func TestHandler_UpdateSettings(t *testing.T) {
 
 		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
 	})
+
+	t.Run("InvalidTokenization", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := settings.NewService(mockRepo)
+		handler := settings.NewHandler(svc)
+
+		body := `{"search_properties": ["title^3"], "tokenization": {"content": "ngram"}}`
+		req := httptest.NewRequest("PUT", "/settings", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+
+		handler.UpdateSettings(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
 }
@@ -3,6 +3,9 @@ package settings
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+
+	"github.com/lib/pq"
 )
 
 type PostgresRepo struct {
@@ -15,20 +18,33 @@ func NewPostgresRepo(db *sql.DB) *PostgresRepo {
 
 func (r *PostgresRepo) Get(ctx context.Context) (*Settings, error) {
 	s := &Settings{}
-	query := `SELECT id, rerank_provider, rerank_api_key, gemini_api_key, search_alpha, search_top_k FROM settings WHERE id = 1`
-	err := r.db.QueryRowContext(ctx, query).Scan(&s.ID, &s.RerankProvider, &s.RerankAPIKey, &s.GeminiAPIKey, &s.SearchAlpha, &s.SearchTopK)
+	var tokenization []byte
+	query := `SELECT id, rerank_provider, rerank_api_key, gemini_api_key, search_alpha, search_top_k, search_properties, tokenization, embed_requests_per_minute, embed_tokens_per_minute FROM settings WHERE id = 1`
+	err := r.db.QueryRowContext(ctx, query).Scan(&s.ID, &s.RerankProvider, &s.RerankAPIKey, &s.GeminiAPIKey, &s.SearchAlpha, &s.SearchTopK, pq.Array(&s.SearchProperties), &tokenization, &s.EmbedRequestsPerMinute, &s.EmbedTokensPerMinute)
 	if err != nil {
 		return nil, err
 	}
+	if len(tokenization) > 0 {
+		if err := json.Unmarshal(tokenization, &s.Tokenization); err != nil {
+			return nil, err
+		}
+	}
 	return s, nil
 }
 
 func (r *PostgresRepo) Update(ctx context.Context, s *Settings) error {
+	tokenization, err := json.Marshal(s.Tokenization)
+	if err != nil {
+		return err
+	}
+	if s.Tokenization == nil {
+		tokenization = []byte("{}")
+	}
 	query := `
 		UPDATE settings 
-		SET rerank_provider = $1, rerank_api_key = $2, gemini_api_key = $3, search_alpha = $4, search_top_k = $5, updated_at = NOW()
+		SET rerank_provider = $1, rerank_api_key = $2, gemini_api_key = $3, search_alpha = $4, search_top_k = $5, search_properties = $6, tokenization = $7, embed_requests_per_minute = $8, embed_tokens_per_minute = $9, updated_at = NOW()
 		WHERE id = 1
 	`
-	_, err := r.db.ExecContext(ctx, query, s.RerankProvider, s.RerankAPIKey, s.GeminiAPIKey, s.SearchAlpha, s.SearchTopK)
+	_, err = r.db.ExecContext(ctx, query, s.RerankProvider, s.RerankAPIKey, s.GeminiAPIKey, s.SearchAlpha, s.SearchTopK, pq.Array(s.SearchProperties), tokenization, s.EmbedRequestsPerMinute, s.EmbedTokensPerMinute)
 	return err
 }
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"qurio/apps/backend/internal/settings"
 )
@@ -18,11 +19,11 @@ func TestPostgresRepo_Get(t *testing.T) {
 	repo := settings.NewPostgresRepo(db)
 
 	t.Run("Success", func(t *testing.T) {
-		rows := sqlmock.NewRows([]string{"id", "rerank_provider", "rerank_api_key", "gemini_api_key", "search_alpha", "search_top_k"}).
-			AddRow(1, "cohere", "key1", "key2", 0.5, 10)
+		rows := sqlmock.NewRows([]string{"id", "rerank_provider", "rerank_api_key", "gemini_api_key", "search_alpha", "search_top_k", "search_properties", "tokenization", "embed_requests_per_minute", "embed_tokens_per_minute"}).
+			AddRow(1, "cohere", "key1", "key2", 0.5, 10, "{title^3,content}", []byte(`{"content":"trigram"}`), 1500, 1000000)
 
 		// Regex matching for the query
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT id, rerank_provider, rerank_api_key, gemini_api_key, search_alpha, search_top_k FROM settings WHERE id = 1")).
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id, rerank_provider, rerank_api_key, gemini_api_key, search_alpha, search_top_k, search_properties, tokenization, embed_requests_per_minute, embed_tokens_per_minute FROM settings WHERE id = 1")).
 			WillReturnRows(rows)
 
 		s, err := repo.Get(context.Background())
@@ -30,6 +31,10 @@ func TestPostgresRepo_Get(t *testing.T) {
 		assert.NotNil(t, s)
 		assert.Equal(t, "cohere", s.RerankProvider)
 		assert.Equal(t, float32(0.5), s.SearchAlpha)
+		assert.Equal(t, []string{"title^3", "content"}, s.SearchProperties)
+		assert.Equal(t, "trigram", s.Tokenization["content"])
+		assert.Equal(t, 1500, s.EmbedRequestsPerMinute)
+		assert.Equal(t, 1000000, s.EmbedTokensPerMinute)
 	})
 
 	t.Run("Error", func(t *testing.T) {
@@ -51,15 +56,19 @@ func TestPostgresRepo_Update(t *testing.T) {
 
 	t.Run("Success", func(t *testing.T) {
 		s := &settings.Settings{
-			RerankProvider: "jina",
-			RerankAPIKey:   "k1",
-			GeminiAPIKey:   "k2",
-			SearchAlpha:    0.7,
-			SearchTopK:     20,
+			RerankProvider:   "jina",
+			RerankAPIKey:     "k1",
+			GeminiAPIKey:     "k2",
+			SearchAlpha:      0.7,
+			SearchTopK:       20,
+			SearchProperties: []string{"title^3", "content"},
+			Tokenization:     map[string]string{"title": "field"},
+
+			EmbedRequestsPerMinute: 100,
 		}
 
-		mock.ExpectExec(regexp.QuoteMeta("UPDATE settings SET rerank_provider = $1, rerank_api_key = $2, gemini_api_key = $3, search_alpha = $4, search_top_k = $5, updated_at = NOW() WHERE id = 1")).
-			WithArgs(s.RerankProvider, s.RerankAPIKey, s.GeminiAPIKey, s.SearchAlpha, s.SearchTopK).
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE settings SET rerank_provider = $1, rerank_api_key = $2, gemini_api_key = $3, search_alpha = $4, search_top_k = $5, search_properties = $6, tokenization = $7, embed_requests_per_minute = $8, embed_tokens_per_minute = $9, updated_at = NOW() WHERE id = 1")).
+			WithArgs(s.RerankProvider, s.RerankAPIKey, s.GeminiAPIKey, s.SearchAlpha, s.SearchTopK, pq.Array(s.SearchProperties), []byte(`{"title":"field"}`), 100, 0).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
 		err := repo.Update(context.Background(), s)
@@ -2,6 +2,20 @@ package settings
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidSettings is returned by Update when the submitted settings fail validation.
+var ErrInvalidSettings = errors.New("invalid settings")
+
+// Tokenization modes accepted for BM25 text properties.
+const (
+	TokenizationWord    = "word"
+	TokenizationField   = "field"
+	TokenizationTrigram = "trigram"
 )
 
 type Settings struct {
@@ -11,6 +25,44 @@ type Settings struct {
 	GeminiAPIKey   string  `json:"gemini_api_key"`
 	SearchAlpha    float32 `json:"search_alpha"`
 	SearchTopK     int     `json:"search_top_k"`
+
+	// SearchProperties lists the properties queried by the keyword half of hybrid
+	// search, optionally boosted with a "^N" suffix (e.g. "title^3").
+	// Empty means all text properties with equal weight.
+	SearchProperties []string `json:"search_properties"`
+	// Tokenization maps a text property to its BM25 tokenization mode
+	// (word, field or trigram). It is applied when the schema is created.
+	Tokenization map[string]string `json:"tokenization"`
+
+	// Embedding provider budgets per worker process. 0 means unlimited.
+	EmbedRequestsPerMinute int `json:"embed_requests_per_minute"`
+	EmbedTokensPerMinute   int `json:"embed_tokens_per_minute"`
+}
+
+// Validate checks the search weighting, tokenization and rate limit settings.
+func (s *Settings) Validate() error {
+	if s.EmbedRequestsPerMinute < 0 || s.EmbedTokensPerMinute < 0 {
+		return fmt.Errorf("%w: embedding rate limits must not be negative", ErrInvalidSettings)
+	}
+	for _, p := range s.SearchProperties {
+		name, boost, hasBoost := strings.Cut(p, "^")
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("%w: empty search property %q", ErrInvalidSettings, p)
+		}
+		if hasBoost {
+			if w, err := strconv.ParseFloat(boost, 64); err != nil || w <= 0 {
+				return fmt.Errorf("%w: invalid boost in search property %q", ErrInvalidSettings, p)
+			}
+		}
+	}
+	for prop, mode := range s.Tokenization {
+		switch mode {
+		case TokenizationWord, TokenizationField, TokenizationTrigram:
+		default:
+			return fmt.Errorf("%w: unsupported tokenization %q for property %q", ErrInvalidSettings, mode, prop)
+		}
+	}
+	return nil
 }
 
 type Repository interface {
@@ -31,5 +83,8 @@ func (s *Service) Get(ctx context.Context) (*Settings, error) {
 }
 
 func (s *Service) Update(ctx context.Context, set *Settings) error {
+	if err := set.Validate(); err != nil {
+		return err
+	}
 	return s.repo.Update(ctx, set)
 }
@@ -2,6 +2,7 @@ package settings
 
 import (
 	"context"
+	"errors"
 	"testing"
 )
 
@@ -57,3 +58,39 @@ func TestUpdateSettings(t *testing.T) {
 		t.Errorf("expected cohere, got %s", mockRepo.settings.RerankProvider)
 	}
 }
+
+func TestUpdateSettings_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		set     *Settings
+		wantErr bool
+	}{
+		{"Valid", &Settings{SearchProperties: []string{"title^3", "content"}, Tokenization: map[string]string{"content": "trigram", "title": "field"}}, false},
+		{"Bad Boost", &Settings{SearchProperties: []string{"title^x"}}, true},
+		{"Empty Property", &Settings{SearchProperties: []string{"^2"}}, true},
+		{"Bad Tokenization", &Settings{Tokenization: map[string]string{"content": "ngram"}}, true},
+		{"Rate Limits", &Settings{EmbedRequestsPerMinute: 1500, EmbedTokensPerMinute: 1000000}, false},
+		{"Negative Rate Limit", &Settings{EmbedTokensPerMinute: -1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockRepo{settings: &Settings{}}
+			svc := NewService(mockRepo)
+
+			err := svc.Update(context.Background(), tt.set)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidSettings) {
+					t.Fatalf("expected ErrInvalidSettings, got %v", err)
+				}
+				if mockRepo.settings == tt.set {
+					t.Error("invalid settings should not be persisted")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package text
+
+import (
+	"regexp"
+	"strings"
+)
+
+// blockKind classifies the Markdown blocks of a prose section.
+type blockKind int
+
+const (
+	blockParagraph blockKind = iota
+	blockTable
+	blockList
+	blockAdmonition
+)
+
+// mdBlock is a run of lines that the chunker handles as one unit.
+type mdBlock struct {
+	Kind    blockKind
+	Content string
+}
+
+var (
+	listItemRe = regexp.MustCompile(`^\s{0,3}(?:[-*+]|\d{1,9}[.)])(?:\s|$)`)
+	// tableDelimRe matches the delimiter row under a GFM table header, e.g. "|---|:--:|".
+	tableDelimRe = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(?:\|\s*:?-+:?\s*)*\|?\s*$`)
+	// admonitionRe matches MkDocs ("!!! note", "??? tip") admonition openers.
+	admonitionRe = regexp.MustCompile(`^(?:!!!|\?\?\?\+?)\s*\w+`)
+	// fencedAdmonitionRe matches Docusaurus/VitePress ":::note" containers.
+	fencedAdmonitionRe = regexp.MustCompile(`^\s*:{3,}\s*\w+`)
+)
+
+// splitBlocks splits a prose section into paragraphs, GFM tables, lists and
+// admonitions. Blank lines separate paragraphs but not list items of a loose
+// list or the body of an admonition.
+func splitBlocks(section string) []mdBlock {
+	lines := strings.Split(section, "\n")
+	var blocks []mdBlock
+	add := func(kind blockKind, ls []string) {
+		content := strings.TrimSpace(strings.Join(ls, "\n"))
+		if content != "" {
+			blocks = append(blocks, mdBlock{Kind: kind, Content: content})
+		}
+	}
+
+	var para []string
+	flushPara := func() {
+		add(blockParagraph, para)
+		para = nil
+	}
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "":
+			flushPara()
+			i++
+
+		case isTableStart(lines, i):
+			flushPara()
+			end := i + 2
+			for end < len(lines) && strings.TrimSpace(lines[end]) != "" && strings.Contains(lines[end], "|") {
+				end++
+			}
+			add(blockTable, lines[i:end])
+			i = end
+
+		case fencedAdmonitionRe.MatchString(line):
+			flushPara()
+			end := i + 1
+			for end < len(lines) && !isFenceClose(lines[end]) {
+				end++
+			}
+			if end < len(lines) {
+				end++ // include the closing fence
+			}
+			add(blockAdmonition, lines[i:end])
+			i = end
+
+		case admonitionRe.MatchString(line):
+			flushPara()
+			end := indentedBlockEnd(lines, i+1, false, func(l string) bool { return isIndented(l, 4) })
+			add(blockAdmonition, lines[i:end])
+			i = end
+
+		case strings.HasPrefix(strings.TrimSpace(line), ">"):
+			// Blockquotes, including GitHub "> [!NOTE]" callouts
+			flushPara()
+			end := i + 1
+			for end < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[end]), ">") {
+				end++
+			}
+			kind := blockParagraph
+			if strings.HasPrefix(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), ">")), "[!") {
+				kind = blockAdmonition
+			}
+			add(kind, lines[i:end])
+			i = end
+
+		case listItemRe.MatchString(line):
+			flushPara()
+			ordered := isOrderedItem(line)
+			end := indentedBlockEnd(lines, i+1, true, func(l string) bool {
+				// Switching between bullets and numbers starts a new list
+				return isIndented(l, 2) || (listItemRe.MatchString(l) && isOrderedItem(l) == ordered)
+			})
+			add(blockList, lines[i:end])
+			i = end
+
+		default:
+			para = append(para, line)
+			i++
+		}
+	}
+	flushPara()
+	return blocks
+}
+
+// indentedBlockEnd returns the index after the run of lines starting at i
+// that belong to the block. Blank lines are included only when a
+// continuation line follows them. With lazy set, unindented text directly
+// following a block line continues it, as with list items.
+func indentedBlockEnd(lines []string, i int, lazy bool, continues func(string) bool) int {
+	end := i
+	for j := i; j < len(lines); j++ {
+		switch {
+		case strings.TrimSpace(lines[j]) == "":
+			continue
+		case continues(lines[j]):
+			end = j + 1
+		case lazy && j == end && !isBlockStart(lines, j):
+			end = j + 1
+		default:
+			return end
+		}
+	}
+	return end
+}
+
+func isBlockStart(lines []string, i int) bool {
+	l := lines[i]
+	return isTableStart(lines, i) || listItemRe.MatchString(l) || admonitionRe.MatchString(l) ||
+		fencedAdmonitionRe.MatchString(l) || strings.HasPrefix(strings.TrimSpace(l), ">")
+}
+
+func isOrderedItem(line string) bool {
+	t := strings.TrimSpace(line)
+	return t != "" && t[0] >= '0' && t[0] <= '9'
+}
+
+func isTableStart(lines []string, i int) bool {
+	return i+1 < len(lines) && strings.Contains(lines[i], "|") &&
+		strings.Contains(lines[i+1], "|") && tableDelimRe.MatchString(lines[i+1])
+}
+
+func isFenceClose(line string) bool {
+	t := strings.TrimSpace(line)
+	return len(t) >= 3 && strings.Trim(t, ":") == ""
+}
+
+func isIndented(line string, n int) bool {
+	return strings.HasPrefix(line, "\t") || strings.HasPrefix(line, strings.Repeat(" ", n))
+}
+
+// isTable reports whether content, ignoring a leading heading line, is a
+// single GFM table.
+func isTable(content string) bool {
+	lines := strings.Split(strings.TrimSpace(content), "\n")
+	if len(lines) > 0 && headingRe.MatchString(lines[0]) {
+		lines = lines[1:]
+	}
+	body := strings.TrimSpace(strings.Join(lines, "\n"))
+	blocks := splitBlocks(body)
+	return len(blocks) == 1 && blocks[0].Kind == blockTable
+}
+
+// splitListItems splits a list block into its top-level items. Nested items
+// and continuation lines stay with their parent.
+func splitListItems(list string) []string {
+	var items []string
+	var current []string
+	for _, line := range strings.Split(list, "\n") {
+		if listItemRe.MatchString(line) && !isIndented(line, 2) && len(current) > 0 {
+			items = append(items, strings.TrimRight(strings.Join(current, "\n"), "\n "))
+			current = nil
+		}
+		current = append(current, line)
+	}
+	if len(current) > 0 {
+		items = append(items, strings.TrimRight(strings.Join(current, "\n"), "\n "))
+	}
+	return items
+}
+
+// chunkTable splits a table by rows, repeating the header and delimiter rows
+// in every chunk so each part stays self-describing. A single row is never
+// split, even if it exceeds maxTokens.
+func (c *Chunker) chunkTable(table string, maxTokens int) []ChunkResult {
+	lines := strings.Split(table, "\n")
+	if len(lines) < 3 {
+		return []ChunkResult{{Content: table, Type: ChunkTypeTable}}
+	}
+	header := lines[0] + "\n" + lines[1]
+	headerTokens := c.tok.Count(header) + 1
+
+	var chunks []ChunkResult
+	var rows []string
+	tokens := headerTokens
+	flush := func() {
+		if len(rows) == 0 {
+			return
+		}
+		chunks = append(chunks, ChunkResult{
+			Content: header + "\n" + strings.Join(rows, "\n"),
+			Type:    ChunkTypeTable,
+		})
+		rows = nil
+		tokens = headerTokens
+	}
+
+	for _, row := range lines[2:] {
+		n := c.tok.Count(row) + 1 // newline
+		if len(rows) > 0 && tokens+n > maxTokens {
+			flush()
+		}
+		rows = append(rows, row)
+		tokens += n
+	}
+	flush()
+	return chunks
+}
@@ -13,76 +13,120 @@ const (
 	ChunkTypeAPI    ChunkType = "api"
 	ChunkTypeConfig ChunkType = "config"
 	ChunkTypeCmd    ChunkType = "cmd"
+	ChunkTypeTable  ChunkType = "table"
 )
 
 type ChunkResult struct {
 	Content  string
 	Type     ChunkType
 	Language string
+	Symbol   string // Declared symbols of code chunks, comma separated
+	Section  string // Heading breadcrumb, e.g. "Webhooks > Signatures > Verifying", or JSON path of structured data
 }
 
-// ChunkMarkdown implements a simplified chunker that splits text into chunks,
-// preserving code blocks and identifying their language.
-// It also splits large prose blocks into smaller chunks.
+var headingRe = regexp.MustCompile(`^(#{1,6})\s+(.*?)(?:\s+#+)?\s*$`)
+
+// headingPath tracks the Markdown heading hierarchy while chunking.
+type headingPath struct {
+	titles [6]string
+}
+
+// update records the heading on line, if any, and clears deeper levels.
+func (h *headingPath) update(line string) {
+	m := headingRe.FindStringSubmatch(line)
+	if m == nil {
+		return
+	}
+	level := len(m[1])
+	h.titles[level-1] = m[2]
+	for i := level; i < len(h.titles); i++ {
+		h.titles[i] = ""
+	}
+}
+
+func (h *headingPath) String() string {
+	var parts []string
+	for _, t := range h.titles {
+		if t != "" {
+			parts = append(parts, t)
+		}
+	}
+	return strings.Join(parts, " > ")
+}
+
+// Chunker splits documents into chunks whose size is measured by a Tokenizer.
+type Chunker struct {
+	tok Tokenizer
+}
+
+// NewChunker returns a Chunker using tok, or the byte-based estimate if nil.
+func NewChunker(tok Tokenizer) *Chunker {
+	if tok == nil {
+		tok = EstimateTokenizer{}
+	}
+	return &Chunker{tok: tok}
+}
+
+var defaultChunker = NewChunker(nil)
+
+// ChunkMarkdown chunks text using the default token estimate.
 func ChunkMarkdown(text string, maxTokens, overlap int) []ChunkResult {
-	var results []ChunkResult
+	return defaultChunker.ChunkMarkdown(text, maxTokens, overlap)
+}
 
-	// Regex for code fences: ```lang\n content \n```
-	// We use (?s) to allow . to match newlines
-	// Safe regex using character classes to avoid escape hell in Go strings
-	re := regexp.MustCompile("(?s)```([a-zA-Z0-9_]+)?[[:space:]]*\\n(.*?)\\n[[:space:]]*```")
+// ChunkMarkdown splits text into chunks, preserving fenced and indented code
+// blocks and identifying their language.
+// It also splits large prose blocks into smaller chunks, repeating up to
+// overlap tokens of each chunk at the start of the next.
+func (c *Chunker) ChunkMarkdown(text string, maxTokens, overlap int) []ChunkResult {
+	var results []ChunkResult
+	path := &headingPath{}
 
 	lastIndex := 0
-	matches := re.FindAllStringSubmatchIndex(text, -1)
-
-	for _, match := range matches {
+	for _, block := range scanCodeBlocks(text) {
 		// 1. Prose before the code block
-		if match[0] > lastIndex {
-			prose := strings.TrimSpace(text[lastIndex:match[0]])
+		if block.Start > lastIndex {
+			prose := strings.TrimSpace(text[lastIndex:block.Start])
 			if len(prose) > 0 {
-				proseChunks := chunkProse(prose, maxTokens, overlap)
+				proseChunks := c.chunkProse(prose, maxTokens, overlap, path)
 				results = append(results, proseChunks...)
 			}
 		}
+		lastIndex = block.End
 
 		// 2. The code block itself
-		lang := ""
-		if match[2] != -1 {
-			lang = text[match[2]:match[3]]
-		}
-		content := text[match[4]:match[5]]
-
-		cType := ChunkTypeCode
-		if lang == "yaml" || lang == "json" || lang == "toml" {
-			cType = ChunkTypeConfig
-		} else if lang == "bash" || lang == "sh" || lang == "shell" {
-			cType = ChunkTypeCmd
-		} else if lang == "http" || lang == "graphql" || lang == "openapi" || lang == "swagger" {
-			cType = ChunkTypeAPI
+		lang, content := block.Lang, block.Content
+		if strings.TrimSpace(content) == "" {
+			continue
 		}
 
-		// Estimate tokens (approx 4 chars per token)
-		estimatedTokens := len(content) / 4
-		if estimatedTokens > maxTokens {
-			codeChunks := chunkCode(content, lang, cType, maxTokens)
+		cType := codeChunkType(lang)
+		if c.tok.Count(content) > maxTokens {
+			codeChunks := c.chunkCode(content, lang, cType, maxTokens)
+			for i := range codeChunks {
+				codeChunks[i].Section = path.String()
+			}
 			results = append(results, codeChunks...)
 		} else {
-			fullBlock := "```" + lang + "\n" + content + "\n```"
+			symbol := ""
+			if cType == ChunkTypeCode {
+				symbol = codeSymbols(content, lang)
+			}
 			results = append(results, ChunkResult{
-				Content:  fullBlock,
+				Content:  wrapFence(lang, content),
 				Type:     cType,
 				Language: lang,
+				Symbol:   symbol,
+				Section:  path.String(),
 			})
 		}
-
-		lastIndex = match[1]
 	}
 
 	// 3. Remaining prose after the last code block
 	if lastIndex < len(text) {
 		prose := strings.TrimSpace(text[lastIndex:])
 		if len(prose) > 0 {
-			proseChunks := chunkProse(prose, maxTokens, overlap)
+			proseChunks := c.chunkProse(prose, maxTokens, overlap, path)
 			results = append(results, proseChunks...)
 		}
 	}
@@ -90,22 +134,44 @@ func ChunkMarkdown(text string, maxTokens, overlap int) []ChunkResult {
 	return results
 }
 
-// chunkProse splits prose into chunks respecting structure: Headers -> Paragraphs -> Lines -> Words
-func chunkProse(text string, maxTokens, overlap int) []ChunkResult {
+func codeChunkType(lang string) ChunkType {
+	switch strings.ToLower(lang) {
+	case "yaml", "yml", "json", "toml":
+		return ChunkTypeConfig
+	case "bash", "sh", "shell", "zsh", "console":
+		return ChunkTypeCmd
+	case "http", "graphql", "openapi", "swagger":
+		return ChunkTypeAPI
+	}
+	return ChunkTypeCode
+}
+
+// chunkProse splits prose into chunks respecting structure: Headers -> Blocks -> Lines -> Words -> Characters.
+// Tables are split by rows with their header repeated, lists by items, and
+// admonitions are kept whole whenever they fit.
+// Overlap is applied between chunks of the same section, never across headers
+// or from the tail of a table, list or admonition.
+// path carries the heading hierarchy across calls and may be nil.
+func (c *Chunker) chunkProse(text string, maxTokens, overlap int, path *headingPath) []ChunkResult {
 	if text == "" {
 		return nil
 	}
-	
-	// Approx chars per token
-	maxChars := maxTokens * 4
-	
+	if path == nil {
+		path = &headingPath{}
+	}
+
+	// Overlap must leave room for new content
+	if overlap > maxTokens/2 {
+		overlap = maxTokens / 2
+	}
+
 	// 1. Split by Headers (level 1-6)
 	headerRe := regexp.MustCompile(`(?m)^#{1,6}\s`)
 	headerIndices := headerRe.FindAllStringIndex(text, -1)
-	
+
 	var sections []string
 	lastIdx := 0
-	
+
 	for _, loc := range headerIndices {
 		if loc[0] > lastIdx {
 			sections = append(sections, text[lastIdx:loc[0]])
@@ -115,134 +181,200 @@ func chunkProse(text string, maxTokens, overlap int) []ChunkResult {
 	if lastIdx < len(text) {
 		sections = append(sections, text[lastIdx:])
 	}
-	
+
 	var chunks []ChunkResult
-	
+
 	for _, section := range sections {
 		section = strings.TrimSpace(section)
 		if len(section) == 0 {
 			continue
 		}
-		
-		if len(section) <= maxChars {
-			chunks = append(chunks, ChunkResult{Content: section, Type: detectChunkType(section)})
+
+		firstLine, _, _ := strings.Cut(section, "\n")
+		path.update(firstLine)
+		breadcrumb := path.String()
+
+		if c.tok.Count(section) <= maxTokens {
+			chunks = append(chunks, ChunkResult{Content: section, Type: detectChunkType(section), Section: breadcrumb})
 			continue
 		}
-		
-		// 2. Split by Paragraphs
-		paragraphs := strings.Split(section, "\n\n")
-		var currentChunk strings.Builder
-		
-		for _, para := range paragraphs {
-			para = strings.TrimSpace(para)
-			if len(para) == 0 {
-				continue
-			}
-		
-			// If paragraph fits in current chunk
-			if currentChunk.Len() + len(para) + 2 <= maxChars {
-				if currentChunk.Len() > 0 {
-					currentChunk.WriteString("\n\n")
-				}
-				currentChunk.WriteString(para)
-			} else {
-				// Flush current chunk if not empty
-				if currentChunk.Len() > 0 {
-					chunks = append(chunks, ChunkResult{Content: currentChunk.String(), Type: detectChunkType(currentChunk.String())})
-					currentChunk.Reset()
+
+		// 2. Split by Blocks (and finer, see addUnit)
+		b := &proseBuilder{tok: c.tok, maxTokens: maxTokens, overlap: overlap}
+		for _, blk := range splitBlocks(section) {
+			fits := c.tok.Count(blk.Content) <= maxTokens
+			switch {
+			case blk.Kind == blockTable && !fits:
+				// Keep a pending heading with the first part of the table
+				heading := b.pendingHeading()
+				b.flush()
+				b.reset()
+				tableChunks := c.chunkTable(blk.Content, maxTokens-c.tok.Count(heading))
+				if heading != "" {
+					tableChunks[0].Content = heading + "\n\n" + tableChunks[0].Content
 				}
-				
-				// Handle large paragraph
-				if len(para) > maxChars {
-					// 3. Split by Lines
-					lines := strings.Split(para, "\n")
-					for _, line := range lines {
-						if currentChunk.Len() + len(line) + 1 <= maxChars {
-							if currentChunk.Len() > 0 {
-								currentChunk.WriteString("\n")
-							}
-							currentChunk.WriteString(line)
-						} else {
-							if currentChunk.Len() > 0 {
-								chunks = append(chunks, ChunkResult{Content: currentChunk.String(), Type: detectChunkType(currentChunk.String())})
-								currentChunk.Reset()
-							}
-							
-							// 4. Split by Words (Fallback)
-							if len(line) > maxChars {
-								words := strings.Fields(line)
-								for _, word := range words {
-									if currentChunk.Len() + len(word) + 1 <= maxChars {
-										if currentChunk.Len() > 0 {
-											currentChunk.WriteString(" ")
-										}
-										currentChunk.WriteString(word)
-									} else {
-										chunks = append(chunks, ChunkResult{Content: currentChunk.String(), Type: detectChunkType(currentChunk.String())})
-										currentChunk.Reset()
-										currentChunk.WriteString(word)
-									}
-								}
-							} else {
-								currentChunk.WriteString(line)
-							}
-						}
+				b.chunks = append(b.chunks, tableChunks...)
+			case blk.Kind == blockList && !fits:
+				for i, item := range splitListItems(blk.Content) {
+					sep := proseSeps[1]
+					if i == 0 {
+						sep = proseSeps[0]
 					}
-				} else {
-					currentChunk.WriteString(para)
+					// Items only split further when a single item is too large
+					b.addUnit(item, 1, sep)
+					b.noTail = true
 				}
+			default:
+				b.addUnit(blk.Content, 0, proseSeps[0])
+			}
+			// Overlap only carries running text, not the tail of a structure
+			if blk.Kind != blockParagraph {
+				b.noTail = true
 			}
 		}
-		
-		if currentChunk.Len() > 0 {
-			chunks = append(chunks, ChunkResult{Content: currentChunk.String(), Type: detectChunkType(currentChunk.String())})
+		for _, chunk := range b.finish() {
+			chunk.Section = breadcrumb
+			chunks = append(chunks, chunk)
 		}
 	}
-	
+
 	return chunks
 }
 
-// chunkCode splits a large code block into smaller chunks by line
-func chunkCode(content, lang string, cType ChunkType, maxTokens int) []ChunkResult {
-	lines := strings.Split(content, "\n")
-	var chunks []ChunkResult
-	
-	charsPerToken := 4
-	maxChars := maxTokens * charsPerToken
-	
-	var currentChunk strings.Builder
-	currentLen := 0
-	
-	for _, line := range lines {
-		lineLen := len(line) + 1 
-		
-		if currentLen + lineLen > maxChars && currentLen > 0 {
-			chunks = append(chunks, ChunkResult{
-				Content:  "```" + lang + "\n" + currentChunk.String() + "\n```",
-				Type:     cType,
-				Language: lang,
-			})
-			currentChunk.Reset()
-			currentLen = 0
+// Prose split levels: paragraphs, lines, words, characters.
+var proseSeps = []string{"\n\n", "\n", " ", ""}
+
+func splitProse(text string, level int) []string {
+	var parts []string
+	switch level {
+	case 0, 1:
+		for _, p := range strings.Split(text, proseSeps[level]) {
+			if p = strings.TrimSpace(p); p != "" {
+				parts = append(parts, p)
+			}
 		}
-		
-		currentChunk.WriteString(line)
-		currentChunk.WriteString("\n")
-		currentLen += lineLen
-	}
-	
-	if currentLen > 0 {
-		chunks = append(chunks, ChunkResult{
-			Content:  "```" + lang + "\n" + currentChunk.String() + "\n```",
-			Type:     cType,
-			Language: lang,
-		})
-	}
-	
-	return chunks
+	case 2:
+		parts = strings.Fields(text)
+	default:
+		for _, r := range text {
+			parts = append(parts, string(r))
+		}
+	}
+	return parts
+}
+
+// proseBuilder accumulates prose units into chunks of at most maxTokens,
+// seeding each new chunk with the tail of the previous one.
+type proseBuilder struct {
+	tok       Tokenizer
+	maxTokens int
+	overlap   int
+
+	buf    strings.Builder
+	tokens int
+	seeded bool // buf only holds overlap from the previous chunk
+	noTail bool // last unit was a structural block, don't repeat it as overlap
+	chunks []ChunkResult
+}
+
+// addUnit appends a unit of the given split level, joined with sep, and
+// descends to a finer level when the unit alone exceeds the limit.
+func (b *proseBuilder) addUnit(unit string, level int, sep string) {
+	n := b.tok.Count(unit)
+	if n > b.maxTokens && level < len(proseSeps)-1 {
+		for i, sub := range splitProse(unit, level+1) {
+			if i > 0 {
+				sep = proseSeps[level+1]
+			}
+			b.addUnit(sub, level+1, sep)
+		}
+		return
+	}
+
+	if b.tokens+n > b.maxTokens {
+		b.flush()
+	}
+	if b.tokens+n > b.maxTokens {
+		// Overlap and unit don't fit together; the unit wins.
+		b.reset()
+	}
+
+	if b.buf.Len() > 0 {
+		b.buf.WriteString(sep)
+	}
+	b.buf.WriteString(unit)
+	b.tokens += n
+	b.seeded = false
+	b.noTail = false
+}
+
+// pendingHeading returns the buffer if it holds nothing but a heading line.
+func (b *proseBuilder) pendingHeading() string {
+	content := b.buf.String()
+	if b.seeded || strings.Contains(content, "\n") || !headingRe.MatchString(content) {
+		return ""
+	}
+	b.reset()
+	return content
+}
+
+func (b *proseBuilder) flush() {
+	if b.seeded || b.buf.Len() == 0 {
+		return
+	}
+	content := b.buf.String()
+	b.chunks = append(b.chunks, ChunkResult{Content: content, Type: detectChunkType(content)})
+
+	noTail := b.noTail
+	b.reset()
+	if noTail {
+		return
+	}
+	if tail := overlapTail(content, b.overlap, b.tok); tail != "" {
+		b.buf.WriteString(tail)
+		b.tokens = b.tok.Count(tail)
+		b.seeded = true
+	}
+}
+
+func (b *proseBuilder) reset() {
+	b.buf.Reset()
+	b.tokens = 0
+	b.seeded = false
+	b.noTail = false
+}
+
+func (b *proseBuilder) finish() []ChunkResult {
+	b.flush()
+	return b.chunks
+}
+
+// overlapTail returns the trailing words of s worth at most n tokens. It
+// returns "" rather than repeating a whole chunk.
+func overlapTail(s string, n int, tok Tokenizer) string {
+	if n <= 0 {
+		return ""
+	}
+	words := strings.Fields(s)
+	start, used := len(words), 0
+	for start > 0 {
+		wt := tok.Count(words[start-1])
+		if used+wt > n {
+			break
+		}
+		used += wt
+		start--
+	}
+	if start == 0 || start == len(words) {
+		return ""
+	}
+	return strings.Join(words[start:], " ")
 }
 
 func detectChunkType(content string) ChunkType {
+	if isTable(content) {
+		return ChunkTypeTable
+	}
 	lower := strings.ToLower(content)
 	if strings.Contains(lower, "swagger") || strings.Contains(lower, "openapi") {
 		return ChunkTypeAPI
@@ -1,11 +1,19 @@
 package text
 
 import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
 func TestChunkMarkdown(t *testing.T) {
 	t.Run("Basic Prose", func(t *testing.T) {
 		text := "This is a simple paragraph."
@@ -65,7 +73,7 @@ func TestChunkMarkdown(t *testing.T) {
 func TestChunkProse(t *testing.T) {
 	t.Run("Headers Split", func(t *testing.T) {
 		text := "# Header 1\nContent 1\n## Header 2\nContent 2"
-		chunks := chunkProse(text, 100, 0)
+		chunks := defaultChunker.chunkProse(text, 100, 0, nil)
 		assert.Len(t, chunks, 2)
 		assert.Contains(t, chunks[0].Content, "Header 1")
 		assert.Contains(t, chunks[1].Content, "Header 2")
@@ -80,7 +88,7 @@ func TestChunkProse(t *testing.T) {
 		// If maxTokens is small enough to force split
 		// "Short paragraph." (16) -> Chunk 1
 		// "Another short paragraph." (24) -> Split to "Another short" (13) and "paragraph." (10)
-		chunks := chunkProse(text, 5, 0) // Very small limit (approx 20 chars)
+		chunks := defaultChunker.chunkProse(text, 5, 0, nil) // Very small limit (approx 20 chars)
 		assert.Len(t, chunks, 3)
 	})
 
@@ -90,20 +98,144 @@ func TestChunkProse(t *testing.T) {
 		line2 := "Line 2 is also long."
 		text := line1 + "\n" + line2
 		
-		chunks := chunkProse(text, 5, 0)
+		chunks := defaultChunker.chunkProse(text, 5, 0, nil)
 		assert.True(t, len(chunks) >= 2)
 	})
 	
 	t.Run("Word Split", func(t *testing.T) {
 		// Very long line
 		text := "VeryLongWordThatExceedsLimit AnotherWord"
-		chunks := chunkProse(text, 2, 0) // ~8 chars
+		chunks := defaultChunker.chunkProse(text, 2, 0, nil) // ~8 chars
 		assert.True(t, len(chunks) >= 2)
 	})
 }
 
+func TestChunkProse_Overlap(t *testing.T) {
+	c := NewChunker(wordTokenizer{})
+	text := "w1 w2 w3 w4 w5 w6 w7 w8 w9 w10"
+
+	chunks := c.chunkProse(text, 4, 1, nil)
+	assert.Equal(t, []string{"w1 w2 w3 w4", "w4 w5 w6 w7", "w7 w8 w9 w10"}, contents(chunks))
+
+	// Without overlap nothing is repeated
+	chunks = c.chunkProse(text, 4, 0, nil)
+	assert.Equal(t, []string{"w1 w2 w3 w4", "w5 w6 w7 w8", "w9 w10"}, contents(chunks))
+
+	// Overlap never crosses a header
+	chunks = c.chunkProse("# A\nw1 w2 w3 w4 w5\n# B\nw6", 4, 1, nil)
+	assert.Equal(t, []string{"# A\nw1 w2", "w2 w3 w4 w5", "# B\nw6"}, contents(chunks))
+}
+
+func TestChunkProse_CJKWithoutSpaces(t *testing.T) {
+	// 12 CJK characters without whitespace still split under a 5 token limit
+	chunks := defaultChunker.chunkProse("日本語のテキストを分割する", 5, 0, nil)
+	assert.True(t, len(chunks) >= 3)
+	for _, c := range chunks {
+		assert.LessOrEqual(t, EstimateTokenizer{}.Count(c.Content), 5)
+	}
+}
+
+func contents(chunks []ChunkResult) []string {
+	var out []string
+	for _, c := range chunks {
+		out = append(out, c.Content)
+	}
+	return out
+}
+
+func TestChunkMarkdown_SectionBreadcrumbs(t *testing.T) {
+	text := `# Webhooks
+Intro.
+
+## Signatures
+About signatures.
+
+### Verifying
+Compute the HMAC:
+
+` + "```go\nfunc Verify() {}\n```" + `
+
+## Retries ##
+Retried three times.`
+
+	chunks := ChunkMarkdown(text, 100, 0)
+	var sections []string
+	for _, c := range chunks {
+		sections = append(sections, c.Section)
+	}
+	assert.Equal(t, []string{
+		"Webhooks",
+		"Webhooks > Signatures",
+		"Webhooks > Signatures > Verifying",
+		"Webhooks > Signatures > Verifying", // code block inherits the open section
+		"Webhooks > Retries",                // closing hashes stripped, deeper level cleared
+	}, sections)
+}
+
+func TestChunkMarkdown_NoHeadings(t *testing.T) {
+	chunks := ChunkMarkdown("Just text.", 100, 0)
+	assert.Equal(t, "", chunks[0].Section)
+}
+
 func TestDetectChunkType(t *testing.T) {
 	assert.Equal(t, ChunkTypeAPI, detectChunkType("Swagger API Definition"))
 	assert.Equal(t, ChunkTypeAPI, detectChunkType("API Endpoint URL Method"))
 	assert.Equal(t, ChunkTypeProse, detectChunkType("Just some text"))
+	assert.Equal(t, ChunkTypeTable, detectChunkType("## Codes\n| a | b |\n|---|---|\n| 1 | 2 |"))
+}
+
+// TestChunkMarkdown_Golden chunks each testdata/chunker/*.md with a small
+// limit and compares against the matching .golden file. Run with -update
+// to regenerate.
+func TestChunkMarkdown_Golden(t *testing.T) {
+	inputs, err := filepath.Glob("testdata/chunker/*.md")
+	require.NoError(t, err)
+	require.NotEmpty(t, inputs)
+
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".md")
+		t.Run(name, func(t *testing.T) {
+			src, err := os.ReadFile(input)
+			require.NoError(t, err)
+
+			var got strings.Builder
+			for i, c := range ChunkMarkdown(string(src), 60, 10) {
+				fmt.Fprintf(&got, "--- chunk %d type=%s section=%q ---\n%s\n", i, c.Type, c.Section, c.Content)
+			}
+
+			golden := strings.TrimSuffix(input, ".md") + ".golden"
+			if *update {
+				require.NoError(t, os.WriteFile(golden, []byte(got.String()), 0o644))
+			}
+			want, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			assert.Equal(t, string(want), got.String())
+		})
+	}
+}
+
+func TestSplitBlocks(t *testing.T) {
+	section := "Intro line.\n- a\n- b\n  more b\n\n- c\n\n| h |\n|---|\n| r |\n\n!!! note\n    body\n\n    more\nAfter."
+	var kinds []blockKind
+	for _, b := range splitBlocks(section) {
+		kinds = append(kinds, b.Kind)
+	}
+	assert.Equal(t, []blockKind{blockParagraph, blockList, blockTable, blockAdmonition, blockParagraph}, kinds)
+}
+
+func TestChunkTable_RepeatsHeader(t *testing.T) {
+	c := NewChunker(wordTokenizer{})
+	table := "| a | b |\n|---|---|\n| 1 | 2 |\n| 3 | 4 |\n| 5 | 6 |"
+
+	chunks := c.chunkTable(table, 20)
+	assert.Len(t, chunks, 2)
+	for _, chunk := range chunks {
+		assert.True(t, strings.HasPrefix(chunk.Content, "| a | b |\n|---|---|\n"))
+		assert.Equal(t, ChunkTypeTable, chunk.Type)
+	}
+}
+
+func TestSplitListItems(t *testing.T) {
+	items := splitListItems("- one\n  continued\n  - nested\n- two")
+	assert.Equal(t, []string{"- one\n  continued\n  - nested", "- two"}, items)
 }
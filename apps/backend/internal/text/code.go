@@ -0,0 +1,375 @@
+package text
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strings"
+)
+
+// codeSegment is one top-level declaration of a code block, including its
+// doc comment and everything up to the next declaration.
+type codeSegment struct {
+	Content string
+	Symbol  string
+}
+
+// splitDeclarations splits code at top-level declaration boundaries. Go is
+// parsed with go/parser; everything else goes through a lightweight scanner
+// that tracks strings, comments and bracket depth. It returns nil when no
+// declaration could be found.
+func splitDeclarations(content, lang string) []codeSegment {
+	lang = strings.ToLower(lang)
+	if lang == "go" || lang == "golang" {
+		if segs := splitGoDeclarations(content); segs != nil {
+			return segs
+		}
+	}
+	return splitGenericDeclarations(content, lang)
+}
+
+// codeSymbols returns the declared symbol names of a code block, comma separated.
+func codeSymbols(content, lang string) string {
+	var symbols []string
+	for _, seg := range splitDeclarations(content, lang) {
+		if seg.Symbol != "" {
+			symbols = append(symbols, seg.Symbol)
+		}
+	}
+	return strings.Join(symbols, ", ")
+}
+
+// chunkCode splits a large code block at declaration boundaries, packing
+// small declarations together. Declarations that are still too large fall
+// back to line-based splitting and keep their symbol name.
+func (c *Chunker) chunkCode(content, lang string, cType ChunkType, maxTokens int) []ChunkResult {
+	segs := splitDeclarations(content, lang)
+	if len(segs) == 0 {
+		return c.chunkCodeByLines(content, lang, cType, maxTokens, "")
+	}
+
+	var chunks []ChunkResult
+	var current strings.Builder
+	currentTokens := 0
+	var symbols []string
+
+	flush := func() {
+		body := strings.TrimRight(current.String(), "\n")
+		if strings.TrimSpace(body) != "" {
+			chunks = append(chunks, ChunkResult{
+				Content:  wrapFence(lang, body),
+				Type:     cType,
+				Language: lang,
+				Symbol:   strings.Join(symbols, ", "),
+			})
+		}
+		current.Reset()
+		currentTokens = 0
+		symbols = nil
+	}
+
+	for _, seg := range segs {
+		if strings.TrimSpace(seg.Content) == "" {
+			continue
+		}
+		n := c.tok.Count(seg.Content)
+		if n > maxTokens {
+			flush()
+			chunks = append(chunks, c.chunkCodeByLines(strings.TrimRight(seg.Content, "\n"), lang, cType, maxTokens, seg.Symbol)...)
+			continue
+		}
+		if current.Len() > 0 && currentTokens+n > maxTokens {
+			flush()
+		}
+		current.WriteString(seg.Content)
+		currentTokens += n
+		if seg.Symbol != "" {
+			symbols = append(symbols, seg.Symbol)
+		}
+	}
+	flush()
+
+	return chunks
+}
+
+// chunkCodeByLines splits a code block into smaller chunks by line
+func (c *Chunker) chunkCodeByLines(content, lang string, cType ChunkType, maxTokens int, symbol string) []ChunkResult {
+	lines := strings.Split(content, "\n")
+	var chunks []ChunkResult
+
+	var currentChunk strings.Builder
+	currentLen := 0
+
+	for _, line := range lines {
+		lineLen := c.tok.Count(line) + 1 // newline
+
+		if currentLen+lineLen > maxTokens && currentLen > 0 {
+			chunks = append(chunks, ChunkResult{
+				Content:  wrapFence(lang, currentChunk.String()),
+				Type:     cType,
+				Language: lang,
+				Symbol:   symbol,
+			})
+			currentChunk.Reset()
+			currentLen = 0
+		}
+
+		currentChunk.WriteString(line)
+		currentChunk.WriteString("\n")
+		currentLen += lineLen
+	}
+
+	if currentLen > 0 {
+		chunks = append(chunks, ChunkResult{
+			Content:  wrapFence(lang, currentChunk.String()),
+			Type:     cType,
+			Language: lang,
+			Symbol:   symbol,
+		})
+	}
+
+	return chunks
+}
+
+// --- Go ---
+
+func splitGoDeclarations(content string) []codeSegment {
+	fset := token.NewFileSet()
+	src, shift := content, 0
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		// Documentation snippets usually omit the package clause.
+		const header = "package snippet\n"
+		src, shift = header+content, len(header)
+		fset = token.NewFileSet()
+		if file, err = parser.ParseFile(fset, "", src, parser.ParseComments); err != nil {
+			return nil
+		}
+	}
+
+	tf := fset.File(file.Pos())
+	var starts []int
+	var symbols []string
+	for _, decl := range file.Decls {
+		pos := decl.Pos()
+		name := ""
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Doc != nil {
+				pos = d.Doc.Pos()
+			}
+			name = goFuncName(d)
+		case *ast.GenDecl:
+			if d.Doc != nil {
+				pos = d.Doc.Pos()
+			}
+			name = goGenDeclName(d)
+		}
+		offset := tf.Offset(pos) - shift
+		if offset < 0 {
+			offset = 0
+		}
+		starts = append(starts, offset)
+		symbols = append(symbols, name)
+	}
+
+	return segmentsFromStarts(content, starts, symbols)
+}
+
+func goFuncName(d *ast.FuncDecl) string {
+	if d.Recv == nil || len(d.Recv.List) == 0 {
+		return d.Name.Name
+	}
+	expr := d.Recv.List[0].Type
+	for {
+		switch t := expr.(type) {
+		case *ast.StarExpr:
+			expr = t.X
+			continue
+		case *ast.IndexExpr:
+			expr = t.X
+			continue
+		case *ast.IndexListExpr:
+			expr = t.X
+			continue
+		case *ast.Ident:
+			return t.Name + "." + d.Name.Name
+		}
+		return d.Name.Name
+	}
+}
+
+func goGenDeclName(d *ast.GenDecl) string {
+	var names []string
+	for _, spec := range d.Specs {
+		switch s := spec.(type) {
+		case *ast.TypeSpec:
+			names = append(names, s.Name.Name)
+		case *ast.ValueSpec:
+			for _, n := range s.Names {
+				if n.Name != "_" {
+					names = append(names, n.Name)
+				}
+			}
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// --- Other languages ---
+
+// declRe matches a declaration that starts at column 0, e.g. "def foo",
+// "export async function bar", "pub struct Baz" or "public class Qux".
+var declRe = regexp.MustCompile(`^(?:export\s+)?(?:default\s+)?(?:pub(?:\([a-z]+\))?\s+)?(?:(?:public|private|protected|internal|static|abstract|final|sealed|data|async|unsafe)\s+)*(def|class|function\*?|func|fn|struct|enum|trait|impl|interface|type|module|object|record|namespace|const|let|var)\s+(?:\([^)]*\)\s*)?([A-Za-z_$][\w$]*)`)
+
+// hashCommentLangs use '#' for line comments.
+var hashCommentLangs = map[string]bool{
+	"python": true, "py": true, "ruby": true, "rb": true, "perl": true,
+	"r": true, "elixir": true, "ex": true, "sh": true, "bash": true, "shell": true,
+}
+
+func splitGenericDeclarations(content, lang string) []codeSegment {
+	lines := strings.Split(content, "\n")
+	hashComments := hashCommentLangs[lang]
+
+	var starts []int
+	var symbols []string
+
+	depth := 0
+	inBlockComment := false
+	multiline := "" // terminator of an open multi-line string
+	lastStartLine := -1
+	offset := 0
+	lineOffsets := make([]int, len(lines))
+
+	for i, line := range lines {
+		lineOffsets[i] = offset
+		offset += len(line) + 1
+
+		if depth == 0 && !inBlockComment && multiline == "" {
+			if m := declRe.FindStringSubmatch(line); m != nil {
+				// Keep doc comments, decorators and attributes attached.
+				start := i
+				for start-1 > lastStartLine && isDocLine(lines[start-1], hashComments) {
+					start--
+				}
+				starts = append(starts, lineOffsets[start])
+				symbols = append(symbols, m[2])
+				lastStartLine = i
+			}
+		}
+
+		depth, inBlockComment, multiline = scanLine(line, depth, inBlockComment, multiline, hashComments)
+	}
+
+	return segmentsFromStarts(content, starts, symbols)
+}
+
+// scanLine advances the scanner state over one line.
+func scanLine(line string, depth int, inBlockComment bool, multiline string, hashComments bool) (int, bool, string) {
+	for i := 0; i < len(line); i++ {
+		switch {
+		case inBlockComment:
+			if strings.HasPrefix(line[i:], "*/") {
+				inBlockComment = false
+				i++
+			}
+		case multiline != "":
+			if line[i] == '\\' {
+				i++
+			} else if strings.HasPrefix(line[i:], multiline) {
+				i += len(multiline) - 1
+				multiline = ""
+			}
+		case strings.HasPrefix(line[i:], "//"):
+			return depth, inBlockComment, multiline
+		case hashComments && line[i] == '#':
+			return depth, inBlockComment, multiline
+		case strings.HasPrefix(line[i:], "/*"):
+			inBlockComment = true
+			i++
+		case strings.HasPrefix(line[i:], `"""`), strings.HasPrefix(line[i:], "'''"):
+			multiline = line[i : i+3]
+			i += 2
+		case line[i] == '`':
+			multiline = "`"
+		case line[i] == '"' || line[i] == '\'':
+			// Single-line string; an unterminated quote (e.g. a Rust
+			// lifetime) simply ends at the line break.
+			quote := line[i]
+			for i++; i < len(line) && line[i] != quote; i++ {
+				if line[i] == '\\' {
+					i++
+				}
+			}
+		case line[i] == '{' || line[i] == '(' || line[i] == '[':
+			depth++
+		case line[i] == '}' || line[i] == ')' || line[i] == ']':
+			if depth > 0 {
+				depth--
+			}
+		}
+	}
+	return depth, inBlockComment, multiline
+}
+
+func isDocLine(line string, hashComments bool) bool {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" {
+		return false
+	}
+	if hashComments && strings.HasPrefix(trimmed, "#") {
+		return true
+	}
+	for _, prefix := range []string{"//", "/*", "*", "@", "#["} {
+		if strings.HasPrefix(trimmed, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// segmentsFromStarts cuts content at the given byte offsets (snapped to line
+// starts). Text before the first declaration becomes an unnamed segment.
+func segmentsFromStarts(content string, starts []int, symbols []string) []codeSegment {
+	if len(starts) == 0 {
+		return nil
+	}
+
+	var cuts []int
+	var names []string
+	for i, start := range starts {
+		start = strings.LastIndex(content[:start], "\n") + 1
+		if len(cuts) > 0 && start <= cuts[len(cuts)-1] {
+			// Several declarations on one line share a segment.
+			names[len(names)-1] = joinSymbol(names[len(names)-1], symbols[i])
+			continue
+		}
+		cuts = append(cuts, start)
+		names = append(names, symbols[i])
+	}
+
+	var segs []codeSegment
+	if cuts[0] > 0 {
+		segs = append(segs, codeSegment{Content: content[:cuts[0]]})
+	}
+	for i, start := range cuts {
+		end := len(content)
+		if i+1 < len(cuts) {
+			end = cuts[i+1]
+		}
+		segs = append(segs, codeSegment{Content: content[start:end], Symbol: names[i]})
+	}
+	return segs
+}
+
+func joinSymbol(a, b string) string {
+	if a == "" {
+		return b
+	}
+	if b == "" {
+		return a
+	}
+	return a + ", " + b
+}
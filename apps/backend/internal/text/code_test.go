@@ -0,0 +1,146 @@
+package text
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const goSnippet = `package server
+
+import "net/http"
+
+// Server serves HTTP.
+type Server struct {
+	mux *http.ServeMux
+}
+
+// New creates a Server.
+func New() *Server {
+	return &Server{mux: http.NewServeMux()}
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+`
+
+func TestSplitDeclarations_Go(t *testing.T) {
+	segs := splitDeclarations(goSnippet, "go")
+
+	var symbols []string
+	for _, s := range segs {
+		symbols = append(symbols, s.Symbol)
+	}
+	assert.Equal(t, []string{"", "", "Server", "New", "Server.ServeHTTP"}, symbols)
+
+	// Doc comments stay with their declaration
+	assert.True(t, strings.HasPrefix(segs[3].Content, "// New creates a Server.\nfunc New()"))
+	// Nothing is lost
+	var joined strings.Builder
+	for _, s := range segs {
+		joined.WriteString(s.Content)
+	}
+	assert.Equal(t, goSnippet, joined.String())
+}
+
+func TestSplitDeclarations_GoSnippetWithoutPackage(t *testing.T) {
+	src := "func A() {}\n\nfunc B() {}\n"
+	segs := splitDeclarations(src, "go")
+	assert.Len(t, segs, 2)
+	assert.Equal(t, "A", segs[0].Symbol)
+	assert.Equal(t, "B", segs[1].Symbol)
+}
+
+func TestSplitDeclarations_Python(t *testing.T) {
+	src := `import os
+
+# Loads config.
+@cache
+def load(path):
+    """Docstring with def inside"""
+    return open(path).read()
+
+class Config:
+    def get(self, key):
+        return "{"
+`
+	segs := splitDeclarations(src, "python")
+	assert.Len(t, segs, 3)
+	assert.Equal(t, "import os\n\n", segs[0].Content)
+	assert.Equal(t, "load", segs[1].Symbol)
+	assert.True(t, strings.HasPrefix(segs[1].Content, "# Loads config.\n@cache\ndef load"))
+	// Methods stay inside their class
+	assert.Equal(t, "Config", segs[2].Symbol)
+}
+
+func TestSplitDeclarations_BraceLanguage(t *testing.T) {
+	src := `/**
+ * Adds numbers.
+ */
+export function add(a, b) {
+  const s = "}"; // tricky
+  return a + b;
+}
+
+export class Calc {
+  run() { return add(1, 2); }
+}
+`
+	segs := splitDeclarations(src, "javascript")
+	assert.Len(t, segs, 2)
+	assert.Equal(t, "add", segs[0].Symbol)
+	assert.True(t, strings.HasPrefix(segs[0].Content, "/**"))
+	assert.Equal(t, "Calc", segs[1].Symbol)
+}
+
+func TestSplitDeclarations_NoDeclarations(t *testing.T) {
+	assert.Nil(t, splitDeclarations("x := 1\nfmt.Println(x)\n", "go"))
+	assert.Nil(t, splitDeclarations("echo hi", "txt"))
+}
+
+func TestChunkCode_KeepsFunctionsWhole(t *testing.T) {
+	// Each function is ~70 chars, limit is 25 tokens (~100 chars)
+	var b strings.Builder
+	for _, name := range []string{"Alpha", "Beta", "Gamma"} {
+		b.WriteString("func " + name + "() {\n\tfmt.Println(\"" + strings.Repeat("x", 40) + "\")\n}\n\n")
+	}
+
+	chunks := defaultChunker.chunkCode(b.String(), "go", ChunkTypeCode, 25)
+	assert.Len(t, chunks, 3)
+	for i, name := range []string{"Alpha", "Beta", "Gamma"} {
+		assert.Equal(t, name, chunks[i].Symbol)
+		assert.Contains(t, chunks[i].Content, "func "+name+"() {")
+		assert.True(t, strings.HasSuffix(chunks[i].Content, "}\n```"))
+	}
+}
+
+func TestChunkCode_PacksSmallDeclarations(t *testing.T) {
+	src := "func A() {}\n\nfunc B() {}\n\nfunc C() {}\n"
+	chunks := defaultChunker.chunkCode(src, "go", ChunkTypeCode, 100)
+	assert.Len(t, chunks, 1)
+	assert.Equal(t, "A, B, C", chunks[0].Symbol)
+}
+
+func TestChunkCode_OversizedDeclarationFallsBackToLines(t *testing.T) {
+	var body strings.Builder
+	body.WriteString("func Big() {\n")
+	for i := 0; i < 20; i++ {
+		body.WriteString("\tdoSomething()\n")
+	}
+	body.WriteString("}\n")
+
+	chunks := defaultChunker.chunkCode(body.String(), "go", ChunkTypeCode, 20)
+	assert.True(t, len(chunks) > 1)
+	for _, c := range chunks {
+		assert.Equal(t, "Big", c.Symbol)
+	}
+}
+
+func TestChunkMarkdown_SmallCodeBlockSymbol(t *testing.T) {
+	chunks := ChunkMarkdown("```python\ndef hello():\n    pass\n```", 100, 0)
+	assert.Len(t, chunks, 1)
+	assert.Equal(t, "hello", chunks[0].Symbol)
+}
@@ -0,0 +1,225 @@
+package text
+
+import (
+	"encoding/json"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// codeBlock is a fenced or indented code block found by scanCodeBlocks.
+// Start and End are byte offsets of the whole block, fences included.
+type codeBlock struct {
+	Start, End int
+	Lang       string
+	Content    string
+}
+
+var (
+	// fenceOpenRe matches an opening fence: up to 3 spaces, 3+ backticks or
+	// tildes, then the info string.
+	fenceOpenRe = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})(.*)$")
+	// fenceCloseRe matches a closing fence candidate.
+	fenceCloseRe = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})[ \t]*$")
+)
+
+// scanCodeBlocks finds code blocks following CommonMark: fences of backticks
+// or tildes closed by a fence of the same character that is at least as long
+// (so ```` fences can wrap ``` examples), unterminated fences running to the
+// end of the text, and indented code blocks outside lists and admonitions.
+func scanCodeBlocks(text string) []codeBlock {
+	lines := strings.SplitAfter(text, "\n")
+	var blocks []codeBlock
+
+	offset := 0
+	prevBlank := true
+	container := false // inside a list or admonition, where indentation isn't code
+	for i := 0; i < len(lines); {
+		line := strings.TrimRight(lines[i], "\r\n")
+		start := offset
+
+		if m := fenceOpenRe.FindStringSubmatch(line); m != nil && !(m[2][0] == '`' && strings.Contains(m[3], "`")) {
+			indent, fence, info := len(m[1]), m[2], strings.TrimSpace(m[3])
+			var body []string
+			end := start + len(lines[i])
+			j := i + 1
+			for ; j < len(lines); j++ {
+				l := strings.TrimRight(lines[j], "\r\n")
+				end += len(lines[j])
+				if c := fenceCloseRe.FindStringSubmatch(l); c != nil && c[1][0] == fence[0] && len(c[1]) >= len(fence) {
+					j++
+					break
+				}
+				body = append(body, trimIndent(l, indent))
+			}
+			content := strings.Join(body, "\n")
+			blocks = append(blocks, codeBlock{
+				Start:   start,
+				End:     end,
+				Lang:    inferLanguage(info, content),
+				Content: content,
+			})
+			offset = end
+			i = j
+			prevBlank, container = true, false
+			continue
+		}
+
+		blank := strings.TrimSpace(line) == ""
+		if !blank && isCodeIndented(line) && prevBlank && !container {
+			// Runs until the first non-blank line with less indentation;
+			// trailing blank lines aren't part of the block.
+			last := i
+			for j := i + 1; j < len(lines); j++ {
+				l := strings.TrimRight(lines[j], "\r\n")
+				if strings.TrimSpace(l) == "" {
+					continue
+				}
+				if !isCodeIndented(l) {
+					break
+				}
+				last = j
+			}
+			var body []string
+			end := start
+			for k := i; k <= last; k++ {
+				body = append(body, trimIndent(strings.TrimRight(lines[k], "\r\n"), 4))
+				end += len(lines[k])
+			}
+			content := strings.Join(body, "\n")
+			blocks = append(blocks, codeBlock{Start: start, End: end, Lang: inferLanguage("", content), Content: content})
+			offset = end
+			i = last + 1
+			prevBlank = false
+			continue
+		}
+
+		switch {
+		case blank:
+		case listItemRe.MatchString(line) || admonitionRe.MatchString(line):
+			container = true
+		case !isIndented(line, 2):
+			container = false
+		}
+		prevBlank = blank
+		offset += len(lines[i])
+		i++
+	}
+	return blocks
+}
+
+func isCodeIndented(line string) bool {
+	return strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t")
+}
+
+// trimIndent removes up to n leading spaces (or one tab).
+func trimIndent(line string, n int) string {
+	if strings.HasPrefix(line, "\t") && n > 0 {
+		return line[1:]
+	}
+	i := 0
+	for i < n && i < len(line) && line[i] == ' ' {
+		i++
+	}
+	return line[i:]
+}
+
+// wrapFence wraps code in a backtick fence long enough not to be closed by
+// any fence inside the code.
+func wrapFence(lang, code string) string {
+	longest := 0
+	for _, line := range strings.Split(code, "\n") {
+		t := strings.TrimLeft(line, " ")
+		n := len(t) - len(strings.TrimLeft(t, "`"))
+		if n > longest {
+			longest = n
+		}
+	}
+	fence := "```"
+	if longest >= 3 {
+		fence = strings.Repeat("`", longest+1)
+	}
+	return fence + lang + "\n" + code + "\n" + fence
+}
+
+var (
+	attrRe     = regexp.MustCompile(`(?:title|file|filename)=["']?([^"'\s}]+)`)
+	langWordRe = regexp.MustCompile(`^[A-Za-z0-9_+#.-]+$`)
+)
+
+// inferLanguage derives the language from a fence info string such as
+// `go title="main.go"`, `{.python}` or `title="app.ts"`, falling back to
+// content heuristics when the info string names none.
+func inferLanguage(info, content string) string {
+	if info != "" {
+		first := strings.Fields(info)[0]
+		switch {
+		case strings.HasPrefix(first, "{"):
+			// Pandoc/Quarto attributes: {.python .numberLines} or {python}
+			for _, f := range strings.Fields(strings.Trim(info, "{}")) {
+				f = strings.TrimPrefix(f, ".")
+				if langWordRe.MatchString(f) {
+					return f
+				}
+			}
+		case !strings.Contains(first, "="):
+			first = strings.TrimPrefix(first, "language-")
+			if lang, _, ok := strings.Cut(first, ","); ok {
+				first = lang
+			}
+			if langWordRe.MatchString(first) {
+				return first
+			}
+		}
+		if m := attrRe.FindStringSubmatch(info); m != nil {
+			if lang := extLanguages[strings.TrimPrefix(path.Ext(m[1]), ".")]; lang != "" {
+				return lang
+			}
+		}
+	}
+	return detectLanguage(content)
+}
+
+var extLanguages = map[string]string{
+	"go": "go", "py": "python", "js": "javascript", "mjs": "javascript", "jsx": "jsx",
+	"ts": "typescript", "tsx": "tsx", "rs": "rust", "rb": "ruby", "java": "java",
+	"kt": "kotlin", "swift": "swift", "c": "c", "h": "c", "cpp": "cpp", "cs": "csharp",
+	"php": "php", "sh": "bash", "bash": "bash", "yml": "yaml", "yaml": "yaml",
+	"json": "json", "toml": "toml", "sql": "sql", "html": "html", "css": "css",
+	"graphql": "graphql", "gql": "graphql", "proto": "protobuf", "tf": "hcl",
+}
+
+var languageHints = []struct {
+	lang string
+	re   *regexp.Regexp
+}{
+	{"go", regexp.MustCompile(`(?m)^package \w+$|^func (\(\w+ \*?\w+\) )?\w+\(`)},
+	{"bash", regexp.MustCompile(`(?m)^#!/bin/(ba)?sh|^\$ \w|^(sudo|npm|go|pip|docker|curl|git|make|cd|export) `)},
+	{"python", regexp.MustCompile(`(?m)^(def|class) \w+.*:\s*$|^from [\w.]+ import |^import \w+$`)},
+	{"rust", regexp.MustCompile(`(?m)^(pub )?fn \w+|let mut |^use \w+::`)},
+	{"php", regexp.MustCompile(`<\?php`)},
+	{"sql", regexp.MustCompile(`(?i)^\s*(SELECT .+ FROM|INSERT INTO|CREATE TABLE|UPDATE \w+ SET)`)},
+	{"html", regexp.MustCompile(`(?i)^\s*<(!doctype|html|div|head|body)`)},
+	{"java", regexp.MustCompile(`(?m)^(public |private )?(class|interface) \w+.*\{|public static void main`)},
+	{"typescript", regexp.MustCompile(`(?m)^(export )?(interface|type) \w+.*[={]|: (string|number|boolean)[;,)]`)},
+	{"javascript", regexp.MustCompile(`(?m)^(const|let|var) \w+ = |=> \{|^(export )?(async )?function \w+\(|require\(`)},
+	{"yaml", regexp.MustCompile(`(?m)\A(---\n)?([\w.-]+:( .+)?\n)+[\w.-]+:( .+)?\s*\z`)},
+}
+
+// detectLanguage guesses the language of unlabelled code from its content.
+// It returns "" when nothing is recognisable.
+func detectLanguage(content string) string {
+	trimmed := strings.TrimSpace(content)
+	if trimmed == "" {
+		return ""
+	}
+	if (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid([]byte(trimmed)) {
+		return "json"
+	}
+	for _, h := range languageHints {
+		if h.re.MatchString(trimmed) {
+			return h.lang
+		}
+	}
+	return ""
+}
@@ -0,0 +1,78 @@
+package text
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanCodeBlocks(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		lang    string
+		content string
+	}{
+		{"Backticks", "```go\nfunc main() {}\n```", "go", "func main() {}"},
+		{"Tildes", "~~~python\nprint(1)\n~~~", "python", "print(1)"},
+		{"Info String Attributes", "```go title=\"main.go\" {3}\nx := 1\n```", "go", "x := 1"},
+		{"Title Only", "```title=\"app.ts\"\nlet x = 1\n```", "typescript", "let x = 1"},
+		{"Pandoc Attributes", "```{.python .numberLines}\nx = 1\n```", "python", "x = 1"},
+		{"Hyphenated Language", "```shell-session\n$ ls\n```", "shell-session", "$ ls"},
+		{"Nested Fence", "````markdown\n```go\nfunc A() {}\n```\n````", "markdown", "```go\nfunc A() {}\n```"},
+		{"Tilde Wraps Backticks", "~~~\n```\n~~~", "", "```"},
+		{"Closing Fence Longer", "```sh\nls\n`````", "sh", "ls"},
+		{"Indented Opening", "  ```go\n  a()\n    b()\n  ```", "go", "a()\n  b()"},
+		{"Unterminated", "```go\nfunc A() {}\nfunc B() {}", "go", "func A() {}\nfunc B() {}"},
+		{"Indented Code", "Example:\n\n    $ go build ./...\n\n    $ go test ./...\n\nDone.", "bash", "$ go build ./...\n\n$ go test ./..."},
+		{"Inferred JSON", "```\n{\"a\": 1}\n```", "json", `{"a": 1}`},
+		{"Inferred Go", "```\npackage main\n\nfunc main() {}\n```", "go", "package main\n\nfunc main() {}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := scanCodeBlocks(tt.text)
+			if assert.Len(t, blocks, 1) {
+				assert.Equal(t, tt.lang, blocks[0].Lang)
+				assert.Equal(t, tt.content, blocks[0].Content)
+			}
+		})
+	}
+}
+
+func TestScanCodeBlocks_Offsets(t *testing.T) {
+	text := "Intro\n~~~yaml\na: 1\n~~~\nOutro"
+	blocks := scanCodeBlocks(text)
+	assert.Len(t, blocks, 1)
+	assert.Equal(t, "~~~yaml\na: 1\n~~~\n", text[blocks[0].Start:blocks[0].End])
+}
+
+func TestScanCodeBlocks_NotCode(t *testing.T) {
+	// Inline backticks in the info string don't open a fence
+	assert.Empty(t, scanCodeBlocks("``` not `a` fence"))
+	// Indentation continuing a paragraph, list item or admonition isn't code
+	assert.Empty(t, scanCodeBlocks("Paragraph\n    still paragraph"))
+	assert.Empty(t, scanCodeBlocks("- item\n\n    continued item"))
+	assert.Empty(t, scanCodeBlocks("!!! note\n    body"))
+}
+
+func TestInferLanguage(t *testing.T) {
+	assert.Equal(t, "rust", inferLanguage("", "fn main() {\n    let mut x = 1;\n}"))
+	assert.Equal(t, "python", inferLanguage("", "def handler(event):\n    return event"))
+	assert.Equal(t, "yaml", inferLanguage("", "name: qurio\nversion: 1"))
+	assert.Equal(t, "sql", inferLanguage("", "SELECT id FROM sources"))
+	assert.Equal(t, "", inferLanguage("", "just some words"))
+	assert.Equal(t, "diff", inferLanguage("language-diff", "+a"))
+}
+
+func TestWrapFence(t *testing.T) {
+	assert.Equal(t, "```go\nx\n```", wrapFence("go", "x"))
+	assert.Equal(t, "````md\n```go\nx\n```\n````", wrapFence("md", "```go\nx\n```"))
+}
+
+func TestChunkMarkdown_TildeFence(t *testing.T) {
+	chunks := ChunkMarkdown("Run it:\n\n~~~bash\nmake\n~~~", 100, 0)
+	assert.Len(t, chunks, 2)
+	assert.Equal(t, "```bash\nmake\n```", chunks[1].Content)
+	assert.Equal(t, ChunkTypeCmd, chunks[1].Type)
+}
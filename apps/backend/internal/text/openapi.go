@@ -0,0 +1,317 @@
+package text
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var httpMethods = map[string]bool{
+	"get": true, "put": true, "post": true, "delete": true,
+	"options": true, "head": true, "patch": true, "trace": true,
+}
+
+// schemaRefDepth limits how deep $refs are inlined into operation chunks.
+const schemaRefDepth = 3
+
+type openAPISpec struct {
+	root map[string]interface{}
+}
+
+// chunkOpenAPI produces an overview chunk for the spec and one chunk per
+// operation with its parameters, request body and response schemas inlined.
+// Operations are listed in document order, Symbol holds the operationId.
+func (c *Chunker) chunkOpenAPI(content string, maxTokens int) ([]ChunkResult, error) {
+	root, err := parseTree(content)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]interface{}
+	if err := root.Decode(&raw); err != nil {
+		return nil, err
+	}
+	spec := &openAPISpec{root: raw}
+
+	var chunks []ChunkResult
+	add := func(chunk ChunkResult) {
+		if c.tok.Count(chunk.Content) <= maxTokens {
+			chunks = append(chunks, chunk)
+			return
+		}
+		b := &proseBuilder{tok: c.tok, maxTokens: maxTokens}
+		for _, para := range splitProse(chunk.Content, 0) {
+			b.addUnit(para, 0, proseSeps[0])
+		}
+		for _, part := range b.finish() {
+			part.Type, part.Symbol, part.Section = chunk.Type, chunk.Symbol, chunk.Section
+			chunks = append(chunks, part)
+		}
+	}
+
+	if overview := spec.overview(); overview != "" {
+		add(ChunkResult{Content: overview, Type: ChunkTypeAPI, Section: "$.info"})
+	}
+
+	paths := mappingValue(root, "paths")
+	if paths == nil {
+		return chunks, nil
+	}
+	for i := 0; i+1 < len(paths.Content); i += 2 {
+		path, item := paths.Content[i].Value, paths.Content[i+1]
+		var itemMap map[string]interface{}
+		if err := item.Decode(&itemMap); err != nil {
+			return nil, err
+		}
+		for j := 0; j+1 < len(item.Content); j += 2 {
+			method := item.Content[j].Value
+			if !httpMethods[method] {
+				continue
+			}
+			op, _ := itemMap[method].(map[string]interface{})
+			opID, _ := op["operationId"].(string)
+			add(ChunkResult{
+				Content: spec.renderOperation(method, path, op, itemMap["parameters"]),
+				Type:    ChunkTypeAPI,
+				Symbol:  opID,
+				Section: "$.paths" + jsonPathKey(path) + "." + method,
+			})
+		}
+	}
+	return chunks, nil
+}
+
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func (s *openAPISpec) overview() string {
+	info, _ := s.root["info"].(map[string]interface{})
+	var b strings.Builder
+	if title, _ := info["title"].(string); title != "" {
+		b.WriteString(title)
+		if version := fmt.Sprint(info["version"]); info["version"] != nil {
+			b.WriteString(" (version " + version + ")")
+		}
+		b.WriteString("\n")
+	}
+	if desc, _ := info["description"].(string); desc != "" {
+		b.WriteString("\n" + strings.TrimSpace(desc) + "\n")
+	}
+	if servers, _ := s.root["servers"].([]interface{}); len(servers) > 0 {
+		b.WriteString("\nServers:\n")
+		for _, srv := range servers {
+			if m, ok := srv.(map[string]interface{}); ok {
+				b.WriteString(fmt.Sprintf("- %v\n", m["url"]))
+			}
+		}
+	} else if host, _ := s.root["host"].(string); host != "" {
+		b.WriteString(fmt.Sprintf("\nHost: %s%v\n", host, valueOr(s.root["basePath"], "")))
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func (s *openAPISpec) renderOperation(method, path string, op map[string]interface{}, pathParams interface{}) string {
+	var b strings.Builder
+	b.WriteString(strings.ToUpper(method) + " " + path + "\n")
+	if summary, _ := op["summary"].(string); summary != "" {
+		b.WriteString("Summary: " + summary + "\n")
+	}
+	if id, _ := op["operationId"].(string); id != "" {
+		b.WriteString("Operation ID: " + id + "\n")
+	}
+	if tags, _ := op["tags"].([]interface{}); len(tags) > 0 {
+		var names []string
+		for _, t := range tags {
+			names = append(names, fmt.Sprint(t))
+		}
+		b.WriteString("Tags: " + strings.Join(names, ", ") + "\n")
+	}
+	if deprecated, _ := op["deprecated"].(bool); deprecated {
+		b.WriteString("Deprecated: true\n")
+	}
+	if desc, _ := op["description"].(string); desc != "" {
+		b.WriteString("\n" + strings.TrimSpace(desc) + "\n")
+	}
+
+	// Path-level parameters apply to every operation
+	params, _ := pathParams.([]interface{})
+	opParams, _ := op["parameters"].([]interface{})
+	params = append(append([]interface{}{}, params...), opParams...)
+	var bodySchema interface{}
+	if len(params) > 0 {
+		var lines []string
+		for _, p := range params {
+			pm, _ := s.resolve(p).(map[string]interface{})
+			if pm == nil {
+				continue
+			}
+			if pm["in"] == "body" {
+				// Swagger 2 request body
+				bodySchema = pm["schema"]
+				continue
+			}
+			lines = append(lines, s.renderParameter(pm))
+		}
+		if len(lines) > 0 {
+			b.WriteString("\nParameters:\n" + strings.Join(lines, "\n") + "\n")
+		}
+	}
+
+	if rb, _ := s.resolve(op["requestBody"]).(map[string]interface{}); rb != nil {
+		for _, media := range sortedKeys(rb["content"]) {
+			mt, _ := rb["content"].(map[string]interface{})[media].(map[string]interface{})
+			b.WriteString("\nRequest Body (" + media + "):\n")
+			b.WriteString(s.renderSchema(mt["schema"]))
+		}
+	} else if bodySchema != nil {
+		b.WriteString("\nRequest Body:\n")
+		b.WriteString(s.renderSchema(bodySchema))
+	}
+
+	if responses, _ := op["responses"].(map[string]interface{}); len(responses) > 0 {
+		b.WriteString("\nResponses:\n")
+		for _, code := range sortedKeys(responses) {
+			resp, _ := s.resolve(responses[code]).(map[string]interface{})
+			desc, _ := resp["description"].(string)
+			b.WriteString(fmt.Sprintf("- %s: %s\n", code, strings.TrimSpace(desc)))
+			if resp["schema"] != nil {
+				b.WriteString(s.renderSchema(resp["schema"]))
+			}
+			for _, media := range sortedKeys(resp["content"]) {
+				mt, _ := resp["content"].(map[string]interface{})[media].(map[string]interface{})
+				if mt["schema"] != nil {
+					b.WriteString(s.renderSchema(mt["schema"]))
+				}
+			}
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func (s *openAPISpec) renderParameter(p map[string]interface{}) string {
+	typ := p["type"]
+	if schema, ok := s.resolve(p["schema"]).(map[string]interface{}); ok && typ == nil {
+		typ = schema["type"]
+	}
+	attrs := []string{fmt.Sprint(valueOr(p["in"], "query"))}
+	if typ != nil {
+		attrs = append(attrs, fmt.Sprint(typ))
+	}
+	if req, _ := p["required"].(bool); req {
+		attrs = append(attrs, "required")
+	}
+	line := fmt.Sprintf("- %v (%s)", p["name"], strings.Join(attrs, ", "))
+	if desc, _ := p["description"].(string); desc != "" {
+		line += ": " + strings.TrimSpace(desc)
+	}
+	return line
+}
+
+// renderSchema inlines $refs up to schemaRefDepth and renders the schema as
+// indented YAML.
+func (s *openAPISpec) renderSchema(schema interface{}) string {
+	if schema == nil {
+		return ""
+	}
+	out, err := yaml.Marshal(s.inline(schema, 0, map[string]bool{}))
+	if err != nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimRight(string(out), "\n"), "\n") {
+		b.WriteString("    " + line + "\n")
+	}
+	return b.String()
+}
+
+func (s *openAPISpec) inline(v interface{}, depth int, seen map[string]bool) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if ref, ok := t["$ref"].(string); ok {
+			if depth >= schemaRefDepth || seen[ref] {
+				return map[string]interface{}{"$ref": ref}
+			}
+			target := s.lookup(ref)
+			if target == nil {
+				return t
+			}
+			seen[ref] = true
+			defer delete(seen, ref)
+			return s.inline(target, depth+1, seen)
+		}
+		out := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			out[k] = s.inline(val, depth, seen)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, val := range t {
+			out[i] = s.inline(val, depth, seen)
+		}
+		return out
+	}
+	return v
+}
+
+// resolve follows a single $ref of a parameter, request body or response.
+func (s *openAPISpec) resolve(v interface{}) interface{} {
+	for i := 0; i < 5; i++ {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return v
+		}
+		ref, ok := m["$ref"].(string)
+		if !ok {
+			return v
+		}
+		if v = s.lookup(ref); v == nil {
+			return m
+		}
+	}
+	return v
+}
+
+// lookup resolves a local JSON pointer such as "#/components/schemas/User".
+func (s *openAPISpec) lookup(ref string) interface{} {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil
+	}
+	var cur interface{} = s.root
+	for _, part := range strings.Split(ref[2:], "/") {
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		cur = m[part]
+	}
+	return cur
+}
+
+func sortedKeys(v interface{}) []string {
+	m, _ := v.(map[string]interface{})
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func valueOr(v, def interface{}) interface{} {
+	if v == nil {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,149 @@
+package text
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Chunking strategies selectable per source.
+const (
+	StrategyMarkdown    = "markdown-structural" // Headers, blocks and code fences (default)
+	StrategyFixedWindow = "fixed-window"        // Sliding token window, ignores structure
+	StrategyParagraph   = "paragraph"           // Paragraphs packed up to the limit
+	StrategyWholePage   = "whole-page"          // One chunk per page that fits the limit
+)
+
+const (
+	DefaultMaxTokens = 512
+	DefaultOverlap   = 50
+
+	minMaxTokens = 32
+	maxMaxTokens = 8192
+)
+
+// ChunkProfile controls how a source's pages are chunked.
+type ChunkProfile struct {
+	Strategy  string `json:"strategy"`
+	MaxTokens int    `json:"max_tokens"`
+	Overlap   int    `json:"overlap"`
+}
+
+// DefaultChunkProfile is used for sources without an explicit profile.
+func DefaultChunkProfile() ChunkProfile {
+	return ChunkProfile{Strategy: StrategyMarkdown, MaxTokens: DefaultMaxTokens, Overlap: DefaultOverlap}
+}
+
+// WithDefaults fills in unset fields. An overlap of 0 is kept when the
+// token limit was set explicitly.
+func (p ChunkProfile) WithDefaults() ChunkProfile {
+	if p.Strategy == "" {
+		p.Strategy = StrategyMarkdown
+	}
+	if p.MaxTokens == 0 {
+		p.MaxTokens = DefaultMaxTokens
+		if p.Overlap == 0 {
+			p.Overlap = DefaultOverlap
+		}
+	}
+	return p
+}
+
+// Key identifies the chunks the profile produces: pages chunked under
+// another key are re-chunked on resync even if their content is the same.
+func (p ChunkProfile) Key() string {
+	p = p.WithDefaults()
+	return fmt.Sprintf("%s/%d/%d", p.Strategy, p.MaxTokens, p.Overlap)
+}
+
+func (p ChunkProfile) Validate() error {
+	switch p.Strategy {
+	case StrategyMarkdown, StrategyFixedWindow, StrategyParagraph, StrategyWholePage:
+	default:
+		return fmt.Errorf("unknown chunk strategy %q", p.Strategy)
+	}
+	if p.MaxTokens < minMaxTokens || p.MaxTokens > maxMaxTokens {
+		return fmt.Errorf("chunk max tokens must be between %d and %d", minMaxTokens, maxMaxTokens)
+	}
+	if p.Overlap < 0 || p.Overlap >= p.MaxTokens {
+		return fmt.Errorf("chunk overlap must be between 0 and max tokens")
+	}
+	return nil
+}
+
+// Chunk splits text according to the profile.
+func (c *Chunker) Chunk(text string, p ChunkProfile) []ChunkResult {
+	switch p.Strategy {
+	case StrategyFixedWindow:
+		return c.chunkFixedWindow(text, p.MaxTokens, p.Overlap)
+	case StrategyParagraph:
+		return c.chunkParagraphs(text, p.MaxTokens, p.Overlap)
+	case StrategyWholePage:
+		text = strings.TrimSpace(text)
+		if text == "" {
+			return nil
+		}
+		if p.MaxTokens > 0 && c.tok.Count(text) > p.MaxTokens {
+			// Longer pages than the embedder takes fall back to windows
+			return c.chunkFixedWindow(text, p.MaxTokens, p.Overlap)
+		}
+		return []ChunkResult{{Content: text, Type: detectChunkType(text)}}
+	default:
+		return c.ChunkMarkdown(text, p.MaxTokens, p.Overlap)
+	}
+}
+
+var wordRe = regexp.MustCompile(`\S+`)
+
+// chunkFixedWindow slides a window of maxTokens over the words of text,
+// stepping back overlap tokens between windows. Chunks are slices of the
+// original text, so whitespace and line breaks are preserved.
+func (c *Chunker) chunkFixedWindow(text string, maxTokens, overlap int) []ChunkResult {
+	words := wordRe.FindAllStringIndex(text, -1)
+	if len(words) == 0 {
+		return nil
+	}
+	if overlap > maxTokens/2 {
+		overlap = maxTokens / 2
+	}
+	counts := make([]int, len(words))
+	for i, w := range words {
+		counts[i] = c.tok.Count(text[w[0]:w[1]])
+	}
+
+	var chunks []ChunkResult
+	for start := 0; start < len(words); {
+		end, tokens := start, 0
+		for end < len(words) && (end == start || tokens+counts[end] <= maxTokens) {
+			tokens += counts[end]
+			end++
+		}
+		content := text[words[start][0]:words[end-1][1]]
+		chunks = append(chunks, ChunkResult{Content: content, Type: detectChunkType(content)})
+		if end == len(words) {
+			break
+		}
+
+		// Step back over up to overlap tokens, always making progress
+		next, back := end, 0
+		for next-1 > start && back+counts[next-1] <= overlap {
+			back += counts[next-1]
+			next--
+		}
+		start = next
+	}
+	return chunks
+}
+
+// chunkParagraphs packs blank-line separated paragraphs up to maxTokens,
+// ignoring headers and code fences.
+func (c *Chunker) chunkParagraphs(text string, maxTokens, overlap int) []ChunkResult {
+	if overlap > maxTokens/2 {
+		overlap = maxTokens / 2
+	}
+	b := &proseBuilder{tok: c.tok, maxTokens: maxTokens, overlap: overlap}
+	for _, para := range splitProse(text, 0) {
+		b.addUnit(para, 0, proseSeps[0])
+	}
+	return b.finish()
+}
@@ -0,0 +1,70 @@
+package text
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChunkProfile_WithDefaults(t *testing.T) {
+	assert.Equal(t, DefaultChunkProfile(), ChunkProfile{}.WithDefaults())
+
+	// Explicit limit keeps a zero overlap
+	p := ChunkProfile{Strategy: StrategyParagraph, MaxTokens: 128}.WithDefaults()
+	assert.Equal(t, ChunkProfile{Strategy: StrategyParagraph, MaxTokens: 128, Overlap: 0}, p)
+}
+
+func TestChunkProfile_Key(t *testing.T) {
+	assert.Equal(t, DefaultChunkProfile().Key(), ChunkProfile{}.Key())
+	assert.NotEqual(t, DefaultChunkProfile().Key(), ChunkProfile{Strategy: StrategyMarkdown, MaxTokens: 256}.Key())
+	assert.NotEqual(t, DefaultChunkProfile().Key(), ChunkProfile{Strategy: StrategyParagraph}.Key())
+}
+
+func TestChunkProfile_Validate(t *testing.T) {
+	assert.NoError(t, DefaultChunkProfile().Validate())
+	assert.Error(t, ChunkProfile{Strategy: "sentences", MaxTokens: 512}.Validate())
+	assert.Error(t, ChunkProfile{Strategy: StrategyMarkdown, MaxTokens: 8}.Validate())
+	assert.Error(t, ChunkProfile{Strategy: StrategyMarkdown, MaxTokens: 100, Overlap: 100}.Validate())
+	assert.Error(t, ChunkProfile{Strategy: StrategyMarkdown, MaxTokens: 100, Overlap: -1}.Validate())
+}
+
+func TestChunk_FixedWindow(t *testing.T) {
+	c := NewChunker(wordTokenizer{})
+	text := "w1 w2\nw3 w4 w5 w6 w7"
+
+	chunks := c.Chunk(text, ChunkProfile{Strategy: StrategyFixedWindow, MaxTokens: 4, Overlap: 1})
+	// Original whitespace is preserved and each window repeats one word
+	assert.Equal(t, []string{"w1 w2\nw3 w4", "w4 w5 w6 w7"}, contents(chunks))
+}
+
+func TestChunk_Paragraph(t *testing.T) {
+	c := NewChunker(wordTokenizer{})
+	text := "# Title\na b\n\nc d\n\ne f g"
+
+	chunks := c.Chunk(text, ChunkProfile{Strategy: StrategyParagraph, MaxTokens: 6})
+	assert.Equal(t, []string{"# Title\na b\n\nc d", "e f g"}, contents(chunks))
+}
+
+func TestChunk_WholePage(t *testing.T) {
+	text := "# Title\n\n" + strings.Repeat("word ", 50)
+	chunks := defaultChunker.Chunk(text, ChunkProfile{Strategy: StrategyWholePage, MaxTokens: 512})
+	assert.Len(t, chunks, 1)
+	assert.Equal(t, strings.TrimSpace(text), chunks[0].Content)
+}
+
+func TestChunk_WholePageOverLimit(t *testing.T) {
+	c := NewChunker(wordTokenizer{})
+	text := "# Title\n\n" + strings.Repeat("word ", 100)
+
+	chunks := c.Chunk(text, ChunkProfile{Strategy: StrategyWholePage, MaxTokens: 40})
+	assert.Len(t, chunks, 3)
+	for _, ch := range chunks {
+		assert.LessOrEqual(t, len(strings.Fields(ch.Content)), 40)
+	}
+}
+
+func TestChunk_MarkdownIsDefault(t *testing.T) {
+	text := "Intro.\n```go\nfunc main() {}\n```"
+	assert.Equal(t, ChunkMarkdown(text, 512, 50), defaultChunker.Chunk(text, DefaultChunkProfile()))
+}
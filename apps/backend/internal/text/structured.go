@@ -0,0 +1,329 @@
+package text
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is the document format a page or upload is chunked as.
+type Format string
+
+const (
+	FormatMarkdown Format = "markdown"
+	FormatJSON     Format = "json"
+	FormatYAML     Format = "yaml"
+	FormatCSV      Format = "csv"
+	FormatOpenAPI  Format = "openapi"
+)
+
+// DetectFormat picks the format from the file extension of path. JSON and
+// YAML documents declaring an "openapi" or "swagger" version are OpenAPI specs.
+func DetectFormat(path, content string) Format {
+	var f Format
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		f = FormatJSON
+	case ".yaml", ".yml":
+		f = FormatYAML
+	case ".csv":
+		f = FormatCSV
+	default:
+		return FormatMarkdown
+	}
+	if f != FormatCSV && isOpenAPI(content) {
+		return FormatOpenAPI
+	}
+	return f
+}
+
+func isOpenAPI(content string) bool {
+	root, err := parseTree(content)
+	if err != nil {
+		return false
+	}
+	return mappingValue(root, "openapi") != nil || mappingValue(root, "swagger") != nil
+}
+
+// ChunkStructured chunks data formats. JSON and YAML are split by keys and
+// array ranges with the JSON path of each part in Section, CSV by row groups
+// with the header repeated, and OpenAPI specs into one chunk per operation.
+// It returns an error if content doesn't parse, so callers can fall back to
+// ChunkMarkdown.
+func (c *Chunker) ChunkStructured(content string, format Format, maxTokens int) ([]ChunkResult, error) {
+	switch format {
+	case FormatJSON, FormatYAML:
+		return c.chunkTree(content, format, maxTokens)
+	case FormatCSV:
+		return c.chunkCSV(content, maxTokens)
+	case FormatOpenAPI:
+		return c.chunkOpenAPI(content, maxTokens)
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+// --- JSON / YAML ---
+
+func parseTree(content string) (*yaml.Node, error) {
+	// YAML is a superset of JSON, and yaml.Node keeps key order.
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil, fmt.Errorf("empty document")
+	}
+	return doc.Content[0], nil
+}
+
+func (c *Chunker) chunkTree(content string, format Format, maxTokens int) ([]ChunkResult, error) {
+	root, err := parseTree(content)
+	if err != nil {
+		return nil, err
+	}
+	lang := string(format)
+	var chunks []ChunkResult
+	emit := func(path, body string) {
+		chunks = append(chunks, ChunkResult{
+			Content:  wrapFence(lang, body),
+			Type:     ChunkTypeConfig,
+			Language: lang,
+			Section:  path,
+		})
+	}
+	if err := c.splitNode("$", root, format, maxTokens, emit); err != nil {
+		return nil, err
+	}
+	return chunks, nil
+}
+
+// treeMember is one key/value pair of a mapping or element of a sequence.
+type treeMember struct {
+	index int
+	path  string
+	nodes []*yaml.Node // key and value for mappings, the element for sequences
+	value *yaml.Node
+}
+
+// splitNode emits n whole if it fits, otherwise packs consecutive members
+// into parts that fit, descending into members too large on their own.
+func (c *Chunker) splitNode(path string, n *yaml.Node, format Format, maxTokens int, emit func(path, body string)) error {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	body, err := renderNode(n, format)
+	if err != nil {
+		return err
+	}
+	if c.tok.Count(body) <= maxTokens || (n.Kind != yaml.MappingNode && n.Kind != yaml.SequenceNode) {
+		emit(path, body)
+		return nil
+	}
+
+	var members []treeMember
+	if n.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, val := n.Content[i], n.Content[i+1]
+			members = append(members, treeMember{index: i / 2, path: path + jsonPathKey(key.Value), nodes: []*yaml.Node{key, val}, value: val})
+		}
+	} else {
+		for i, val := range n.Content {
+			members = append(members, treeMember{index: i, path: fmt.Sprintf("%s[%d]", path, i), nodes: []*yaml.Node{val}, value: val})
+		}
+	}
+
+	var group []treeMember
+	tokens := 0
+	flush := func() error {
+		if len(group) == 0 {
+			return nil
+		}
+		merged := &yaml.Node{Kind: n.Kind, Tag: n.Tag, Style: n.Style}
+		for _, m := range group {
+			merged.Content = append(merged.Content, m.nodes...)
+		}
+		groupPath := group[0].path
+		if len(group) > 1 {
+			groupPath = path
+			if n.Kind == yaml.SequenceNode {
+				groupPath = fmt.Sprintf("%s[%d:%d]", path, group[0].index, group[len(group)-1].index+1)
+			}
+		}
+		body, err := renderNode(merged, format)
+		if err != nil {
+			return err
+		}
+		emit(groupPath, body)
+		group, tokens = nil, 0
+		return nil
+	}
+
+	for _, m := range members {
+		single := &yaml.Node{Kind: n.Kind, Tag: n.Tag, Style: n.Style, Content: m.nodes}
+		mb, err := renderNode(single, format)
+		if err != nil {
+			return err
+		}
+		mt := c.tok.Count(mb)
+		if mt > maxTokens {
+			if err := flush(); err != nil {
+				return err
+			}
+			if err := c.splitNode(m.path, m.value, format, maxTokens, emit); err != nil {
+				return err
+			}
+			continue
+		}
+		if len(group) > 0 && tokens+mt > maxTokens {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		group = append(group, m)
+		tokens += mt
+	}
+	return flush()
+}
+
+var identRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// jsonPathKey returns the JSONPath member accessor for key.
+func jsonPathKey(key string) string {
+	if identRe.MatchString(key) {
+		return "." + key
+	}
+	return "['" + strings.ReplaceAll(key, "'", `\'`) + "']"
+}
+
+func renderNode(n *yaml.Node, format Format) (string, error) {
+	if format == FormatJSON {
+		var compact bytes.Buffer
+		if err := writeJSON(&compact, n); err != nil {
+			return "", err
+		}
+		var out bytes.Buffer
+		if err := json.Indent(&out, compact.Bytes(), "", "  "); err != nil {
+			return "", err
+		}
+		return out.String(), nil
+	}
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(n); err != nil {
+		return "", err
+	}
+	return strings.TrimRight(out.String(), "\n"), nil
+}
+
+// writeJSON writes n as compact JSON, keeping the key order of the source.
+func writeJSON(buf *bytes.Buffer, n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.AliasNode:
+		return writeJSON(buf, n.Alias)
+	case yaml.MappingNode:
+		buf.WriteByte('{')
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(n.Content[i].Value)
+			buf.Write(key)
+			buf.WriteByte(':')
+			if err := writeJSON(buf, n.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, el := range n.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSON(buf, el); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	default:
+		var v interface{}
+		if err := n.Decode(&v); err != nil {
+			return err
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+	}
+	return nil
+}
+
+// --- CSV ---
+
+// chunkCSV groups rows into chunks of at most maxTokens, repeating the
+// header row in each. Section holds the 1-based data row range.
+func (c *Chunker) chunkCSV(content string, maxTokens int) ([]ChunkResult, error) {
+	r := csv.NewReader(strings.NewReader(content))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("empty csv")
+	}
+
+	header := csvLine(records[0])
+	headerTokens := c.tok.Count(header)
+
+	var chunks []ChunkResult
+	var rows []string
+	first, tokens := 1, headerTokens
+	flush := func(last int) {
+		if len(rows) == 0 {
+			return
+		}
+		chunks = append(chunks, ChunkResult{
+			Content:  header + "\n" + strings.Join(rows, "\n"),
+			Type:     ChunkTypeTable,
+			Language: "csv",
+			Section:  fmt.Sprintf("rows %d-%d", first, last),
+		})
+		rows, tokens, first = nil, headerTokens, last+1
+	}
+
+	for i, rec := range records[1:] {
+		row := csvLine(rec)
+		n := c.tok.Count(row) + 1 // newline
+		if len(rows) > 0 && tokens+n > maxTokens {
+			flush(i)
+		}
+		rows = append(rows, row)
+		tokens += n
+	}
+	flush(len(records) - 1)
+
+	if len(chunks) == 0 {
+		// Header only
+		chunks = append(chunks, ChunkResult{Content: header, Type: ChunkTypeTable, Language: "csv"})
+	}
+	return chunks, nil
+}
+
+func csvLine(fields []string) string {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.Write(fields)
+	w.Flush()
+	return strings.TrimRight(buf.String(), "\r\n")
+}
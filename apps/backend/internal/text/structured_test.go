@@ -0,0 +1,163 @@
+package text
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectFormat(t *testing.T) {
+	assert.Equal(t, FormatJSON, DetectFormat("/uploads/data.json", `{"a": 1}`))
+	assert.Equal(t, FormatYAML, DetectFormat("config.YML", "a: 1"))
+	assert.Equal(t, FormatCSV, DetectFormat("rows.csv", "a,b"))
+	assert.Equal(t, FormatOpenAPI, DetectFormat("spec.json", `{"openapi": "3.0.0"}`))
+	assert.Equal(t, FormatOpenAPI, DetectFormat("spec.yaml", "swagger: '2.0'"))
+	assert.Equal(t, FormatMarkdown, DetectFormat("README.md", "openapi: 3"))
+	assert.Equal(t, FormatMarkdown, DetectFormat("", "{}"))
+}
+
+func TestChunkStructured_JSONSplitsByKey(t *testing.T) {
+	c := NewChunker(wordTokenizer{})
+	doc := `{"name": "qurio", "server": {"host": "localhost", "port": 8081, "tls": {"cert": "a b c d e f"}}, "tags": ["a", "b", "c", "d", "e", "f"]}`
+
+	chunks, err := c.ChunkStructured(doc, FormatJSON, 8)
+	require.NoError(t, err)
+
+	var paths []string
+	for _, ch := range chunks {
+		paths = append(paths, ch.Section)
+		assert.Equal(t, ChunkTypeConfig, ch.Type)
+		assert.Equal(t, "json", ch.Language)
+	}
+	// The oversized scalar gets its own chunk, the rest of the parent is packed
+	assert.Equal(t, []string{"$.name", "$.server", "$.server.tls.cert", "$.tags"}, paths)
+	// Key order of the source is kept
+	assert.Contains(t, chunks[1].Content, "\"host\": \"localhost\",\n  \"port\": 8081")
+}
+
+func TestChunkStructured_ArraySlices(t *testing.T) {
+	c := NewChunker(wordTokenizer{})
+	doc := "[" + strings.TrimSuffix(strings.Repeat(`"a b", `, 6), ", ") + "]"
+
+	chunks, err := c.ChunkStructured(doc, FormatJSON, 10)
+	require.NoError(t, err)
+	var paths []string
+	for _, ch := range chunks {
+		paths = append(paths, ch.Section)
+	}
+	assert.Equal(t, []string{"$[0:2]", "$[2:4]", "$[4:6]"}, paths)
+}
+
+func TestChunkStructured_DescendsIntoLargeMembers(t *testing.T) {
+	c := NewChunker(wordTokenizer{})
+	doc := "paths:\n  /users:\n    get: " + strings.Repeat("x ", 6) + "\n  /users/{id}:\n    get: y\n"
+
+	chunks, err := c.ChunkStructured(doc, FormatYAML, 6)
+	require.NoError(t, err)
+
+	var paths []string
+	for _, ch := range chunks {
+		paths = append(paths, ch.Section)
+	}
+	assert.Equal(t, []string{"$.paths['/users'].get", "$.paths['/users/{id}']"}, paths)
+	assert.True(t, strings.HasPrefix(chunks[1].Content, "```yaml\n/users/{id}:"))
+}
+
+func TestChunkStructured_InvalidFallsBack(t *testing.T) {
+	_, err := defaultChunker.ChunkStructured("{not json", FormatJSON, 100)
+	assert.Error(t, err)
+}
+
+func TestChunkStructured_CSVRepeatsHeader(t *testing.T) {
+	c := NewChunker(wordTokenizer{})
+	doc := "name,role\nada,\"admin, owner\"\nbob,dev\ncy,ops\n"
+
+	chunks, err := c.ChunkStructured(doc, FormatCSV, 5)
+	require.NoError(t, err)
+	require.Len(t, chunks, 2)
+	assert.Equal(t, "name,role\nada,\"admin, owner\"", chunks[0].Content)
+	assert.Equal(t, "rows 1-1", chunks[0].Section)
+	assert.Equal(t, "name,role\nbob,dev\ncy,ops", chunks[1].Content)
+	assert.Equal(t, "rows 2-3", chunks[1].Section)
+	assert.Equal(t, ChunkTypeTable, chunks[1].Type)
+}
+
+const petstore = `openapi: 3.0.0
+info:
+  title: Petstore
+  version: 1.0.0
+  description: Sample API.
+servers:
+  - url: https://api.example.com
+paths:
+  /pets/{petId}:
+    parameters:
+      - name: petId
+        in: path
+        required: true
+        schema:
+          type: string
+    get:
+      operationId: getPet
+      summary: Get a pet
+      responses:
+        "200":
+          description: The pet
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/Pet"
+    delete:
+      operationId: deletePet
+      responses:
+        "204":
+          description: Deleted
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        name:
+          type: string
+        parent:
+          $ref: "#/components/schemas/Pet"
+`
+
+func TestChunkStructured_OpenAPI(t *testing.T) {
+	chunks, err := defaultChunker.ChunkStructured(petstore, FormatOpenAPI, 512)
+	require.NoError(t, err)
+	require.Len(t, chunks, 3)
+
+	assert.Equal(t, "$.info", chunks[0].Section)
+	assert.Contains(t, chunks[0].Content, "Petstore (version 1.0.0)")
+	assert.Contains(t, chunks[0].Content, "https://api.example.com")
+
+	get := chunks[1]
+	assert.Equal(t, ChunkTypeAPI, get.Type)
+	assert.Equal(t, "getPet", get.Symbol)
+	assert.Equal(t, "$.paths['/pets/{petId}'].get", get.Section)
+	assert.True(t, strings.HasPrefix(get.Content, "GET /pets/{petId}\nSummary: Get a pet"))
+	// Path-level parameters apply to each operation
+	assert.Contains(t, get.Content, "- petId (path, string, required)")
+	// Schema refs are inlined, recursive refs are kept as references
+	assert.Contains(t, get.Content, "name:")
+	assert.Contains(t, get.Content, "$ref: '#/components/schemas/Pet'")
+
+	assert.Equal(t, "deletePet", chunks[2].Symbol)
+	assert.Contains(t, chunks[2].Content, "- 204: Deleted")
+}
+
+func TestChunkStructured_Swagger2(t *testing.T) {
+	spec := `{"swagger": "2.0", "info": {"title": "Old"}, "host": "api.example.com", "basePath": "/v1",
+"paths": {"/users": {"post": {"parameters": [{"in": "body", "name": "body", "schema": {"type": "object"}}],
+"responses": {"201": {"description": "Created", "schema": {"type": "string"}}}}}}}`
+
+	chunks, err := defaultChunker.ChunkStructured(spec, FormatOpenAPI, 512)
+	require.NoError(t, err)
+	require.Len(t, chunks, 2)
+	assert.Contains(t, chunks[0].Content, "Host: api.example.com/v1")
+	assert.Contains(t, chunks[1].Content, "Request Body:\n    type: object")
+	assert.Contains(t, chunks[1].Content, "- 201: Created\n    type: string")
+}
@@ -0,0 +1,166 @@
+package text
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Tokenizer counts tokens the way the embedding model will.
+type Tokenizer interface {
+	Count(text string) int
+}
+
+// EstimateTokenizer is the default when no vocabulary is configured. Latin
+// text keeps the classic ~4 bytes per token estimate; CJK characters and
+// symbols are counted individually, since models rarely merge them.
+type EstimateTokenizer struct{}
+
+func (EstimateTokenizer) Count(text string) int {
+	run, tokens := 0, 0
+	flush := func() {
+		tokens += (run + 3) / 4
+		run = 0
+	}
+	for _, r := range text {
+		switch {
+		case r < utf8.RuneSelf:
+			run++
+		case isCJK(r):
+			flush()
+			tokens++
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			// Other scripts: about two bytes per token
+			run += 2
+		default:
+			flush()
+			tokens++
+		}
+	}
+	flush()
+	return tokens
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// bpePretokenize approximates the cl100k pre-tokenizer within RE2's limits.
+var bpePretokenize = regexp.MustCompile(`'(?:[sdmt]|ll|ve|re)| ?\p{L}+| ?\p{N}{1,3}| ?[^\s\p{L}\p{N}]+|\s+`)
+
+// BPETokenizer counts tokens with a byte-level BPE vocabulary.
+type BPETokenizer struct {
+	ranks map[string]int
+
+	mu    sync.RWMutex
+	cache map[string]int
+}
+
+// LoadBPETokenizer reads a vocabulary in tiktoken format: one
+// "<base64 token> <rank>" pair per line, lower ranks merging first.
+func LoadBPETokenizer(path string) (*BPETokenizer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(f)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("vocab line %d: expected \"<token> <rank>\"", line)
+		}
+		token, err := base64.StdEncoding.DecodeString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("vocab line %d: %w", line, err)
+		}
+		rank, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("vocab line %d: %w", line, err)
+		}
+		ranks[string(token)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("vocab %s is empty", path)
+	}
+	return NewBPETokenizer(ranks), nil
+}
+
+// NewBPETokenizer builds a tokenizer from token -> merge rank.
+func NewBPETokenizer(ranks map[string]int) *BPETokenizer {
+	return &BPETokenizer{ranks: ranks, cache: make(map[string]int)}
+}
+
+func (t *BPETokenizer) Count(text string) int {
+	total := 0
+	for _, piece := range bpePretokenize.FindAllString(text, -1) {
+		total += t.countPiece(piece)
+	}
+	return total
+}
+
+const bpeCacheLimit = 100000
+
+func (t *BPETokenizer) countPiece(piece string) int {
+	if _, ok := t.ranks[piece]; ok {
+		return 1
+	}
+
+	t.mu.RLock()
+	n, ok := t.cache[piece]
+	t.mu.RUnlock()
+	if ok {
+		return n
+	}
+
+	n = len(t.merge(piece))
+
+	t.mu.Lock()
+	if len(t.cache) < bpeCacheLimit {
+		t.cache[piece] = n
+	}
+	t.mu.Unlock()
+	return n
+}
+
+// merge applies BPE to a single piece, starting from individual bytes and
+// repeatedly merging the adjacent pair with the lowest rank.
+func (t *BPETokenizer) merge(piece string) []string {
+	parts := make([]string, len(piece))
+	for i := 0; i < len(piece); i++ {
+		parts[i] = piece[i : i+1]
+	}
+
+	for len(parts) > 1 {
+		best, bestRank := -1, math.MaxInt
+		for i := 0; i < len(parts)-1; i++ {
+			if rank, ok := t.ranks[parts[i]+parts[i+1]]; ok && rank < bestRank {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		parts[best] += parts[best+1]
+		parts = append(parts[:best+1], parts[best+2:]...)
+	}
+	return parts
+}
@@ -0,0 +1,74 @@
+package text
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateTokenizer(t *testing.T) {
+	tok := EstimateTokenizer{}
+	assert.Equal(t, 0, tok.Count(""))
+	assert.Equal(t, 4, tok.Count("Short paragraph."))
+	// CJK characters count individually instead of 3 bytes / 4
+	assert.Equal(t, 4, tok.Count("日本語文"))
+	assert.Equal(t, 6, tok.Count("abcd日本語文abcd"))
+}
+
+func writeVocab(t *testing.T, tokens ...string) string {
+	var b strings.Builder
+	rank := 0
+	// All single bytes first, as in real byte-level vocabularies
+	for i := 0; i < 256; i++ {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(i)}), rank)
+		rank++
+	}
+	for _, tkn := range tokens {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(tkn)), rank)
+		rank++
+	}
+	path := filepath.Join(t.TempDir(), "vocab.tiktoken")
+	require.NoError(t, os.WriteFile(path, []byte(b.String()), 0644))
+	return path
+}
+
+func TestBPETokenizer(t *testing.T) {
+	tok, err := LoadBPETokenizer(writeVocab(t, "he", "ll", "hell", "hello", " wo", " wor", " world"))
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, tok.Count("hello world"))
+	// "help" -> "hel" is not a token: "he" + "l" + "p"
+	assert.Equal(t, 3, tok.Count("help"))
+	// Cached path returns the same result
+	assert.Equal(t, 3, tok.Count("help"))
+	assert.Equal(t, 0, tok.Count(""))
+}
+
+func TestLoadBPETokenizer_Errors(t *testing.T) {
+	_, err := LoadBPETokenizer(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "bad")
+	require.NoError(t, os.WriteFile(path, []byte("not-base64! 1\n"), 0644))
+	_, err = LoadBPETokenizer(path)
+	assert.Error(t, err)
+}
+
+func TestChunker_UsesTokenizer(t *testing.T) {
+	// One token per word, so the limit is in words
+	c := NewChunker(wordTokenizer{})
+	chunks := c.ChunkMarkdown("one two three four five six", 3, 0)
+	assert.Len(t, chunks, 2)
+	assert.Equal(t, "one two three", chunks[0].Content)
+	assert.Equal(t, "four five six", chunks[1].Content)
+}
+
+type wordTokenizer struct{}
+
+func (wordTokenizer) Count(text string) int { return len(strings.Fields(text)) }
@@ -2,6 +2,7 @@ package vector
 
 import (
 	"context"
+	"log/slog"
 
 	"github.com/weaviate/weaviate/entities/models"
 )
@@ -14,9 +15,28 @@ type SchemaClient interface {
 	AddProperty(ctx context.Context, className string, property *models.Property) error
 }
 
-// EnsureSchema checks if the required classes exist and creates them if not
-func EnsureSchema(ctx context.Context, client SchemaClient) error {
-	className := "DocumentChunk"
+const (
+	// ChunkClass holds the live chunks of every source.
+	ChunkClass = "DocumentChunk"
+	// VersionClass holds the chunks of retained source versions, apart from
+	// the live ones so default searches don't see them.
+	VersionClass = "DocumentChunkVersion"
+)
+
+// EnsureSchema checks if the required classes exist and creates them if not.
+// tokenization optionally overrides the BM25 tokenization of text properties
+// (property name -> word, field or trigram). Weaviate cannot change the
+// tokenization of an existing property, so mismatches are only reported.
+func EnsureSchema(ctx context.Context, client SchemaClient, tokenization map[string]string) error {
+	for _, className := range []string{ChunkClass, VersionClass} {
+		if err := ensureClass(ctx, client, className, tokenization); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ensureClass(ctx context.Context, client SchemaClient, className string, tokenization map[string]string) error {
 	exists, err := client.ClassExists(ctx, className)
 	if err != nil {
 		return err
@@ -55,6 +75,14 @@ func EnsureSchema(ctx context.Context, client SchemaClient) error {
 			Name:     "language",
 			DataType: []string{"string"},
 		},
+		{
+			Name:     "symbol",
+			DataType: []string{"text"}, // Declared code symbols, searchable via BM25
+		},
+		{
+			Name:     "section",
+			DataType: []string{"text"}, // Heading breadcrumb, searchable and filterable
+		},
 		{
 			Name:     "author",
 			DataType: []string{"text"},
@@ -67,8 +95,14 @@ func EnsureSchema(ctx context.Context, client SchemaClient) error {
 			Name:     "pageCount",
 			DataType: []string{"int"},
 		},
+		{
+			Name:     "version",
+			DataType: []string{"string"}, // Source version label (exact match)
+		},
 	}
 
+	applyTokenization(properties, tokenization)
+
 	if !exists {
 		class := &models.Class{
 			Class:       className,
@@ -85,18 +119,43 @@ func EnsureSchema(ctx context.Context, client SchemaClient) error {
 		return err
 	}
 
-	existingProps := make(map[string]bool)
+	existingProps := make(map[string]*models.Property)
 	for _, p := range class.Properties {
-		existingProps[p.Name] = true
+		existingProps[p.Name] = p
 	}
 
 	for _, p := range properties {
-		if !existingProps[p.Name] {
+		existing, ok := existingProps[p.Name]
+		if !ok {
 			if err := client.AddProperty(ctx, className, p); err != nil {
 				return err
 			}
+			continue
+		}
+		if p.Tokenization != "" && existing.Tokenization != p.Tokenization {
+			slog.WarnContext(ctx, "property tokenization differs from settings; recreate the class and re-sync sources to apply it",
+				"property", p.Name, "current", existing.Tokenization, "wanted", p.Tokenization)
 		}
 	}
 
 	return nil
+}
+
+// applyTokenization sets the configured tokenization on matching text properties.
+func applyTokenization(properties []*models.Property, tokenization map[string]string) {
+	if len(tokenization) == 0 {
+		return
+	}
+	byName := make(map[string]*models.Property, len(properties))
+	for _, p := range properties {
+		byName[p.Name] = p
+	}
+	for name, mode := range tokenization {
+		p, ok := byName[name]
+		if !ok || len(p.DataType) == 0 || p.DataType[0] != "text" {
+			slog.Warn("ignoring tokenization for unknown or non-text property", "property", name)
+			continue
+		}
+		p.Tokenization = mode
+	}
 }
\ No newline at end of file
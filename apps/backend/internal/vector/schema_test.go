@@ -36,7 +36,7 @@ func (m *MockSchemaClient) AddProperty(ctx context.Context, className string, pr
 
 func TestEnsureSchema_CreatesClass(t *testing.T) {
 	client := &MockSchemaClient{}
-	if err := EnsureSchema(context.Background(), client); err != nil {
+	if err := EnsureSchema(context.Background(), client, nil); err != nil {
 		t.Fatalf("EnsureSchema failed: %v", err)
 	}
 
@@ -74,7 +74,7 @@ func TestEnsureSchema_AddsMissingProperties(t *testing.T) {
 		ExistingClass: existingClass,
 	}
 
-	if err := EnsureSchema(context.Background(), client); err != nil {
+	if err := EnsureSchema(context.Background(), client, nil); err != nil {
 		t.Fatalf("EnsureSchema failed: %v", err)
 	}
 
@@ -122,7 +122,7 @@ func TestEnsureSchema_AddsNewMetadataProperties(t *testing.T) {
 		ExistingClass: existingClass,
 	}
 
-	if err := EnsureSchema(context.Background(), client); err != nil {
+	if err := EnsureSchema(context.Background(), client, nil); err != nil {
 		t.Fatalf("EnsureSchema failed: %v", err)
 	}
 
@@ -141,3 +141,31 @@ func TestEnsureSchema_AddsNewMetadataProperties(t *testing.T) {
 		t.Error("Missing 'pageCount' property")
 	}
 }
+
+func TestEnsureSchema_AppliesTokenization(t *testing.T) {
+	client := &MockSchemaClient{}
+	tokenization := map[string]string{
+		"content":  "trigram",
+		"title":    "field",
+		"sourceId": "trigram", // not a text property, ignored
+		"missing":  "word",
+	}
+	if err := EnsureSchema(context.Background(), client, tokenization); err != nil {
+		t.Fatalf("EnsureSchema failed: %v", err)
+	}
+
+	got := make(map[string]string)
+	for _, p := range client.CreatedClass.Properties {
+		got[p.Name] = p.Tokenization
+	}
+
+	if got["content"] != "trigram" {
+		t.Errorf("content tokenization = %q, want trigram", got["content"])
+	}
+	if got["title"] != "field" {
+		t.Errorf("title tokenization = %q, want field", got["title"])
+	}
+	if got["sourceId"] != "" {
+		t.Errorf("sourceId tokenization should be untouched, got %q", got["sourceId"])
+	}
+}
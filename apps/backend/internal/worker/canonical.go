@@ -0,0 +1,115 @@
+package worker
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// trackingParams are dropped from every URL. A trailing * matches by prefix.
+var trackingParams = []string{"utm_*", "fbclid", "gclid", "msclkid", "mc_cid", "mc_eid", "_ga", "_gl", "ref_src"}
+
+// defaultDocuments are served for their directory, so /docs/index.html and
+// /docs are the same page.
+var defaultDocuments = map[string]bool{
+	"index.html": true, "index.htm": true, "index.php": true,
+	"default.htm": true, "default.html": true, "default.aspx": true,
+}
+
+var percentEscapeRe = regexp.MustCompile(`%[0-9a-fA-F]{2}`)
+
+// Canonicalizer maps the spellings of a page's URL to one form, so a page is
+// only crawled once: scheme and host are lowercased, default ports, fragments,
+// dot segments, default documents and trailing slashes are removed, and query
+// parameters are filtered and sorted. Path case is kept since servers may
+// treat it as significant.
+type Canonicalizer struct {
+	KeepParams []string // when set, only these query parameters are kept
+	DropParams []string // dropped in addition to tracking parameters
+}
+
+// Canonicalize returns the canonical form of raw.
+func (c Canonicalizer) Canonicalize(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	return c.canonicalURL(u).String(), nil
+}
+
+// CanonicalizeOr returns the canonical form of raw, or raw if it isn't a URL.
+func (c Canonicalizer) CanonicalizeOr(raw string) string {
+	canon, err := c.Canonicalize(raw)
+	if err != nil {
+		return raw
+	}
+	return canon
+}
+
+func (c Canonicalizer) canonicalURL(in *url.URL) *url.URL {
+	u := *in
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = normalizeHost(u.Scheme, u.Host)
+	u.Fragment, u.RawFragment = "", ""
+
+	p := percentEscapeRe.ReplaceAllStringFunc(u.EscapedPath(), strings.ToUpper)
+	if p != "" {
+		p = path.Clean(p)
+		if defaultDocuments[strings.ToLower(path.Base(p))] {
+			p = path.Dir(p)
+		}
+	}
+	if p == "" || p == "." {
+		p = "/"
+	}
+	if unescaped, err := url.PathUnescape(p); err == nil {
+		u.Path, u.RawPath = unescaped, p
+	}
+
+	if u.RawQuery != "" {
+		q := u.Query()
+		for key := range q {
+			if !c.keepParam(key) {
+				q.Del(key)
+			}
+		}
+		u.RawQuery = q.Encode()
+	}
+	u.ForceQuery = false
+	return &u
+}
+
+func (c Canonicalizer) keepParam(key string) bool {
+	if len(c.KeepParams) > 0 {
+		return matchParam(c.KeepParams, key)
+	}
+	return !matchParam(trackingParams, key) && !matchParam(c.DropParams, key)
+}
+
+func matchParam(patterns []string, key string) bool {
+	key = strings.ToLower(key)
+	for _, p := range patterns {
+		p = strings.ToLower(p)
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if key == p {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeHost lowercases host and strips the scheme's default port.
+func normalizeHost(scheme, host string) string {
+	host = strings.ToLower(host)
+	switch {
+	case scheme == "http" && strings.HasSuffix(host, ":80"):
+		return strings.TrimSuffix(host, ":80")
+	case scheme == "https" && strings.HasSuffix(host, ":443"):
+		return strings.TrimSuffix(host, ":443")
+	}
+	return host
+}
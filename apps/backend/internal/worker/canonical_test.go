@@ -0,0 +1,45 @@
+package worker
+
+import "testing"
+
+func TestCanonicalizer_Canonicalize(t *testing.T) {
+	tests := []struct {
+		name string
+		c    Canonicalizer
+		in   string
+		want string
+	}{
+		{"Root Keeps Slash", Canonicalizer{}, "https://Example.COM", "https://example.com/"},
+		{"Trailing Slash Removed", Canonicalizer{}, "https://example.com/docs/", "https://example.com/docs"},
+		{"Default Port Removed", Canonicalizer{}, "http://example.com:80/a", "http://example.com/a"},
+		{"Other Port Kept", Canonicalizer{}, "http://example.com:8080/a", "http://example.com:8080/a"},
+		{"Fragment Removed", Canonicalizer{}, "https://example.com/a#b", "https://example.com/a"},
+		{"Dot Segments", Canonicalizer{}, "https://example.com/a/./b/../c", "https://example.com/a/c"},
+		{"Default Document", Canonicalizer{}, "https://example.com/docs/Index.HTML", "https://example.com/docs"},
+		{"Root Default Document", Canonicalizer{}, "https://example.com/index.php", "https://example.com/"},
+		{"Path Case Kept", Canonicalizer{}, "https://example.com/API/Reference", "https://example.com/API/Reference"},
+		{"Percent Encoding Uppercased", Canonicalizer{}, "https://example.com/caf%c3%a9", "https://example.com/caf%C3%A9"},
+		{"Tracking Params Dropped", Canonicalizer{}, "https://example.com/a?utm_medium=x&gclid=1&b=2", "https://example.com/a?b=2"},
+		{"Empty Query Dropped", Canonicalizer{}, "https://example.com/a?", "https://example.com/a"},
+		{"Drop Prefix", Canonicalizer{DropParams: []string{"sess*"}}, "https://example.com/a?session=1&sessid=2&q=x", "https://example.com/a?q=x"},
+		{"Keep Wins", Canonicalizer{KeepParams: []string{"page"}, DropParams: []string{"page"}}, "https://example.com/a?page=2&x=1", "https://example.com/a?page=2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.c.Canonicalize(tt.in)
+			if err != nil {
+				t.Fatalf("Canonicalize(%q) error: %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("Canonicalize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalizer_CanonicalizeOr(t *testing.T) {
+	if got := (Canonicalizer{}).CanonicalizeOr("://bad"); got != "://bad" {
+		t.Errorf("CanonicalizeOr() = %q, want input back", got)
+	}
+}
@@ -0,0 +1,79 @@
+package worker
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// concurrencyCooldown is the minimum time between two adjustments, giving
+// the previous one time to show an effect.
+const concurrencyCooldown = 5 * time.Second
+
+// InFlightSetter is implemented by bus.Subscription.
+type InFlightSetter interface {
+	ChangeMaxInFlight(maxInFlight int)
+}
+
+// AdaptiveConcurrency adjusts a consumer's max in-flight messages to rate
+// limiting: it halves on throttling and grows back by one per recovered
+// call, staying between min and max.
+type AdaptiveConcurrency struct {
+	target   InFlightSetter
+	min, max int
+
+	mu       sync.Mutex
+	current  int
+	adjusted time.Time
+	now      func() time.Time
+}
+
+// NewAdaptiveConcurrency starts target at max in flight.
+func NewAdaptiveConcurrency(target InFlightSetter, min, max int) *AdaptiveConcurrency {
+	if min < 1 {
+		min = 1
+	}
+	if max < min {
+		max = min
+	}
+	return &AdaptiveConcurrency{
+		target:  target,
+		min:     min,
+		max:     max,
+		current: max,
+		now:     time.Now,
+	}
+}
+
+// Current returns the max in flight last applied.
+func (c *AdaptiveConcurrency) Current() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.current
+}
+
+func (c *AdaptiveConcurrency) Throttled() {
+	c.adjust(func(n int) int { return n / 2 })
+}
+
+func (c *AdaptiveConcurrency) Recovered() {
+	c.adjust(func(n int) int { return n + 1 })
+}
+
+func (c *AdaptiveConcurrency) adjust(next func(int) int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := min(max(next(c.current), c.min), c.max)
+	if n == c.current {
+		return
+	}
+	now := c.now()
+	if !c.adjusted.IsZero() && now.Sub(c.adjusted) < concurrencyCooldown {
+		return
+	}
+	slog.Info("adjusting embed concurrency", "from", c.current, "to", n)
+	c.current = n
+	c.adjusted = now
+	c.target.ChangeMaxInFlight(n)
+}
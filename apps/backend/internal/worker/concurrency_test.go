@@ -0,0 +1,53 @@
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type inFlightRecorder struct {
+	values []int
+}
+
+func (r *inFlightRecorder) ChangeMaxInFlight(n int) { r.values = append(r.values, n) }
+
+func TestAdaptiveConcurrency(t *testing.T) {
+	rec := &inFlightRecorder{}
+	now := time.Unix(1700000000, 0)
+	c := NewAdaptiveConcurrency(rec, 1, 8)
+	c.now = func() time.Time { return now }
+
+	assert.Equal(t, 8, c.Current())
+
+	c.Throttled()
+	assert.Equal(t, 4, c.Current())
+
+	// Within the cooldown further signals are ignored
+	c.Throttled()
+	c.Recovered()
+	assert.Equal(t, 4, c.Current())
+
+	now = now.Add(concurrencyCooldown)
+	c.Throttled()
+	now = now.Add(concurrencyCooldown)
+	c.Throttled()
+	now = now.Add(concurrencyCooldown)
+	c.Throttled()
+	assert.Equal(t, 1, c.Current(), "never below min")
+
+	now = now.Add(concurrencyCooldown)
+	c.Recovered()
+	assert.Equal(t, 2, c.Current())
+
+	assert.Equal(t, []int{4, 2, 1, 2}, rec.values)
+}
+
+func TestAdaptiveConcurrency_StaysAtMax(t *testing.T) {
+	rec := &inFlightRecorder{}
+	c := NewAdaptiveConcurrency(rec, 1, 4)
+	c.Recovered()
+	assert.Equal(t, 4, c.Current())
+	assert.Empty(t, rec.values)
+}
@@ -0,0 +1,259 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"qurio/apps/backend/internal/config"
+)
+
+// dispatchPerSource bounds how many pending pages of one source are looked at
+// per tick, so a huge crawl can't starve the others.
+const dispatchPerSource = 100
+
+// Frontier holds the discovered pages waiting to be crawled.
+type Frontier interface {
+	// PendingPages returns up to perSource pending pages of each web source,
+	// shallowest and oldest first.
+	PendingPages(ctx context.Context, perSource int) ([]PageDTO, error)
+	// ClaimPage moves a pending page to 'processing', reporting false if it
+	// was no longer pending.
+	ClaimPage(ctx context.Context, sourceID, url string) (bool, error)
+	// CountReleasedPages counts the pages handed to the crawler so far.
+	CountReleasedPages(ctx context.Context, sourceID string) (int, error)
+	// SkipPendingPages marks the remaining pending pages 'skipped'.
+	SkipPendingPages(ctx context.Context, sourceID, reason string) (int64, error)
+}
+
+// CrawlBudget caps a crawl; zero values mean no limit.
+type CrawlBudget struct {
+	MaxPages    int
+	MaxDuration time.Duration
+	StartedAt   time.Time
+}
+
+// Dispatcher releases pending pages to the crawler at a per-host rate and
+// within each source's crawl budget, instead of publishing every page as soon
+// as it is discovered. Pages over budget are skipped so the source completes.
+type Dispatcher struct {
+	frontier      Frontier
+	pageManager   PageManager
+	sourceFetcher SourceFetcher
+	updater       SourceStatusUpdater
+	publisher     TaskPublisher
+
+	hostInterval time.Duration
+	catchUp      time.Duration // how far a host's unused slots carry over
+	now          func() time.Time
+
+	mu       sync.Mutex
+	nextSlot map[string]time.Time // per host, when the next page may go
+}
+
+// NewDispatcher releases at most hostPagesPerMinute pages per host.
+func NewDispatcher(f Frontier, pm PageManager, sf SourceFetcher, u SourceStatusUpdater, tp TaskPublisher, hostPagesPerMinute int) *Dispatcher {
+	if hostPagesPerMinute < 1 {
+		hostPagesPerMinute = 1
+	}
+	return &Dispatcher{
+		frontier:      f,
+		pageManager:   pm,
+		sourceFetcher: sf,
+		updater:       u,
+		publisher:     tp,
+		hostInterval:  time.Minute / time.Duration(hostPagesPerMinute),
+		now:           time.Now,
+		nextSlot:      make(map[string]time.Time),
+	}
+}
+
+// Run dispatches every interval until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	d.mu.Lock()
+	d.catchUp = interval
+	d.mu.Unlock()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := d.Dispatch(ctx); err != nil {
+				slog.Error("crawl dispatch failed", "error", err)
+			}
+		}
+	}
+}
+
+// Dispatch releases the pending pages allowed right now and returns how many
+// were published.
+func (d *Dispatcher) Dispatch(ctx context.Context) (int, error) {
+	d.pruneSlots()
+	pages, err := d.frontier.PendingPages(ctx, dispatchPerSource)
+	if err != nil {
+		return 0, err
+	}
+
+	var order []string
+	bySource := make(map[string][]PageDTO)
+	for _, p := range pages {
+		if _, ok := bySource[p.SourceID]; !ok {
+			order = append(order, p.SourceID)
+		}
+		bySource[p.SourceID] = append(bySource[p.SourceID], p)
+	}
+
+	released := 0
+	for _, sourceID := range order {
+		n, err := d.dispatchSource(ctx, sourceID, bySource[sourceID])
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to dispatch source pages", "source_id", sourceID, "error", err)
+		}
+		released += n
+	}
+	return released, nil
+}
+
+func (d *Dispatcher) dispatchSource(ctx context.Context, sourceID string, pages []PageDTO) (int, error) {
+	budget, err := d.sourceFetcher.GetCrawlBudget(ctx, sourceID)
+	if err != nil {
+		return 0, err
+	}
+	if budget.MaxDuration > 0 && !budget.StartedAt.IsZero() && d.now().Sub(budget.StartedAt) >= budget.MaxDuration {
+		return 0, d.skip(ctx, sourceID, "crawl time limit reached")
+	}
+	remaining := -1
+	if budget.MaxPages > 0 {
+		count, err := d.frontier.CountReleasedPages(ctx, sourceID)
+		if err != nil {
+			return 0, err
+		}
+		if remaining = budget.MaxPages - count; remaining <= 0 {
+			return 0, d.skip(ctx, sourceID, "page budget reached")
+		}
+	}
+
+	maxDepth, exclusions, apiKey, _, err := d.sourceFetcher.GetSourceConfig(ctx, sourceID)
+	if err != nil {
+		return 0, err
+	}
+	scope, err := d.sourceFetcher.GetCrawlScope(ctx, sourceID)
+	if err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for _, p := range pages {
+		if remaining == 0 {
+			break
+		}
+		u, err := url.Parse(p.URL)
+		if err != nil || !d.takeSlot(u.Host) {
+			continue
+		}
+		claimed, err := d.frontier.ClaimPage(ctx, sourceID, p.URL)
+		if err != nil || !claimed {
+			// The page went elsewhere, so its slot is given back
+			d.releaseSlot(u.Host)
+		}
+		if err != nil {
+			return released, err
+		}
+		if !claimed {
+			continue
+		}
+
+		correlationID := p.CorrelationID
+		if correlationID == "" {
+			correlationID = uuid.New().String()
+		}
+
+		taskPayload, _ := json.Marshal(map[string]interface{}{
+			"type":           "web",
+			"url":            p.URL,
+			"id":             sourceID,
+			"depth":          p.Depth,
+			"max_depth":      maxDepth,
+			"exclusions":     exclusions,
+			"allowed_hosts":  scope.AllowedHosts,
+			"gemini_api_key": apiKey,
+			"correlation_id": correlationID,
+		})
+		if err := d.publisher.Publish(config.TopicIngestWeb, taskPayload); err != nil {
+			slog.ErrorContext(ctx, "failed to publish task, marking page as failed", "error", err, "url", p.URL)
+			_ = d.pageManager.UpdatePageStatus(ctx, sourceID, p.URL, "failed", fmt.Sprintf("Failed to publish task: %v", err))
+			continue
+		}
+		released++
+		if remaining > 0 {
+			remaining--
+		}
+	}
+	if released > 0 {
+		slog.InfoContext(ctx, "released pages to crawler", "source_id", sourceID, "count", released)
+	}
+	return released, nil
+}
+
+func (d *Dispatcher) skip(ctx context.Context, sourceID, reason string) error {
+	n, err := d.frontier.SkipPendingPages(ctx, sourceID, reason)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		slog.InfoContext(ctx, "skipped pending pages", "source_id", sourceID, "count", n, "reason", reason)
+	}
+	completeSourceIfDone(ctx, d.pageManager, d.updater, sourceID)
+	return nil
+}
+
+// pruneSlots forgets hosts whose slots have lapsed; they start afresh anyway.
+func (d *Dispatcher) pruneSlots() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	floor := d.now().Add(-d.catchUp)
+	for host, next := range d.nextSlot {
+		if next.Before(floor) {
+			delete(d.nextSlot, host)
+		}
+	}
+}
+
+// takeSlot reports whether a page of host may be released now. Slots missed
+// between two ticks carry over, older ones don't, so an idle host can't burst.
+func (d *Dispatcher) takeSlot(host string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	floor := now.Add(-d.catchUp)
+	next, ok := d.nextSlot[host]
+	if !ok || next.Before(floor) {
+		next = floor
+	}
+	if next.After(now) {
+		return false
+	}
+	d.nextSlot[host] = next.Add(d.hostInterval)
+	return true
+}
+
+// releaseSlot gives back the slot just taken for host.
+func (d *Dispatcher) releaseSlot(host string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if next, ok := d.nextSlot[host]; ok {
+		d.nextSlot[host] = next.Add(-d.hostInterval)
+	}
+}
@@ -0,0 +1,160 @@
+package worker_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"qurio/apps/backend/internal/config"
+	"qurio/apps/backend/internal/worker"
+)
+
+func pendingPage(sourceID, url string, depth int) worker.PageDTO {
+	return worker.PageDTO{SourceID: sourceID, URL: url, Status: "pending", Depth: depth}
+}
+
+func TestDispatcher_Dispatch_PerHostRate(t *testing.T) {
+	f := new(MockFrontier)
+	sf := new(MockSourceFetcher)
+	tp := new(MockTaskPublisher)
+	d := worker.NewDispatcher(f, new(MockPageManager), sf, new(MockUpdater), tp, 60)
+
+	f.On("PendingPages", mock.Anything, mock.Anything).Return([]worker.PageDTO{
+		pendingPage("src1", "https://example.com/a", 1),
+		pendingPage("src1", "https://example.com/b", 1),
+		pendingPage("src1", "https://api.example.com/c", 2),
+	}, nil)
+	sf.On("GetCrawlBudget", mock.Anything, "src1").Return(worker.CrawlBudget{}, nil)
+	sf.On("GetSourceConfig", mock.Anything, "src1").Return(3, []string{"/old/"}, "key", "Src", nil)
+	sf.On("GetCrawlScope", mock.Anything, "src1").Return(worker.CrawlScope{AllowedHosts: []string{"api.example.com"}}, nil)
+	f.On("ClaimPage", mock.Anything, "src1", "https://example.com/a").Return(true, nil)
+	f.On("ClaimPage", mock.Anything, "src1", "https://api.example.com/c").Return(true, nil)
+	tp.On("Publish", config.TopicIngestWeb, mock.MatchedBy(func(b []byte) bool {
+		var p map[string]interface{}
+		json.Unmarshal(b, &p)
+		hosts, _ := p["allowed_hosts"].([]interface{})
+		return p["max_depth"] == float64(3) && p["gemini_api_key"] == "key" && len(hosts) == 1
+	})).Return(nil).Twice()
+
+	n, err := d.Dispatch(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n) // one page per host this tick
+	f.AssertNotCalled(t, "ClaimPage", mock.Anything, "src1", "https://example.com/b")
+	tp.AssertExpectations(t)
+}
+
+func TestDispatcher_Dispatch_SkipsClaimedElsewhere(t *testing.T) {
+	f := new(MockFrontier)
+	sf := new(MockSourceFetcher)
+	tp := new(MockTaskPublisher)
+	d := worker.NewDispatcher(f, new(MockPageManager), sf, new(MockUpdater), tp, 60)
+
+	next := pendingPage("src1", "https://example.com/b", 1)
+	next.CorrelationID = "req-123"
+	f.On("PendingPages", mock.Anything, mock.Anything).Return([]worker.PageDTO{pendingPage("src1", "https://example.com/a", 1), next}, nil)
+	sf.On("GetCrawlBudget", mock.Anything, "src1").Return(worker.CrawlBudget{}, nil)
+	sf.On("GetSourceConfig", mock.Anything, "src1").Return(3, []string{}, "", "Src", nil)
+	sf.On("GetCrawlScope", mock.Anything, "src1").Return(worker.CrawlScope{}, nil)
+	f.On("ClaimPage", mock.Anything, "src1", "https://example.com/a").Return(false, nil)
+	f.On("ClaimPage", mock.Anything, "src1", "https://example.com/b").Return(true, nil)
+	tp.On("Publish", config.TopicIngestWeb, mock.MatchedBy(func(b []byte) bool {
+		var p map[string]interface{}
+		json.Unmarshal(b, &p)
+		return p["url"] == "https://example.com/b" && p["correlation_id"] == "req-123"
+	})).Return(nil).Once()
+
+	// The slot of the page claimed elsewhere goes to the next page of the host
+	n, err := d.Dispatch(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	tp.AssertExpectations(t)
+}
+
+func TestDispatcher_Dispatch_PageBudget(t *testing.T) {
+	f := new(MockFrontier)
+	sf := new(MockSourceFetcher)
+	pm := new(MockPageManager)
+	u := new(MockUpdater)
+	tp := new(MockTaskPublisher)
+	d := worker.NewDispatcher(f, pm, sf, u, tp, 60)
+
+	f.On("PendingPages", mock.Anything, mock.Anything).Return([]worker.PageDTO{
+		pendingPage("src1", "https://example.com/a", 1),
+		pendingPage("src1", "https://other.example.com/b", 1),
+	}, nil)
+	sf.On("GetCrawlBudget", mock.Anything, "src1").Return(worker.CrawlBudget{MaxPages: 10}, nil)
+	sf.On("GetSourceConfig", mock.Anything, "src1").Return(3, []string{}, "", "Src", nil)
+	sf.On("GetCrawlScope", mock.Anything, "src1").Return(worker.CrawlScope{}, nil)
+
+	t.Run("ReleasesUpToBudget", func(t *testing.T) {
+		f.On("CountReleasedPages", mock.Anything, "src1").Return(9, nil).Once()
+		f.On("ClaimPage", mock.Anything, "src1", "https://example.com/a").Return(true, nil).Once()
+		tp.On("Publish", config.TopicIngestWeb, mock.Anything).Return(nil).Once()
+
+		n, err := d.Dispatch(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+		f.AssertNotCalled(t, "ClaimPage", mock.Anything, "src1", "https://other.example.com/b")
+	})
+
+	t.Run("SkipsRestWhenSpent", func(t *testing.T) {
+		f.On("CountReleasedPages", mock.Anything, "src1").Return(10, nil).Once()
+		f.On("SkipPendingPages", mock.Anything, "src1", "page budget reached").Return(int64(1), nil).Once()
+		pm.On("CountPendingPages", mock.Anything, "src1").Return(0, nil).Once()
+		u.On("CompleteSync", mock.Anything, "src1").Return(nil).Once()
+
+		n, err := d.Dispatch(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 0, n)
+		f.AssertExpectations(t)
+		u.AssertExpectations(t)
+	})
+}
+
+func TestDispatcher_Dispatch_TimeLimit(t *testing.T) {
+	f := new(MockFrontier)
+	sf := new(MockSourceFetcher)
+	pm := new(MockPageManager)
+	u := new(MockUpdater)
+	tp := new(MockTaskPublisher)
+	d := worker.NewDispatcher(f, pm, sf, u, tp, 60)
+
+	f.On("PendingPages", mock.Anything, mock.Anything).Return([]worker.PageDTO{pendingPage("src1", "https://example.com/a", 1)}, nil)
+	sf.On("GetCrawlBudget", mock.Anything, "src1").Return(worker.CrawlBudget{
+		MaxDuration: 30 * time.Minute,
+		StartedAt:   time.Now().Add(-time.Hour),
+	}, nil)
+	f.On("SkipPendingPages", mock.Anything, "src1", "crawl time limit reached").Return(int64(1), nil)
+	pm.On("CountPendingPages", mock.Anything, "src1").Return(2, nil) // pages still being crawled
+
+	n, err := d.Dispatch(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	f.AssertExpectations(t)
+	u.AssertNotCalled(t, "CompleteSync", mock.Anything, mock.Anything)
+	tp.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+}
+
+func TestDispatcher_Dispatch_PublishFailure(t *testing.T) {
+	f := new(MockFrontier)
+	sf := new(MockSourceFetcher)
+	pm := new(MockPageManager)
+	tp := new(MockTaskPublisher)
+	d := worker.NewDispatcher(f, pm, sf, new(MockUpdater), tp, 60)
+
+	f.On("PendingPages", mock.Anything, mock.Anything).Return([]worker.PageDTO{pendingPage("src1", "https://example.com/a", 1)}, nil)
+	sf.On("GetCrawlBudget", mock.Anything, "src1").Return(worker.CrawlBudget{}, nil)
+	sf.On("GetSourceConfig", mock.Anything, "src1").Return(3, []string{}, "", "Src", nil)
+	sf.On("GetCrawlScope", mock.Anything, "src1").Return(worker.CrawlScope{}, nil)
+	f.On("ClaimPage", mock.Anything, "src1", "https://example.com/a").Return(true, nil)
+	tp.On("Publish", config.TopicIngestWeb, mock.Anything).Return(assert.AnError)
+	pm.On("UpdatePageStatus", mock.Anything, "src1", "https://example.com/a", "failed", mock.Anything).Return(nil)
+
+	n, err := d.Dispatch(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	pm.AssertExpectations(t)
+}
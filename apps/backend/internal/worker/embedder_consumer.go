@@ -7,23 +7,42 @@ import (
 	"log/slog"
 	"time"
 
-	"github.com/nsqio/go-nsq"
+	"qurio/apps/backend/features/job"
+	"qurio/apps/backend/internal/bus"
 	"qurio/apps/backend/internal/middleware"
 )
 
 type EmbedderConsumer struct {
 	embedder Embedder
 	store    VectorStore
+	jobRepo  job.Repository
+	retry    RetryPolicy
+	pages    PageManager
+	updater  SourceStatusUpdater
 }
 
-func NewEmbedderConsumer(e Embedder, s VectorStore) *EmbedderConsumer {
+func NewEmbedderConsumer(e Embedder, s VectorStore, j job.Repository) *EmbedderConsumer {
 	return &EmbedderConsumer{
 		embedder: e,
 		store:    s,
+		jobRepo:  j,
 	}
 }
 
-func (h *EmbedderConsumer) HandleMessage(m *nsq.Message) error {
+// SetRetryPolicy bounds the attempts per message. A message failing its last
+// attempt is saved to failed_jobs instead of being requeued.
+func (h *EmbedderConsumer) SetRetryPolicy(p RetryPolicy) {
+	h.retry = p
+}
+
+// SetProgressTracking counts stored chunks against their page, completing
+// pages and then sources once all of their embeddings have landed.
+func (h *EmbedderConsumer) SetProgressTracking(pm PageManager, u SourceStatusUpdater) {
+	h.pages = pm
+	h.updater = u
+}
+
+func (h *EmbedderConsumer) HandleMessage(m *bus.Message) error {
 	if len(m.Body) == 0 {
 		return nil
 	}
@@ -43,15 +62,24 @@ func (h *EmbedderConsumer) HandleMessage(m *nsq.Message) error {
 	// Reconstruct Contextual String
 	// Format:
 	// Title: <Page Title>
+	// Section: <Heading Breadcrumb> (Optional)
 	// URL: <Page URL>
 	// Type: <Content Type>
+	// Symbol: <Declared Symbols> (Optional)
 	// Author: <Author> (Optional)
 	// Created: <Created At> (Optional)
 	// ---
 	// <Raw Chunk Content>
-	contextualString := fmt.Sprintf("Title: %s\nSource: %s\nPath: %s\nURL: %s\nType: %s",
-		payload.Title, payload.SourceName, payload.Path, payload.SourceURL, payload.ChunkType)
+	contextualString := fmt.Sprintf("Title: %s\n", payload.Title)
+	if payload.Section != "" {
+		contextualString += fmt.Sprintf("Section: %s\n", payload.Section)
+	}
+	contextualString += fmt.Sprintf("Source: %s\nPath: %s\nURL: %s\nType: %s",
+		payload.SourceName, payload.Path, payload.SourceURL, payload.ChunkType)
 
+	if payload.Symbol != "" {
+		contextualString += fmt.Sprintf("\nSymbol: %s", payload.Symbol)
+	}
 	if payload.Author != "" {
 		contextualString += fmt.Sprintf("\nAuthor: %s", payload.Author)
 	}
@@ -68,8 +96,8 @@ func (h *EmbedderConsumer) HandleMessage(m *nsq.Message) error {
 
 	vector, err := h.embedder.Embed(embedCtx, contextualString)
 	if err != nil {
-		slog.ErrorContext(ctx, "embedding failed", "error", err, "source_id", payload.SourceID, "url", payload.SourceURL)
-		return err // Retry
+		slog.ErrorContext(ctx, "embedding failed", "error", err, "source_id", payload.SourceID, "url", payload.SourceURL, "attempt", m.Attempts)
+		return h.fail(ctx, m, payload, err)
 	}
 
 	// Store Chunk
@@ -81,18 +109,61 @@ func (h *EmbedderConsumer) HandleMessage(m *nsq.Message) error {
 		ChunkIndex: payload.ChunkIndex,
 		Type:       payload.ChunkType,
 		Language:   payload.Language,
+		Symbol:     payload.Symbol,
+		Section:    payload.Section,
 		Title:      payload.Title,
 		SourceName: payload.SourceName,
 		Author:     payload.Author,
 		CreatedAt:  payload.CreatedAt,
 		PageCount:  payload.PageCount,
+		Version:    payload.Version,
 	}
 
 	if err := h.store.StoreChunk(embedCtx, chunk); err != nil {
-		slog.ErrorContext(ctx, "store chunk failed", "error", err, "source_id", payload.SourceID, "url", payload.SourceURL)
-		return err // Retry
+		slog.ErrorContext(ctx, "store chunk failed", "error", err, "source_id", payload.SourceID, "url", payload.SourceURL, "attempt", m.Attempts)
+		return h.fail(ctx, m, payload, err)
 	}
 
 	slog.InfoContext(ctx, "chunk stored successfully", "source_id", payload.SourceID, "chunk_index", payload.ChunkIndex)
+
+	if h.pages != nil {
+		pageDone, err := h.pages.MarkChunkEmbedded(ctx, payload.SourceID, payload.SourceURL, payload.ChunkIndex)
+		if err != nil {
+			// The chunk is stored; a retry would only store it again.
+			slog.WarnContext(ctx, "failed to record embedded chunk", "error", err, "source_id", payload.SourceID, "url", payload.SourceURL)
+		} else if pageDone {
+			completeSourceIfDone(ctx, h.pages, h.updater, payload.SourceID)
+		}
+	}
+	return nil
+}
+
+// fail returns err so NSQ retries the message, unless this was its last
+// attempt, in which case it is dead-lettered to failed_jobs.
+func (h *EmbedderConsumer) fail(ctx context.Context, m *bus.Message, payload IngestEmbedPayload, err error) error {
+	if !h.retry.Exhausted(m.Attempts) || h.jobRepo == nil {
+		return err // Retry
+	}
+
+	failedJob := &job.Job{
+		SourceID: payload.SourceID,
+		Handler:  job.HandlerEmbedder,
+		Payload:  m.Body,
+		Error:    err.Error(),
+	}
+	if saveErr := h.jobRepo.Save(ctx, failedJob); saveErr != nil {
+		slog.ErrorContext(ctx, "failed to save failed embed job", "error", saveErr, "source_id", payload.SourceID)
+		return err
+	}
+
+	slog.WarnContext(ctx, "embed attempts exhausted, saved failed job", "job_id", failedJob.ID, "source_id", payload.SourceID, "attempts", m.Attempts)
+
+	// The page can't complete without this chunk
+	if h.pages != nil {
+		if err := h.pages.UpdatePageStatus(ctx, payload.SourceID, payload.SourceURL, "failed", "embedding failed: "+err.Error()); err != nil {
+			slog.WarnContext(ctx, "failed to update page status", "error", err)
+		}
+		completeSourceIfDone(ctx, h.pages, h.updater, payload.SourceID)
+	}
 	return nil
 }
@@ -2,11 +2,13 @@ package worker_test
 
 import (
 	"encoding/json"
+	"errors"
 	"testing"
 
-	"github.com/nsqio/go-nsq"
+	"qurio/apps/backend/internal/bus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"qurio/apps/backend/features/job"
 	"qurio/apps/backend/internal/worker"
 )
 
@@ -14,7 +16,7 @@ func TestEmbedderConsumer_HandleMessage_Success(t *testing.T) {
 	e := new(MockEmbedder)
 	s := new(MockVectorStore)
 	
-	consumer := worker.NewEmbedderConsumer(e, s)
+	consumer := worker.NewEmbedderConsumer(e, s, nil)
 
 	payload := worker.IngestEmbedPayload{
 		SourceID:   "src1",
@@ -25,16 +27,19 @@ func TestEmbedderConsumer_HandleMessage_Success(t *testing.T) {
 		Author:     "John Doe",
 		CreatedAt:  "2023-01-01",
 		ChunkType:  "text",
+		Symbol:     "Server.ServeHTTP",
+		Section:    "Guide > Serving",
 	}
 	body, _ := json.Marshal(payload)
-	msg := &nsq.Message{Body: body}
+	msg := &bus.Message{Body: body}
 
 	// Expect Embed call with formatted context string
 	e.On("Embed", mock.Anything, mock.MatchedBy(func(text string) bool {
 		// Check that metadata is included in embedding context
-		return assert.Contains(t, text, "Title: Title") &&
+		return assert.Contains(t, text, "Title: Title\nSection: Guide > Serving\n") &&
 		       assert.Contains(t, text, "Author: John Doe") &&
 			   assert.Contains(t, text, "Created: 2023-01-01") &&
+			   assert.Contains(t, text, "Symbol: Server.ServeHTTP") &&
 			   assert.Contains(t, text, "Chunk Content")
 	})).Return([]float32{0.1, 0.2}, nil)
 
@@ -42,6 +47,8 @@ func TestEmbedderConsumer_HandleMessage_Success(t *testing.T) {
 	s.On("StoreChunk", mock.Anything, mock.MatchedBy(func(c worker.Chunk) bool {
 		return c.SourceID == "src1" && 
 		       c.Author == "John Doe" && 
+			   c.Symbol == "Server.ServeHTTP" &&
+			   c.Section == "Guide > Serving" &&
 			   c.Vector[0] == 0.1
 	})).Return(nil)
 
@@ -55,14 +62,14 @@ func TestEmbedderConsumer_HandleMessage_Success(t *testing.T) {
 func TestEmbedderConsumer_HandleMessage_EmbedError(t *testing.T) {
 	e := new(MockEmbedder)
 	s := new(MockVectorStore)
-	consumer := worker.NewEmbedderConsumer(e, s)
+	consumer := worker.NewEmbedderConsumer(e, s, nil)
 
 	payload := worker.IngestEmbedPayload{
 		SourceID: "src1",
 		Content: "content",
 	}
 	body, _ := json.Marshal(payload)
-	msg := &nsq.Message{Body: body}
+	msg := &bus.Message{Body: body}
 
 	e.On("Embed", mock.Anything, mock.Anything).Return(nil, assert.AnError)
 
@@ -74,14 +81,14 @@ func TestEmbedderConsumer_HandleMessage_EmbedError(t *testing.T) {
 func TestEmbedderConsumer_HandleMessage_StoreError(t *testing.T) {
 	e := new(MockEmbedder)
 	s := new(MockVectorStore)
-	consumer := worker.NewEmbedderConsumer(e, s)
+	consumer := worker.NewEmbedderConsumer(e, s, nil)
 
 	payload := worker.IngestEmbedPayload{
 		SourceID: "src1",
 		Content: "content",
 	}
 	body, _ := json.Marshal(payload)
-	msg := &nsq.Message{Body: body}
+	msg := &bus.Message{Body: body}
 
 	e.On("Embed", mock.Anything, mock.Anything).Return([]float32{0.1}, nil)
 	s.On("StoreChunk", mock.Anything, mock.Anything).Return(assert.AnError)
@@ -91,9 +98,120 @@ func TestEmbedderConsumer_HandleMessage_StoreError(t *testing.T) {
 }
 
 func TestEmbedderConsumer_HandleMessage_PoisonPill(t *testing.T) {
-	consumer := worker.NewEmbedderConsumer(nil, nil)
-	msg := &nsq.Message{Body: []byte("invalid json")}
+	consumer := worker.NewEmbedderConsumer(nil, nil, nil)
+	msg := &bus.Message{Body: []byte("invalid json")}
 	
 	err := consumer.HandleMessage(msg)
 	assert.NoError(t, err) // No retry
-}
\ No newline at end of file
+}
+func TestEmbedderConsumer_HandleMessage_DeadLetter(t *testing.T) {
+	e := new(MockEmbedder)
+	s := new(MockVectorStore)
+	j := new(MockJobRepo)
+	consumer := worker.NewEmbedderConsumer(e, s, j)
+	consumer.SetRetryPolicy(worker.NewRetryPolicy(3, 1000, 60000))
+
+	payload := worker.IngestEmbedPayload{SourceID: "src1", Content: "content"}
+	body, _ := json.Marshal(payload)
+
+	e.On("Embed", mock.Anything, mock.Anything).Return(nil, assert.AnError)
+
+	// Attempts left: NSQ retries
+	err := consumer.HandleMessage(&bus.Message{Body: body, Attempts: 2})
+	assert.Error(t, err)
+	j.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+
+	// Last attempt: saved to failed_jobs and finished
+	j.On("Save", mock.Anything, mock.MatchedBy(func(fj *job.Job) bool {
+		return fj.Handler == job.HandlerEmbedder &&
+			fj.SourceID == "src1" &&
+			string(fj.Payload) == string(body) &&
+			fj.Error == assert.AnError.Error()
+	})).Return(nil).Once()
+
+	err = consumer.HandleMessage(&bus.Message{Body: body, Attempts: 3})
+	assert.NoError(t, err)
+	j.AssertExpectations(t)
+}
+
+func TestEmbedderConsumer_HandleMessage_DeadLetterSaveError(t *testing.T) {
+	e := new(MockEmbedder)
+	s := new(MockVectorStore)
+	j := new(MockJobRepo)
+	consumer := worker.NewEmbedderConsumer(e, s, j)
+	consumer.SetRetryPolicy(worker.NewRetryPolicy(1, 1000, 60000))
+
+	body, _ := json.Marshal(worker.IngestEmbedPayload{SourceID: "src1", Content: "content"})
+	e.On("Embed", mock.Anything, mock.Anything).Return([]float32{0.1}, nil)
+	s.On("StoreChunk", mock.Anything, mock.Anything).Return(assert.AnError)
+	j.On("Save", mock.Anything, mock.Anything).Return(errors.New("db down"))
+
+	err := consumer.HandleMessage(&bus.Message{Body: body, Attempts: 1})
+	assert.Equal(t, assert.AnError, err)
+}
+
+func TestEmbedderConsumer_HandleMessage_CompletesPageAndSource(t *testing.T) {
+	e := new(MockEmbedder)
+	s := new(MockVectorStore)
+	pm := new(MockPageManager)
+	u := new(MockUpdater)
+	consumer := worker.NewEmbedderConsumer(e, s, nil)
+	consumer.SetProgressTracking(pm, u)
+
+	body, _ := json.Marshal(worker.IngestEmbedPayload{SourceID: "src1", SourceURL: "http://example.com", Content: "content", ChunkIndex: 3})
+
+	e.On("Embed", mock.Anything, mock.Anything).Return([]float32{0.1}, nil)
+	s.On("StoreChunk", mock.Anything, mock.Anything).Return(nil)
+	pm.On("MarkChunkEmbedded", mock.Anything, "src1", "http://example.com", 3).Return(true, nil)
+	pm.On("CountPendingPages", mock.Anything, "src1").Return(0, nil)
+	u.On("CompleteSync", mock.Anything, "src1").Return(nil)
+
+	err := consumer.HandleMessage(&bus.Message{Body: body})
+	assert.NoError(t, err)
+	pm.AssertExpectations(t)
+	u.AssertExpectations(t)
+}
+
+func TestEmbedderConsumer_HandleMessage_PageStillEmbedding(t *testing.T) {
+	e := new(MockEmbedder)
+	s := new(MockVectorStore)
+	pm := new(MockPageManager)
+	u := new(MockUpdater)
+	consumer := worker.NewEmbedderConsumer(e, s, nil)
+	consumer.SetProgressTracking(pm, u)
+
+	body, _ := json.Marshal(worker.IngestEmbedPayload{SourceID: "src1", SourceURL: "http://example.com", Content: "content"})
+
+	e.On("Embed", mock.Anything, mock.Anything).Return([]float32{0.1}, nil)
+	s.On("StoreChunk", mock.Anything, mock.Anything).Return(nil)
+	pm.On("MarkChunkEmbedded", mock.Anything, "src1", "http://example.com", 0).Return(false, nil)
+
+	err := consumer.HandleMessage(&bus.Message{Body: body})
+	assert.NoError(t, err)
+	pm.AssertNotCalled(t, "CountPendingPages", mock.Anything, mock.Anything)
+	u.AssertNotCalled(t, "CompleteSync", mock.Anything, mock.Anything)
+}
+
+func TestEmbedderConsumer_HandleMessage_DeadLetterFailsPage(t *testing.T) {
+	e := new(MockEmbedder)
+	s := new(MockVectorStore)
+	j := new(MockJobRepo)
+	pm := new(MockPageManager)
+	u := new(MockUpdater)
+	consumer := worker.NewEmbedderConsumer(e, s, j)
+	consumer.SetRetryPolicy(worker.NewRetryPolicy(1, 1000, 60000))
+	consumer.SetProgressTracking(pm, u)
+
+	body, _ := json.Marshal(worker.IngestEmbedPayload{SourceID: "src1", SourceURL: "http://example.com", Content: "content"})
+
+	e.On("Embed", mock.Anything, mock.Anything).Return(nil, assert.AnError)
+	j.On("Save", mock.Anything, mock.Anything).Return(nil)
+	pm.On("UpdatePageStatus", mock.Anything, "src1", "http://example.com", "failed", mock.Anything).Return(nil)
+	pm.On("CountPendingPages", mock.Anything, "src1").Return(0, nil)
+	u.On("CompleteSync", mock.Anything, "src1").Return(nil)
+
+	err := consumer.HandleMessage(&bus.Message{Body: body, Attempts: 1})
+	assert.NoError(t, err)
+	pm.AssertExpectations(t)
+	u.AssertExpectations(t)
+}
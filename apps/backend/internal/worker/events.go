@@ -12,11 +12,14 @@ type IngestEmbedPayload struct {
 	ChunkIndex int    `json:"chunk_index"`
 	ChunkType  string `json:"chunk_type"`
 	Language   string `json:"language"`
+	Symbol     string `json:"symbol,omitempty"` // Declared symbols of code chunks
+	Section    string `json:"section,omitempty"` // Heading breadcrumb
 
 	// Context Metadata
 	Author    string `json:"author,omitempty"`
 	CreatedAt string `json:"created_at,omitempty"`
 	PageCount int    `json:"page_count,omitempty"`
+	Version   string `json:"version,omitempty"` // Source version label
 
 	CorrelationID string `json:"correlation_id"`
 }
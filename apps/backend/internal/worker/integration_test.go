@@ -6,14 +6,16 @@ import (
 	"testing"
 	"time"
 
-	"github.com/nsqio/go-nsq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"qurio/apps/backend/features/job"
 	"qurio/apps/backend/features/source"
+	"qurio/apps/backend/internal/adapter/nsqbus"
 	"qurio/apps/backend/internal/adapter/weaviate"
+	"qurio/apps/backend/internal/bus"
 	"qurio/apps/backend/internal/testutils"
+	"qurio/apps/backend/internal/text"
 	"qurio/apps/backend/internal/worker"
 	"qurio/apps/backend/internal/config"
 )
@@ -42,6 +44,46 @@ func (f *TestSourceFetcher) GetSourceConfig(ctx context.Context, id string) (int
 	return src.MaxDepth, src.Exclusions, "dummy-api-key", src.Name, nil
 }
 
+func (f *TestSourceFetcher) GetChunkProfile(ctx context.Context, id string) (text.ChunkProfile, error) {
+	src, err := f.Repo.Get(ctx, id)
+	if err != nil {
+		return text.ChunkProfile{}, err
+	}
+	return src.ChunkProfile(), nil
+}
+
+func (f *TestSourceFetcher) GetCrawlScope(ctx context.Context, id string) (worker.CrawlScope, error) {
+	src, err := f.Repo.Get(ctx, id)
+	if err != nil {
+		return worker.CrawlScope{}, err
+	}
+	return src.CrawlScope(), nil
+}
+
+func (f *TestSourceFetcher) GetCrawlBudget(ctx context.Context, id string) (worker.CrawlBudget, error) {
+	src, err := f.Repo.Get(ctx, id)
+	if err != nil {
+		return worker.CrawlBudget{}, err
+	}
+	return src.CrawlBudget(), nil
+}
+
+func (f *TestSourceFetcher) GetSourceVersion(ctx context.Context, id string) (string, error) {
+	src, err := f.Repo.Get(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	return src.Version, nil
+}
+
+func (f *TestSourceFetcher) GetSeeds(ctx context.Context, id string) ([]string, error) {
+	src, err := f.Repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return src.Seeds(), nil
+}
+
 func (f *TestSourceFetcher) GetSourceDetails(ctx context.Context, id string) (string, string, error) {
 	src, err := f.Repo.Get(ctx, id)
 	if err != nil {
@@ -75,6 +117,28 @@ func (a *PageManagerAdapter) CountPendingPages(ctx context.Context, sourceID str
 	return a.Repo.CountPendingPages(ctx, sourceID)
 }
 
+func (a *PageManagerAdapter) SetPageChunks(ctx context.Context, sourceID, url string, depth, expected int) error {
+	return a.Repo.SetPageChunks(ctx, sourceID, url, depth, expected)
+}
+
+func (a *PageManagerAdapter) MarkChunkEmbedded(ctx context.Context, sourceID, url string, chunkIndex int) (bool, error) {
+	return a.Repo.MarkChunkEmbedded(ctx, sourceID, url, chunkIndex)
+}
+
+func (a *PageManagerAdapter) RecordPageContent(ctx context.Context, sourceID, url, hash, chunkKey, etag, lastModified string) (string, error) {
+	return a.Repo.RecordPageContent(ctx, sourceID, url, hash, chunkKey, etag, lastModified)
+}
+
+// TestStatusUpdater completes syncs through the source service
+type TestStatusUpdater struct {
+	*source.PostgresRepo
+	Service *source.Service
+}
+
+func (u *TestStatusUpdater) CompleteSync(ctx context.Context, id string) error {
+	return u.Service.CompleteSync(ctx, id)
+}
+
 func TestIngestIntegration(t *testing.T) {
 	s := testutils.NewIntegrationSuite(t)
 	s.Setup()
@@ -96,10 +160,12 @@ func TestIngestIntegration(t *testing.T) {
 
 	pageManager := &PageManagerAdapter{Repo: sourceRepo}
 
+	updater := &TestStatusUpdater{PostgresRepo: sourceRepo, Service: source.NewService(sourceRepo, nil, vectorStore, nil)}
+
 	// ResultConsumer (Coordinator)
 	consumer := worker.NewResultConsumer(
 		vectorStore,
-		sourceRepo, // SourceStatusUpdater
+		updater, // SourceStatusUpdater
 		jobRepo,
 		sourceFetcher,
 		pageManager, // PageManager
@@ -107,17 +173,14 @@ func TestIngestIntegration(t *testing.T) {
 	)
 
 	// EmbedderConsumer (Worker)
-	embedderConsumer := worker.NewEmbedderConsumer(embedder, vectorStore)
+	embedderConsumer := worker.NewEmbedderConsumer(embedder, vectorStore, nil)
 	
 	// Wire EmbedderConsumer to NSQ
-	nsqCfg := nsq.NewConfig()
-	embedNsqConsumer, err := nsq.NewConsumer(config.TopicIngestEmbed, "integration-test", nsqCfg)
+	nsqBus, err := nsqbus.New(appCfg.NSQDHost, "")
 	require.NoError(t, err)
-	embedNsqConsumer.AddHandler(embedderConsumer)
-	
-	err = embedNsqConsumer.ConnectToNSQD(appCfg.NSQDHost)
+	defer nsqBus.Stop()
+	_, err = nsqBus.Subscribe(config.TopicIngestEmbed, bus.SubscribeOptions{Channel: "integration-test", Concurrency: 1}, embedderConsumer)
 	require.NoError(t, err)
-	defer embedNsqConsumer.Stop()
 
 	// 2. Setup Data: Create Source & Page
 	src := &source.Source{
@@ -152,10 +215,11 @@ func TestIngestIntegration(t *testing.T) {
 		},
 	}
 	body, _ := json.Marshal(payload)
-	msg := &nsq.Message{
-		Body:      body,
-		ID:        nsq.MessageID{'1', '2', '3', '4', '5', '6', '7', '8', '9', '0', 'a', 'b', 'c', 'd', 'e', 'f'},
-		Timestamp: time.Now().UnixNano(),
+	msg := &bus.Message{
+		Body:     body,
+		ID:       "1234567890abcdef",
+		Topic:    config.TopicIngestResult,
+		Attempts: 1,
 	}
 
 	// Exec HandleMessage (ResultConsumer)
@@ -3,44 +3,131 @@ package worker
 import (
 	"net/url"
 	"regexp"
+	"strings"
 )
 
-func DiscoverLinks(sourceID, host string, links []string, currentDepth, maxDepth int, exclusions []string) []PageDTO {
+// CrawlScope decides which discovered links of a source are crawled.
+type CrawlScope struct {
+	Host         string   // links may stay on this host
+	AllowedHosts []string // or go to these; "*.example.com" matches subdomains
+	PathPrefix   string   // when set, links must be at or below this path
+	Include      []string // when set, links must match one of these regexes
+	Exclude      []string // links matching any of these regexes are skipped
+
+	Canonicalizer Canonicalizer
+}
+
+// Allows reports whether the canonical URL u is in scope. Patterns that
+// don't compile never match.
+func (s CrawlScope) Allows(u *url.URL) bool {
+	return s.compile().allows(u)
+}
+
+type compiledScope struct {
+	CrawlScope
+	include, exclude []*regexp.Regexp
+}
+
+func (s CrawlScope) compile() compiledScope {
+	return compiledScope{CrawlScope: s, include: compilePatterns(s.Include), exclude: compilePatterns(s.Exclude)}
+}
+
+func compilePatterns(patterns []string) []*regexp.Regexp {
+	var res []*regexp.Regexp
+	for _, p := range patterns {
+		if re, err := regexp.Compile(p); err == nil {
+			res = append(res, re)
+		}
+	}
+	return res
+}
+
+func (s compiledScope) allows(u *url.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	if !s.allowsHost(u) {
+		return false
+	}
+	if !underPrefix(u.Path, s.PathPrefix) {
+		return false
+	}
+
+	link := u.String()
+	if len(s.Include) > 0 {
+		included := false
+		for _, re := range s.include {
+			if re.MatchString(link) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	for _, re := range s.exclude {
+		if re.MatchString(link) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s compiledScope) allowsHost(u *url.URL) bool {
+	if u.Host == normalizeHost(u.Scheme, s.Host) {
+		return true
+	}
+	for _, pattern := range s.AllowedHosts {
+		if MatchHost(pattern, u.Host) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchHost reports whether host matches pattern, either exactly or, for
+// "*.example.com", as a subdomain of example.com.
+func MatchHost(pattern, host string) bool {
+	pattern, host = strings.ToLower(pattern), strings.ToLower(host)
+	if domain, ok := strings.CutPrefix(pattern, "*."); ok {
+		return strings.HasSuffix(host, "."+domain)
+	}
+	return host == pattern
+}
+
+// underPrefix matches whole path segments: /docs/v2 covers /docs/v2 and
+// /docs/v2/intro but not /docs/v20.
+func underPrefix(p, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		return true
+	}
+	return p == prefix || strings.HasPrefix(p, prefix+"/")
+}
+
+// DiscoverLinks canonicalizes links and returns the in-scope ones as pending
+// pages one level deeper, without duplicates.
+func DiscoverLinks(sourceID string, scope CrawlScope, links []string, currentDepth, maxDepth int) []PageDTO {
 	if currentDepth >= maxDepth {
 		return nil
 	}
 
+	compiled := scope.compile()
 	var newPages []PageDTO
 	seen := make(map[string]bool)
 
 	for _, link := range links {
-		// 1. External Check
 		linkU, err := url.Parse(link)
-		if err != nil || linkU.Host != host {
-			continue
-		}
-
-		// Scheme Check
-		if linkU.Scheme != "http" && linkU.Scheme != "https" {
+		if err != nil {
 			continue
 		}
-
-		// Normalize: Strip Fragment
-		linkU.Fragment = ""
-		normalizedLink := linkU.String()
-
-		// 2. Exclusion Check
-		excluded := false
-		for _, ex := range exclusions {
-			if matched, _ := regexp.MatchString(ex, normalizedLink); matched {
-				excluded = true
-				break
-			}
-		}
-		if excluded {
+		canon := scope.Canonicalizer.canonicalURL(linkU)
+		if !compiled.allows(canon) {
 			continue
 		}
 
+		normalizedLink := canon.String()
 		if seen[normalizedLink] {
 			continue
 		}
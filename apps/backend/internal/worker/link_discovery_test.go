@@ -11,7 +11,7 @@ func TestDiscoverLinks_Comprehensive(t *testing.T) {
 		links        []string
 		currentDepth int
 		maxDepth     int
-		exclusions   []string
+		scope        CrawlScope
 	}
 	tests := []struct {
 		name string
@@ -72,11 +72,11 @@ func TestDiscoverLinks_Comprehensive(t *testing.T) {
 		{
 			name: "Exclusion Pattern",
 			args: args{
-				sourceID:   "src1",
-				host:       "example.com",
-				links:      []string{"https://example.com/valid", "https://example.com/exclude/me"},
-				exclusions: []string{".*exclude.*"},
-				maxDepth:   5,
+				sourceID: "src1",
+				host:     "example.com",
+				links:    []string{"https://example.com/valid", "https://example.com/exclude/me"},
+				scope:    CrawlScope{Exclude: []string{".*exclude.*"}},
+				maxDepth: 5,
 			},
 			want: []string{"https://example.com/valid"},
 		},
@@ -155,6 +155,94 @@ func TestDiscoverLinks_Comprehensive(t *testing.T) {
 			},
 			want: nil, // "example.com:8080" != "example.com"
 		},
+		{
+			name: "Allowed Hosts",
+			args: args{
+				sourceID: "src1",
+				host:     "docs.example.com",
+				links: []string{
+					"https://api.example.com/ref",
+					"https://v2.api.example.com/ref",
+					"https://example.com/home",
+					"https://notexample.com/x",
+					"https://other.org/page",
+					"https://blog.other.org/post",
+				},
+				scope:    CrawlScope{AllowedHosts: []string{"*.example.com", "other.org"}},
+				maxDepth: 5,
+			},
+			want: []string{"https://api.example.com/ref", "https://v2.api.example.com/ref", "https://other.org/page"},
+		},
+		{
+			name: "Include Patterns",
+			args: args{
+				sourceID: "src1",
+				host:     "example.com",
+				links:    []string{"https://example.com/docs/a", "https://example.com/api/b", "https://example.com/blog/c"},
+				scope:    CrawlScope{Include: []string{"/docs/", "/api/"}},
+				maxDepth: 5,
+			},
+			want: []string{"https://example.com/docs/a", "https://example.com/api/b"},
+		},
+		{
+			name: "Exclusion Wins Over Include",
+			args: args{
+				sourceID: "src1",
+				host:     "example.com",
+				links:    []string{"https://example.com/docs/a", "https://example.com/docs/old/b"},
+				scope:    CrawlScope{Include: []string{"/docs/"}, Exclude: []string{"/old/"}},
+				maxDepth: 5,
+			},
+			want: []string{"https://example.com/docs/a"},
+		},
+		{
+			name: "Path Prefix On Segment Boundary",
+			args: args{
+				sourceID: "src1",
+				host:     "example.com",
+				links:    []string{"https://example.com/docs/v2", "https://example.com/docs/v2/intro", "https://example.com/docs/v20", "https://example.com/docs"},
+				scope:    CrawlScope{PathPrefix: "/docs/v2/"},
+				maxDepth: 5,
+			},
+			want: []string{"https://example.com/docs/v2", "https://example.com/docs/v2/intro"},
+		},
+		{
+			name: "Deduplication via Canonicalization",
+			args: args{
+				sourceID: "src1",
+				host:     "example.com",
+				links: []string{
+					"https://example.com/guide",
+					"https://EXAMPLE.com:443/guide/",
+					"https://example.com/a/../guide/index.html",
+					"https://example.com/guide?utm_source=x&fbclid=y",
+				},
+				maxDepth: 5,
+			},
+			want: []string{"https://example.com/guide"},
+		},
+		{
+			name: "Query Parameters Sorted And Filtered",
+			args: args{
+				sourceID: "src1",
+				host:     "example.com",
+				links:    []string{"https://example.com/search?sort=asc&q=foo&session=1", "https://example.com/search?q=foo&sort=asc"},
+				scope:    CrawlScope{Canonicalizer: Canonicalizer{DropParams: []string{"session"}}},
+				maxDepth: 5,
+			},
+			want: []string{"https://example.com/search?q=foo&sort=asc"},
+		},
+		{
+			name: "Query Parameters Allow List",
+			args: args{
+				sourceID: "src1",
+				host:     "example.com",
+				links:    []string{"https://example.com/page?id=1&lang=en&ref=nav"},
+				scope:    CrawlScope{Canonicalizer: Canonicalizer{KeepParams: []string{"id"}}},
+				maxDepth: 5,
+			},
+			want: []string{"https://example.com/page?id=1"},
+		},
 		{
 			name: "Escaped Spaces",
 			args: args{
@@ -169,13 +257,14 @@ func TestDiscoverLinks_Comprehensive(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			scope := tt.args.scope
+			scope.Host = tt.args.host
 			got := DiscoverLinks(
 				tt.args.sourceID,
-				tt.args.host,
+				scope,
 				tt.args.links,
 				tt.args.currentDepth,
 				tt.args.maxDepth,
-				tt.args.exclusions,
 			)
 
 			if len(got) != len(tt.want) {
@@ -5,6 +5,7 @@ import (
 
 	"github.com/stretchr/testify/mock"
 	"qurio/apps/backend/features/job"
+	"qurio/apps/backend/internal/text"
 	"qurio/apps/backend/internal/worker"
 )
 
@@ -32,8 +33,8 @@ func (m *MockUpdater) UpdateStatus(ctx context.Context, id, status string) error
 	args := m.Called(ctx, id, status)
 	return args.Error(0)
 }
-func (m *MockUpdater) UpdateBodyHash(ctx context.Context, id, hash string) error {
-	args := m.Called(ctx, id, hash)
+func (m *MockUpdater) CompleteSync(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
@@ -52,6 +53,26 @@ func (m *MockSourceFetcher) GetSourceConfig(ctx context.Context, id string) (int
 	args := m.Called(ctx, id)
 	return args.Int(0), args.Get(1).([]string), args.String(2), args.String(3), args.Error(4)
 }
+func (m *MockSourceFetcher) GetChunkProfile(ctx context.Context, id string) (text.ChunkProfile, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(text.ChunkProfile), args.Error(1)
+}
+func (m *MockSourceFetcher) GetCrawlScope(ctx context.Context, id string) (worker.CrawlScope, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(worker.CrawlScope), args.Error(1)
+}
+func (m *MockSourceFetcher) GetCrawlBudget(ctx context.Context, id string) (worker.CrawlBudget, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(worker.CrawlBudget), args.Error(1)
+}
+func (m *MockSourceFetcher) GetSourceVersion(ctx context.Context, id string) (string, error) {
+	args := m.Called(ctx, id)
+	return args.String(0), args.Error(1)
+}
+func (m *MockSourceFetcher) GetSeeds(ctx context.Context, id string) ([]string, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).([]string), args.Error(1)
+}
 func (m *MockSourceFetcher) GetSourceDetails(ctx context.Context, id string) (string, string, error) {
 	args := m.Called(ctx, id)
 	return args.String(0), args.String(1), args.Error(2)
@@ -71,6 +92,19 @@ func (m *MockPageManager) CountPendingPages(ctx context.Context, sourceID string
 	args := m.Called(ctx, sourceID)
 	return args.Int(0), args.Error(1)
 }
+func (m *MockPageManager) SetPageChunks(ctx context.Context, sourceID, url string, depth, expected int) error {
+	args := m.Called(ctx, sourceID, url, depth, expected)
+	return args.Error(0)
+}
+func (m *MockPageManager) MarkChunkEmbedded(ctx context.Context, sourceID, url string, chunkIndex int) (bool, error) {
+	args := m.Called(ctx, sourceID, url, chunkIndex)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPageManager) RecordPageContent(ctx context.Context, sourceID, url, hash, chunkKey, etag, lastModified string) (string, error) {
+	args := m.Called(ctx, sourceID, url, hash, chunkKey, etag, lastModified)
+	return args.String(0), args.Error(1)
+}
 
 type MockTaskPublisher struct { mock.Mock }
 func (m *MockTaskPublisher) Publish(topic string, body []byte) error {
@@ -82,3 +116,21 @@ func (m *MockTaskPublisher) Publish(topic string, body []byte) error {
 func NewTestContext() context.Context {
 	return context.Background()
 }
+
+type MockFrontier struct { mock.Mock }
+func (m *MockFrontier) PendingPages(ctx context.Context, perSource int) ([]worker.PageDTO, error) {
+	args := m.Called(ctx, perSource)
+	return args.Get(0).([]worker.PageDTO), args.Error(1)
+}
+func (m *MockFrontier) ClaimPage(ctx context.Context, sourceID, url string) (bool, error) {
+	args := m.Called(ctx, sourceID, url)
+	return args.Bool(0), args.Error(1)
+}
+func (m *MockFrontier) CountReleasedPages(ctx context.Context, sourceID string) (int, error) {
+	args := m.Called(ctx, sourceID)
+	return args.Int(0), args.Error(1)
+}
+func (m *MockFrontier) SkipPendingPages(ctx context.Context, sourceID, reason string) (int64, error) {
+	args := m.Called(ctx, sourceID, reason)
+	return args.Get(0).(int64), args.Error(1)
+}
@@ -0,0 +1,23 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+)
+
+// completeSourceIfDone marks the source completed once none of its pages are
+// pending, processing or waiting for embeddings.
+func completeSourceIfDone(ctx context.Context, pm PageManager, u SourceStatusUpdater, sourceID string) {
+	pendingCount, err := pm.CountPendingPages(ctx, sourceID)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to count pending pages", "error", err)
+		return
+	}
+	if pendingCount > 0 {
+		return
+	}
+	slog.InfoContext(ctx, "source ingestion completed", "source_id", sourceID)
+	if err := u.CompleteSync(ctx, sourceID); err != nil {
+		slog.WarnContext(ctx, "failed to update source status to completed", "error", err)
+	}
+}
@@ -0,0 +1,270 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"qurio/apps/backend/internal/bus"
+	"qurio/apps/backend/internal/text"
+)
+
+const (
+	// rateLimitRetries is how often a call rejected by the provider is retried
+	// in place before the error is returned to the consumer.
+	rateLimitRetries = 3
+	// defaultRateLimitPause is used when a 429 carries no Retry-After hint.
+	defaultRateLimitPause = 10 * time.Second
+	// throttleThreshold is the wait for budget above which the limiter
+	// reports the worker as throttled.
+	throttleThreshold = 5 * time.Second
+	// limitsRefreshInterval bounds how often limits are re-read from settings.
+	limitsRefreshInterval = 30 * time.Second
+	// maxEmbedWait bounds the time one call waits for budget in total. Past
+	// NSQ's 60 second message timeout the message would be redelivered while
+	// still waiting, so longer waits requeue it instead.
+	maxEmbedWait = 40 * time.Second
+)
+
+// RateLimits are the embedding budgets of one worker. Zero means unlimited.
+type RateLimits struct {
+	RequestsPerMinute int
+	TokensPerMinute   int
+}
+
+// RateLimitError is returned by embedders when the provider rejected a call
+// for exceeding its quota. RetryAfter is zero when the provider gave no hint.
+type RateLimitError struct {
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("rate limited, retry after %s: %v", e.RetryAfter, e.Err)
+	}
+	return fmt.Sprintf("rate limited: %v", e.Err)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+// Throttler is told when embedding is being held back by rate limits and when
+// calls go through without waiting again.
+type Throttler interface {
+	Throttled()
+	Recovered()
+}
+
+// tokenBucket refills at perMinute/60 per second up to perMinute. Reservations
+// may drive the level negative; the deficit is the wait until they're covered.
+type tokenBucket struct {
+	perMinute int
+	level     float64
+	updated   time.Time
+}
+
+func (b *tokenBucket) setLimit(perMinute int, now time.Time) {
+	if perMinute == b.perMinute {
+		return
+	}
+	if b.perMinute == 0 || b.level > float64(perMinute) {
+		b.level = float64(perMinute)
+	}
+	b.perMinute = perMinute
+	b.updated = now
+}
+
+// reserve takes n from the bucket and returns how long the caller must wait
+// before spending it.
+func (b *tokenBucket) reserve(n int, now time.Time) time.Duration {
+	if b.perMinute <= 0 {
+		return 0
+	}
+	rate := float64(b.perMinute) / 60
+	b.level += now.Sub(b.updated).Seconds() * rate
+	if b.level > float64(b.perMinute) {
+		b.level = float64(b.perMinute)
+	}
+	b.updated = now
+	// A single call larger than the whole budget only has to wait for a full bucket
+	b.level -= float64(min(n, b.perMinute))
+	if b.level >= 0 {
+		return 0
+	}
+	return time.Duration(-b.level / rate * float64(time.Second))
+}
+
+// cancel returns a reservation that won't be spent.
+func (b *tokenBucket) cancel(n int) {
+	if b.perMinute <= 0 {
+		return
+	}
+	b.level += float64(min(n, b.perMinute))
+}
+
+// RateLimitedEmbedder paces calls to an Embedder so they stay within
+// requests- and tokens-per-minute budgets, shared by every consumer goroutine
+// of the worker. Calls the provider rejects with a RateLimitError pause all
+// callers for the Retry-After period and are retried. A call that would wait
+// longer than maxEmbedWait returns a *bus.RequeueError with the wait left.
+type RateLimitedEmbedder struct {
+	next      Embedder
+	limits    func(ctx context.Context) (RateLimits, error)
+	tok       text.Tokenizer
+	throttler Throttler
+
+	mu          sync.Mutex
+	requests    tokenBucket
+	tokens      tokenBucket
+	pausedUntil time.Time
+	refreshedAt time.Time
+
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// NewRateLimitedEmbedder wraps next. limits is consulted at most every 30
+// seconds, so budget changes in settings apply without a restart.
+func NewRateLimitedEmbedder(next Embedder, limits func(ctx context.Context) (RateLimits, error)) *RateLimitedEmbedder {
+	return &RateLimitedEmbedder{
+		next:   next,
+		limits: limits,
+		tok:    text.EstimateTokenizer{},
+		now:    time.Now,
+		sleep:  sleepContext,
+	}
+}
+
+// SetTokenizer sets how input tokens are counted against the token budget.
+func (e *RateLimitedEmbedder) SetTokenizer(tok text.Tokenizer) {
+	e.tok = tok
+}
+
+// SetThrottler registers t to be told when calls are held back.
+func (e *RateLimitedEmbedder) SetThrottler(t Throttler) {
+	e.throttler = t
+}
+
+func (e *RateLimitedEmbedder) Embed(ctx context.Context, input string) ([]float32, error) {
+	cost := e.tok.Count(input)
+
+	var waited time.Duration
+	for attempt := 0; ; attempt++ {
+		e.refreshLimits(ctx)
+		wait := e.reserve(cost)
+		if wait > throttleThreshold {
+			e.throttled()
+		}
+		if waited+wait > maxEmbedWait {
+			e.cancel(cost)
+			return nil, bus.Requeue(fmt.Errorf("embedding rate limited for another %s", wait), wait)
+		}
+		if wait > 0 {
+			if err := e.sleep(ctx, wait); err != nil {
+				e.cancel(cost)
+				return nil, err
+			}
+			waited += wait
+		}
+
+		vec, err := e.next.Embed(ctx, input)
+		var rl *RateLimitError
+		if errors.As(err, &rl) {
+			e.pause(rl.RetryAfter)
+			e.throttled()
+			if attempt < rateLimitRetries {
+				slog.WarnContext(ctx, "embedding rate limited, retrying", "retry_after", rl.RetryAfter, "attempt", attempt+1)
+				continue
+			}
+		}
+		if err == nil && wait <= throttleThreshold && e.throttler != nil {
+			e.throttler.Recovered()
+		}
+		return vec, err
+	}
+}
+
+// refreshLimits re-reads the limits if due. The lookup runs without the
+// lock so a slow settings read doesn't hold up callers with budget.
+func (e *RateLimitedEmbedder) refreshLimits(ctx context.Context) {
+	if e.limits == nil {
+		return
+	}
+	e.mu.Lock()
+	now := e.now()
+	due := e.refreshedAt.IsZero() || now.Sub(e.refreshedAt) >= limitsRefreshInterval
+	if due {
+		// Claimed before loading so concurrent callers don't load too
+		e.refreshedAt = now
+	}
+	e.mu.Unlock()
+	if !due {
+		return
+	}
+
+	l, err := e.limits(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to load embedding rate limits, keeping previous", "error", err)
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	now = e.now()
+	e.requests.setLimit(l.RequestsPerMinute, now)
+	e.tokens.setLimit(l.TokensPerMinute, now)
+}
+
+// reserve books one request of cost tokens, returning the wait before it may
+// be sent.
+func (e *RateLimitedEmbedder) reserve(cost int) time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now()
+	wait := max(e.requests.reserve(1, now), e.tokens.reserve(cost, now))
+	if paused := e.pausedUntil.Sub(now); paused > wait {
+		wait = paused
+	}
+	return wait
+}
+
+func (e *RateLimitedEmbedder) cancel(cost int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.requests.cancel(1)
+	e.tokens.cancel(cost)
+}
+
+// pause holds back every caller for d, or a default if the provider gave none.
+func (e *RateLimitedEmbedder) pause(d time.Duration) {
+	if d <= 0 {
+		d = defaultRateLimitPause
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if until := e.now().Add(d); until.After(e.pausedUntil) {
+		e.pausedUntil = until
+	}
+}
+
+func (e *RateLimitedEmbedder) throttled() {
+	if e.throttler != nil {
+		e.throttler.Throttled()
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
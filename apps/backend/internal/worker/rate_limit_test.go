@@ -0,0 +1,208 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"qurio/apps/backend/internal/bus"
+)
+
+type fakeClock struct {
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+	return ctx.Err()
+}
+
+type stubEmbedder struct {
+	errs  []error
+	calls int
+}
+
+func (s *stubEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	s.calls++
+	if len(s.errs) > 0 {
+		err := s.errs[0]
+		s.errs = s.errs[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+	return []float32{0.1}, nil
+}
+
+type throttleRecorder struct {
+	throttled, recovered int
+}
+
+func (r *throttleRecorder) Throttled() { r.throttled++ }
+func (r *throttleRecorder) Recovered() { r.recovered++ }
+
+func newTestLimiter(next Embedder, limits RateLimits) (*RateLimitedEmbedder, *fakeClock, *throttleRecorder) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	rec := &throttleRecorder{}
+	e := NewRateLimitedEmbedder(next, func(ctx context.Context) (RateLimits, error) { return limits, nil })
+	e.now = clock.Now
+	e.sleep = clock.Sleep
+	e.SetThrottler(rec)
+	return e, clock, rec
+}
+
+func TestRateLimitedEmbedder_RequestBudget(t *testing.T) {
+	next := &stubEmbedder{}
+	e, clock, _ := newTestLimiter(next, RateLimits{RequestsPerMinute: 60})
+
+	// The bucket starts full, then refills at one request per second.
+	for i := 0; i < 60; i++ {
+		_, err := e.Embed(context.Background(), "hi")
+		assert.NoError(t, err)
+	}
+	assert.Empty(t, clock.sleeps)
+
+	_, err := e.Embed(context.Background(), "hi")
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Duration{time.Second}, clock.sleeps)
+	assert.Equal(t, 61, next.calls)
+}
+
+func TestRateLimitedEmbedder_TokenBudget(t *testing.T) {
+	e, clock, rec := newTestLimiter(&stubEmbedder{}, RateLimits{TokensPerMinute: 600})
+	input := string(make([]byte, 1200)) // ~300 tokens with the estimate
+
+	cost := e.tok.Count(input)
+	assert.Greater(t, cost, 0)
+	for i := 0; i < 600/cost; i++ {
+		_, _ = e.Embed(context.Background(), input)
+	}
+	assert.Empty(t, clock.sleeps)
+
+	_, err := e.Embed(context.Background(), input)
+	assert.NoError(t, err)
+	if assert.Len(t, clock.sleeps, 1) {
+		assert.Greater(t, clock.sleeps[0], throttleThreshold)
+	}
+	assert.Equal(t, 1, rec.throttled)
+}
+
+func TestRateLimitedEmbedder_Unlimited(t *testing.T) {
+	e, clock, rec := newTestLimiter(&stubEmbedder{}, RateLimits{})
+	for i := 0; i < 1000; i++ {
+		_, err := e.Embed(context.Background(), "hi")
+		assert.NoError(t, err)
+	}
+	assert.Empty(t, clock.sleeps)
+	assert.Equal(t, 1000, rec.recovered)
+}
+
+func TestRateLimitedEmbedder_HonorsRetryAfter(t *testing.T) {
+	next := &stubEmbedder{errs: []error{&RateLimitError{RetryAfter: 20 * time.Second, Err: errors.New("429")}}}
+	e, clock, rec := newTestLimiter(next, RateLimits{})
+
+	vec, err := e.Embed(context.Background(), "hi")
+	assert.NoError(t, err)
+	assert.NotNil(t, vec)
+	assert.Equal(t, 2, next.calls)
+	assert.Equal(t, []time.Duration{20 * time.Second}, clock.sleeps)
+	assert.Equal(t, 2, rec.throttled)
+	assert.Equal(t, 0, rec.recovered)
+}
+
+func TestRateLimitedEmbedder_GivesUpAfterRetries(t *testing.T) {
+	rl := &RateLimitError{Err: errors.New("429")}
+	next := &stubEmbedder{errs: []error{rl, rl, rl, rl, rl}}
+	e, clock, _ := newTestLimiter(next, RateLimits{})
+
+	_, err := e.Embed(context.Background(), "hi")
+	var got *RateLimitError
+	assert.ErrorAs(t, err, &got)
+	assert.Equal(t, rateLimitRetries+1, next.calls)
+	assert.Len(t, clock.sleeps, rateLimitRetries)
+	assert.Equal(t, defaultRateLimitPause, clock.sleeps[0])
+}
+
+func TestRateLimitedEmbedder_RequeuesLongWaits(t *testing.T) {
+	next := &stubEmbedder{errs: []error{&RateLimitError{RetryAfter: 2 * time.Minute, Err: errors.New("429")}}}
+	e, clock, _ := newTestLimiter(next, RateLimits{RequestsPerMinute: 60})
+
+	_, err := e.Embed(context.Background(), "hi")
+	assert.Equal(t, 2*time.Minute, bus.RequeueDelay(err))
+	assert.Equal(t, 1, next.calls)
+	assert.Empty(t, clock.sleeps, "the message isn't held past its timeout")
+	assert.Equal(t, float64(59), e.requests.level, "only the call sent is charged")
+
+	// Waits add up across retries
+	rl := &RateLimitError{RetryAfter: 25 * time.Second, Err: errors.New("429")}
+	next = &stubEmbedder{errs: []error{rl, rl}}
+	e, clock, _ = newTestLimiter(next, RateLimits{})
+
+	_, err = e.Embed(context.Background(), "hi")
+	assert.Equal(t, 25*time.Second, bus.RequeueDelay(err))
+	assert.Equal(t, []time.Duration{25 * time.Second}, clock.sleeps)
+}
+
+func TestRateLimitedEmbedder_OtherErrorsPassThrough(t *testing.T) {
+	next := &stubEmbedder{errs: []error{errors.New("boom")}}
+	e, _, rec := newTestLimiter(next, RateLimits{})
+
+	_, err := e.Embed(context.Background(), "hi")
+	assert.EqualError(t, err, "boom")
+	assert.Equal(t, 1, next.calls)
+	assert.Equal(t, 0, rec.throttled)
+}
+
+func TestRateLimitedEmbedder_CancelledWaitReturnsBudget(t *testing.T) {
+	next := &stubEmbedder{}
+	e, _, _ := newTestLimiter(next, RateLimits{RequestsPerMinute: 2})
+
+	for i := 0; i < 2; i++ {
+		_, err := e.Embed(context.Background(), "hi")
+		assert.NoError(t, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := e.Embed(ctx, "hi")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 2, next.calls)
+	assert.Equal(t, float64(0), e.requests.level)
+}
+
+func TestRateLimitedEmbedder_RefreshesLimits(t *testing.T) {
+	limits := RateLimits{RequestsPerMinute: 1}
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	e := NewRateLimitedEmbedder(&stubEmbedder{}, func(ctx context.Context) (RateLimits, error) { return limits, nil })
+	e.now, e.sleep = clock.Now, clock.Sleep
+
+	_, _ = e.Embed(context.Background(), "hi")
+	limits = RateLimits{}
+	clock.now = clock.now.Add(limitsRefreshInterval)
+	for i := 0; i < 10; i++ {
+		_, _ = e.Embed(context.Background(), "hi")
+	}
+	assert.Empty(t, clock.sleeps)
+}
+
+func TestRateLimitedEmbedder_LoadsLimitsUnlocked(t *testing.T) {
+	var e *RateLimitedEmbedder
+	e = NewRateLimitedEmbedder(&stubEmbedder{}, func(ctx context.Context) (RateLimits, error) {
+		// Other callers can reserve budget while settings are read
+		locked := e.mu.TryLock()
+		if locked {
+			e.mu.Unlock()
+		}
+		assert.True(t, locked)
+		return RateLimits{}, nil
+	})
+
+	_, err := e.Embed(context.Background(), "hi")
+	assert.NoError(t, err)
+}
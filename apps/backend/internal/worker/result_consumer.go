@@ -7,10 +7,12 @@ import (
 	"fmt"
 	"log/slog"
 	"net/url"
+	"slices"
+	"time"
 
 	"github.com/google/uuid"
-	"github.com/nsqio/go-nsq"
 	"qurio/apps/backend/features/job"
+	"qurio/apps/backend/internal/bus"
 	"qurio/apps/backend/internal/middleware"
 	"qurio/apps/backend/internal/text"
 	"qurio/apps/backend/internal/config"
@@ -21,12 +23,25 @@ type PageDTO struct {
 	URL      string
 	Status   string
 	Depth    int
+	LastMod  *time.Time // from the sitemap, if listed there
+	// CorrelationID is that of the request whose crawl discovered the page,
+	// carried into its crawl task
+	CorrelationID string
 }
 
 type PageManager interface {
 	BulkCreatePages(ctx context.Context, pages []PageDTO) ([]string, error)
 	UpdatePageStatus(ctx context.Context, sourceID, url, status, err string) error
 	CountPendingPages(ctx context.Context, sourceID string) (int, error)
+	// SetPageChunks moves a page to 'embedding' until expected chunks are stored.
+	SetPageChunks(ctx context.Context, sourceID, url string, depth, expected int) error
+	// MarkChunkEmbedded counts a stored chunk, once per chunk index, and
+	// reports whether that completed the page.
+	MarkChunkEmbedded(ctx context.Context, sourceID, url string, chunkIndex int) (bool, error)
+	// RecordPageContent stores a crawled page's content hash, chunk key and
+	// HTTP validators, returning "added", "changed" or "unchanged" against
+	// the previous sync.
+	RecordPageContent(ctx context.Context, sourceID, url, hash, chunkKey, etag, lastModified string) (string, error)
 }
 
 type TaskPublisher interface {
@@ -40,6 +55,8 @@ type ResultConsumer struct {
 	sourceFetcher SourceFetcher
 	pageManager   PageManager
 	publisher     TaskPublisher
+	chunker       *text.Chunker
+	retry         RetryPolicy
 }
 
 func NewResultConsumer(s VectorStore, u SourceStatusUpdater, j job.Repository, sf SourceFetcher, pm PageManager, tp TaskPublisher) *ResultConsumer {
@@ -50,10 +67,23 @@ func NewResultConsumer(s VectorStore, u SourceStatusUpdater, j job.Repository, s
 		sourceFetcher: sf,
 		pageManager:   pm,
 		publisher:     tp,
+		chunker:       text.NewChunker(nil),
 	}
 }
 
-func (h *ResultConsumer) HandleMessage(m *nsq.Message) error {
+// SetTokenizer makes chunk sizes follow the embedding model's tokenizer
+// instead of the default estimate.
+func (h *ResultConsumer) SetTokenizer(tok text.Tokenizer) {
+	h.chunker = text.NewChunker(tok)
+}
+
+// SetRetryPolicy bounds the attempts per message. A result failing its last
+// attempt is saved to failed_jobs and its page marked failed.
+func (h *ResultConsumer) SetRetryPolicy(p RetryPolicy) {
+	h.retry = p
+}
+
+func (h *ResultConsumer) HandleMessage(m *bus.Message) error {
 	if len(m.Body) == 0 {
 		return nil
 	}
@@ -64,6 +94,9 @@ func (h *ResultConsumer) HandleMessage(m *nsq.Message) error {
 		Title           string                 `json:"title"`
 		Path            string                 `json:"path"`
 		URL             string                 `json:"url"`
+		CanonicalURL    string                 `json:"canonical_url,omitempty"`
+		ETag            string                 `json:"etag,omitempty"`
+		LastModified    string                 `json:"last_modified,omitempty"`
 		Status          string                 `json:"status,omitempty"` // "success" or "failed"
 		Error           string                 `json:"error,omitempty"`
 		Links           []string               `json:"links,omitempty"`
@@ -102,13 +135,15 @@ func (h *ResultConsumer) HandleMessage(m *nsq.Message) error {
 			_ = h.pageManager.UpdatePageStatus(ctx, payload.SourceID, payload.URL, "failed", payload.Error)
 		}
 
-		// Check if we should fail the source (maybe not? individual page failure shouldn't fail source?)
-		// For now, let's keep the source "in_progress" but log the failure.
-		// If it was the SEED page, maybe fail source?
-		if payload.Depth == 0 {
+		// A failed seed fails the source; other pages, including those of a
+		// sitemap the user gave, fail alone
+		if payload.Depth == 0 && h.isSeed(ctx, payload.SourceID, payload.URL) {
 			if err := h.updater.UpdateStatus(ctx, payload.SourceID, "failed"); err != nil {
 				slog.WarnContext(ctx, "failed to update source status to failed", "error", err)
 			}
+		} else {
+			// The failed page may have been the last one the source waited on
+			completeSourceIfDone(ctx, h.pageManager, h.updater, payload.SourceID)
 		}
 
 		// Save Failed Job
@@ -133,23 +168,94 @@ func (h *ResultConsumer) HandleMessage(m *nsq.Message) error {
 	slog.InfoContext(ctx, "received result", "source_id", payload.SourceID, "url", payload.URL, "content_len", len(payload.Content))
 
 	// Fetch Source Config & Name
-	maxDepth, exclusions, apiKey, sourceName, err := h.sourceFetcher.GetSourceConfig(ctx, payload.SourceID)
+	maxDepth, exclusions, _, sourceName, err := h.sourceFetcher.GetSourceConfig(ctx, payload.SourceID)
 	if err != nil {
 		slog.WarnContext(ctx, "failed to fetch source config", "error", err)
 	}
+
+	var scope CrawlScope
+	if len(payload.Links) > 0 || payload.CanonicalURL != "" {
+		scope, err = h.sourceFetcher.GetCrawlScope(ctx, payload.SourceID)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to fetch crawl scope", "error", err)
+			scope = CrawlScope{Exclude: exclusions}
+		}
+		if u, err := url.Parse(payload.URL); err == nil {
+			scope.Host = u.Host
+		}
+	}
+
+	// A page declaring another in-scope URL canonical is indexed under that
+	// URL, and only once however many aliases point at it.
+	if canonical, ok := canonicalHint(scope, payload.URL, payload.CanonicalURL); ok {
+		created, err := h.pageManager.BulkCreatePages(ctx, []PageDTO{{
+			SourceID: payload.SourceID,
+			URL:      canonical,
+			Status:   "processing",
+			Depth:    payload.Depth,
+		}})
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to create canonical page", "error", err)
+			return h.fail(ctx, m, payload.SourceID, payload.URL, err)
+		}
+		if err := h.pageManager.UpdatePageStatus(ctx, payload.SourceID, payload.URL, "completed", ""); err != nil {
+			slog.WarnContext(ctx, "failed to update page status", "error", err)
+		}
+		if len(created) == 0 {
+			slog.InfoContext(ctx, "skipping duplicate of canonical page", "url", payload.URL, "canonical_url", canonical)
+			completeSourceIfDone(ctx, h.pageManager, h.updater, payload.SourceID)
+			return nil
+		}
+		slog.InfoContext(ctx, "indexing page under its canonical url", "url", payload.URL, "canonical_url", canonical)
+		payload.URL = canonical
+	}
 	
-	// 1. Delete Old Chunks (Idempotency)
-	if payload.URL != "" {
+	profile, err := h.sourceFetcher.GetChunkProfile(ctx, payload.SourceID)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to fetch chunk profile, using default", "error", err)
+		profile = text.DefaultChunkProfile()
+	}
+	version, err := h.sourceFetcher.GetSourceVersion(ctx, payload.SourceID)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to fetch source version", "error", err)
+	}
+
+	// 1. Compare with the previous sync; unchanged pages chunked the same
+	// way keep their chunks
+	hash := sha256.Sum256([]byte(payload.Content))
+	change, err := h.pageManager.RecordPageContent(ctx, payload.SourceID, payload.URL, fmt.Sprintf("%x", hash), ChunkKey(profile, version), payload.ETag, payload.LastModified)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to record page content", "error", err)
+		return h.fail(ctx, m, payload.SourceID, payload.URL, err)
+	}
+	unchanged := change == "unchanged"
+	if unchanged {
+		slog.InfoContext(ctx, "page unchanged, keeping its chunks", "url", payload.URL)
+	}
+
+	// 2. Delete Old Chunks (Idempotency)
+	if !unchanged {
 		if err := h.store.DeleteChunksByURL(ctx, payload.SourceID, payload.URL); err != nil {
 			slog.ErrorContext(ctx, "failed to delete old chunks", "error", err)
-			return err 
+			return h.fail(ctx, m, payload.SourceID, payload.URL, err)
 		}
 	}
 
-	// 2. Chunk and Publish
-	if payload.Content != "" {
-		chunks := text.ChunkMarkdown(payload.Content, 512, 50)
+	// 3. Chunk and Publish
+	queued := 0
+	if payload.Content != "" && !unchanged {
+		var chunks []text.ChunkResult
+		if format := text.DetectFormat(payload.Path, payload.Content); format != text.FormatMarkdown {
+			chunks, err = h.chunker.ChunkStructured(payload.Content, format, profile.MaxTokens)
+			if err != nil {
+				slog.WarnContext(ctx, "failed to parse structured content, chunking as text", "error", err, "format", format, "path", payload.Path)
+			}
+		}
+		if chunks == nil {
+			chunks = h.chunker.Chunk(payload.Content, profile)
+		}
 		if len(chunks) > 0 {
+			var bodies [][]byte
 			for i, c := range chunks {
 				// Construct IngestEmbedPayload
 				embedPayload := IngestEmbedPayload{
@@ -163,6 +269,9 @@ func (h *ResultConsumer) HandleMessage(m *nsq.Message) error {
 					ChunkIndex:    i,
 					ChunkType:     string(c.Type),
 					Language:      c.Language,
+					Symbol:        c.Symbol,
+					Section:       c.Section,
+					Version:       version,
 					
 					CorrelationID: correlationID,
 				}
@@ -182,27 +291,29 @@ func (h *ResultConsumer) HandleMessage(m *nsq.Message) error {
 					slog.ErrorContext(ctx, "failed to marshal embed payload", "error", err)
 					continue
 				}
+				bodies = append(bodies, bytes)
+			}
 
-				if err := h.publisher.Publish(config.TopicIngestEmbed, bytes); err != nil {
+			// Record the expected count before publishing so no embedding can
+			// land before the page knows how many to wait for.
+			if err := h.pageManager.SetPageChunks(ctx, payload.SourceID, payload.URL, payload.Depth, len(bodies)); err != nil {
+				slog.ErrorContext(ctx, "failed to record page chunk count", "error", err)
+				return h.fail(ctx, m, payload.SourceID, payload.URL, err)
+			}
+			for _, body := range bodies {
+				if err := h.publisher.Publish(config.TopicIngestEmbed, body); err != nil {
 					slog.ErrorContext(ctx, "failed to publish to ingest.embed", "error", err)
-					return err // Durable: Fail if publish fails
+					return h.fail(ctx, m, payload.SourceID, payload.URL, err) // Durable: Fail if publish fails
 				}
 			}
-			slog.InfoContext(ctx, "published embedding tasks", "count", len(chunks))
+			queued = len(bodies)
+			slog.InfoContext(ctx, "published embedding tasks", "count", queued, "strategy", profile.Strategy)
 		}
 	}
 
-	// 3. Update Source Body Hash (Only for seed? Or aggregate? Maybe just last update)
-	hash := sha256.Sum256([]byte(payload.Content))
-	hashStr := fmt.Sprintf("%x", hash)
-	_ = h.updater.UpdateBodyHash(ctx, payload.SourceID, hashStr)
-
 	// 4. Distributed Crawl: Link Discovery
 	if payload.URL != "" && len(payload.Links) > 0 {
 		{
-			u, _ := url.Parse(payload.URL)
-			host := u.Host
-			
 			// Virtual Depth for llms.txt: Treat it as having +1 depth allowance
 			effectiveMaxDepth := maxDepth
 			isManifest := false
@@ -212,62 +323,108 @@ func (h *ResultConsumer) HandleMessage(m *nsq.Message) error {
 				slog.InfoContext(ctx, "processing manifest links with extended depth", "url", payload.URL)
 			}
 
-			newPages := DiscoverLinks(payload.SourceID, host, payload.Links, payload.Depth, effectiveMaxDepth, exclusions)
-			
+			newPages := DiscoverLinks(payload.SourceID, scope, payload.Links, payload.Depth, effectiveMaxDepth)
+			for i := range newPages {
+				newPages[i].CorrelationID = correlationID
+			}
+
 			if len(newPages) > 0 {
 				newURLs, err := h.pageManager.BulkCreatePages(ctx, newPages)
 				if err != nil {
 					slog.ErrorContext(ctx, "failed to bulk create pages", "error", err)
-					return err
+					return h.fail(ctx, m, payload.SourceID, payload.URL, err)
 				}
 
+				// The pages stay pending until the Dispatcher releases them.
+				// Links of llms.txt at maxDepth land at maxDepth+1 and
+				// discover nothing further, one level past the limit.
 				slog.InfoContext(ctx, "discovered new pages", "count", len(newURLs))
-				for _, newURL := range newURLs {
-					// Ensure tasks generated from llms.txt at maxDepth don't exceed maxDepth+1 endlessly
-					// Actually, DiscoverLinks sets new page depth as parent.Depth + 1.
-					// If parent is llms.txt (depth=maxDepth), child will be maxDepth+1.
-					// The child won't discover further links because its depth > maxDepth.
-					// This is exactly what we want (1 level deeper than max).
-					
-					taskPayload, _ := json.Marshal(map[string]interface{}{
-						"type":           "web",
-						"url":            newURL,
-						"id":             payload.SourceID,
-						"depth":          payload.Depth + 1,
-						"max_depth":      maxDepth,
-						"exclusions":     exclusions,
-						"gemini_api_key": apiKey,
-						"correlation_id": correlationID,
-					})
-					if err := h.publisher.Publish(config.TopicIngestWeb, taskPayload); err != nil {
-						slog.ErrorContext(ctx, "failed to publish task, marking page as failed", "error", err, "url", newURL)
-						_ = h.pageManager.UpdatePageStatus(ctx, payload.SourceID, newURL, "failed", fmt.Sprintf("Failed to publish task: %v", err))
-					}
-				}
 			} else if isManifest {
 				slog.InfoContext(ctx, "no new pages discovered from manifest (might be duplicates or excluded)", "url", payload.URL)
 			}
 		}
 	}
 
-	// 5. Update Page Status to Completed (Coordinator considers it done once chunks are queued)
-	if payload.URL != "" {
+	// 5. Update Page Status to Completed. Pages with queued chunks stay in
+	// 'embedding' until the EmbedderConsumer has stored all of them.
+	if queued == 0 {
 		if err := h.pageManager.UpdatePageStatus(ctx, payload.SourceID, payload.URL, "completed", ""); err != nil {
 			slog.WarnContext(ctx, "failed to update page status", "error", err)
 		}
 	}
 
 	// 6. Check Source Completion
+	completeSourceIfDone(ctx, h.pageManager, h.updater, payload.SourceID)
 
-	pendingCount, err := h.pageManager.CountPendingPages(ctx, payload.SourceID)
-	if err != nil {
-		slog.WarnContext(ctx, "failed to count pending pages", "error", err)
-	} else if pendingCount == 0 {
-		slog.InfoContext(ctx, "source ingestion completed", "source_id", payload.SourceID)
-		if err := h.updater.UpdateStatus(ctx, payload.SourceID, "completed"); err != nil {
-			slog.WarnContext(ctx, "failed to update source status to completed", "error", err)
-		}
+	return nil
+}
+
+// fail returns err so NSQ retries the message, unless this was its last
+// attempt, in which case it is dead-lettered to failed_jobs.
+func (h *ResultConsumer) fail(ctx context.Context, m *bus.Message, sourceID, pageURL string, err error) error {
+	if !h.retry.Exhausted(m.Attempts) {
+		return err // Retry
 	}
-	
+
+	failedJob := &job.Job{
+		SourceID: sourceID,
+		Handler:  job.HandlerResult,
+		Payload:  m.Body,
+		Error:    err.Error(),
+	}
+	if saveErr := h.jobRepo.Save(ctx, failedJob); saveErr != nil {
+		slog.ErrorContext(ctx, "failed to save failed result job", "error", saveErr, "source_id", sourceID)
+		return err
+	}
+
+	slog.WarnContext(ctx, "result attempts exhausted, saved failed job", "job_id", failedJob.ID, "source_id", sourceID, "url", pageURL, "attempts", m.Attempts)
+
+	// Nothing else will move the page out of processing
+	if err := h.pageManager.UpdatePageStatus(ctx, sourceID, pageURL, "failed", "processing result failed: "+err.Error()); err != nil {
+		slog.WarnContext(ctx, "failed to update page status", "error", err)
+	}
+	completeSourceIfDone(ctx, h.pageManager, h.updater, sourceID)
 	return nil
-}
\ No newline at end of file
+}
+
+// isSeed reports whether pageURL is one of the source's seeds. If the seeds
+// can't be read, every depth 0 page is taken for one.
+func (h *ResultConsumer) isSeed(ctx context.Context, sourceID, pageURL string) bool {
+	seeds, err := h.sourceFetcher.GetSeeds(ctx, sourceID)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to fetch source seeds", "error", err)
+		return true
+	}
+	return slices.Contains(seeds, pageURL)
+}
+
+// ChunkKey identifies how a page's chunks are made: the chunk profile and
+// the version label they are stored under. Pages chunked under another key
+// are re-chunked on resync even if their content is the same.
+func ChunkKey(profile text.ChunkProfile, version string) string {
+	if version == "" {
+		return profile.Key()
+	}
+	return profile.Key() + "@" + version
+}
+
+// canonicalHint returns the page's declared canonical URL, resolved against
+// the page, when it names a different page within scope.
+func canonicalHint(scope CrawlScope, pageURL, canonicalURL string) (string, bool) {
+	if canonicalURL == "" {
+		return "", false
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return "", false
+	}
+	u, err := base.Parse(canonicalURL)
+	if err != nil {
+		return "", false
+	}
+	canon := scope.Canonicalizer.canonicalURL(u)
+	if canon.String() == scope.Canonicalizer.CanonicalizeOr(pageURL) || !scope.Allows(canon) {
+		return "", false
+	}
+	return canon.String(), true
+}
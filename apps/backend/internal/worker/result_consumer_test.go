@@ -4,11 +4,12 @@ import (
 	"encoding/json"
 	"testing"
 
-	"github.com/nsqio/go-nsq"
+	"qurio/apps/backend/internal/bus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"qurio/apps/backend/features/job"
 	"qurio/apps/backend/internal/config"
+	"qurio/apps/backend/internal/text"
 	"qurio/apps/backend/internal/worker"
 )
 
@@ -34,13 +35,17 @@ func TestResultConsumer_HandleMessage_Success(t *testing.T) {
 		"depth":     0,
 	}
 	body, _ := json.Marshal(payload)
-	msg := &nsq.Message{Body: body}
+	msg := &bus.Message{Body: body}
 
 	// Expectations
 	// 1. Fetch Config
 	sf.On("GetSourceConfig", mock.Anything, "src1").Return(2, []string{}, "api-key", "My Source", nil)
+	sf.On("GetCrawlScope", mock.Anything, "src1").Return(worker.CrawlScope{}, nil)
+	sf.On("GetChunkProfile", mock.Anything, "src1").Return(text.DefaultChunkProfile(), nil).Maybe()
+	sf.On("GetSourceVersion", mock.Anything, "src1").Return("", nil).Maybe()
 	
-	// 2. Delete Old Chunks
+	// 2. Record Content Hash, Delete Old Chunks
+	pm.On("RecordPageContent", mock.Anything, "src1", "http://example.com", mock.Anything, text.DefaultChunkProfile().Key(), "", "").Return("changed", nil)
 	s.On("DeleteChunksByURL", mock.Anything, "src1", "http://example.com").Return(nil)
 	
 	// 3. Publish Embed Tasks (Chunking happens internally)
@@ -50,26 +55,16 @@ func TestResultConsumer_HandleMessage_Success(t *testing.T) {
 		return p.SourceID == "src1" && p.SourceName == "My Source" && p.Content == "Some content"
 	})).Return(nil)
 
-	// 4. Update Body Hash
-	u.On("UpdateBodyHash", mock.Anything, "src1", mock.Anything).Return(nil)
-
-	// 5. Link Discovery -> Publish Web Task
+	// 5. Link Discovery -> pending page, released later by the dispatcher
 	pm.On("BulkCreatePages", mock.Anything, mock.MatchedBy(func(pages []worker.PageDTO) bool {
-		return len(pages) == 1 && pages[0].URL == "http://example.com/subpage"
+		return len(pages) == 1 && pages[0].URL == "http://example.com/subpage" && pages[0].Depth == 1 && pages[0].Status == "pending"
 	})).Return([]string{"http://example.com/subpage"}, nil)
 
-	tp.On("Publish", config.TopicIngestWeb, mock.MatchedBy(func(b []byte) bool {
-		var p map[string]interface{}
-		json.Unmarshal(b, &p)
-		return p["url"] == "http://example.com/subpage" && p["depth"] == float64(1)
-	})).Return(nil)
+	// 6. Page waits for its chunk to be embedded
+	pm.On("SetPageChunks", mock.Anything, "src1", "http://example.com", 0, 1).Return(nil)
 
-	// 6. Update Page Status (Completed)
-	pm.On("UpdatePageStatus", mock.Anything, "src1", "http://example.com", "completed", "").Return(nil)
-	
-	// 7. Check Source Completion
-	pm.On("CountPendingPages", mock.Anything, "src1").Return(0, nil)
-	u.On("UpdateStatus", mock.Anything, "src1", "completed").Return(nil)
+	// 7. Check Source Completion: the page is still embedding
+	pm.On("CountPendingPages", mock.Anything, "src1").Return(1, nil)
 
 	err := consumer.HandleMessage(msg)
 	assert.NoError(t, err)
@@ -102,11 +97,12 @@ func TestResultConsumer_HandleMessage_Failure(t *testing.T) {
 		"original_payload": originalPayload,
 	}
 	body, _ := json.Marshal(payload)
-	msg := &nsq.Message{Body: body}
+	msg := &bus.Message{Body: body}
 
 	// Expectations
+	sf.On("GetSeeds", mock.Anything, "src1").Return([]string{"http://example.com"}, nil)
 	pm.On("UpdatePageStatus", mock.Anything, "src1", "http://example.com", "failed", "Some error").Return(nil)
-	u.On("UpdateStatus", mock.Anything, "src1", "failed").Return(nil) // Depth 0 -> Update Source Status
+	u.On("UpdateStatus", mock.Anything, "src1", "failed").Return(nil) // Seed -> Update Source Status
 	j.On("Save", mock.Anything, mock.MatchedBy(func(job *job.Job) bool {
 		return job.SourceID == "src1" && job.Error == "Some error"
 	})).Return(nil)
@@ -119,6 +115,67 @@ func TestResultConsumer_HandleMessage_Failure(t *testing.T) {
 	j.AssertExpectations(t)
 }
 
+func TestResultConsumer_HandleMessage_FailureCompletesSource(t *testing.T) {
+	s := new(MockVectorStore)
+	u := new(MockUpdater)
+	j := new(MockJobRepo)
+	sf := new(MockSourceFetcher)
+	pm := new(MockPageManager)
+	tp := new(MockTaskPublisher)
+
+	consumer := worker.NewResultConsumer(s, u, j, sf, pm, tp)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"source_id": "src1",
+		"url":       "/uploads/git/src1/docs/broken.md",
+		"status":    "failed",
+		"error":     "No content extracted",
+		"depth":     1,
+	})
+
+	// A page past the seeds fails alone; being the last one, it completes the source
+	pm.On("UpdatePageStatus", mock.Anything, "src1", "/uploads/git/src1/docs/broken.md", "failed", "No content extracted").Return(nil)
+	pm.On("CountPendingPages", mock.Anything, "src1").Return(0, nil)
+	u.On("CompleteSync", mock.Anything, "src1").Return(nil)
+
+	err := consumer.HandleMessage(&bus.Message{Body: body})
+	assert.NoError(t, err)
+
+	pm.AssertExpectations(t)
+	u.AssertExpectations(t)
+	u.AssertNotCalled(t, "UpdateStatus", mock.Anything, "src1", "failed")
+}
+
+func TestResultConsumer_HandleMessage_SitemapPageFailureKeepsSource(t *testing.T) {
+	s := new(MockVectorStore)
+	u := new(MockUpdater)
+	j := new(MockJobRepo)
+	sf := new(MockSourceFetcher)
+	pm := new(MockPageManager)
+	tp := new(MockTaskPublisher)
+
+	consumer := worker.NewResultConsumer(s, u, j, sf, pm, tp)
+
+	// Pages of a sitemap the user gave are crawled at depth 0
+	body, _ := json.Marshal(map[string]interface{}{
+		"source_id": "src1",
+		"url":       "https://example.com/docs/gone",
+		"status":    "failed",
+		"error":     "404",
+		"depth":     0,
+	})
+
+	sf.On("GetSeeds", mock.Anything, "src1").Return([]string{}, nil)
+	pm.On("UpdatePageStatus", mock.Anything, "src1", "https://example.com/docs/gone", "failed", "404").Return(nil)
+	pm.On("CountPendingPages", mock.Anything, "src1").Return(3, nil)
+
+	err := consumer.HandleMessage(&bus.Message{Body: body})
+	assert.NoError(t, err)
+
+	pm.AssertExpectations(t)
+	u.AssertNotCalled(t, "UpdateStatus", mock.Anything, "src1", "failed")
+}
+
 func TestResultConsumer_HandleMessage_LLMsTxt_ExtendedDepth(t *testing.T) {
 	s := new(MockVectorStore)
 	u := new(MockUpdater)
@@ -135,44 +192,46 @@ func TestResultConsumer_HandleMessage_LLMsTxt_ExtendedDepth(t *testing.T) {
 		"content":   "content",
 		"links":     []string{"http://example.com/doc.md"},
 		"depth":     2,
+
+		"correlation_id": "req-123",
 	}
 	body, _ := json.Marshal(payload)
-	msg := &nsq.Message{Body: body}
+	msg := &bus.Message{Body: body}
 
 	// Mock Config: Max Depth is 2.
 	// Normal logic: Depth 2 == Max Depth 2 -> No new links.
 	// LLMs.txt logic: Effective Max Depth = 3. -> New links allowed.
 	sf.On("GetSourceConfig", mock.Anything, "src1").Return(2, []string{}, "", "Src", nil)
+	sf.On("GetCrawlScope", mock.Anything, "src1").Return(worker.CrawlScope{}, nil)
+	sf.On("GetChunkProfile", mock.Anything, "src1").Return(text.DefaultChunkProfile(), nil).Maybe()
+	sf.On("GetSourceVersion", mock.Anything, "src1").Return("", nil).Maybe()
+	pm.On("RecordPageContent", mock.Anything, "src1", "http://example.com/llms.txt", mock.Anything, text.DefaultChunkProfile().Key(), "", "").Return("changed", nil)
 	s.On("DeleteChunksByURL", mock.Anything, "src1", "http://example.com/llms.txt").Return(nil)
 	tp.On("Publish", config.TopicIngestEmbed, mock.Anything).Return(nil)
-	u.On("UpdateBodyHash", mock.Anything, "src1", mock.Anything).Return(nil)
 
 	// Expect Link Discovery
 	pm.On("BulkCreatePages", mock.Anything, mock.MatchedBy(func(pages []worker.PageDTO) bool {
-		return len(pages) == 1 && pages[0].URL == "http://example.com/doc.md"
+		// 2 + 1, remembering the crawl's correlation ID for its task
+		return len(pages) == 1 && pages[0].URL == "http://example.com/doc.md" && pages[0].Depth == 3 && pages[0].CorrelationID == "req-123"
 	})).Return([]string{"http://example.com/doc.md"}, nil)
 
-	tp.On("Publish", config.TopicIngestWeb, mock.MatchedBy(func(b []byte) bool {
-		var p map[string]interface{}
-		json.Unmarshal(b, &p)
-		return p["depth"] == float64(3) // 2 + 1
-	})).Return(nil)
-
-	pm.On("UpdatePageStatus", mock.Anything, "src1", "http://example.com/llms.txt", "completed", "").Return(nil)
+	pm.On("SetPageChunks", mock.Anything, "src1", "http://example.com/llms.txt", 2, 1).Return(nil)
 	pm.On("CountPendingPages", mock.Anything, "src1").Return(1, nil) // Still pending pages
 
 	err := consumer.HandleMessage(msg)
 	assert.NoError(t, err)
 
 	tp.AssertExpectations(t)
+	pm.AssertExpectations(t)
 }
 
 func TestResultConsumer_HandleMessage_DeleteChunksError(t *testing.T) {
 	s := new(MockVectorStore)
 	// other mocks...
 	sf := new(MockSourceFetcher)
+	pm := new(MockPageManager)
 	
-	consumer := worker.NewResultConsumer(s, nil, nil, sf, nil, nil)
+	consumer := worker.NewResultConsumer(s, nil, nil, sf, pm, nil)
 
 	payload := map[string]interface{}{
 		"source_id": "src1",
@@ -180,9 +239,12 @@ func TestResultConsumer_HandleMessage_DeleteChunksError(t *testing.T) {
 		"status":    "success",
 	}
 	body, _ := json.Marshal(payload)
-	msg := &nsq.Message{Body: body}
+	msg := &bus.Message{Body: body}
 
 	sf.On("GetSourceConfig", mock.Anything, "src1").Return(2, []string{}, "", "Src", nil)
+	sf.On("GetChunkProfile", mock.Anything, "src1").Return(text.DefaultChunkProfile(), nil).Maybe()
+	sf.On("GetSourceVersion", mock.Anything, "src1").Return("", nil).Maybe()
+	pm.On("RecordPageContent", mock.Anything, "src1", "http://example.com", mock.Anything, text.DefaultChunkProfile().Key(), "", "").Return("changed", nil)
 	s.On("DeleteChunksByURL", mock.Anything, "src1", "http://example.com").Return(assert.AnError)
 
 	err := consumer.HandleMessage(msg)
@@ -190,9 +252,46 @@ func TestResultConsumer_HandleMessage_DeleteChunksError(t *testing.T) {
 	assert.Equal(t, assert.AnError, err)
 }
 
+func TestResultConsumer_HandleMessage_LastAttemptDeadLetters(t *testing.T) {
+	s := new(MockVectorStore)
+	u := new(MockUpdater)
+	j := new(MockJobRepo)
+	sf := new(MockSourceFetcher)
+	pm := new(MockPageManager)
+
+	consumer := worker.NewResultConsumer(s, u, j, sf, pm, nil)
+	consumer.SetRetryPolicy(worker.NewRetryPolicy(3, 1000, 5000))
+
+	payload := map[string]interface{}{
+		"source_id": "src1",
+		"url":       "http://example.com",
+		"status":    "success",
+	}
+	body, _ := json.Marshal(payload)
+	msg := &bus.Message{Body: body, Attempts: 3}
+
+	sf.On("GetSourceConfig", mock.Anything, "src1").Return(2, []string{}, "", "Src", nil)
+	sf.On("GetChunkProfile", mock.Anything, "src1").Return(text.DefaultChunkProfile(), nil).Maybe()
+	sf.On("GetSourceVersion", mock.Anything, "src1").Return("", nil).Maybe()
+	pm.On("RecordPageContent", mock.Anything, "src1", "http://example.com", mock.Anything, text.DefaultChunkProfile().Key(), "", "").Return("changed", nil)
+	s.On("DeleteChunksByURL", mock.Anything, "src1", "http://example.com").Return(assert.AnError)
+	j.On("Save", mock.Anything, mock.MatchedBy(func(fj *job.Job) bool {
+		return fj.Handler == job.HandlerResult && fj.SourceID == "src1" && string(fj.Payload) == string(body)
+	})).Return(nil)
+	pm.On("UpdatePageStatus", mock.Anything, "src1", "http://example.com", "failed", mock.Anything).Return(nil)
+	pm.On("CountPendingPages", mock.Anything, "src1").Return(0, nil)
+	u.On("CompleteSync", mock.Anything, "src1").Return(nil)
+
+	err := consumer.HandleMessage(msg)
+	assert.NoError(t, err)
+	j.AssertExpectations(t)
+	pm.AssertExpectations(t)
+	u.AssertExpectations(t)
+}
+
 func TestResultConsumer_HandleMessage_PoisonPill(t *testing.T) {
 	consumer := worker.NewResultConsumer(nil, nil, nil, nil, nil, nil)
-	msg := &nsq.Message{Body: []byte("invalid json")}
+	msg := &bus.Message{Body: []byte("invalid json")}
 	
 	err := consumer.HandleMessage(msg)
 	assert.NoError(t, err)
@@ -204,81 +303,306 @@ func TestResultConsumer_HandleMessage_MissingRequiredFields(t *testing.T) {
 	// Missing URL
 	payload := map[string]interface{}{"source_id": "src1"}
 	body, _ := json.Marshal(payload)
-	msg := &nsq.Message{Body: body}
+	msg := &bus.Message{Body: body}
 
 	err := consumer.HandleMessage(msg)
 	assert.NoError(t, err)
 }
 
-func TestResultConsumer_HandleMessage_LinkDiscoveryPublishFailure(t *testing.T) {
+func TestResultConsumer_HandleMessage_EmptyContent_NoEmbedPublish(t *testing.T) {
 	s := new(MockVectorStore)
 	sf := new(MockSourceFetcher)
-	pm := new(MockPageManager)
-	tp := new(MockTaskPublisher)
 	u := new(MockUpdater)
-
-	consumer := worker.NewResultConsumer(s, u, nil, sf, pm, tp)
+	pm := new(MockPageManager)
+	
+	consumer := worker.NewResultConsumer(s, u, nil, sf, pm, nil)
 
 	payload := map[string]interface{}{
 		"source_id": "src1",
 		"url":       "http://example.com",
-		"content":   "c",
-		"links":     []string{"http://example.com/sub"},
+		"content":   "", // Empty
+		"status":    "success",
 	}
 	body, _ := json.Marshal(payload)
-	msg := &nsq.Message{Body: body}
+	msg := &bus.Message{Body: body}
 
 	sf.On("GetSourceConfig", mock.Anything, "src1").Return(5, []string{}, "", "Src", nil)
+	sf.On("GetChunkProfile", mock.Anything, "src1").Return(text.DefaultChunkProfile(), nil).Maybe()
+	sf.On("GetSourceVersion", mock.Anything, "src1").Return("", nil).Maybe()
+	pm.On("RecordPageContent", mock.Anything, "src1", "http://example.com", mock.Anything, text.DefaultChunkProfile().Key(), "", "").Return("changed", nil)
 	s.On("DeleteChunksByURL", mock.Anything, "src1", "http://example.com").Return(nil)
-	tp.On("Publish", config.TopicIngestEmbed, mock.Anything).Return(nil)
-	u.On("UpdateBodyHash", mock.Anything, "src1", mock.Anything).Return(nil)
+	pm.On("UpdatePageStatus", mock.Anything, "src1", "http://example.com", "completed", "").Return(nil)
+	pm.On("CountPendingPages", mock.Anything, "src1").Return(1, nil)
 
-	// Link Discovery Success
-	pm.On("BulkCreatePages", mock.Anything, mock.Anything).Return([]string{"http://example.com/sub"}, nil)
+	// Expect NO calls to publisher
 
-	// Publish Web Task Failure
-	tp.On("Publish", config.TopicIngestWeb, mock.Anything).Return(assert.AnError)
-	
-	// Should mark new page as failed
-	pm.On("UpdatePageStatus", mock.Anything, "src1", "http://example.com/sub", "failed", mock.Anything).Return(nil)
+	err := consumer.HandleMessage(msg)
+	assert.NoError(t, err)
+}
 
-	// Should still complete the current page
-	pm.On("UpdatePageStatus", mock.Anything, "src1", "http://example.com", "completed", "").Return(nil)
-	pm.On("CountPendingPages", mock.Anything, "src1").Return(0, nil)
-	u.On("UpdateStatus", mock.Anything, "src1", "completed").Return(nil)
+func TestResultConsumer_HandleMessage_AppliesChunkProfile(t *testing.T) {
+	s := new(MockVectorStore)
+	u := new(MockUpdater)
+	j := new(MockJobRepo)
+	sf := new(MockSourceFetcher)
+	pm := new(MockPageManager)
+	tp := new(MockTaskPublisher)
+
+	consumer := worker.NewResultConsumer(s, u, j, sf, pm, tp)
+
+	// Two sections would be two chunks with the default profile
+	content := "# One\nFirst.\n\n# Two\nSecond."
+	payload := map[string]interface{}{
+		"source_id": "src1",
+		"url":       "http://example.com",
+		"content":   content,
+		"depth":     1,
+	}
+	body, _ := json.Marshal(payload)
+	msg := &bus.Message{Body: body}
+
+	sf.On("GetSourceConfig", mock.Anything, "src1").Return(2, []string{}, "", "Src", nil)
+	sf.On("GetChunkProfile", mock.Anything, "src1").Return(text.ChunkProfile{Strategy: text.StrategyWholePage, MaxTokens: 512}, nil)
+	sf.On("GetSourceVersion", mock.Anything, "src1").Return("", nil).Maybe()
+	pm.On("RecordPageContent", mock.Anything, "src1", "http://example.com", mock.Anything, "whole-page/512/0", "", "").Return("changed", nil)
+	s.On("DeleteChunksByURL", mock.Anything, "src1", "http://example.com").Return(nil)
+	tp.On("Publish", config.TopicIngestEmbed, mock.MatchedBy(func(b []byte) bool {
+		var p worker.IngestEmbedPayload
+		json.Unmarshal(b, &p)
+		return p.Content == content && p.ChunkIndex == 0
+	})).Return(nil).Once()
+	pm.On("SetPageChunks", mock.Anything, "src1", "http://example.com", 1, 1).Return(nil)
+	pm.On("CountPendingPages", mock.Anything, "src1").Return(1, nil)
 
 	err := consumer.HandleMessage(msg)
-	assert.NoError(t, err) // Non-fatal for the consumer
-	
+	assert.NoError(t, err)
+
+	sf.AssertExpectations(t)
 	tp.AssertExpectations(t)
-	pm.AssertExpectations(t)
 }
 
-func TestResultConsumer_HandleMessage_EmptyContent_NoEmbedPublish(t *testing.T) {
+func TestResultConsumer_HandleMessage_StructuredUpload(t *testing.T) {
 	s := new(MockVectorStore)
-	sf := new(MockSourceFetcher)
 	u := new(MockUpdater)
+	j := new(MockJobRepo)
+	sf := new(MockSourceFetcher)
 	pm := new(MockPageManager)
-	
-	consumer := worker.NewResultConsumer(s, u, nil, sf, pm, nil)
+	tp := new(MockTaskPublisher)
+
+	consumer := worker.NewResultConsumer(s, u, j, sf, pm, tp)
 
 	payload := map[string]interface{}{
 		"source_id": "src1",
-		"url":       "http://example.com",
-		"content":   "", // Empty
-		"status":    "success",
+		"url":       "/uploads/spec.yaml",
+		"path":      "/uploads/spec.yaml",
+		"content":   "openapi: 3.0.0\ninfo:\n  title: Pets\npaths:\n  /pets:\n    get:\n      operationId: listPets\n",
+		"depth":     0,
 	}
 	body, _ := json.Marshal(payload)
-	msg := &nsq.Message{Body: body}
+	msg := &bus.Message{Body: body}
 
-	sf.On("GetSourceConfig", mock.Anything, "src1").Return(5, []string{}, "", "Src", nil)
+	sf.On("GetSourceConfig", mock.Anything, "src1").Return(0, []string{}, "", "Spec", nil)
+	sf.On("GetChunkProfile", mock.Anything, "src1").Return(text.DefaultChunkProfile(), nil)
+	sf.On("GetSourceVersion", mock.Anything, "src1").Return("", nil).Maybe()
+	pm.On("RecordPageContent", mock.Anything, "src1", "/uploads/spec.yaml", mock.Anything, text.DefaultChunkProfile().Key(), "", "").Return("changed", nil)
+	s.On("DeleteChunksByURL", mock.Anything, "src1", "/uploads/spec.yaml").Return(nil)
+	tp.On("Publish", config.TopicIngestEmbed, mock.MatchedBy(func(b []byte) bool {
+		var p worker.IngestEmbedPayload
+		json.Unmarshal(b, &p)
+		return p.ChunkIndex == 0 && p.Section == "$.info"
+	})).Return(nil).Once()
+	tp.On("Publish", config.TopicIngestEmbed, mock.MatchedBy(func(b []byte) bool {
+		var p worker.IngestEmbedPayload
+		json.Unmarshal(b, &p)
+		return p.ChunkIndex == 1 && p.ChunkType == "api" && p.Symbol == "listPets"
+	})).Return(nil).Once()
+	pm.On("SetPageChunks", mock.Anything, "src1", "/uploads/spec.yaml", 0, 2).Return(nil)
+	pm.On("CountPendingPages", mock.Anything, "src1").Return(1, nil)
+
+	err := consumer.HandleMessage(msg)
+	assert.NoError(t, err)
+
+	tp.AssertExpectations(t)
+}
+
+func TestResultConsumer_HandleMessage_SetPageChunksError(t *testing.T) {
+	s := new(MockVectorStore)
+	u := new(MockUpdater)
+	sf := new(MockSourceFetcher)
+	pm := new(MockPageManager)
+	tp := new(MockTaskPublisher)
+
+	consumer := worker.NewResultConsumer(s, u, nil, sf, pm, tp)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"source_id": "src1",
+		"url":       "http://example.com",
+		"content":   "Some content",
+	})
+
+	sf.On("GetSourceConfig", mock.Anything, "src1").Return(2, []string{}, "", "Src", nil)
+	sf.On("GetChunkProfile", mock.Anything, "src1").Return(text.DefaultChunkProfile(), nil)
+	sf.On("GetSourceVersion", mock.Anything, "src1").Return("", nil).Maybe()
+	pm.On("RecordPageContent", mock.Anything, "src1", "http://example.com", mock.Anything, text.DefaultChunkProfile().Key(), "", "").Return("changed", nil)
 	s.On("DeleteChunksByURL", mock.Anything, "src1", "http://example.com").Return(nil)
-	u.On("UpdateBodyHash", mock.Anything, "src1", mock.Anything).Return(nil)
-	pm.On("UpdatePageStatus", mock.Anything, "src1", "http://example.com", "completed", "").Return(nil)
+	pm.On("SetPageChunks", mock.Anything, "src1", "http://example.com", 0, 1).Return(assert.AnError)
+
+	err := consumer.HandleMessage(&bus.Message{Body: body})
+	assert.Error(t, err) // Retry before anything is published
+	tp.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+}
+
+func TestResultConsumer_HandleMessage_ScopedLinkDiscovery(t *testing.T) {
+	s := new(MockVectorStore)
+	u := new(MockUpdater)
+	sf := new(MockSourceFetcher)
+	pm := new(MockPageManager)
+	tp := new(MockTaskPublisher)
+
+	consumer := worker.NewResultConsumer(s, u, nil, sf, pm, tp)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"source_id": "src1",
+		"url":       "http://example.com/docs/",
+		"links": []string{
+			"http://example.com/docs/guide/?utm_source=nav",
+			"http://example.com/docs/guide/index.html",
+			"http://example.com/blog/post",
+			"http://example.com/docs/v2/api",
+			"http://api.example.com/docs/",
+			"http://www.example.com/docs/",
+		},
+	})
+
+	sf.On("GetSourceConfig", mock.Anything, "src1").Return(2, []string{}, "", "Src", nil)
+	sf.On("GetChunkProfile", mock.Anything, "src1").Return(text.DefaultChunkProfile(), nil)
+	sf.On("GetSourceVersion", mock.Anything, "src1").Return("", nil)
+	sf.On("GetCrawlScope", mock.Anything, "src1").Return(worker.CrawlScope{AllowedHosts: []string{"api.example.com"}, PathPrefix: "/docs", Exclude: []string{"/v2/"}}, nil)
+	pm.On("RecordPageContent", mock.Anything, "src1", "http://example.com/docs/", mock.Anything, text.DefaultChunkProfile().Key(), "", "").Return("changed", nil)
+	s.On("DeleteChunksByURL", mock.Anything, "src1", "http://example.com/docs/").Return(nil)
+	pm.On("BulkCreatePages", mock.Anything, mock.MatchedBy(func(pages []worker.PageDTO) bool {
+		return len(pages) == 2 && pages[0].URL == "http://example.com/docs/guide" && pages[1].URL == "http://api.example.com/docs"
+	})).Return([]string{"http://example.com/docs/guide", "http://api.example.com/docs"}, nil)
+	pm.On("UpdatePageStatus", mock.Anything, "src1", "http://example.com/docs/", "completed", "").Return(nil)
 	pm.On("CountPendingPages", mock.Anything, "src1").Return(1, nil)
 
-	// Expect NO calls to publisher
+	err := consumer.HandleMessage(&bus.Message{Body: body})
+	assert.NoError(t, err)
+	pm.AssertExpectations(t)
+	tp.AssertNotCalled(t, "Publish", config.TopicIngestWeb, mock.Anything)
+}
 
-	err := consumer.HandleMessage(msg)
+func TestResultConsumer_HandleMessage_CanonicalHint(t *testing.T) {
+	s := new(MockVectorStore)
+	u := new(MockUpdater)
+	sf := new(MockSourceFetcher)
+	pm := new(MockPageManager)
+	tp := new(MockTaskPublisher)
+
+	consumer := worker.NewResultConsumer(s, u, nil, sf, pm, tp)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"source_id":     "src1",
+		"url":           "http://example.com/print/guide",
+		"canonical_url": "/guide#top",
+		"content":       "Some content",
+		"depth":         1,
+	})
+
+	sf.On("GetSourceConfig", mock.Anything, "src1").Return(2, []string{}, "", "Src", nil)
+	sf.On("GetCrawlScope", mock.Anything, "src1").Return(worker.CrawlScope{}, nil)
+	sf.On("GetChunkProfile", mock.Anything, "src1").Return(text.DefaultChunkProfile(), nil)
+	sf.On("GetSourceVersion", mock.Anything, "src1").Return("", nil).Maybe()
+	pm.On("BulkCreatePages", mock.Anything, []worker.PageDTO{{SourceID: "src1", URL: "http://example.com/guide", Status: "processing", Depth: 1}}).
+		Return([]string{"http://example.com/guide"}, nil)
+	pm.On("UpdatePageStatus", mock.Anything, "src1", "http://example.com/print/guide", "completed", "").Return(nil)
+
+	// Indexed under the canonical URL
+	pm.On("RecordPageContent", mock.Anything, "src1", "http://example.com/guide", mock.Anything, text.DefaultChunkProfile().Key(), "", "").Return("changed", nil)
+	s.On("DeleteChunksByURL", mock.Anything, "src1", "http://example.com/guide").Return(nil)
+	tp.On("Publish", config.TopicIngestEmbed, mock.MatchedBy(func(b []byte) bool {
+		var p worker.IngestEmbedPayload
+		json.Unmarshal(b, &p)
+		return p.SourceURL == "http://example.com/guide"
+	})).Return(nil)
+	pm.On("SetPageChunks", mock.Anything, "src1", "http://example.com/guide", 1, 1).Return(nil)
+	pm.On("CountPendingPages", mock.Anything, "src1").Return(1, nil)
+
+	err := consumer.HandleMessage(&bus.Message{Body: body})
+	assert.NoError(t, err)
+	pm.AssertExpectations(t)
+	tp.AssertExpectations(t)
+}
+
+func TestResultConsumer_HandleMessage_CanonicalHint_Duplicate(t *testing.T) {
+	s := new(MockVectorStore)
+	u := new(MockUpdater)
+	sf := new(MockSourceFetcher)
+	pm := new(MockPageManager)
+	tp := new(MockTaskPublisher)
+
+	consumer := worker.NewResultConsumer(s, u, nil, sf, pm, tp)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"source_id":     "src1",
+		"url":           "http://example.com/guide?lang=en",
+		"canonical_url": "http://example.com/guide",
+		"content":       "Some content",
+		"links":         []string{"http://example.com/other"},
+		"depth":         1,
+	})
+
+	sf.On("GetSourceConfig", mock.Anything, "src1").Return(2, []string{}, "", "Src", nil)
+	sf.On("GetCrawlScope", mock.Anything, "src1").Return(worker.CrawlScope{}, nil)
+	pm.On("BulkCreatePages", mock.Anything, mock.Anything).Return([]string{}, nil).Once()
+	pm.On("UpdatePageStatus", mock.Anything, "src1", "http://example.com/guide?lang=en", "completed", "").Return(nil)
+	pm.On("CountPendingPages", mock.Anything, "src1").Return(1, nil)
+
+	err := consumer.HandleMessage(&bus.Message{Body: body})
+	assert.NoError(t, err)
+	pm.AssertExpectations(t)
+	s.AssertNotCalled(t, "DeleteChunksByURL", mock.Anything, mock.Anything, mock.Anything)
+	tp.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+}
+
+func TestResultConsumer_HandleMessage_UnchangedContent(t *testing.T) {
+	s := new(MockVectorStore)
+	u := new(MockUpdater)
+	sf := new(MockSourceFetcher)
+	pm := new(MockPageManager)
+	tp := new(MockTaskPublisher)
+
+	consumer := worker.NewResultConsumer(s, u, nil, sf, pm, tp)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"source_id": "src1",
+		"url":       "http://example.com",
+		"content":   "Same content as last sync",
+		"links":     []string{"http://example.com/subpage"},
+		"etag":      "\"v1\"",
+	})
+
+	sf.On("GetSourceConfig", mock.Anything, "src1").Return(2, []string{}, "", "Src", nil)
+	sf.On("GetChunkProfile", mock.Anything, "src1").Return(text.DefaultChunkProfile(), nil)
+	sf.On("GetSourceVersion", mock.Anything, "src1").Return("", nil)
+	sf.On("GetCrawlScope", mock.Anything, "src1").Return(worker.CrawlScope{}, nil)
+	pm.On("RecordPageContent", mock.Anything, "src1", "http://example.com", mock.Anything, text.DefaultChunkProfile().Key(), "\"v1\"", "").Return("unchanged", nil)
+	// Links are still followed so the pages they reach aren't removed as stale
+	pm.On("BulkCreatePages", mock.Anything, mock.MatchedBy(func(pages []worker.PageDTO) bool {
+		return len(pages) == 1 && pages[0].URL == "http://example.com/subpage"
+	})).Return([]string{}, nil)
+	pm.On("UpdatePageStatus", mock.Anything, "src1", "http://example.com", "completed", "").Return(nil)
+	pm.On("CountPendingPages", mock.Anything, "src1").Return(1, nil)
+
+	err := consumer.HandleMessage(&bus.Message{Body: body})
 	assert.NoError(t, err)
+	pm.AssertExpectations(t)
+	s.AssertNotCalled(t, "DeleteChunksByURL", mock.Anything, mock.Anything, mock.Anything)
+	tp.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+}
+
+func TestChunkKey(t *testing.T) {
+	profile := text.DefaultChunkProfile()
+	assert.Equal(t, profile.Key(), worker.ChunkKey(profile, ""))
+	assert.NotEqual(t, worker.ChunkKey(profile, "v1.x"), worker.ChunkKey(profile, "v2.x"))
 }
@@ -0,0 +1,70 @@
+package worker
+
+import (
+	"time"
+
+	"qurio/apps/backend/internal/bus"
+)
+
+// RetryPolicy bounds how often a consumer attempts a message and how long it
+// waits between attempts. The delay doubles with every attempt.
+type RetryPolicy struct {
+	MaxAttempts  uint16 // 0 retries forever
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+}
+
+// NewRetryPolicy builds a policy from the millisecond settings in config.
+func NewRetryPolicy(maxAttempts, initialDelayMS, maxDelayMS int) RetryPolicy {
+	if maxAttempts < 0 {
+		maxAttempts = 0
+	}
+	if maxAttempts > 65535 {
+		maxAttempts = 65535
+	}
+	return RetryPolicy{
+		MaxAttempts:  uint16(maxAttempts),
+		InitialDelay: time.Duration(initialDelayMS) * time.Millisecond,
+		MaxDelay:     time.Duration(maxDelayMS) * time.Millisecond,
+	}
+}
+
+// Delay returns how long to wait before retrying a message that failed its
+// attempt-th attempt (1-based).
+func (p RetryPolicy) Delay(attempt uint16) time.Duration {
+	d := p.InitialDelay
+	for i := uint16(1); i < attempt; i++ {
+		d *= 2
+		if p.MaxDelay > 0 && d >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return d
+}
+
+// Exhausted reports whether a message on its attempts-th attempt won't be
+// delivered again.
+func (p RetryPolicy) Exhausted(attempts uint16) bool {
+	return p.MaxAttempts > 0 && attempts >= p.MaxAttempts
+}
+
+// Apply sets the attempt limit and delay bound on subscription options.
+func (p RetryPolicy) Apply(opts *bus.SubscribeOptions) {
+	opts.MaxAttempts = p.MaxAttempts
+	opts.MaxRequeueDelay = p.MaxDelay
+}
+
+// WithBackoff requeues messages that h fails with the policy's exponential
+// delay instead of the bus default.
+func WithBackoff(h bus.Handler, p RetryPolicy) bus.Handler {
+	return bus.HandlerFunc(func(m *bus.Message) error {
+		err := h.HandleMessage(m)
+		if err != nil && bus.RequeueDelay(err) < 0 {
+			return bus.Requeue(err, p.Delay(m.Attempts))
+		}
+		return err
+	})
+}
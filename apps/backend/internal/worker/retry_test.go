@@ -0,0 +1,68 @@
+package worker_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"qurio/apps/backend/internal/bus"
+	"qurio/apps/backend/internal/worker"
+)
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	p := worker.NewRetryPolicy(5, 1000, 5000)
+
+	assert.Equal(t, time.Second, p.Delay(1))
+	assert.Equal(t, 2*time.Second, p.Delay(2))
+	assert.Equal(t, 4*time.Second, p.Delay(3))
+	assert.Equal(t, 5*time.Second, p.Delay(4))
+	assert.Equal(t, 5*time.Second, p.Delay(60000))
+}
+
+func TestRetryPolicy_Exhausted(t *testing.T) {
+	p := worker.NewRetryPolicy(3, 1000, 5000)
+	assert.False(t, p.Exhausted(2))
+	assert.True(t, p.Exhausted(3))
+
+	unbounded := worker.NewRetryPolicy(0, 1000, 5000)
+	assert.False(t, unbounded.Exhausted(1000))
+}
+
+func TestRetryPolicy_Apply(t *testing.T) {
+	opts := bus.SubscribeOptions{Channel: "backend"}
+	worker.NewRetryPolicy(7, 2000, 60000).Apply(&opts)
+
+	assert.Equal(t, uint16(7), opts.MaxAttempts)
+	assert.Equal(t, time.Minute, opts.MaxRequeueDelay)
+}
+
+func TestWithBackoff(t *testing.T) {
+	p := worker.NewRetryPolicy(5, 1000, 60000)
+
+	t.Run("RequeuesWithExponentialDelay", func(t *testing.T) {
+		h := worker.WithBackoff(bus.HandlerFunc(func(m *bus.Message) error {
+			return errors.New("boom")
+		}), p)
+
+		err := h.HandleMessage(&bus.Message{Attempts: 3})
+		assert.EqualError(t, err, "boom")
+		assert.Equal(t, 4*time.Second, bus.RequeueDelay(err))
+	})
+
+	t.Run("KeepsHandlerDelay", func(t *testing.T) {
+		h := worker.WithBackoff(bus.HandlerFunc(func(m *bus.Message) error {
+			return bus.Requeue(errors.New("later"), time.Minute)
+		}), p)
+
+		assert.Equal(t, time.Minute, bus.RequeueDelay(h.HandleMessage(&bus.Message{Attempts: 1})))
+	})
+
+	t.Run("PassesSuccess", func(t *testing.T) {
+		h := worker.WithBackoff(bus.HandlerFunc(func(m *bus.Message) error {
+			return nil
+		}), p)
+
+		assert.NoError(t, h.HandleMessage(&bus.Message{Attempts: 1}))
+	})
+}
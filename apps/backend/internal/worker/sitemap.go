@@ -0,0 +1,243 @@
+package worker
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// maxSitemaps bounds how many sitemap documents one discovery fetches,
+	// following indexes included.
+	maxSitemaps = 50
+	// maxSitemapBytes caps a single (decompressed) sitemap document.
+	maxSitemapBytes = 50 << 20
+)
+
+// SitemapFetcher retrieves a sitemap document.
+type SitemapFetcher interface {
+	FetchSitemap(ctx context.Context, url string) ([]byte, error)
+}
+
+// HTTPSitemapFetcher fetches sitemaps over HTTP.
+type HTTPSitemapFetcher struct {
+	client *http.Client
+}
+
+func NewHTTPSitemapFetcher(timeout time.Duration) *HTTPSitemapFetcher {
+	return &HTTPSitemapFetcher{client: &http.Client{Timeout: timeout}}
+}
+
+func (f *HTTPSitemapFetcher) FetchSitemap(ctx context.Context, sitemapURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sitemapURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch %s: status %d", sitemapURL, resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxSitemapBytes))
+}
+
+// SitemapEntry is a page listed in a sitemap. LastMod is nil when the
+// sitemap doesn't say.
+type SitemapEntry struct {
+	URL     string
+	LastMod *time.Time
+}
+
+type sitemapDoc struct {
+	XMLName  xml.Name
+	URLs     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+type sitemapLoc struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// ParseSitemap reads a urlset or a sitemap index, gzipped or not, returning
+// the pages it lists and the child sitemaps of an index.
+func ParseSitemap(data []byte) ([]SitemapEntry, []string, error) {
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, err
+		}
+		defer zr.Close()
+		if data, err = io.ReadAll(io.LimitReader(zr, maxSitemapBytes)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var doc sitemapDoc
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, err
+	}
+	switch doc.XMLName.Local {
+	case "urlset":
+		entries := make([]SitemapEntry, 0, len(doc.URLs))
+		for _, u := range doc.URLs {
+			if loc := strings.TrimSpace(u.Loc); loc != "" {
+				entries = append(entries, SitemapEntry{URL: loc, LastMod: parseLastMod(u.LastMod)})
+			}
+		}
+		return entries, nil, nil
+	case "sitemapindex":
+		var children []string
+		for _, s := range doc.Sitemaps {
+			if loc := strings.TrimSpace(s.Loc); loc != "" {
+				children = append(children, loc)
+			}
+		}
+		return nil, children, nil
+	default:
+		return nil, nil, fmt.Errorf("not a sitemap: <%s>", doc.XMLName.Local)
+	}
+}
+
+// lastModLayouts are the W3C datetime forms sitemaps use.
+var lastModLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04Z07:00", "2006-01-02"}
+
+func parseLastMod(s string) *time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range lastModLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
+// IsSitemapURL reports whether u points at a sitemap rather than a page.
+func IsSitemapURL(u string) bool {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+	p := strings.ToLower(parsed.Path)
+	return strings.Contains(p, "sitemap") && (strings.HasSuffix(p, ".xml") || strings.HasSuffix(p, ".xml.gz"))
+}
+
+// SitemapDiscoverer walks sitemaps and their indexes.
+type SitemapDiscoverer struct {
+	fetcher SitemapFetcher
+}
+
+func NewSitemapDiscoverer(f SitemapFetcher) *SitemapDiscoverer {
+	return &SitemapDiscoverer{fetcher: f}
+}
+
+// Discover returns the pages listed by sitemapURL, following index files up
+// to maxSitemaps documents. Only children on the host of sitemapURL or one of
+// allowedHosts are followed, so an index can't point the server at internal
+// addresses. Children that fail to load are logged and skipped; only a
+// failure of sitemapURL itself is returned.
+func (d *SitemapDiscoverer) Discover(ctx context.Context, sitemapURL string, allowedHosts []string) ([]SitemapEntry, error) {
+	root, err := url.Parse(sitemapURL)
+	if err != nil {
+		return nil, err
+	}
+	var entries []SitemapEntry
+	queue := []string{sitemapURL}
+	seen := map[string]bool{sitemapURL: true}
+
+	for fetched := 0; len(queue) > 0 && fetched < maxSitemaps; fetched++ {
+		current := queue[0]
+		queue = queue[1:]
+
+		data, err := d.fetcher.FetchSitemap(ctx, current)
+		if err == nil {
+			var children []string
+			var found []SitemapEntry
+			if found, children, err = ParseSitemap(data); err == nil {
+				entries = append(entries, found...)
+				for _, child := range children {
+					if seen[child] {
+						continue
+					}
+					seen[child] = true
+					if !childSitemapAllowed(root.Host, allowedHosts, child) {
+						slog.WarnContext(ctx, "skipping sitemap on another host", "url", child, "index", current)
+						continue
+					}
+					queue = append(queue, child)
+				}
+				continue
+			}
+		}
+		if current == sitemapURL {
+			return nil, err
+		}
+		slog.WarnContext(ctx, "skipping unreadable sitemap", "url", current, "error", err)
+	}
+	if len(queue) > 0 {
+		slog.WarnContext(ctx, "sitemap limit reached", "url", sitemapURL, "limit", maxSitemaps, "unfetched", len(queue))
+	}
+	return entries, nil
+}
+
+// childSitemapAllowed reports whether an index may send discovery to child:
+// over http(s), on the root sitemap's host or an allowed one.
+func childSitemapAllowed(rootHost string, allowedHosts []string, child string) bool {
+	u, err := url.Parse(child)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	if strings.EqualFold(u.Host, rootHost) {
+		return true
+	}
+	for _, pattern := range allowedHosts {
+		if MatchHost(pattern, u.Host) {
+			return true
+		}
+	}
+	return false
+}
+
+// SitemapPages canonicalizes sitemap entries and returns the in-scope ones
+// as pending pages at depth, without duplicates.
+func SitemapPages(sourceID string, scope CrawlScope, entries []SitemapEntry, depth int) []PageDTO {
+	compiled := scope.compile()
+	var pages []PageDTO
+	seen := make(map[string]bool)
+
+	for _, e := range entries {
+		u, err := url.Parse(e.URL)
+		if err != nil {
+			continue
+		}
+		canon := scope.Canonicalizer.canonicalURL(u)
+		if !compiled.allows(canon) {
+			continue
+		}
+		link := canon.String()
+		if seen[link] {
+			continue
+		}
+		seen[link] = true
+
+		pages = append(pages, PageDTO{
+			SourceID: sourceID,
+			URL:      link,
+			Status:   "pending",
+			Depth:    depth,
+			LastMod:  e.LastMod,
+		})
+	}
+	return pages
+}
@@ -0,0 +1,158 @@
+package worker_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"qurio/apps/backend/internal/worker"
+)
+
+// staticFetcher serves sitemaps from memory.
+type staticFetcher map[string]string
+
+func (f staticFetcher) FetchSitemap(ctx context.Context, url string) ([]byte, error) {
+	body, ok := f[url]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return []byte(body), nil
+}
+
+// recordingFetcher serves sitemaps from memory and records what was fetched.
+type recordingFetcher struct {
+	docs    map[string]string
+	fetched []string
+}
+
+func (f *recordingFetcher) FetchSitemap(ctx context.Context, url string) ([]byte, error) {
+	f.fetched = append(f.fetched, url)
+	return staticFetcher(f.docs).FetchSitemap(ctx, url)
+}
+
+const urlset = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>https://example.com/docs/a</loc><lastmod>2025-01-02</lastmod></url>
+  <url><loc> https://example.com/docs/b </loc><lastmod>2025-01-03T10:00:00+00:00</lastmod></url>
+  <url><loc>https://example.com/blog/c</loc></url>
+</urlset>`
+
+func TestParseSitemap(t *testing.T) {
+	t.Run("URLSet", func(t *testing.T) {
+		entries, children, err := worker.ParseSitemap([]byte(urlset))
+		require.NoError(t, err)
+		assert.Empty(t, children)
+		require.Len(t, entries, 3)
+		assert.Equal(t, "https://example.com/docs/b", entries[1].URL)
+		assert.Equal(t, time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC), *entries[0].LastMod)
+		assert.True(t, entries[1].LastMod.Equal(time.Date(2025, 1, 3, 10, 0, 0, 0, time.UTC)))
+		assert.Nil(t, entries[2].LastMod)
+	})
+
+	t.Run("Index", func(t *testing.T) {
+		entries, children, err := worker.ParseSitemap([]byte(`<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>https://example.com/sitemap-1.xml</loc></sitemap>
+  <sitemap><loc>https://example.com/sitemap-2.xml.gz</loc></sitemap>
+</sitemapindex>`))
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+		assert.Equal(t, []string{"https://example.com/sitemap-1.xml", "https://example.com/sitemap-2.xml.gz"}, children)
+	})
+
+	t.Run("Gzipped", func(t *testing.T) {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write([]byte(urlset))
+		zw.Close()
+
+		entries, _, err := worker.ParseSitemap(buf.Bytes())
+		require.NoError(t, err)
+		assert.Len(t, entries, 3)
+	})
+
+	t.Run("NotASitemap", func(t *testing.T) {
+		_, _, err := worker.ParseSitemap([]byte(`<html><body>404</body></html>`))
+		assert.Error(t, err)
+	})
+}
+
+func TestSitemapDiscoverer_Discover(t *testing.T) {
+	fetcher := staticFetcher{
+		"https://example.com/sitemap.xml": `<sitemapindex>
+  <sitemap><loc>https://example.com/sitemap-docs.xml</loc></sitemap>
+  <sitemap><loc>https://example.com/sitemap-missing.xml</loc></sitemap>
+  <sitemap><loc>https://example.com/sitemap.xml</loc></sitemap>
+</sitemapindex>`,
+		"https://example.com/sitemap-docs.xml": urlset,
+	}
+	d := worker.NewSitemapDiscoverer(fetcher)
+
+	entries, err := d.Discover(context.Background(), "https://example.com/sitemap.xml", nil)
+	require.NoError(t, err) // the missing child is skipped
+	assert.Len(t, entries, 3)
+
+	_, err = d.Discover(context.Background(), "https://other.com/sitemap.xml", nil)
+	assert.Error(t, err)
+}
+
+func TestSitemapDiscoverer_Discover_ChildHosts(t *testing.T) {
+	fetcher := &recordingFetcher{docs: map[string]string{
+		"https://example.com/sitemap.xml": `<sitemapindex>
+  <sitemap><loc>http://169.254.169.254/latest/meta-data/sitemap.xml</loc></sitemap>
+  <sitemap><loc>http://localhost:8080/sitemap.xml</loc></sitemap>
+  <sitemap><loc>file:///etc/sitemap.xml</loc></sitemap>
+  <sitemap><loc>https://docs.example.com/sitemap.xml</loc></sitemap>
+  <sitemap><loc>https://example.com/sitemap-docs.xml</loc></sitemap>
+</sitemapindex>`,
+		"https://example.com/sitemap-docs.xml": urlset,
+		"https://docs.example.com/sitemap.xml": urlset,
+	}}
+	d := worker.NewSitemapDiscoverer(fetcher)
+
+	// Only the seed host and allowed hosts are fetched
+	_, err := d.Discover(context.Background(), "https://example.com/sitemap.xml", []string{"*.example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"https://example.com/sitemap.xml",
+		"https://docs.example.com/sitemap.xml",
+		"https://example.com/sitemap-docs.xml",
+	}, fetcher.fetched)
+
+	fetcher.fetched = nil
+	_, err = d.Discover(context.Background(), "https://example.com/sitemap.xml", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com/sitemap.xml", "https://example.com/sitemap-docs.xml"}, fetcher.fetched)
+}
+
+func TestSitemapPages(t *testing.T) {
+	entries, _, err := worker.ParseSitemap([]byte(urlset))
+	require.NoError(t, err)
+	entries = append(entries,
+		worker.SitemapEntry{URL: "https://example.com/docs/a/"},
+		worker.SitemapEntry{URL: "https://elsewhere.com/docs/x"},
+	)
+
+	pages := worker.SitemapPages("src1", worker.CrawlScope{
+		AllowedHosts: []string{"example.com"},
+		Include:      []string{"/docs/"},
+		Exclude:      []string{"/b$"},
+	}, entries, 1)
+
+	require.Len(t, pages, 1)
+	assert.Equal(t, "https://example.com/docs/a", pages[0].URL)
+	assert.Equal(t, "pending", pages[0].Status)
+	assert.Equal(t, 1, pages[0].Depth)
+	assert.NotNil(t, pages[0].LastMod)
+}
+
+func TestIsSitemapURL(t *testing.T) {
+	assert.True(t, worker.IsSitemapURL("https://example.com/sitemap.xml"))
+	assert.True(t, worker.IsSitemapURL("https://example.com/docs/sitemap-index.xml.gz"))
+	assert.False(t, worker.IsSitemapURL("https://example.com/sitemap"))
+	assert.False(t, worker.IsSitemapURL("https://example.com/feed.xml"))
+}
@@ -18,8 +18,13 @@ import (
 
 type MockChunkStore struct {}
 func (m *MockChunkStore) GetChunks(ctx context.Context, sourceID string, limit, offset int) ([]worker.Chunk, error) { return nil, nil }
+func (m *MockChunkStore) GetChunksWithVectorsAfter(ctx context.Context, sourceID, after string, limit int) ([]worker.Chunk, error) { return nil, nil }
+func (m *MockChunkStore) StoreChunk(ctx context.Context, chunk worker.Chunk) error { return nil }
 func (m *MockChunkStore) DeleteChunksBySourceID(ctx context.Context, sourceID string) error { return nil }
+func (m *MockChunkStore) DeleteChunksByURL(ctx context.Context, sourceID, url string) error { return nil }
 func (m *MockChunkStore) CountChunksBySource(ctx context.Context, sourceID string) (int, error) { return 0, nil }
+func (m *MockChunkStore) StoreVersionChunks(ctx context.Context, chunks []worker.Chunk) error { return nil }
+func (m *MockChunkStore) DeleteVersionChunks(ctx context.Context, sourceID, version string) error { return nil }
 
 type MockSettings struct {}
 func (m *MockSettings) Get(ctx context.Context) (*settings.Settings, error) { return nil, nil }
@@ -2,9 +2,14 @@ package worker
 
 import (
 	"context"
+	"errors"
+
+	"qurio/apps/backend/internal/text"
 )
 
 type Chunk struct {
+	// ID is the stored object's ID, set on chunks read back for paging
+	ID         string    `json:"-"`
 	Content    string    `json:"content"`
 	Vector     []float32 `json:"vector"`
 	SourceURL  string    `json:"source_url"`
@@ -13,16 +18,23 @@ type Chunk struct {
 	ChunkIndex int       `json:"chunk_index"`
 	Type       string    `json:"type"`
 	Language   string    `json:"language"`
+	Symbol     string    `json:"symbol"`
+	Section    string    `json:"section"`
 	Title      string    `json:"title"`
 	Author     string    `json:"author"`
 	CreatedAt  string    `json:"created_at"`
 	PageCount  int       `json:"page_count"`
+	Version    string    `json:"version,omitempty"`
 }
 
 type Embedder interface {
 	Embed(ctx context.Context, text string) ([]float32, error)
 }
 
+// ErrTooManyGroups is returned by grouped chunk counts with more distinct
+// values than the store can return at once.
+var ErrTooManyGroups = errors.New("too many groups to count")
+
 type VectorStore interface {
 	StoreChunk(ctx context.Context, chunk Chunk) error
 	DeleteChunksByURL(ctx context.Context, sourceID, url string) error
@@ -30,10 +42,20 @@ type VectorStore interface {
 
 type SourceStatusUpdater interface {
 	UpdateStatus(ctx context.Context, id, status string) error
-	UpdateBodyHash(ctx context.Context, id, hash string) error
+	// CompleteSync marks the source completed once its crawl has finished,
+	// removing what the crawl no longer found.
+	CompleteSync(ctx context.Context, id string) error
 }
 
 type SourceFetcher interface {
 	GetSourceDetails(ctx context.Context, id string) (string, string, error)
 	GetSourceConfig(ctx context.Context, id string) (int, []string, string, string, error)
+	GetChunkProfile(ctx context.Context, id string) (text.ChunkProfile, error)
+	GetCrawlScope(ctx context.Context, id string) (CrawlScope, error)
+	GetCrawlBudget(ctx context.Context, id string) (CrawlBudget, error)
+	// GetSourceVersion returns the version label new chunks are stored under.
+	GetSourceVersion(ctx context.Context, id string) (string, error)
+	// GetSeeds returns the URLs the source's crawl starts from; pages of a
+	// sitemap the user gave are crawled at depth 0 too but aren't seeds.
+	GetSeeds(ctx context.Context, id string) ([]string, error)
 }
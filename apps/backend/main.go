@@ -2,19 +2,23 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
+	"qurio/apps/backend/features/source"
 	"qurio/apps/backend/internal/app"
+	"qurio/apps/backend/internal/bus"
 	"qurio/apps/backend/internal/config"
+	"qurio/apps/backend/internal/lifecycle"
 	"qurio/apps/backend/internal/logger"
 	"qurio/apps/backend/internal/scheduler"
-
-	"github.com/nsqio/go-nsq"
+	"qurio/apps/backend/internal/worker"
 )
 
 func main() {
@@ -40,7 +44,7 @@ func main() {
 }
 
 func run(ctx context.Context, cfg *config.Config, logger *slog.Logger) error {
-	// 2. Bootstrap Infrastructure (DB, Weaviate, NSQ Producer, Migrations)
+	// 2. Bootstrap Infrastructure (DB, Weaviate, Message Bus, Migrations)
 	deps, err := app.Bootstrap(ctx, cfg)
 	if err != nil {
 		return fmt.Errorf("bootstrap failed: %w", err)
@@ -48,96 +52,165 @@ func run(ctx context.Context, cfg *config.Config, logger *slog.Logger) error {
 	defer deps.DB.Close()
 
 	// 3. Initialize App
-	application, err := app.New(cfg, deps.DB, deps.VectorStore, deps.NSQProducer, logger, nil)
+	application, err := app.New(cfg, deps.DB, deps.VectorStore, deps.Bus, logger, nil)
 	if err != nil {
 		return fmt.Errorf("failed to initialize app: %w", err)
 	}
+	lc := application.Lifecycle
 
 	// 4. Worker (Result Consumer) Setup
-	nsqCfg := nsq.NewConfig()
-	// nsqCfg.MaxMsgSize = cfg.NSQMaxMsgSize // Field undefined in go-nsq v1.1.0
-	consumer, err := nsq.NewConsumer("ingest.result", "backend", nsqCfg)
-	if err != nil {
-		slog.Error("failed to create NSQ consumer for results", "error", err)
-	} else {
-		// Use AddConcurrentHandlers
-		consumer.AddConcurrentHandlers(nsq.HandlerFunc(func(m *nsq.Message) error {
-			return application.ResultConsumer.HandleMessage(m)
-		}), cfg.IngestionConcurrency)
-
-		// Connect to Lookupd or NSQD
-		if cfg.NSQLookupd != "" {
-			if err := consumer.ConnectToNSQLookupd(cfg.NSQLookupd); err != nil {
-				slog.Error("failed to connect to NSQLookupd", "error", err)
-			} else {
-				slog.Info("NSQ Result Consumer connected via Lookupd", "lookupd", cfg.NSQLookupd, "concurrency", cfg.IngestionConcurrency)
-			}
-		} else if cfg.NSQDHost != "" {
-			if err := consumer.ConnectToNSQD(cfg.NSQDHost); err != nil {
-				slog.Error("failed to connect to NSQD", "error", err)
-			} else {
-				slog.Info("NSQ Result Consumer connected via NSQD", "nsqd", cfg.NSQDHost, "concurrency", cfg.IngestionConcurrency)
-			}
-		}
+	// Each consumer gets its own options so attempts and backoff can differ.
+	resultRetry := worker.NewRetryPolicy(cfg.ResultMaxAttempts, cfg.ResultRetryInitialDelayMS, cfg.ConsumerRetryMaxDelayMS)
+	resultOpts := bus.SubscribeOptions{Channel: "backend", Concurrency: cfg.IngestionConcurrency}
+	resultRetry.Apply(&resultOpts)
+	if _, err := deps.Bus.Subscribe(config.TopicIngestResult, resultOpts, worker.WithBackoff(application.ResultConsumer, resultRetry)); err != nil {
+		slog.Error("failed to subscribe result consumer", "error", err)
 	}
 
 	// 5. Worker (Embedder Consumer) Setup
 	if application.EmbedderConsumer != nil {
-		consumer, err := nsq.NewConsumer(config.TopicIngestEmbed, "backend-embedder", nsqCfg)
+		embedRetry := worker.NewRetryPolicy(cfg.EmbedMaxAttempts, cfg.EmbedRetryInitialDelayMS, cfg.ConsumerRetryMaxDelayMS)
+		embedOpts := bus.SubscribeOptions{Channel: "backend-embedder", Concurrency: max(cfg.IngestionConcurrency, 1)}
+		embedRetry.Apply(&embedOpts)
+		sub, err := deps.Bus.Subscribe(config.TopicIngestEmbed, embedOpts, worker.WithBackoff(application.EmbedderConsumer, embedRetry))
 		if err != nil {
-			slog.Error("failed to create NSQ consumer for embed", "error", err)
-		} else {
-			consumer.AddConcurrentHandlers(nsq.HandlerFunc(func(m *nsq.Message) error {
-				return application.EmbedderConsumer.HandleMessage(m)
-			}), cfg.IngestionConcurrency)
-
-			if cfg.NSQLookupd != "" {
-				if err := consumer.ConnectToNSQLookupd(cfg.NSQLookupd); err != nil {
-					slog.Error("failed to connect Embedder Consumer to NSQLookupd", "error", err)
-				} else {
-					slog.Info("NSQ Embedder Consumer connected via Lookupd", "lookupd", cfg.NSQLookupd)
-				}
-			} else if cfg.NSQDHost != "" {
-				if err := consumer.ConnectToNSQD(cfg.NSQDHost); err != nil {
-					slog.Error("failed to connect Embedder Consumer to NSQD", "error", err)
-				} else {
-					slog.Info("NSQ Embedder Consumer connected via NSQD", "nsqd", cfg.NSQDHost)
-				}
-			}
+			slog.Error("failed to subscribe embedder consumer", "error", err)
+		} else if application.EmbedLimiter != nil {
+			// Back off in-flight messages while the embedding provider throttles us
+			application.EmbedLimiter.SetThrottler(worker.NewAdaptiveConcurrency(sub, 1, embedOpts.Concurrency))
 		}
 	}
 
-	// Background Janitor
+	// 6. Source syncs queued by the API: sitemaps, checkouts, directory scans.
+	// Only the API is configured for git and directory sources.
+	if cfg.EnableAPI {
+		syncOpts := bus.SubscribeOptions{Channel: "backend", Concurrency: max(cfg.IngestionConcurrency, 1)}
+		if _, err := deps.Bus.Subscribe(config.TopicSourceSync, syncOpts, source.NewSyncConsumer(application.SourceService)); err != nil {
+			slog.Error("failed to subscribe sync consumer", "error", err)
+		}
+	}
+
+	hooks := shutdownHooks{consumers: deps.Bus.StopConsumers, producer: deps.Bus.Stop}
+
+	// Background jobs run on their own context so shutdown can stop them
+	// after the consumers have drained.
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	var background sync.WaitGroup
+
+	// 7. Scheduler (Sync)
+	if cfg.EnableAPI { // Only run scheduler in API mode (leader)
+		sched := scheduler.New(application.SourceRepo, application.SourceService)
+		sched.Start()
+		hooks.scheduler = sched.Stop
+	}
+
+	// Background Janitor; it also reconciles Postgres <-> Weaviate drift
+	// in API mode (leader), once the reconcile interval has passed
+	reconcileEvery := time.Duration(cfg.ReconcileIntervalMinutes) * time.Minute
+	background.Add(1)
 	go func() {
+		defer background.Done()
 		ticker := time.NewTicker(5 * time.Minute)
 		defer ticker.Stop()
+		lastReconciled := time.Now()
 		for {
 			select {
-			case <-ctx.Done():
+			case <-bgCtx.Done():
 				return
-			case <-ticker.C:
-				if err := application.SourceService.ResetStuckPages(context.Background()); err != nil {
+			case now := <-ticker.C:
+				if err := application.SourceService.ResetStuckPages(bgCtx); err != nil {
 					slog.Error("failed to reset stuck pages", "error", err)
 				}
+				if cfg.EnableAPI && reconcileEvery > 0 && now.Sub(lastReconciled) >= reconcileEvery {
+					lastReconciled = now
+					if _, err := application.Reconciler.Run(bgCtx, cfg.ReconcileDeleteOrphans); err != nil {
+						slog.Error("reconciliation failed", "error", err)
+					}
+				}
 			}
 		}
 	}()
 
-	// 6. Scheduler (Sync)
-	if cfg.EnableAPI { // Only run scheduler in API mode (leader)
-		sched := scheduler.New(application.SourceRepo, application.SourceService)
-		sched.Start()
-		defer sched.Stop()
+	// Crawl dispatcher: releases discovered pages per host and within budget
+	if cfg.EnableAPI {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			application.Dispatcher.Run(bgCtx, time.Duration(cfg.CrawlDispatchIntervalSeconds)*time.Second)
+		}()
 	}
 
-	// 5. Start Server
+	// Directory watcher: resyncs watched directory sources when files change
 	if cfg.EnableAPI {
-		if err := application.Run(ctx); err != nil {
-			return fmt.Errorf("server failed: %w", err)
-		}
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			watcher := source.NewWatcher(application.SourceService, 2*time.Second)
+			if err := watcher.Run(bgCtx, 30*time.Second); err != nil {
+				slog.Error("directory watcher stopped", "error", err)
+			}
+		}()
+	}
+
+	hooks.background = func() {
+		stopBackground()
+		background.Wait()
+	}
+
+	// 8. Start Server
+	serverErr := make(chan error, 1)
+	if cfg.EnableAPI {
+		hooks.server = application.Shutdown
+		go func() {
+			serverErr <- application.Run()
+		}()
 	} else {
 		slog.Info("API disabled, running in worker mode")
-		<-ctx.Done()
 	}
-	return nil
+	registerShutdown(lc, hooks)
+	lc.SetReady(true)
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+	case err := <-serverErr:
+		if err != nil {
+			runErr = fmt.Errorf("server failed: %w", err)
+		}
+	}
+
+	// Drain consumers even when the server failed, so no page is cut off
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeoutSeconds)*time.Second)
+	defer cancel()
+	if err := lc.Shutdown(shutdownCtx); err != nil {
+		return errors.Join(runErr, fmt.Errorf("shutdown incomplete: %w", err))
+	}
+	slog.Info("shutdown complete")
+	return runErr
+}
+
+// shutdownHooks are what shutdown stops; server and scheduler are nil in
+// worker mode.
+type shutdownHooks struct {
+	server     func(ctx context.Context) error
+	consumers  func()
+	scheduler  func()
+	background func()
+	producer   func()
+}
+
+// registerShutdown stops the HTTP server and the consumers first, so no new
+// work comes in and in-flight requests and messages finish, then the jobs
+// that publish on their own. The producer goes last, once nothing is left
+// that publishes.
+func registerShutdown(lc *lifecycle.Manager, h shutdownHooks) {
+	if h.server != nil {
+		lc.OnShutdown("http server", h.server)
+	}
+	lc.OnShutdown("consumers", lifecycle.Blocking(h.consumers))
+	if h.scheduler != nil {
+		lc.OnShutdown("scheduler", lifecycle.Blocking(h.scheduler))
+	}
+	lc.OnShutdown("background jobs", lifecycle.Blocking(h.background))
+	lc.OnShutdown("producer", lifecycle.Blocking(h.producer))
 }
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"qurio/apps/backend/internal/lifecycle"
+)
+
+func TestRegisterShutdown_Order(t *testing.T) {
+	var order []string
+	record := func(name string) func() {
+		return func() { order = append(order, name) }
+	}
+
+	t.Run("API", func(t *testing.T) {
+		order = nil
+		lc := lifecycle.New(0)
+		registerShutdown(lc, shutdownHooks{
+			server: func(ctx context.Context) error {
+				record("http server")()
+				return nil
+			},
+			consumers:  record("consumers"),
+			scheduler:  record("scheduler"),
+			background: record("background jobs"),
+			producer:   record("producer"),
+		})
+		assert.NoError(t, lc.Shutdown(context.Background()))
+		assert.Equal(t, []string{"http server", "consumers", "scheduler", "background jobs", "producer"}, order)
+	})
+
+	t.Run("Worker", func(t *testing.T) {
+		order = nil
+		lc := lifecycle.New(0)
+		registerShutdown(lc, shutdownHooks{
+			consumers:  record("consumers"),
+			background: record("background jobs"),
+			producer:   record("producer"),
+		})
+		assert.NoError(t, lc.Shutdown(context.Background()))
+		assert.Equal(t, []string{"consumers", "background jobs", "producer"}, order)
+	})
+}
@@ -0,0 +1,2 @@
+ALTER TABLE settings DROP COLUMN search_properties;
+ALTER TABLE settings DROP COLUMN tokenization;
@@ -0,0 +1,2 @@
+ALTER TABLE settings ADD COLUMN IF NOT EXISTS search_properties TEXT[] DEFAULT '{"title^3","sourceName^2","content"}';
+ALTER TABLE settings ADD COLUMN IF NOT EXISTS tokenization JSONB DEFAULT '{}';
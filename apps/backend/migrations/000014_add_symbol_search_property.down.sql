@@ -0,0 +1,3 @@
+ALTER TABLE settings ALTER COLUMN search_properties SET DEFAULT '{"title^3","sourceName^2","content"}';
+UPDATE settings SET search_properties = '{"title^3","sourceName^2","content"}'
+WHERE search_properties = '{"title^3","symbol^2","sourceName^2","content"}';
@@ -0,0 +1,3 @@
+ALTER TABLE sources DROP COLUMN chunk_strategy;
+ALTER TABLE sources DROP COLUMN chunk_max_tokens;
+ALTER TABLE sources DROP COLUMN chunk_overlap;
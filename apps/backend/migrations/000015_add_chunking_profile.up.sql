@@ -0,0 +1,7 @@
+ALTER TABLE sources
+ADD COLUMN chunk_strategy TEXT NOT NULL DEFAULT 'markdown-structural';
+-- markdown-structural, fixed-window, paragraph, whole-page
+ALTER TABLE sources
+ADD COLUMN chunk_max_tokens INTEGER NOT NULL DEFAULT 512;
+ALTER TABLE sources
+ADD COLUMN chunk_overlap INTEGER NOT NULL DEFAULT 50;
@@ -0,0 +1,2 @@
+ALTER TABLE source_pages DROP COLUMN chunks_expected;
+ALTER TABLE source_pages DROP COLUMN chunks_embedded;
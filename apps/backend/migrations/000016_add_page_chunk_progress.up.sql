@@ -0,0 +1,6 @@
+ALTER TABLE source_pages
+ADD COLUMN chunks_expected INTEGER NOT NULL DEFAULT 0;
+-- Chunks queued for embedding on the last ingestion of the page
+ALTER TABLE source_pages
+ADD COLUMN chunks_embedded INTEGER NOT NULL DEFAULT 0;
+-- Pages stay in status 'embedding' until chunks_embedded reaches chunks_expected
@@ -0,0 +1,2 @@
+ALTER TABLE settings DROP COLUMN embed_requests_per_minute;
+ALTER TABLE settings DROP COLUMN embed_tokens_per_minute;
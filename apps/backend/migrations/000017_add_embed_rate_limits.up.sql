@@ -0,0 +1,2 @@
+ALTER TABLE settings ADD COLUMN IF NOT EXISTS embed_requests_per_minute INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE settings ADD COLUMN IF NOT EXISTS embed_tokens_per_minute INTEGER NOT NULL DEFAULT 0;
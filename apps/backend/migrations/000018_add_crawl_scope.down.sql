@@ -0,0 +1,4 @@
+ALTER TABLE sources DROP COLUMN include_patterns;
+ALTER TABLE sources DROP COLUMN path_prefix;
+ALTER TABLE sources DROP COLUMN keep_query_params;
+ALTER TABLE sources DROP COLUMN drop_query_params;
@@ -0,0 +1,9 @@
+ALTER TABLE sources
+ADD COLUMN include_patterns TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE sources
+ADD COLUMN path_prefix TEXT NOT NULL DEFAULT '';
+-- Query parameters kept (allow-list) or dropped when canonicalizing URLs
+ALTER TABLE sources
+ADD COLUMN keep_query_params TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE sources
+ADD COLUMN drop_query_params TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,2 @@
+ALTER TABLE sources DROP COLUMN seed_urls;
+ALTER TABLE sources DROP COLUMN allowed_hosts;
@@ -0,0 +1,5 @@
+ALTER TABLE sources
+ADD COLUMN seed_urls TEXT[] NOT NULL DEFAULT '{}';
+-- Hosts links may reach besides the seeds' hosts, e.g. *.example.com
+ALTER TABLE sources
+ADD COLUMN allowed_hosts TEXT[] NOT NULL DEFAULT '{}';
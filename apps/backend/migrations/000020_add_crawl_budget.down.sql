@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_source_pages_pending;
+ALTER TABLE sources DROP COLUMN max_pages;
+ALTER TABLE sources DROP COLUMN max_crawl_minutes;
+ALTER TABLE sources DROP COLUMN crawl_started_at;
@@ -0,0 +1,9 @@
+ALTER TABLE sources
+ADD COLUMN max_pages INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE sources
+ADD COLUMN max_crawl_minutes INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE sources
+ADD COLUMN crawl_started_at TIMESTAMP WITH TIME ZONE;
+
+-- The dispatcher picks pending pages per source, shallowest first
+CREATE INDEX IF NOT EXISTS idx_source_pages_pending ON source_pages (source_id, depth, created_at) WHERE status = 'pending';
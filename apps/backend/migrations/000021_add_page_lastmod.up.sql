@@ -0,0 +1,2 @@
+ALTER TABLE source_pages
+ADD COLUMN lastmod TIMESTAMP WITH TIME ZONE;
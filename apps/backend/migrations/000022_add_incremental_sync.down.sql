@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS source_syncs;
+ALTER TABLE source_pages DROP COLUMN content_hash;
+ALTER TABLE source_pages DROP COLUMN etag;
+ALTER TABLE source_pages DROP COLUMN last_modified;
+ALTER TABLE source_pages DROP COLUMN change;
@@ -0,0 +1,22 @@
+-- Per-page change detection: a resync only re-embeds pages whose content hash moved
+ALTER TABLE source_pages
+ADD COLUMN content_hash TEXT;
+ALTER TABLE source_pages
+ADD COLUMN etag TEXT;
+ALTER TABLE source_pages
+ADD COLUMN last_modified TEXT;
+ALTER TABLE source_pages
+ADD COLUMN change TEXT; -- added, changed or unchanged in the current sync
+
+CREATE TABLE IF NOT EXISTS source_syncs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    source_id UUID NOT NULL REFERENCES sources(id) ON DELETE CASCADE,
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    pages_added INTEGER NOT NULL DEFAULT 0,
+    pages_changed INTEGER NOT NULL DEFAULT 0,
+    pages_unchanged INTEGER NOT NULL DEFAULT 0,
+    pages_removed INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_source_syncs_source ON source_syncs (source_id, finished_at DESC);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS source_version_pages;
+DROP TABLE IF EXISTS source_versions;
+ALTER TABLE sources DROP COLUMN keep_versions;
+ALTER TABLE sources DROP COLUMN version;
@@ -0,0 +1,23 @@
+-- A version label per source; with keep_versions > 0 the content of a
+-- replaced version is kept as a searchable snapshot
+ALTER TABLE sources
+ADD COLUMN version TEXT NOT NULL DEFAULT '';
+ALTER TABLE sources
+ADD COLUMN keep_versions INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS source_versions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    source_id UUID NOT NULL REFERENCES sources(id) ON DELETE CASCADE,
+    version TEXT NOT NULL,
+    page_count INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (source_id, version)
+);
+
+-- The pages of a snapshot with their content hashes, for diffing versions
+CREATE TABLE IF NOT EXISTS source_version_pages (
+    version_id UUID NOT NULL REFERENCES source_versions(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    content_hash TEXT,
+    PRIMARY KEY (version_id, url)
+);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS collection_sources;
+DROP TABLE IF EXISTS collections;
+DROP TABLE IF EXISTS source_tags;
+DROP TABLE IF EXISTS tags;
@@ -0,0 +1,32 @@
+-- Free-form tags on sources
+CREATE TABLE IF NOT EXISTS tags (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS source_tags (
+    source_id UUID NOT NULL REFERENCES sources(id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (source_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_source_tags_tag_id ON source_tags(tag_id);
+
+-- Named collections of sources; searches can be scoped to one
+CREATE TABLE IF NOT EXISTS collections (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS collection_sources (
+    collection_id UUID NOT NULL REFERENCES collections(id) ON DELETE CASCADE,
+    source_id UUID NOT NULL REFERENCES sources(id) ON DELETE CASCADE,
+    added_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (collection_id, source_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_collection_sources_source_id ON collection_sources(source_id);
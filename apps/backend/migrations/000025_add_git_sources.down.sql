@@ -0,0 +1,3 @@
+ALTER TABLE sources DROP COLUMN include_globs;
+ALTER TABLE sources DROP COLUMN commit_sha;
+ALTER TABLE sources DROP COLUMN git_ref;
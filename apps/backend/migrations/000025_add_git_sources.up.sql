@@ -0,0 +1,8 @@
+-- Git sources check out git_ref and ingest the files matching include_globs;
+-- commit_sha is the commit last synced, resyncs only process files changed since
+ALTER TABLE sources
+ADD COLUMN git_ref TEXT NOT NULL DEFAULT '';
+ALTER TABLE sources
+ADD COLUMN commit_sha TEXT NOT NULL DEFAULT '';
+ALTER TABLE sources
+ADD COLUMN include_globs TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,4 @@
+ALTER TABLE source_pages DROP COLUMN file_hash;
+ALTER TABLE source_pages DROP COLUMN mtime;
+ALTER TABLE sources DROP COLUMN watch;
+ALTER TABLE sources DROP COLUMN exclude_globs;
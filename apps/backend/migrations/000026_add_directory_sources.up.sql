@@ -0,0 +1,12 @@
+-- Directory sources walk a path with include and exclude globs; watch
+-- resyncs them when files change
+ALTER TABLE sources
+ADD COLUMN exclude_globs TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE sources
+ADD COLUMN watch BOOLEAN NOT NULL DEFAULT false;
+
+-- The modification time and hash of a file page, to skip unchanged files
+ALTER TABLE source_pages
+ADD COLUMN mtime TIMESTAMP WITH TIME ZONE;
+ALTER TABLE source_pages
+ADD COLUMN file_hash TEXT;
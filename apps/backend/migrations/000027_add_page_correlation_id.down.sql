@@ -0,0 +1 @@
+ALTER TABLE source_pages DROP COLUMN correlation_id;
@@ -0,0 +1,4 @@
+-- The correlation ID of the request whose crawl discovered a page, carried
+-- into the task the dispatcher publishes for it
+ALTER TABLE source_pages
+ADD COLUMN correlation_id TEXT NOT NULL DEFAULT '';
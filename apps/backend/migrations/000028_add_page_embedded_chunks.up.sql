@@ -0,0 +1,4 @@
+-- The indexes of a page's chunks stored so far, so a redelivered embed
+-- message isn't counted twice
+ALTER TABLE source_pages
+ADD COLUMN embedded_chunks INTEGER[] NOT NULL DEFAULT '{}';
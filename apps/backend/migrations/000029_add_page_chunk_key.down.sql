@@ -0,0 +1,2 @@
+ALTER TABLE sources ADD COLUMN IF NOT EXISTS body_hash TEXT;
+ALTER TABLE source_pages DROP COLUMN IF EXISTS chunk_key;
@@ -0,0 +1,8 @@
+-- The chunk profile a page's chunks were made with, so a resync re-chunks
+-- pages whose content is unchanged when the profile isn't. Pages chunked
+-- before this have none and are re-chunked once.
+ALTER TABLE source_pages ADD COLUMN IF NOT EXISTS chunk_key TEXT;
+
+-- One hash per source, overwritten by whichever page finished last; the
+-- per-page hashes replaced it
+ALTER TABLE sources DROP COLUMN IF EXISTS body_hash;
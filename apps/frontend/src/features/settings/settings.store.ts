@@ -7,6 +7,10 @@ export const useSettingsStore = defineStore('settings', () => {
   const geminiApiKey = ref('')
   const searchAlpha = ref(0.5)
   const searchTopK = ref(20)
+  const searchProperties = ref<string[]>([])
+  const tokenization = ref<Record<string, string>>({})
+  const embedRequestsPerMinute = ref(0)
+  const embedTokensPerMinute = ref(0)
   const isLoading = ref(false)
   const error = ref<string | null>(null)
   const successMessage = ref<string | null>(null)
@@ -24,6 +28,10 @@ export const useSettingsStore = defineStore('settings', () => {
       geminiApiKey.value = data.gemini_api_key || ''
       searchAlpha.value = data.search_alpha ?? 0.5
       searchTopK.value = data.search_top_k ?? 20
+      searchProperties.value = data.search_properties || []
+      tokenization.value = data.tokenization || {}
+      embedRequestsPerMinute.value = data.embed_requests_per_minute ?? 0
+      embedTokensPerMinute.value = data.embed_tokens_per_minute ?? 0
     } catch (e: any) { // eslint-disable-line @typescript-eslint/no-explicit-any
       error.value = e.message
     } finally {
@@ -45,6 +53,10 @@ export const useSettingsStore = defineStore('settings', () => {
           gemini_api_key: geminiApiKey.value,
           search_alpha: searchAlpha.value,
           search_top_k: searchTopK.value,
+          search_properties: searchProperties.value,
+          tokenization: tokenization.value,
+          embed_requests_per_minute: embedRequestsPerMinute.value,
+          embed_tokens_per_minute: embedTokensPerMinute.value,
         }),
       })
       if (!res.ok) throw new Error('Failed to update settings')
@@ -63,6 +75,10 @@ export const useSettingsStore = defineStore('settings', () => {
     geminiApiKey,
     searchAlpha,
     searchTopK,
+    searchProperties,
+    tokenization,
+    embedRequestsPerMinute,
+    embedTokensPerMinute,
     isLoading,
     error,
     successMessage,
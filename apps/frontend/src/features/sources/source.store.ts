@@ -22,9 +22,26 @@ export interface Source {
   updated_at?: string
   max_depth?: number
   exclusions?: string[]
+  include_patterns?: string[]
+  path_prefix?: string
+  keep_query_params?: string[]
+  drop_query_params?: string[]
+  seed_urls?: string[]
+  allowed_hosts?: string[]
+  max_pages?: number
+  max_crawl_minutes?: number
   sync_enabled?: boolean
   sync_schedule?: string
   last_synced_at?: string
+  version?: string
+  keep_versions?: number
+  git_ref?: string
+  commit_sha?: string
+  include_globs?: string[]
+  exclude_globs?: string[]
+  watch?: boolean
+  tags?: string[]
+  collections?: string[]
   chunks?: Chunk[]
   total_chunks?: number
 }
@@ -36,6 +53,8 @@ export interface SourcePage {
   status: string
   depth: number
   error?: string
+  lastmod?: string
+  change?: 'added' | 'changed' | 'unchanged'
   created_at: string
   updated_at: string
 }
@@ -134,6 +153,28 @@ export const useSourceStore = defineStore('sources', () => {
     }
   }
 
+  async function updateSource(id: string, changes: Partial<Omit<Source, 'id'>>, resync = false) {
+    isLoading.value = true
+    error.value = null
+    try {
+      const res = await fetch(`/api/sources/${id}${resync ? '?resync=true' : ''}`, {
+        method: 'PATCH',
+        headers: {
+          'Content-Type': 'application/json',
+        },
+        body: JSON.stringify(changes),
+      })
+      if (!res.ok) throw new Error(`Failed to update source: ${res.statusText}`)
+      const json = await res.json()
+      const idx = sources.value.findIndex(s => s.id === id)
+      if (idx !== -1) sources.value[idx] = { ...sources.value[idx], ...json.data }
+    } catch (e: any) { // eslint-disable-line @typescript-eslint/no-explicit-any
+      error.value = e.message || 'Unknown error'
+    } finally {
+      isLoading.value = false
+    }
+  }
+
   async function uploadSource(file: File, name: string) {
     isLoading.value = true
     error.value = null
@@ -224,6 +265,7 @@ export const useSourceStore = defineStore('sources', () => {
     addSource,
     deleteSource,
     resyncSource,
+    updateSource,
     uploadSource,
     getSource,
     getSourcePages,
@@ -174,11 +174,60 @@ executor = pebble.ProcessPool(
 # Increase timeout to 30 minutes to accommodate large PDF books with OCR
 TIMEOUT_SECONDS = 1800
 
+# Structured data is chunked by the backend from the raw text, so these
+# files skip Docling conversion.
+STRUCTURED_EXTENSIONS = {".json", ".yaml", ".yml", ".csv"}
+
+# Plain text and source code, e.g. from git sources, are read as they are;
+# code is wrapped in a fence so the backend chunks it as a code block.
+TEXT_EXTENSIONS = {".rst", ".txt"}
+CODE_LANGUAGES = {
+    ".go": "go", ".py": "python", ".js": "javascript", ".jsx": "jsx",
+    ".ts": "typescript", ".tsx": "tsx", ".java": "java", ".kt": "kotlin",
+    ".rs": "rust", ".c": "c", ".h": "c", ".cpp": "cpp", ".hpp": "cpp",
+    ".cs": "csharp", ".rb": "ruby", ".php": "php", ".swift": "swift",
+    ".scala": "scala", ".sh": "bash", ".sql": "sql", ".proto": "protobuf",
+}
+
+def read_structured_file(file_path: str, language: str | None = None) -> list[dict]:
+    with open(file_path, encoding="utf-8", errors="replace") as f:
+        content = f.read()
+
+    if not content.strip():
+        raise IngestionError(ERR_EMPTY, "File contains no text")
+
+    if language:
+        content = f"```{language}\n{content.rstrip()}\n```\n"
+
+    title = os.path.basename(file_path)
+    return [{
+        "content": content,
+        "metadata": {
+            "title": title,
+            "author": None,
+            "created_at": None,
+            "pages": 0,
+            "language": "en",
+        },
+        "url": file_path,
+        "path": file_path,
+        "title": title,
+        "links": []
+    }]
+
 async def handle_file_task(file_path: str) -> list[dict]:
     """
     Converts a document to markdown using Docling.
     Executes in a Pebble ProcessPool to enforce hard timeouts and kill stuck processes.
     """
+    ext = os.path.splitext(file_path)[1].lower()
+    if ext in STRUCTURED_EXTENSIONS or ext in TEXT_EXTENSIONS:
+        logger.info("structured_file_passthrough", path=file_path)
+        return read_structured_file(file_path)
+    if ext in CODE_LANGUAGES:
+        logger.info("code_file_passthrough", path=file_path)
+        return read_structured_file(file_path, CODE_LANGUAGES[ext])
+
     logger.info("conversion_starting", path=file_path)
     
     try:
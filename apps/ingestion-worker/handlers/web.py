@@ -34,9 +34,23 @@ INSTRUCTION = """
     - Numbered lists for sequential steps
 """
 
-def extract_web_metadata(result, url: str) -> dict:
+def host_allowed(host: str, allowed_hosts: list[str]) -> bool:
     """
-    Extracts metadata (title, path, links) from a crawl result.
+    Matches a host against allowed host patterns; "*.example.com" matches subdomains.
+    """
+    host = host.lower()
+    for pattern in allowed_hosts:
+        pattern = pattern.lower()
+        if pattern.startswith("*."):
+            if host.endswith(pattern[1:]):
+                return True
+        elif host == pattern:
+            return True
+    return False
+
+def extract_web_metadata(result, url: str, allowed_hosts: list[str] = None) -> dict:
+    """
+    Extracts metadata (title, path, links, canonical url) from a crawl result.
     """
     # Extract internal links
     # Crawl4AI result.links is usually a dictionary with 'internal' and 'external' keys
@@ -56,8 +70,9 @@ def extract_web_metadata(result, url: str) -> dict:
         for link in markdown_links:
             # Resolve relative URLs
             full_url = urljoin(url, link)
-            # Filter internal
-            if urlparse(full_url).netloc == base_domain:
+            # Filter internal (or allowed cross-host) links
+            netloc = urlparse(full_url).netloc
+            if netloc == base_domain or host_allowed(netloc, allowed_hosts or []):
                 internal_links.append(full_url)
 
     # De-duplicate
@@ -70,21 +85,39 @@ def extract_web_metadata(result, url: str) -> dict:
         if match:
             title = match.group(1).strip()
     
+    # Extract <link rel="canonical">, resolved against the page
+    canonical_url = ""
+    if isinstance(result.html, str):
+        for tag in re.findall(r'<link\b[^>]*>', result.html, re.IGNORECASE):
+            if re.search(r'rel=["\']?canonical\b', tag, re.IGNORECASE):
+                href = re.search(r'href=["\']([^"\']+)["\']', tag, re.IGNORECASE)
+                if href:
+                    canonical_url = urljoin(url, href.group(1).strip())
+                break
+
     # Extract path (breadcrumbs)
     parsed_url = urlparse(result.url)
     path_segments = [s for s in parsed_url.path.split('/') if s]
     path_str = " > ".join(path_segments)
     
+    # Validators let the backend tell a resync which pages changed
+    headers = {}
+    if isinstance(getattr(result, 'response_headers', None), dict):
+        headers = {k.lower(): v for k, v in result.response_headers.items()}
+
     return {
         "title": title,
         "path": path_str,
-        "links": internal_links
+        "links": internal_links,
+        "canonical_url": canonical_url,
+        "etag": headers.get('etag', ''),
+        "last_modified": headers.get('last-modified', '')
     }
 
 def default_crawler_factory(config=None, **kwargs):
     return AsyncWebCrawler(config=config, **kwargs)
 
-async def handle_web_task(url: str, api_key: str = None, crawler=None) -> list[dict]:
+async def handle_web_task(url: str, api_key: str = None, crawler=None, allowed_hosts: list[str] = None) -> list[dict]:
     """
     Crawls a single page and returns content and discovered internal links.
     """
@@ -142,7 +175,7 @@ async def handle_web_task(url: str, api_key: str = None, crawler=None) -> list[d
                 logger.error("crawl_failed", url=url, error=result.error_message)
                 raise Exception(f"Crawl failed: {result.error_message}")
                 
-            meta = extract_web_metadata(result, url)
+            meta = extract_web_metadata(result, url, allowed_hosts)
 
             logger.info("crawl_completed", url=url, links_found=len(meta['links']), title=meta['title'], path=meta['path'])
 
@@ -151,7 +184,10 @@ async def handle_web_task(url: str, api_key: str = None, crawler=None) -> list[d
                 "title": meta['title'],
                 "path": meta['path'],
                 "content": result.markdown,
-                "links": meta['links']
+                "links": meta['links'],
+                "canonical_url": meta['canonical_url'],
+                "etag": meta['etag'],
+                "last_modified": meta['last_modified']
             })
             return results
             
@@ -169,7 +205,7 @@ async def handle_web_task(url: str, api_key: str = None, crawler=None) -> list[d
                     logger.error("crawl_failed", url=url, error=result.error_message)
                     raise Exception(f"Crawl failed: {result.error_message}")
                     
-                meta = extract_web_metadata(result, url)
+                meta = extract_web_metadata(result, url, allowed_hosts)
 
                 logger.info("crawl_completed", url=url, links_found=len(meta['links']), title=meta['title'], path=meta['path'])
 
@@ -178,7 +214,10 @@ async def handle_web_task(url: str, api_key: str = None, crawler=None) -> list[d
                     "title": meta['title'],
                     "path": meta['path'],
                     "content": result.markdown,
-                    "links": meta['links']
+                    "links": meta['links'],
+                    "canonical_url": meta['canonical_url'],
+                    "etag": meta['etag'],
+                    "last_modified": meta['last_modified']
                 })
                 
                 return results
@@ -88,8 +88,9 @@ async def process_message(message):
                 url = data.get('url')
                 # exclusions = data.get('exclusions', []) # Deprecated/Unused
                 api_key = data.get('gemini_api_key')
+                allowed_hosts = data.get('allowed_hosts') or []
                 # Pass the global crawler
-                results_list = await handle_web_task(url, api_key=api_key, crawler=CRAWLER)
+                results_list = await handle_web_task(url, api_key=api_key, crawler=CRAWLER, allowed_hosts=allowed_hosts)
             
             elif task_type == 'file':
                 file_path = data.get('path')
@@ -107,6 +108,9 @@ async def process_message(message):
                     "path": res.get('path', ''),
                     "status": "success",
                     "links": res.get('links', []),
+                    "canonical_url": res.get('canonical_url', ''),
+                    "etag": res.get('etag', ''),
+                    "last_modified": res.get('last_modified', ''),
                     "depth": data.get('depth', 0)
                 }
                 
@@ -125,6 +129,7 @@ async def process_message(message):
                 "source_id": source_id,
                 "correlation_id": source_id,
                 "status": "failed",
+                "depth": data.get('depth', 0),
                 "error": "No content extracted",
                 "url": data.get('url', ''),
                 "content": ""
@@ -152,6 +157,7 @@ async def process_message(message):
                 "source_id": source_id,
                 "correlation_id": source_id,
                 "status": "failed",
+                "depth": data.get('depth', 0),
                 "code": error_code,
                 "error": f"[{e.code}] {e}",
                 "url": data.get('url', '') or data.get('path', ''),
@@ -207,8 +213,9 @@ async def process_message(message):
                 "source_id": source_id,
                 "correlation_id": source_id,
                 "status": "failed",
+                "depth": data.get('depth', 0),
                 "error": str(e),
-                "url": data.get('url', ''),
+                "url": data.get('url', '') or data.get('path', ''),
                 "content": "",
                 "original_payload": data
             }
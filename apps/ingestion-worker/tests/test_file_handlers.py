@@ -3,7 +3,7 @@ from unittest.mock import MagicMock, patch
 import asyncio
 from concurrent.futures import Future
 import handlers.file
-from handlers.file import handle_file_task, ERR_ENCRYPTED, ERR_INVALID_FORMAT, ERR_TIMEOUT, IngestionError
+from handlers.file import handle_file_task, ERR_EMPTY, ERR_ENCRYPTED, ERR_INVALID_FORMAT, ERR_TIMEOUT, IngestionError
 
 # Helper to create a done future for asyncio.wrap_future
 def create_done_future(result=None, exception=None):
@@ -126,4 +126,51 @@ async def test_handle_file_task_returns_list_structure():
             assert item['path'] == "/path/to/file.pdf"
             assert item['url'] == "/path/to/file.pdf"
             assert item['title'] == "Test"
-            assert item['links'] == []
\ No newline at end of file
+            assert item['links'] == []
+
+@pytest.mark.asyncio
+async def test_structured_file_skips_conversion(tmp_path):
+    """JSON, YAML and CSV uploads are passed through as raw text."""
+    spec = tmp_path / "openapi.yaml"
+    spec.write_text("openapi: 3.0.0\npaths: {}\n")
+
+    with patch.object(handlers.file, 'executor') as mock_executor:
+        result = await handle_file_task(str(spec))
+
+        mock_executor.schedule.assert_not_called()
+        assert result[0]['content'] == "openapi: 3.0.0\npaths: {}\n"
+        assert result[0]['path'] == str(spec)
+        assert result[0]['title'] == "openapi.yaml"
+
+@pytest.mark.asyncio
+async def test_structured_file_empty(tmp_path):
+    empty = tmp_path / "rows.csv"
+    empty.write_text("  \n")
+
+    with pytest.raises(IngestionError) as exc:
+        await handle_file_task(str(empty))
+    assert exc.value.code == ERR_EMPTY
+
+@pytest.mark.asyncio
+async def test_code_file_wrapped_in_fence(tmp_path):
+    """Source code is passed through as a fenced block tagged with its language."""
+    code = tmp_path / "main.go"
+    code.write_text("package main\n\nfunc main() {}\n")
+
+    with patch.object(handlers.file, 'executor') as mock_executor:
+        result = await handle_file_task(str(code))
+
+        mock_executor.schedule.assert_not_called()
+        assert result[0]['content'] == "```go\npackage main\n\nfunc main() {}\n```\n"
+        assert result[0]['title'] == "main.go"
+
+@pytest.mark.asyncio
+async def test_rst_file_passthrough(tmp_path):
+    doc = tmp_path / "index.rst"
+    doc.write_text("Title\n=====\n")
+
+    with patch.object(handlers.file, 'executor') as mock_executor:
+        result = await handle_file_task(str(doc))
+
+        mock_executor.schedule.assert_not_called()
+        assert result[0]['content'] == "Title\n=====\n"
@@ -100,6 +100,17 @@ def test_file_metadata_extraction_logic(doc_mock, result_mock, file_path, expect
         "http://e.com/",
         {"title": "Index", "path": "", "links_count": 1} # Only internal 'subpage' -> 'http://e.com/subpage'
     ),
+    # Case 4: rel=canonical hint, resolved against the page
+    (
+        MagicMock(
+            markdown="# Guide",
+            html='<head><link href="/docs/guide" rel="canonical"></head>',
+            links={},
+            url="http://e.com/print/guide"
+        ),
+        "http://e.com/print/guide",
+        {"title": "Guide", "path": "print > guide", "canonical_url": "http://e.com/docs/guide"}
+    ),
 ])
 def test_web_metadata_extraction_logic(crawl_result_mock, url, expected_meta):
     meta = extract_web_metadata(crawl_result_mock, url)
@@ -108,3 +119,15 @@ def test_web_metadata_extraction_logic(crawl_result_mock, url, expected_meta):
     assert meta['path'] == expected_meta['path']
     if 'links_count' in expected_meta:
         assert len(meta['links']) == expected_meta['links_count']
+    assert meta['canonical_url'] == expected_meta.get('canonical_url', '')
+
+
+def test_web_metadata_allowed_hosts():
+    result = MagicMock(
+        markdown="[API](https://api.e.com/ref) [Blog](https://blog.other.com/) [Docs](/docs)",
+        links={},
+        url="http://e.com/llms.txt"
+    )
+    meta = extract_web_metadata(result, "http://e.com/llms.txt", ["*.e.com"])
+
+    assert sorted(meta['links']) == ["http://e.com/docs", "https://api.e.com/ref"]